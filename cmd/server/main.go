@@ -1,47 +1,204 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"errors"
+	"flag"
+	"fmt"
 	"io/fs"
 	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"gandalf-budget/internal/app"
 	httpinternal "gandalf-budget/internal/http"
 	"gandalf-budget/internal/store"
 
+	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 //go:embed all:embedded_web_dist
 var staticFiles embed.FS
 
+// backupPassphraseEnv holds the passphrase for encrypted backups; an environment
+// variable keeps it out of the process list and shell history.
+const backupPassphraseEnv = "GANDALF_BACKUP_PASSPHRASE"
+
 func main() {
+	backupDir := flag.String("backup-dir", "backups", "directory for scheduled on-disk database backups")
+	backupInterval := flag.Duration("backup-interval", 24*time.Hour, "time between scheduled backups (0 disables scheduling)")
+	keepDaily := flag.Int("backup-keep-daily", app.DefaultRetentionPolicy.Daily, "number of daily backups to keep")
+	keepWeekly := flag.Int("backup-keep-weekly", app.DefaultRetentionPolicy.Weekly, "number of weekly backups to keep")
+	keepMonthly := flag.Int("backup-keep-monthly", app.DefaultRetentionPolicy.Monthly, "number of monthly backups to keep")
+	decryptBackup := flag.String("decrypt-backup", "", "decrypt the given .enc backup next to it using $"+backupPassphraseEnv+" and exit")
+	exportLedger := flag.String("export-ledger", "", "write a plain-text accounting journal for -ledger-year to the given file and exit")
+	ledgerFormat := flag.String("ledger-format", string(app.LedgerFormatHledger), "journal format for -export-ledger: hledger, ledger or beancount")
+	ledgerYear := flag.Int("ledger-year", time.Now().Year(), "year to export with -export-ledger")
+	ledgerAccounts := flag.String("ledger-accounts", "", "category account overrides for -export-ledger, e.g. Food=Expenses:Groceries,Home=Expenses:Housing")
+	sqliteJournalMode := flag.String("sqlite-journal-mode", store.DefaultSQLiteOptions.JournalMode, "SQLite journal_mode, e.g. WAL or DELETE")
+	sqliteSynchronous := flag.String("sqlite-synchronous", store.DefaultSQLiteOptions.Synchronous, "SQLite synchronous level: OFF, NORMAL, FULL or EXTRA")
+	sqliteBusyTimeout := flag.Duration("sqlite-busy-timeout", store.DefaultSQLiteOptions.BusyTimeout, "how long a connection waits for a locked database")
+	currency := flag.String("currency", "", "switch the budget to this ISO 4217 currency, e.g. EUR or CLP; amounts are re-rounded to its decimals, not converted. Required once when upgrading a database whose amounts predate minor units, to name their currency")
+	sqliteMaxConns := flag.Int("sqlite-max-conns", store.DefaultSQLiteOptions.MaxOpenConns, "maximum number of open database connections")
+	queryTimeout := flag.Duration("query-timeout", httpinternal.DefaultOptions.QueryTimeout, "time limit for the database work of an API request (0 disables it)")
+	exportTimeout := flag.Duration("export-timeout", httpinternal.DefaultOptions.ExportTimeout, "time limit for exports, imports, PDF reports and backups (0 disables it)")
+	postgresDSN := flag.String("postgres-dsn", "", "keep the budget in this PostgreSQL database instead of budget.db, e.g. postgres://budget@localhost/budget?sslmode=disable; the password may come from $PGPASSWORD")
+	trashRetentionDays := flag.Int("trash-retention-days", app.DefaultTrashRetentionDays, "days deleted categories and budget lines stay in the trash before they are purged (0 keeps them forever)")
+	allowFinalizedOverride := flag.Bool("allow-finalized-override", false, "let API requests with override_finalized=1 change finalized months without reopening them; each such change is recorded in the audit log")
+	demo := flag.Bool("demo", false, "serve a sample budget kept in memory instead of budget.db; nothing is saved and backups are disabled")
+	flag.Parse()
+
+	passphrase := os.Getenv(backupPassphraseEnv)
+	if *decryptBackup != "" {
+		if !strings.HasSuffix(*decryptBackup, ".enc") {
+			log.Fatalf("Encrypted backup %s must have the .enc suffix", *decryptBackup)
+		}
+		dst := strings.TrimSuffix(*decryptBackup, ".enc")
+		if err := app.DecryptBackupFile(*decryptBackup, dst, passphrase); err != nil {
+			log.Fatalf("Failed to decrypt backup: %v", err)
+		}
+		log.Printf("Decrypted backup written to %s", dst)
+		return
+	}
+
 	log.Println("Starting Gandalf Budget application...")
+	routerOptions := httpinternal.Options{QueryTimeout: *queryTimeout, ExportTimeout: *exportTimeout, AllowFinalizedOverride: *allowFinalizedOverride}
 
-	db, err := store.NewStore("budget.db")
+	if *demo {
+		demoStore, err := newDemoStore(*currency)
+		if err != nil {
+			log.Fatalf("Failed to set up demo data: %v", err)
+		}
+		log.Printf("Demo mode: serving a sample budget in %s from memory; nothing is saved", store.BudgetCurrency())
+		serve(demoStore, nil, routerOptions)
+		return
+	}
+
+	var db *sqlx.DB
+	var err error
+	if *postgresDSN != "" {
+		db, err = store.NewPostgresStore(*postgresDSN)
+	} else {
+		dbOptions := store.DefaultSQLiteOptions
+		dbOptions.JournalMode = *sqliteJournalMode
+		dbOptions.Synchronous = *sqliteSynchronous
+		dbOptions.BusyTimeout = *sqliteBusyTimeout
+		dbOptions.MaxOpenConns = *sqliteMaxConns
+		db, err = store.NewStore("budget.db", dbOptions)
+	}
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
 
-	if err := store.RunMigrations(db, "internal/store/migrations"); err != nil {
+	var budgetCurrency store.Currency
+	if *currency != "" {
+		if budgetCurrency, err = store.ParseCurrency(*currency); err != nil {
+			log.Fatalf("Invalid -currency: %v", err)
+		}
+	}
+	if err := store.RunMigrationsWith(db, store.MigrationOptions{Currency: budgetCurrency}); err != nil {
+		if errors.Is(err, store.ErrMigrationCurrencyRequired) {
+			log.Fatalf("Failed to run database migrations: %v; restart with -currency set to the currency your amounts are in", err)
+		}
 		log.Fatalf("Failed to run database migrations: %v", err)
 	}
+	if err := store.LoadBudgetCurrency(db); err != nil {
+		log.Fatalf("Failed to load budget currency: %v", err)
+	}
+	if budgetCurrency != "" {
+		if err := store.ChangeBudgetCurrency(db, budgetCurrency); err != nil {
+			log.Fatalf("Failed to change budget currency: %v", err)
+		}
+	}
+	log.Printf("Budget currency: %s", store.BudgetCurrency())
+
+	if *exportLedger != "" {
+		if err := writeLedgerFile(db, *exportLedger, *ledgerFormat, *ledgerYear, *ledgerAccounts); err != nil {
+			log.Fatalf("Failed to export journal: %v", err)
+		}
+		log.Printf("Journal for %d written to %s", *ledgerYear, *exportLedger)
+		return
+	}
 
 	if err := app.SeedInitialMonth(db); err != nil {
 		log.Fatalf("Failed to seed initial data: %v", err)
 	}
 
+	// On-disk backups copy the SQLite file; a PostgreSQL server has its own tools.
+	var backups *app.BackupScheduler
+	if *postgresDSN == "" {
+		retention := app.RetentionPolicy{Daily: *keepDaily, Weekly: *keepWeekly, Monthly: *keepMonthly}
+		backups = app.NewBackupScheduler(db, store.NewSQLStore(db), *backupDir, *backupInterval, retention, passphrase)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		backups.Start(ctx)
+	} else {
+		log.Println("Scheduled backups are disabled with PostgreSQL; use pg_dump or the JSON export instead.")
+	}
+
+	appStore := store.NewSQLStore(db)
+	trashCtx, cancelTrash := context.WithCancel(context.Background())
+	defer cancelTrash()
+	app.NewTrashPurger(appStore, *trashRetentionDays).Start(trashCtx)
+
+	serve(appStore, backups, routerOptions)
+}
+
+// serve runs the HTTP server until it fails. backups is nil in demo mode and
+// with PostgreSQL.
+func serve(appStore store.Store, backups *app.BackupScheduler, opts httpinternal.Options) {
 	log.Println("Setting up router...")
 	distFS, err := fs.Sub(staticFiles, "embedded_web_dist")
 	if err != nil {
 		log.Fatalf("Failed to create sub VFS for embedded_web_dist: %v", err)
 	}
-	router := httpinternal.NewRouter(distFS, db)
+	router := httpinternal.NewRouter(distFS, appStore, backups, opts)
 
 	log.Println("Starting HTTP server on :8080")
 	if err := http.ListenAndServe(":8080", router); err != nil {
 		log.Fatalf("ListenAndServe error: %v", err)
 	}
 }
+
+// newDemoStore returns an in-memory store holding the sample budget, in the
+// given currency when one is set.
+func newDemoStore(currency string) (store.Store, error) {
+	if currency != "" {
+		c, err := store.ParseCurrency(currency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -currency: %w", err)
+		}
+		store.SetBudgetCurrency(c)
+	}
+	demoStore := store.NewMemoryStore()
+	if err := app.SeedDemoData(context.Background(), demoStore, time.Now()); err != nil {
+		return nil, err
+	}
+	return demoStore, nil
+}
+
+func writeLedgerFile(db *sqlx.DB, path, format string, year int, accounts string) error {
+	opts := app.DefaultLedgerOptions(app.LedgerFormat(format))
+	var err error
+	if opts.Accounts, err = app.ParseLedgerAccounts(accounts); err != nil {
+		return err
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := app.WriteLedger(context.Background(), f, store.NewSQLStore(db), year, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
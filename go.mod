@@ -4,8 +4,11 @@ go 1.22.2
 
 require (
 	github.com/jmoiron/sqlx v1.4.0
+	github.com/jung-kurt/gofpdf v1.16.2
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.28
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/crypto v0.33.0
 )
 
 require (
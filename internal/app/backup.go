@@ -0,0 +1,346 @@
+package app
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gandalf-budget/internal/store"
+)
+
+// BackupSchemaVersion is written at the top of every JSON backup so restores
+// can reject files produced by an incompatible version of the app.
+const BackupSchemaVersion = 1
+
+// Backup is the decoded form of a JSON backup file. Currency is the budget
+// currency its amounts are written in; backups from before it was recorded
+// leave it empty.
+type Backup struct {
+	SchemaVersion int            `json:"schema_version"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	Currency      store.Currency `json:"currency,omitempty"`
+	store.DatabaseDump
+}
+
+// MaxBackupBytes caps how large a gzip backup may grow once decompressed, so
+// a small upload cannot expand into an unbounded amount of JSON.
+const MaxBackupBytes = 512 << 20
+
+// ErrBackupTooLarge is returned when a gzip backup decompresses to more than
+// MaxBackupBytes.
+var ErrBackupTooLarge = fmt.Errorf("backup is larger than %d MiB once decompressed", MaxBackupBytes>>20)
+
+// BackupValidationError lists every problem found in a backup, so a broken
+// file can be fixed in one go instead of one error at a time.
+type BackupValidationError struct {
+	Problems []string
+}
+
+func (e *BackupValidationError) Error() string {
+	return "invalid backup: " + strings.Join(e.Problems, "; ")
+}
+
+// WriteJSONBackup streams a pretty-printed JSON dump of the whole database to w.
+func WriteJSONBackup(ctx context.Context, w io.Writer, s store.Store, exportedAt time.Time) error {
+	bw := bufio.NewWriter(w)
+	exportedAtJSON, err := json.Marshal(exportedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to encode export timestamp: %w", err)
+	}
+	if _, err := fmt.Fprintf(bw, "{\n  \"schema_version\": %d,\n  \"exported_at\": %s,\n  \"currency\": %q",
+		BackupSchemaVersion, exportedAtJSON, store.BudgetCurrency()); err != nil {
+		return err
+	}
+	if err := s.ExportAll(ctx, &jsonBackupSink{w: bw}); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(bw, "\n}\n"); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+type jsonBackupSink struct {
+	w    io.Writer
+	rows int
+}
+
+func (j *jsonBackupSink) BeginTable(name string) error {
+	key, err := json.Marshal(name)
+	if err != nil {
+		return err
+	}
+	j.rows = 0
+	_, err = fmt.Fprintf(j.w, ",\n  %s: [", key)
+	return err
+}
+
+func (j *jsonBackupSink) WriteRow(row interface{}) error {
+	rowJSON, err := json.MarshalIndent(row, "    ", "  ")
+	if err != nil {
+		return err
+	}
+	separator := ","
+	if j.rows == 0 {
+		separator = ""
+	}
+	j.rows++
+	_, err = fmt.Fprintf(j.w, "%s\n    %s", separator, rowJSON)
+	return err
+}
+
+func (j *jsonBackupSink) EndTable() error {
+	closing := "\n  ]"
+	if j.rows == 0 {
+		closing = "]"
+	}
+	_, err := io.WriteString(j.w, closing)
+	return err
+}
+
+// ReadJSONBackup decodes a backup written by WriteJSONBackup, transparently
+// decrypting and decompressing it, and validates it before returning it.
+// The passphrase is only needed for encrypted backups.
+func ReadJSONBackup(r io.Reader, passphrase string) (*Backup, error) {
+	return readJSONBackup(r, passphrase, MaxBackupBytes)
+}
+
+// readJSONBackup is ReadJSONBackup with the decompressed size capped at limit.
+func readJSONBackup(r io.Reader, passphrase string, limit int64) (*Backup, error) {
+	br := bufio.NewReader(r)
+	if IsEncryptedBackup(br) {
+		decrypted, err := NewDecryptReader(br, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(decrypted)
+	}
+	var in io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip backup: %w", err)
+		}
+		defer gz.Close()
+		in = &cappedReader{r: gz, left: limit}
+	}
+
+	var backup Backup
+	if err := json.NewDecoder(in).Decode(&backup); err != nil {
+		return nil, fmt.Errorf("failed to decode backup JSON: %w", err)
+	}
+	if err := ValidateBackup(&backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// cappedReader fails with ErrBackupTooLarge once more than left bytes are read.
+type cappedReader struct {
+	r    io.Reader
+	left int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.left < 0 {
+		return 0, ErrBackupTooLarge
+	}
+	if int64(len(p)) > c.left+1 {
+		p = p[:c.left+1]
+	}
+	n, err := c.r.Read(p)
+	c.left -= int64(n)
+	if c.left < 0 {
+		return n, ErrBackupTooLarge
+	}
+	return n, err
+}
+
+// ValidateBackup checks the schema version, budget currency and referential
+// integrity of a backup. Snapshot timestamps are normalised to the format
+// FinalizeMonth writes.
+func ValidateBackup(b *Backup) error {
+	if b.SchemaVersion != BackupSchemaVersion {
+		return &BackupValidationError{Problems: []string{
+			fmt.Sprintf("unsupported schema_version %d (expected %d)", b.SchemaVersion, BackupSchemaVersion),
+		}}
+	}
+	// Amounts are decoded with the decimals of this budget's currency, so a
+	// backup kept in another one cannot be read correctly.
+	if base := store.BudgetCurrency(); b.Currency != "" && b.Currency != base {
+		return &BackupValidationError{Problems: []string{
+			fmt.Sprintf("backup amounts are in %s but this budget is kept in %s; switch the budget to %s (-currency %s) before restoring", b.Currency, base, b.Currency, b.Currency),
+		}}
+	}
+
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	monthIDs := make(map[int64]bool)
+	periods := make(map[[2]int]bool)
+	for _, m := range b.Months {
+		if monthIDs[m.ID] {
+			addProblem("duplicate month id %d", m.ID)
+		}
+		monthIDs[m.ID] = true
+		if m.Month < 1 || m.Month > 12 {
+			addProblem("month %d has invalid month number %d", m.ID, m.Month)
+		}
+		period := [2]int{m.Year, m.Month}
+		if periods[period] {
+			addProblem("month %d-%02d appears more than once", m.Year, m.Month)
+		}
+		periods[period] = true
+	}
+
+	categoryIDs := make(map[int64]bool)
+	categoryNames := make(map[string]bool)
+	for _, c := range b.Categories {
+		if categoryIDs[c.ID] {
+			addProblem("duplicate category id %d", c.ID)
+		}
+		categoryIDs[c.ID] = true
+		if c.Name == "" || c.Color == "" {
+			addProblem("category %d is missing its name or color", c.ID)
+		}
+		if categoryNames[c.Name] {
+			addProblem("duplicate category name %q", c.Name)
+		}
+		categoryNames[c.Name] = true
+	}
+
+	budgetLineIDs := make(map[int64]bool)
+	for _, bl := range b.BudgetLines {
+		id := int64(bl.ID)
+		if budgetLineIDs[id] {
+			addProblem("duplicate budget line id %d", id)
+		}
+		budgetLineIDs[id] = true
+		if !monthIDs[int64(bl.MonthID)] {
+			addProblem("budget line %d references missing month %d", id, bl.MonthID)
+		}
+		if !categoryIDs[int64(bl.CategoryID)] {
+			addProblem("budget line %d references missing category %d", id, bl.CategoryID)
+		}
+	}
+
+	actualLineIDs := make(map[int64]bool)
+	for _, al := range b.ActualLines {
+		if actualLineIDs[al.ID] {
+			addProblem("duplicate actual line id %d", al.ID)
+		}
+		actualLineIDs[al.ID] = true
+		if !budgetLineIDs[al.BudgetLineID] {
+			addProblem("actual line %d references missing budget line %d", al.ID, al.BudgetLineID)
+		}
+		if al.Actual < 0 {
+			addProblem("actual line %d has negative amount", al.ID)
+		}
+	}
+
+	transactionIDs := make(map[int64]bool)
+	for _, t := range b.Transactions {
+		if transactionIDs[t.ID] {
+			addProblem("duplicate transaction id %d", t.ID)
+		}
+		transactionIDs[t.ID] = true
+		if !budgetLineIDs[t.BudgetLineID] {
+			addProblem("transaction %d references missing budget line %d", t.ID, t.BudgetLineID)
+		}
+		if t.Date.IsZero() {
+			addProblem("transaction %d has no date", t.ID)
+		}
+	}
+
+	snapIDs := make(map[int64]bool)
+	snapVersions := make(map[[2]int64]bool)
+	for i, snap := range b.AnnualSnaps {
+		if snapIDs[snap.ID] {
+			addProblem("duplicate annual snap id %d", snap.ID)
+		}
+		snapIDs[snap.ID] = true
+		if !monthIDs[snap.MonthID] {
+			addProblem("annual snap %d references missing month %d", snap.ID, snap.MonthID)
+		}
+		version := int64(snap.Version)
+		if version == 0 {
+			version = 1
+		}
+		if snapVersions[[2]int64{snap.MonthID, version}] {
+			addProblem("month %d has more than one annual snap version %d", snap.MonthID, version)
+		}
+		snapVersions[[2]int64{snap.MonthID, version}] = true
+		if !json.Valid([]byte(snap.SnapJSON)) {
+			addProblem("annual snap %d does not hold valid JSON", snap.ID)
+		}
+		createdAt, err := parseSnapTimestamp(snap.CreatedAt)
+		if err != nil {
+			addProblem("annual snap %d has invalid created_at %q", snap.ID, snap.CreatedAt)
+			continue
+		}
+		b.AnnualSnaps[i].CreatedAt = createdAt.Format("2006-01-02 15:04:05")
+	}
+
+	ratePeriods := make(map[string]bool)
+	for _, rate := range b.ExchangeRates {
+		period := fmt.Sprintf("%s %d-%02d", rate.Currency, rate.Year, rate.Month)
+		if ratePeriods[period] {
+			addProblem("more than one exchange rate for %s", period)
+		}
+		ratePeriods[period] = true
+		if rate.Rate <= 0 || rate.Month < 1 || rate.Month > 12 {
+			addProblem("exchange rate %d is invalid", rate.ID)
+		}
+	}
+
+	ruleIDs := make(map[int64]bool)
+	for _, rule := range b.MatchRules {
+		if ruleIDs[rule.ID] {
+			addProblem("duplicate match rule id %d", rule.ID)
+		}
+		ruleIDs[rule.ID] = true
+		if rule.CategoryID != nil && !categoryIDs[*rule.CategoryID] {
+			addProblem("match rule %d references missing category %d", rule.ID, *rule.CategoryID)
+		}
+	}
+
+	bankTransactionIDs := make(map[int64]bool)
+	externalIDs := make(map[string]bool)
+	for _, t := range b.BankTransactions {
+		if bankTransactionIDs[t.ID] {
+			addProblem("duplicate bank transaction id %d", t.ID)
+		}
+		bankTransactionIDs[t.ID] = true
+		if externalIDs[t.ExternalID] {
+			addProblem("duplicate bank transaction external id %q", t.ExternalID)
+		}
+		externalIDs[t.ExternalID] = true
+		if t.MonthID != nil && !monthIDs[*t.MonthID] {
+			addProblem("bank transaction %d references missing month %d", t.ID, *t.MonthID)
+		}
+		if t.BudgetLineID != nil && !budgetLineIDs[*t.BudgetLineID] {
+			addProblem("bank transaction %d references missing budget line %d", t.ID, *t.BudgetLineID)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &BackupValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func parseSnapTimestamp(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q", value)
+}
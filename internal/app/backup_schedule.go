@@ -0,0 +1,246 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"gandalf-budget/internal/store"
+)
+
+const (
+	backupFilePrefix     = "budget-"
+	backupFileTimeLayout = "20060102-150405"
+	backupFileSuffix     = ".db"
+	encryptedFileSuffix  = ".enc"
+)
+
+// RetentionPolicy is how many daily, weekly and monthly on-disk backups to keep.
+// Each bucket keeps the newest backup of that many distinct days, ISO weeks or months.
+type RetentionPolicy struct {
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+var DefaultRetentionPolicy = RetentionPolicy{Daily: 7, Weekly: 4, Monthly: 12}
+
+type BackupFile struct {
+	Name      string    `json:"name"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+	Encrypted bool      `json:"encrypted"`
+}
+
+// BackupScheduler takes periodic online copies of the SQLite database into a
+// directory and prunes old copies according to its retention policy.
+// With a passphrase set, each copy is encrypted and the plaintext removed.
+type BackupScheduler struct {
+	db         *sqlx.DB
+	store      store.Store
+	dir        string
+	interval   time.Duration
+	retention  RetentionPolicy
+	passphrase string
+
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+func NewBackupScheduler(db *sqlx.DB, s store.Store, dir string, interval time.Duration, retention RetentionPolicy, passphrase string) *BackupScheduler {
+	return &BackupScheduler{
+		db:         db,
+		store:      s,
+		dir:        dir,
+		interval:   interval,
+		retention:  retention,
+		passphrase: passphrase,
+		now:        time.Now,
+	}
+}
+
+func (b *BackupScheduler) Dir() string { return b.dir }
+
+func (b *BackupScheduler) Interval() time.Duration { return b.interval }
+
+// Start runs backups every interval until ctx is cancelled. A backup is taken
+// straight away when the newest one on disk is already older than the interval.
+// A zero interval disables scheduling; RunNow keeps working.
+func (b *BackupScheduler) Start(ctx context.Context) {
+	if b.interval <= 0 {
+		log.Println("Scheduled backups are disabled.")
+		return
+	}
+	log.Printf("Scheduled backups every %s into %s", b.interval, b.dir)
+
+	go func() {
+		if files, err := b.List(); err != nil {
+			log.Printf("Error listing existing backups: %v", err)
+		} else if len(files) == 0 || b.now().Sub(files[0].CreatedAt) >= b.interval {
+			b.runScheduled(ctx)
+		}
+
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				b.runScheduled(ctx)
+			}
+		}
+	}()
+}
+
+func (b *BackupScheduler) runScheduled(ctx context.Context) {
+	if _, err := b.RunNow(ctx); err != nil {
+		log.Printf("Scheduled backup failed: %v", err)
+	}
+}
+
+// RunNow takes a backup immediately, records it and prunes old backups.
+func (b *BackupScheduler) RunNow(ctx context.Context) (*BackupFile, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := os.MkdirAll(b.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory %s: %w", b.dir, err)
+	}
+
+	createdAt := b.now().UTC().Truncate(time.Second)
+	name := backupFilePrefix + createdAt.Format(backupFileTimeLayout) + backupFileSuffix
+	path := filepath.Join(b.dir, name)
+	if err := store.BackupDatabase(ctx, b.db, path); err != nil {
+		return nil, err
+	}
+	if b.passphrase != "" {
+		plainPath := path
+		name += encryptedFileSuffix
+		path += encryptedFileSuffix
+		err := EncryptBackupFile(plainPath, path, b.passphrase)
+		if removeErr := os.Remove(plainPath); removeErr != nil && err == nil {
+			err = fmt.Errorf("failed to remove unencrypted backup %s: %w", plainPath, removeErr)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat new backup %s: %w", path, err)
+	}
+	file := &BackupFile{Name: name, SizeBytes: info.Size(), CreatedAt: createdAt, Encrypted: b.passphrase != ""}
+
+	if err := b.store.RecordBackupRun(ctx, &store.BackupRun{
+		Kind:      store.BackupKindSnapshot,
+		Location:  path,
+		SizeBytes: file.SizeBytes,
+		CreatedAt: createdAt,
+	}); err != nil {
+		return nil, err
+	}
+	log.Printf("Backup written to %s (%d bytes)", path, file.SizeBytes)
+
+	if err := b.prune(); err != nil {
+		log.Printf("Error pruning old backups: %v", err)
+	}
+	return file, nil
+}
+
+// List returns the backups in the directory, newest first.
+func (b *BackupScheduler) List() ([]BackupFile, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []BackupFile{}, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory %s: %w", b.dir, err)
+	}
+
+	files := []BackupFile{}
+	for _, entry := range entries {
+		name := entry.Name()
+		encrypted := strings.HasSuffix(name, backupFileSuffix+encryptedFileSuffix)
+		if entry.IsDir() || !strings.HasPrefix(name, backupFilePrefix) || !(encrypted || strings.HasSuffix(name, backupFileSuffix)) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, backupFilePrefix), encryptedFileSuffix), backupFileSuffix)
+		createdAt, err := time.Parse(backupFileTimeLayout, stamp)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat backup %s: %w", name, err)
+		}
+		files = append(files, BackupFile{Name: name, SizeBytes: info.Size(), CreatedAt: createdAt, Encrypted: encrypted})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].CreatedAt.After(files[j].CreatedAt) })
+	return files, nil
+}
+
+func (b *BackupScheduler) prune() error {
+	files, err := b.List()
+	if err != nil {
+		return err
+	}
+	for _, f := range selectBackupsToPrune(files, b.retention) {
+		if err := os.Remove(filepath.Join(b.dir, f.Name)); err != nil {
+			return fmt.Errorf("failed to remove old backup %s: %w", f.Name, err)
+		}
+		log.Printf("Pruned old backup %s", f.Name)
+	}
+	return nil
+}
+
+// selectBackupsToPrune expects files newest first. The newest backup is always kept.
+func selectBackupsToPrune(files []BackupFile, policy RetentionPolicy) []BackupFile {
+	if len(files) == 0 || policy == (RetentionPolicy{}) {
+		return nil
+	}
+
+	keep := map[string]bool{files[0].Name: true}
+	keepNewestPerPeriod(files, policy.Daily, keep, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+	keepNewestPerPeriod(files, policy.Weekly, keep, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+	keepNewestPerPeriod(files, policy.Monthly, keep, func(t time.Time) string {
+		return t.Format("2006-01")
+	})
+
+	var prune []BackupFile
+	for _, f := range files {
+		if !keep[f.Name] {
+			prune = append(prune, f)
+		}
+	}
+	return prune
+}
+
+func keepNewestPerPeriod(files []BackupFile, limit int, keep map[string]bool, period func(time.Time) string) {
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if len(seen) >= limit {
+			return
+		}
+		key := period(f.CreatedAt.Local())
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		keep[f.Name] = true
+	}
+}
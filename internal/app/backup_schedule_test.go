@@ -0,0 +1,68 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func backupsEvery(start time.Time, step time.Duration, count int) []BackupFile {
+	files := make([]BackupFile, 0, count)
+	for i := 0; i < count; i++ {
+		createdAt := start.Add(time.Duration(i) * step)
+		files = append(files, BackupFile{Name: fmt.Sprintf("budget-%s.db", createdAt.Format(backupFileTimeLayout)), CreatedAt: createdAt})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].CreatedAt.After(files[j].CreatedAt) })
+	return files
+}
+
+func TestSelectBackupsToPrune(t *testing.T) {
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.Local)
+	files := backupsEvery(start, 24*time.Hour, 400)
+
+	pruned := selectBackupsToPrune(files, DefaultRetentionPolicy)
+	kept := len(files) - len(pruned)
+
+	// 7 daily + up to 4 weekly + up to 12 monthly, with overlaps between the buckets.
+	if kept < 12 || kept > 7+4+12 {
+		t.Fatalf("Kept %d backups, expected between 12 and 23", kept)
+	}
+
+	prunedNames := make(map[string]bool)
+	for _, f := range pruned {
+		prunedNames[f.Name] = true
+	}
+	for i := 0; i < 7; i++ {
+		if prunedNames[files[i].Name] {
+			t.Errorf("Daily backup %s should be kept", files[i].Name)
+		}
+	}
+	months := make(map[string]bool)
+	for _, f := range files {
+		if !prunedNames[f.Name] {
+			months[f.CreatedAt.Format("2006-01")] = true
+		}
+	}
+	if len(months) != 12 {
+		t.Errorf("Expected backups from 12 distinct months to survive, got %d", len(months))
+	}
+}
+
+func TestSelectBackupsToPrune_KeepsNewestAndRespectsEmptyPolicy(t *testing.T) {
+	files := backupsEvery(time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), time.Hour, 5)
+
+	if pruned := selectBackupsToPrune(files, RetentionPolicy{}); len(pruned) != 0 {
+		t.Errorf("An empty policy must not prune anything, pruned %d", len(pruned))
+	}
+
+	pruned := selectBackupsToPrune(files, RetentionPolicy{Daily: 1})
+	if len(pruned) != 4 {
+		t.Fatalf("Expected 4 same-day backups to be pruned, got %d", len(pruned))
+	}
+	for _, f := range pruned {
+		if f.Name == files[0].Name {
+			t.Errorf("The newest backup must never be pruned")
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+	"time"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadJSONBackup_CapsDecompressedSize(t *testing.T) {
+	var plain bytes.Buffer
+	require.NoError(t, WriteJSONBackup(context.Background(), &plain, store.NewMemoryStore(), time.Now()))
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write(plain.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	_, err = readJSONBackup(bytes.NewReader(compressed.Bytes()), "", int64(plain.Len()))
+	assert.NoError(t, err, "a backup of exactly the limit is read")
+	_, err = readJSONBackup(bytes.NewReader(compressed.Bytes()), "", int64(plain.Len()/2))
+	assert.ErrorIs(t, err, ErrBackupTooLarge)
+}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gandalf-budget/internal/store"
+)
+
+// BankImportResult is what an uploaded statement turned into: the spending
+// transactions queued for review, with a proposed budget line where one was found.
+type BankImportResult struct {
+	Transactions []store.BankTransaction `json:"transactions"`
+	Rejected     []StatementRejection    `json:"rejected"`
+	// Credits counts incoming payments, which are not budget spending and are skipped.
+	Credits int `json:"credits"`
+}
+
+// PrepareBankTransactions turns statement spending into review-queue entries: each
+// is placed in the month of its posting date and matched against that month's
+// budget lines using the match rules, then the line labels, then the amounts.
+// Transactions in months that do not exist or are finalized get no proposal.
+func PrepareBankTransactions(ctx context.Context, s store.Store, statement []StatementTransaction) (*BankImportResult, error) {
+	rules, err := s.GetMatchRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BankImportResult{Transactions: []store.BankTransaction{}, Rejected: []StatementRejection{}}
+	monthsByYear := make(map[int][]store.Month)
+	boards := make(map[int64]*store.BoardDataPayload)
+	for _, st := range statement {
+		if st.Amount >= 0 {
+			result.Credits++
+			continue
+		}
+		txn := store.BankTransaction{
+			ExternalID:  st.ExternalID,
+			PostedOn:    st.PostedOn,
+			Amount:      -st.Amount,
+			Description: st.Description,
+			Status:      store.BankTransactionPending,
+		}
+
+		year := st.PostedOn.Year()
+		months, ok := monthsByYear[year]
+		if !ok {
+			if months, err = s.GetMonthsByYear(ctx, year); err != nil {
+				return nil, err
+			}
+			monthsByYear[year] = months
+		}
+		for _, m := range months {
+			if m.Month != int(st.PostedOn.Month()) {
+				continue
+			}
+			monthID := m.ID
+			txn.MonthID = &monthID
+			if m.Finalized {
+				break
+			}
+			board, ok := boards[m.ID]
+			if !ok {
+				if board, err = s.GetBoardData(ctx, int(m.ID)); err != nil {
+					return nil, fmt.Errorf("failed to load board for %d-%02d: %w", m.Year, m.Month, err)
+				}
+				boards[m.ID] = board
+			}
+			if line, reason := matchBudgetLine(txn.Description, txn.Amount, board.BudgetLines, rules); line != nil {
+				lineID := line.ID
+				txn.BudgetLineID = &lineID
+				txn.MatchReason = reason
+			}
+			break
+		}
+		result.Transactions = append(result.Transactions, txn)
+	}
+	return result, nil
+}
+
+// matchBudgetLine proposes a budget line for a transaction, returning nil when
+// nothing fits well enough to suggest.
+func matchBudgetLine(description string, amount store.Money, lines []store.BudgetLineWithActual, rules []store.MatchRule) (*store.BudgetLineWithActual, string) {
+	desc := normalizeMatchText(description)
+
+	for _, rule := range rules {
+		pattern := normalizeMatchText(rule.Pattern)
+		if pattern == "" || !strings.Contains(desc, pattern) {
+			continue
+		}
+		if rule.Amount != nil && *rule.Amount != amount {
+			continue
+		}
+		for i := range lines {
+			line := &lines[i]
+			if strings.EqualFold(strings.TrimSpace(line.Label), strings.TrimSpace(rule.Label)) &&
+				(rule.CategoryID == nil || *rule.CategoryID == line.CategoryID) {
+				return line, fmt.Sprintf("rule %q", rule.Pattern)
+			}
+		}
+	}
+
+	// A line whose label appears in the description; if several do, the one whose
+	// remaining budget is closest to the amount.
+	var best *store.BudgetLineWithActual
+	for i := range lines {
+		line := &lines[i]
+		if !labelMatches(desc, normalizeMatchText(line.Label)) {
+			continue
+		}
+		if best == nil || (remaining(line)-amount).Abs() < (remaining(best)-amount).Abs() {
+			best = line
+		}
+	}
+	if best != nil {
+		return best, "label"
+	}
+
+	// Otherwise only an unambiguous amount: exactly one line still expecting it.
+	var byAmount *store.BudgetLineWithActual
+	for i := range lines {
+		if remaining(&lines[i]) > 0 && remaining(&lines[i]) == amount {
+			if byAmount != nil {
+				return nil, ""
+			}
+			byAmount = &lines[i]
+		}
+	}
+	if byAmount != nil {
+		return byAmount, "amount"
+	}
+	return nil, ""
+}
+
+// labelMatches reports whether the label appears in the description, either as
+// a whole or as all of its significant words.
+func labelMatches(desc, label string) bool {
+	if label == "" {
+		return false
+	}
+	if strings.Contains(" "+desc+" ", " "+label+" ") {
+		return true
+	}
+	descWords := make(map[string]bool)
+	for _, w := range strings.Fields(desc) {
+		descWords[w] = true
+	}
+	significant := 0
+	for _, w := range strings.Fields(label) {
+		if len(w) < 3 {
+			continue
+		}
+		if !descWords[w] {
+			return false
+		}
+		significant++
+	}
+	return significant > 0
+}
+
+// normalizeMatchText lower-cases text and turns punctuation into single spaces,
+// so "CARD PAYMENT*NETFLIX.COM" and "Netflix" can be compared word by word.
+func normalizeMatchText(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func remaining(line *store.BudgetLineWithActual) store.Money {
+	return line.ExpectedAmount - line.ActualAmount
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gandalf-budget/internal/store"
+)
+
+func TestMatchBudgetLine(t *testing.T) {
+	lines := []store.BudgetLineWithActual{
+		{ID: 1, CategoryID: 10, Label: "Groceries", ExpectedAmount: 400_00, ActualAmount: 100_00},
+		{ID: 2, CategoryID: 10, Label: "Groceries", ExpectedAmount: 50_00, ActualAmount: 0},
+		{ID: 3, CategoryID: 20, Label: "Streaming services", ExpectedAmount: 12_99},
+		{ID: 4, CategoryID: 30, Label: "Rent", ExpectedAmount: 900_00},
+		{ID: 5, CategoryID: 30, Label: "Gym", ExpectedAmount: 30_00},
+		{ID: 6, CategoryID: 30, Label: "Phone", ExpectedAmount: 30_00},
+	}
+	amount := store.Money(12_99)
+	category := int64(20)
+	rules := []store.MatchRule{
+		{Pattern: "netflix", CategoryID: &category, Label: "streaming services"},
+		{Pattern: "spotify", Label: "Streaming services", Amount: &amount},
+		{Pattern: "whole foods", Label: "No such line"},
+	}
+
+	tests := []struct {
+		name        string
+		description string
+		amount      store.Money
+		wantID      int64
+		wantReason  string
+	}{
+		{"Rule", "CARD PAYMENT*NETFLIX.COM", 15_49, 3, `rule "netflix"`},
+		{"Rule with amount", "Spotify AB", 12_99, 3, `rule "spotify"`},
+		{"Rule amount mismatch falls through", "Spotify AB", 20_00, 0, ""},
+		{"Label, closest remaining amount", "Groceries market", 45_00, 2, "label"},
+		{"Unmatched rule target falls back to label", "WHOLE FOODS GROCERIES", 280_00, 1, "label"},
+		{"Unambiguous amount", "Transfer 8812", 900_00, 4, "amount"},
+		{"Ambiguous amount", "Direct debit", 30_00, 0, ""},
+		{"Nothing", "ATM withdrawal", 60_00, 0, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			line, reason := matchBudgetLine(tc.description, tc.amount, lines, rules)
+			var gotID int64
+			if line != nil {
+				gotID = line.ID
+			}
+			if gotID != tc.wantID || reason != tc.wantReason {
+				t.Errorf("Expected line %d (%q), got %d (%q)", tc.wantID, tc.wantReason, gotID, reason)
+			}
+		})
+	}
+}
+
+func TestPrepareBankTransactions(t *testing.T) {
+	s := &store.ReusableMockStore{
+		MockGetMatchRules: func(ctx context.Context) ([]store.MatchRule, error) { return nil, nil },
+		MockGetMonthsByYear: func(ctx context.Context, year int) ([]store.Month, error) {
+			return []store.Month{
+				{ID: 1, Year: year, Month: 1, Finalized: true},
+				{ID: 2, Year: year, Month: 2},
+			}, nil
+		},
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
+			if monthID != 2 {
+				t.Errorf("Board of month %d should not be loaded", monthID)
+			}
+			return &store.BoardDataPayload{BudgetLines: []store.BudgetLineWithActual{
+				{ID: 7, Label: "Rent", ExpectedAmount: 900_00},
+			}}, nil
+		},
+	}
+	day := func(month time.Month, d int) time.Time { return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC) }
+	statement := []StatementTransaction{
+		{ExternalID: "a", PostedOn: day(time.January, 3), Amount: -900_00, Description: "Rent"},
+		{ExternalID: "b", PostedOn: day(time.February, 1), Amount: -900_00, Description: "Rent"},
+		{ExternalID: "c", PostedOn: day(time.February, 2), Amount: 2000_00, Description: "Salary"},
+		{ExternalID: "d", PostedOn: day(time.March, 1), Amount: -10_00, Description: "Rent"},
+	}
+
+	result, err := PrepareBankTransactions(context.Background(), s, statement)
+	if err != nil {
+		t.Fatalf("PrepareBankTransactions failed: %v", err)
+	}
+	if result.Credits != 1 || len(result.Transactions) != 3 {
+		t.Fatalf("Expected 3 spending transactions and 1 credit, got %+v", result)
+	}
+
+	finalized, open, noMonth := result.Transactions[0], result.Transactions[1], result.Transactions[2]
+	if finalized.MonthID == nil || *finalized.MonthID != 1 || finalized.BudgetLineID != nil {
+		t.Errorf("Transaction in a finalized month should have its month but no proposal: %+v", finalized)
+	}
+	if open.BudgetLineID == nil || *open.BudgetLineID != 7 || open.Amount != 900_00 || open.Status != store.BankTransactionPending {
+		t.Errorf("Expected the February rent to be proposed for line 7: %+v", open)
+	}
+	if noMonth.MonthID != nil || noMonth.BudgetLineID != nil {
+		t.Errorf("Transaction without a month should stay unmatched: %+v", noMonth)
+	}
+}
@@ -0,0 +1,312 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"gandalf-budget/internal/store"
+)
+
+type BankStatementFormat string
+
+const (
+	BankFormatOFX BankStatementFormat = "ofx"
+	BankFormatQFX BankStatementFormat = "qfx"
+	BankFormatCSV BankStatementFormat = "csv"
+)
+
+// BankCSVMapping names the columns of a bank's CSV export (by header text,
+// case-insensitive). Amounts come either from one signed Amount column, where
+// money spent is negative, or from separate Debit and Credit columns.
+type BankCSVMapping struct {
+	Date        string `json:"date"`
+	Description string `json:"description"`
+	Amount      string `json:"amount,omitempty"`
+	Debit       string `json:"debit,omitempty"`
+	Credit      string `json:"credit,omitempty"`
+
+	// DateFormat is written with YYYY, YY, MM and DD, e.g. "MM/DD/YYYY". When empty,
+	// ISO dates and day-first dates such as 31/01/2024 are recognised.
+	DateFormat       string `json:"date_format,omitempty"`
+	Delimiter        string `json:"delimiter,omitempty"`
+	DecimalSeparator string `json:"decimal_separator,omitempty"`
+}
+
+// StatementTransaction is one entry of a bank statement. Amount is signed as the
+// bank reports it: negative for money leaving the account.
+type StatementTransaction struct {
+	ExternalID  string
+	PostedOn    time.Time
+	Amount      store.Money
+	Description string
+}
+
+// StatementRejection reports an entry that could not be read. Line is the file
+// line for CSV statements and the position of the transaction for OFX ones.
+type StatementRejection struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// ParseBankStatement reads the transactions of an OFX/QFX or CSV statement. The
+// mapping is only used for CSV.
+func ParseBankStatement(data []byte, format BankStatementFormat, mapping BankCSVMapping) ([]StatementTransaction, []StatementRejection, error) {
+	switch format {
+	case BankFormatOFX, BankFormatQFX:
+		return parseOFX(data)
+	case BankFormatCSV:
+		return parseBankCSV(data, mapping)
+	default:
+		return nil, nil, fmt.Errorf("unsupported statement format %q: use ofx, qfx or csv", format)
+	}
+}
+
+// parseOFX handles both OFX 1.x (SGML, where leaf elements are not closed) and
+// OFX 2.x (XML) by reading the tags in order rather than parsing a document.
+func parseOFX(data []byte) ([]StatementTransaction, []StatementRejection, error) {
+	start := bytes.Index(bytes.ToUpper(data), []byte("<OFX>"))
+	if start < 0 {
+		return nil, nil, errors.New("not an OFX statement: no <OFX> element found")
+	}
+	body := string(data[start:])
+
+	txns := []StatementTransaction{}
+	rejected := []StatementRejection{}
+	var account string
+	var current map[string]string
+	position := 0
+	for len(body) > 0 {
+		open := strings.IndexByte(body, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(body[open:], '>')
+		if end < 0 {
+			break
+		}
+		tag := strings.ToUpper(strings.TrimSpace(body[open+1 : open+end]))
+		body = body[open+end+1:]
+		next := strings.IndexByte(body, '<')
+		if next < 0 {
+			next = len(body)
+		}
+		value := strings.TrimSpace(html.UnescapeString(body[:next]))
+
+		switch tag {
+		case "STMTTRN":
+			current = make(map[string]string)
+			position++
+		case "/STMTTRN":
+			if current == nil {
+				continue
+			}
+			txn, err := ofxTransaction(current, account)
+			if err != nil {
+				rejected = append(rejected, StatementRejection{Line: position, Reason: err.Error()})
+			} else {
+				txns = append(txns, *txn)
+			}
+			current = nil
+		case "ACCTID":
+			account = value
+		default:
+			if current != nil && !strings.HasPrefix(tag, "/") {
+				current[tag] = value
+			}
+		}
+	}
+	if position == 0 {
+		return nil, nil, errors.New("the statement has no transactions")
+	}
+	return txns, rejected, nil
+}
+
+func ofxTransaction(fields map[string]string, account string) (*StatementTransaction, error) {
+	dt := fields["DTPOSTED"]
+	if len(dt) < 8 {
+		return nil, fmt.Errorf("invalid posting date %q", dt)
+	}
+	posted, err := time.Parse("20060102", dt[:8])
+	if err != nil {
+		return nil, fmt.Errorf("invalid posting date %q", dt)
+	}
+
+	amountStr := fields["TRNAMT"]
+	if !strings.Contains(amountStr, ".") {
+		// Some European banks write the amount with a decimal comma.
+		amountStr = strings.Replace(amountStr, ",", ".", 1)
+	}
+	amount, err := store.ParseMoney(amountStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount %q", fields["TRNAMT"])
+	}
+
+	description := fields["NAME"]
+	if memo := fields["MEMO"]; memo != "" && !strings.EqualFold(memo, description) {
+		if description == "" {
+			description = memo
+		} else {
+			description += " - " + memo
+		}
+	}
+
+	txn := &StatementTransaction{PostedOn: posted, Amount: amount, Description: description}
+	if fitid := fields["FITID"]; fitid != "" {
+		txn.ExternalID = "ofx:" + account + ":" + fitid
+	} else {
+		txn.ExternalID = statementHash("ofx:"+account, txn, 0)
+	}
+	return txn, nil
+}
+
+func parseBankCSV(data []byte, mapping BankCSVMapping) ([]StatementTransaction, []StatementRejection, error) {
+	records, lines, err := readLegacyCSV(data, mapping.Delimiter)
+	if err != nil {
+		return nil, nil, err
+	}
+	decimal := '.'
+	switch mapping.DecimalSeparator {
+	case "", ".", "dot":
+	case ",", "comma":
+		decimal = ','
+	default:
+		return nil, nil, fmt.Errorf("invalid decimal separator %q: use dot or comma", mapping.DecimalSeparator)
+	}
+	layouts := bankDateLayouts
+	if mapping.DateFormat != "" {
+		layouts = []string{strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02").Replace(mapping.DateFormat)}
+	}
+
+	headerIndex := -1
+	for i, record := range records {
+		if !isBlankRecord(record) {
+			headerIndex = i
+			break
+		}
+	}
+	if headerIndex < 0 {
+		return nil, nil, errors.New("the file has no header row")
+	}
+	header := records[headerIndex]
+	find := func(field, name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(name)) {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("column %q (mapped to %s) not found in the header row", name, field)
+	}
+	var dateCol, descCol, amountCol, debitCol, creditCol int
+	for _, c := range []struct {
+		field, name string
+		col         *int
+	}{
+		{"date", mapping.Date, &dateCol},
+		{"description", mapping.Description, &descCol},
+		{"amount", mapping.Amount, &amountCol},
+		{"debit", mapping.Debit, &debitCol},
+		{"credit", mapping.Credit, &creditCol},
+	} {
+		if *c.col, err = find(c.field, c.name); err != nil {
+			return nil, nil, err
+		}
+	}
+	if dateCol < 0 || descCol < 0 {
+		return nil, nil, errors.New("column mappings for date and description are required")
+	}
+	if amountCol < 0 && debitCol < 0 {
+		return nil, nil, errors.New("a column mapping for amount or debit is required")
+	}
+
+	txns := []StatementTransaction{}
+	rejected := []StatementRejection{}
+	seen := make(map[string]int)
+	for i := headerIndex + 1; i < len(records); i++ {
+		record := records[i]
+		if isBlankRecord(record) {
+			continue
+		}
+		cell := func(col int) string {
+			if col < 0 || col >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[col])
+		}
+
+		txn, err := bankCSVTransaction(cell(dateCol), cell(descCol), cell(amountCol), cell(debitCol), cell(creditCol), layouts, decimal)
+		if err != nil {
+			rejected = append(rejected, StatementRejection{Line: lines[i], Reason: err.Error()})
+			continue
+		}
+		// Identical rows (two coffees on the same day) are told apart by their
+		// order in the file, so re-uploading the statement still de-duplicates.
+		key := statementHash("csv", txn, 0)
+		txn.ExternalID = statementHash("csv", txn, seen[key])
+		seen[key]++
+		txns = append(txns, *txn)
+	}
+	return txns, rejected, nil
+}
+
+var bankDateLayouts = []string{
+	"2006-01-02", "2006/01/02", "20060102", "02/01/2006", "2/1/2006", "02.01.2006", "02-01-2006", "2 Jan 2006", "02 Jan 2006",
+}
+
+func bankCSVTransaction(date, description, amount, debit, credit string, layouts []string, decimal rune) (*StatementTransaction, error) {
+	txn := &StatementTransaction{Description: description}
+	if date == "" {
+		return nil, errors.New("date is empty")
+	}
+	parsed := false
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			txn.PostedOn, parsed = t, true
+			break
+		}
+	}
+	if !parsed {
+		return nil, fmt.Errorf("unrecognised date %q", date)
+	}
+	if description == "" {
+		return nil, errors.New("description is empty")
+	}
+
+	var err error
+	switch {
+	case amount != "":
+		if txn.Amount, err = parseLegacyAmount(amount, decimal); err != nil {
+			return nil, fmt.Errorf("invalid amount: %w", err)
+		}
+	case debit != "":
+		var v store.Money
+		if v, err = parseLegacyAmount(debit, decimal); err != nil {
+			return nil, fmt.Errorf("invalid debit: %w", err)
+		}
+		txn.Amount = -v.Abs()
+	case credit != "":
+		var v store.Money
+		if v, err = parseLegacyAmount(credit, decimal); err != nil {
+			return nil, fmt.Errorf("invalid credit: %w", err)
+		}
+		txn.Amount = v.Abs()
+	default:
+		return nil, errors.New("amount is empty")
+	}
+	return txn, nil
+}
+
+// statementHash identifies a transaction that has no bank-assigned ID.
+func statementHash(prefix string, txn *StatementTransaction, occurrence int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%.2f|%s|%d",
+		txn.PostedOn.Format("2006-01-02"), txn.Amount.Float64(), strings.ToLower(txn.Description), occurrence)))
+	return prefix + ":" + hex.EncodeToString(sum[:12])
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKACCTFROM><BANKID>123<ACCTID>987654<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240115120000[-5:EST]
+<TRNAMT>-45.20
+<FITID>20240115001
+<NAME>WHOLE FOODS #123
+<MEMO>Groceries &amp; more
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240120
+<TRNAMT>1500,00
+<FITID>20240120001
+<NAME>PAYROLL
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>2024
+<TRNAMT>-1
+<FITID>bad
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+func TestParseBankStatement_OFXSGML(t *testing.T) {
+	txns, rejected, err := ParseBankStatement([]byte(sgmlStatement), BankFormatQFX, BankCSVMapping{})
+	if err != nil {
+		t.Fatalf("ParseBankStatement failed: %v", err)
+	}
+	if len(txns) != 2 {
+		t.Fatalf("Expected 2 transactions, got %+v", txns)
+	}
+
+	first := txns[0]
+	if first.ExternalID != "ofx:987654:20240115001" {
+		t.Errorf("Unexpected external ID %q", first.ExternalID)
+	}
+	if first.PostedOn.Format("2006-01-02") != "2024-01-15" || first.Amount != -45_20 {
+		t.Errorf("Unexpected first transaction: %+v", first)
+	}
+	if first.Description != "WHOLE FOODS #123 - Groceries & more" {
+		t.Errorf("Unexpected description %q", first.Description)
+	}
+	if txns[1].Amount != 1500_00 {
+		t.Errorf("Expected decimal-comma credit of 1500, got %v", txns[1].Amount)
+	}
+
+	if len(rejected) != 1 || rejected[0].Line != 3 || !strings.Contains(rejected[0].Reason, "posting date") {
+		t.Errorf("Expected the third transaction to be rejected for its date, got %+v", rejected)
+	}
+}
+
+func TestParseBankStatement_OFXXML(t *testing.T) {
+	xmlStatement := `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+<CCACCTFROM><ACCTID>4111</ACCTID></CCACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20240203</DTPOSTED><TRNAMT>-12.99</TRNAMT><FITID>A1</FITID><NAME>NETFLIX.COM</NAME></STMTTRN>
+</BANKTRANLIST>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>`
+
+	txns, rejected, err := ParseBankStatement([]byte(xmlStatement), BankFormatOFX, BankCSVMapping{})
+	if err != nil {
+		t.Fatalf("ParseBankStatement failed: %v", err)
+	}
+	if len(rejected) != 0 || len(txns) != 1 {
+		t.Fatalf("Expected 1 transaction and no rejections, got %+v / %+v", txns, rejected)
+	}
+	if txns[0].ExternalID != "ofx:4111:A1" || txns[0].Description != "NETFLIX.COM" || txns[0].Amount != -12_99 {
+		t.Errorf("Unexpected transaction: %+v", txns[0])
+	}
+
+	if _, _, err := ParseBankStatement([]byte("Date,Amount\n"), BankFormatOFX, BankCSVMapping{}); err == nil {
+		t.Error("Expected an error for a file that is not OFX")
+	}
+}
+
+func TestParseBankStatement_CSV(t *testing.T) {
+	csvData := "Fecha;Concepto;Cargo;Abono\n" +
+		"31/01/2024;Supermercado Lider;12.345,60;\n" +
+		"31/01/2024;Cafe;2.500;\n" +
+		"31/01/2024;Cafe;2.500;\n" +
+		"01/02/2024;Sueldo;;1.000.000\n" +
+		"2024-13-01;Nope;1;\n"
+	mapping := BankCSVMapping{
+		Date:             "fecha",
+		Description:      "concepto",
+		Debit:            "cargo",
+		Credit:           "abono",
+		DecimalSeparator: "comma",
+	}
+
+	txns, rejected, err := ParseBankStatement([]byte(csvData), BankFormatCSV, mapping)
+	if err != nil {
+		t.Fatalf("ParseBankStatement failed: %v", err)
+	}
+	if len(txns) != 4 {
+		t.Fatalf("Expected 4 transactions, got %+v", txns)
+	}
+	if txns[0].Amount != -12345_60 || txns[0].PostedOn.Format("2006-01-02") != "2024-01-31" {
+		t.Errorf("Unexpected first transaction: %+v", txns[0])
+	}
+	if txns[1].ExternalID == txns[2].ExternalID {
+		t.Error("Identical rows must get distinct external IDs")
+	}
+	if txns[3].Amount != 1000000_00 {
+		t.Errorf("Expected a credit of 1000000, got %v", txns[3].Amount)
+	}
+	if len(rejected) != 1 || rejected[0].Line != 6 {
+		t.Errorf("Expected line 6 to be rejected, got %+v", rejected)
+	}
+
+	again, _, _ := ParseBankStatement([]byte(csvData), BankFormatCSV, mapping)
+	for i := range txns {
+		if txns[i].ExternalID != again[i].ExternalID {
+			t.Errorf("External IDs must be stable across uploads, got %q and %q", txns[i].ExternalID, again[i].ExternalID)
+		}
+	}
+}
+
+func TestParseBankStatement_CSVDateFormat(t *testing.T) {
+	csvData := "Date,Description,Amount\n01/31/2024,Rent,-900\n"
+	mapping := BankCSVMapping{Date: "Date", Description: "Description", Amount: "Amount", DateFormat: "MM/DD/YYYY"}
+
+	txns, rejected, err := ParseBankStatement([]byte(csvData), BankFormatCSV, mapping)
+	if err != nil || len(rejected) != 0 || len(txns) != 1 {
+		t.Fatalf("Unexpected result: %+v %+v %v", txns, rejected, err)
+	}
+	if txns[0].PostedOn.Format("2006-01-02") != "2024-01-31" || txns[0].Amount != -900_00 {
+		t.Errorf("Unexpected transaction: %+v", txns[0])
+	}
+
+	mapping.Amount = ""
+	if _, _, err := ParseBankStatement([]byte(csvData), BankFormatCSV, mapping); err == nil {
+		t.Error("Expected an error when neither amount nor debit is mapped")
+	}
+}
@@ -0,0 +1,197 @@
+package app
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gandalf-budget/internal/store"
+)
+
+// CSVFormat selects the field delimiter and decimal separator of CSV exports,
+// e.g. ';' and ',' for spreadsheets in locales that use a decimal comma.
+type CSVFormat struct {
+	Delimiter        rune
+	DecimalSeparator rune
+}
+
+var DefaultCSVFormat = CSVFormat{Delimiter: ',', DecimalSeparator: '.'}
+
+func (f CSVFormat) Validate() error {
+	switch f.Delimiter {
+	case ',', ';', '\t', '|':
+	default:
+		return fmt.Errorf("unsupported delimiter %q: use comma, semicolon, tab or pipe", f.Delimiter)
+	}
+	if f.DecimalSeparator != '.' && f.DecimalSeparator != ',' {
+		return fmt.Errorf("unsupported decimal separator %q: use '.' or ','", f.DecimalSeparator)
+	}
+	if f.Delimiter == f.DecimalSeparator {
+		return errors.New("delimiter and decimal separator must differ")
+	}
+	return nil
+}
+
+func (f CSVFormat) amount(v store.Money) string {
+	s := v.String()
+	if f.DecimalSeparator != '.' {
+		s = strings.Replace(s, ".", string(f.DecimalSeparator), 1)
+	}
+	return s
+}
+
+func (f CSVFormat) newWriter(w io.Writer) (*csv.Writer, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	cw := csv.NewWriter(w)
+	cw.Comma = f.Delimiter
+	return cw, nil
+}
+
+// csvText keeps user-entered labels from being evaluated as formulas when the
+// file is opened in a spreadsheet.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// WriteBoardCSV writes one row per budget line of a month's board.
+func WriteBoardCSV(w io.Writer, board *store.BoardDataPayload, f CSVFormat) error {
+	cw, err := f.newWriter(w)
+	if err != nil {
+		return err
+	}
+	cw.Write([]string{"Category", "Label", "Expected", "Actual", "Difference"})
+	for _, line := range board.BudgetLines {
+		cw.Write([]string{
+			csvText(line.CategoryName),
+			csvText(line.Label),
+			f.amount(line.ExpectedAmount),
+			f.amount(line.ActualAmount),
+			f.amount(line.ExpectedAmount - line.ActualAmount),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteDashboardCSV writes the per-category totals of a dashboard followed by a total row.
+func WriteDashboardCSV(w io.Writer, payload *DashboardPayload, f CSVFormat) error {
+	cw, err := f.newWriter(w)
+	if err != nil {
+		return err
+	}
+	cw.Write([]string{"Category", "Budget Lines", "Expected", "Actual", "Difference"})
+	for _, summary := range payload.CategorySummaries {
+		cw.Write([]string{
+			csvText(summary.CategoryName),
+			strconv.Itoa(len(summary.BudgetLines)),
+			f.amount(summary.TotalExpected),
+			f.amount(summary.TotalActual),
+			f.amount(summary.Difference),
+		})
+	}
+	cw.Write([]string{"Total", "", f.amount(payload.TotalExpected), f.amount(payload.TotalActual), f.amount(payload.TotalDifference)})
+	cw.Flush()
+	return cw.Error()
+}
+
+// YearMatrix holds every budget line of a year with its amounts per calendar month.
+// Lines are matched across months by category and label.
+type YearMatrix struct {
+	Year int
+	Rows []YearMatrixRow
+}
+
+type YearMatrixRow struct {
+	CategoryName string
+	Label        string
+	Expected     [12]store.Money
+	Actual       [12]store.Money
+}
+
+// BuildYearMatrix gathers the board of every month of the year into a YearMatrix,
+// with rows ordered by category and label.
+func BuildYearMatrix(ctx context.Context, s store.Store, year int) (*YearMatrix, error) {
+	months, err := s.GetMonthsByYear(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := &YearMatrix{Year: year, Rows: []YearMatrixRow{}}
+	rowIndex := make(map[[2]string]int)
+	for _, month := range months {
+		board, err := LoadBoardInBaseCurrency(ctx, s, int(month.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load board for %d-%02d: %w", month.Year, month.Month, err)
+		}
+		for _, line := range board.BudgetLines {
+			key := [2]string{line.CategoryName, line.Label}
+			i, ok := rowIndex[key]
+			if !ok {
+				i = len(matrix.Rows)
+				rowIndex[key] = i
+				matrix.Rows = append(matrix.Rows, YearMatrixRow{CategoryName: line.CategoryName, Label: line.Label})
+			}
+			matrix.Rows[i].Expected[month.Month-1] += line.ExpectedAmount
+			matrix.Rows[i].Actual[month.Month-1] += line.ActualAmount
+		}
+	}
+
+	sort.Slice(matrix.Rows, func(i, j int) bool {
+		a, b := matrix.Rows[i], matrix.Rows[j]
+		if a.CategoryName != b.CategoryName {
+			return a.CategoryName < b.CategoryName
+		}
+		return a.Label < b.Label
+	})
+	return matrix, nil
+}
+
+// WriteYearMatrixCSV writes one row per budget line with expected and actual
+// columns for each month, the yearly totals, and a final total row.
+func WriteYearMatrixCSV(w io.Writer, matrix *YearMatrix, f CSVFormat) error {
+	cw, err := f.newWriter(w)
+	if err != nil {
+		return err
+	}
+
+	header := []string{"Category", "Label"}
+	for m := time.January; m <= time.December; m++ {
+		name := m.String()[:3]
+		header = append(header, name+" Expected", name+" Actual")
+	}
+	cw.Write(append(header, "Total Expected", "Total Actual"))
+
+	var total YearMatrixRow
+	writeRow := func(first, second string, row YearMatrixRow) {
+		record := []string{first, second}
+		var expected, actual store.Money
+		for i := 0; i < 12; i++ {
+			record = append(record, f.amount(row.Expected[i]), f.amount(row.Actual[i]))
+			expected += row.Expected[i]
+			actual += row.Actual[i]
+		}
+		cw.Write(append(record, f.amount(expected), f.amount(actual)))
+	}
+	for _, row := range matrix.Rows {
+		writeRow(csvText(row.CategoryName), csvText(row.Label), row)
+		for i := 0; i < 12; i++ {
+			total.Expected[i] += row.Expected[i]
+			total.Actual[i] += row.Actual[i]
+		}
+	}
+	writeRow("Total", "", total)
+
+	cw.Flush()
+	return cw.Error()
+}
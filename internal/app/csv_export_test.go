@@ -0,0 +1,91 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"gandalf-budget/internal/store"
+)
+
+func TestWriteBoardCSV_LocaleFormat(t *testing.T) {
+	board := &store.BoardDataPayload{
+		Year:      2024,
+		MonthName: "March",
+		BudgetLines: []store.BudgetLineWithActual{
+			{CategoryName: "Food", Label: "Groceries", ExpectedAmount: 1234_50, ActualAmount: 1000_00},
+			{CategoryName: "Home", Label: "=HYPERLINK(\"x\")", ExpectedAmount: 10_00, ActualAmount: 12_25},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBoardCSV(&buf, board, CSVFormat{Delimiter: ';', DecimalSeparator: ','}); err != nil {
+		t.Fatalf("WriteBoardCSV failed: %v", err)
+	}
+	expected := "Category;Label;Expected;Actual;Difference\n" +
+		"Food;Groceries;1234,50;1000,00;234,50\n" +
+		"Home;\"'=HYPERLINK(\"\"x\"\")\";10,00;12,25;-2,25\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected CSV:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
+
+func TestCSVFormat_Validate(t *testing.T) {
+	if err := DefaultCSVFormat.Validate(); err != nil {
+		t.Errorf("Default format should be valid: %v", err)
+	}
+	if err := (CSVFormat{Delimiter: ',', DecimalSeparator: ','}).Validate(); err == nil {
+		t.Error("Expected an error when the delimiter and decimal separator are the same")
+	}
+	if err := (CSVFormat{Delimiter: '"', DecimalSeparator: '.'}).Validate(); err == nil {
+		t.Error("Expected an error for an unsupported delimiter")
+	}
+}
+
+func TestBuildYearMatrix(t *testing.T) {
+	boards := map[int]*store.BoardDataPayload{
+		1: {BudgetLines: []store.BudgetLineWithActual{
+			{CategoryName: "Home", Label: "Rent", ExpectedAmount: 500_00, ActualAmount: 500_00},
+			{CategoryName: "Food", Label: "Groceries", ExpectedAmount: 100_00, ActualAmount: 90_00},
+		}},
+		2: {BudgetLines: []store.BudgetLineWithActual{
+			{CategoryName: "Home", Label: "Rent", ExpectedAmount: 500_00, ActualAmount: 510_00},
+		}},
+	}
+	mockStore := &store.ReusableMockStore{
+		MockGetMonthsByYear: func(ctx context.Context, year int) ([]store.Month, error) {
+			return []store.Month{{ID: 1, Year: year, Month: 1}, {ID: 2, Year: year, Month: 3}}, nil
+		},
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
+			return boards[monthID], nil
+		},
+	}
+
+	matrix, err := BuildYearMatrix(context.Background(), mockStore, 2024)
+	if err != nil {
+		t.Fatalf("BuildYearMatrix failed: %v", err)
+	}
+	if len(matrix.Rows) != 2 || matrix.Rows[0].Label != "Groceries" || matrix.Rows[1].Label != "Rent" {
+		t.Fatalf("Expected rows sorted by category and label, got %+v", matrix.Rows)
+	}
+	rent := matrix.Rows[1]
+	if rent.Actual[0] != 500_00 || rent.Actual[1] != 0 || rent.Actual[2] != 510_00 {
+		t.Errorf("Rent actuals not placed in their calendar months: %v", rent.Actual)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteYearMatrixCSV(&buf, matrix, DefaultCSVFormat); err != nil {
+		t.Fatalf("WriteYearMatrixCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected header, 2 rows and a total row, got %d lines", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "Category,Label,Jan Expected,Jan Actual,Feb Expected") {
+		t.Errorf("Unexpected header: %s", lines[0])
+	}
+	if !strings.HasSuffix(lines[3], ",1100.00,1100.00") {
+		t.Errorf("Unexpected total row: %s", lines[3])
+	}
+}
@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"gandalf-budget/internal/store"
+)
+
+// BaseCurrencyBoard is a month's board with every amount in the budget currency,
+// along with the exchange rates used to get there. Finalize snapshots are stored
+// in this shape so they keep the rates of their month.
+type BaseCurrencyBoard struct {
+	*store.BoardDataPayload
+	BaseCurrency  store.Currency             `json:"base_currency"`
+	ExchangeRates map[store.Currency]float64 `json:"exchange_rates"`
+}
+
+// LoadBoardInBaseCurrency loads a month's board and converts the amounts kept in
+// other currencies with the rates in effect for that month. Rates are only looked
+// up when the board has such amounts.
+func LoadBoardInBaseCurrency(ctx context.Context, s store.Store, monthID int) (*BaseCurrencyBoard, error) {
+	board, err := s.GetBoardData(ctx, monthID)
+	if err != nil {
+		return nil, err
+	}
+	if board == nil {
+		return nil, fmt.Errorf("no board data for month %d", monthID)
+	}
+
+	var rates map[store.Currency]float64
+	for _, line := range board.BudgetLines {
+		if isForeign(line.Currency) || isForeign(line.ActualCurrency) {
+			if rates, err = s.GetExchangeRatesForMonth(ctx, monthID); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return ConvertBoard(board, rates)
+}
+
+// ConvertBoard returns a copy of the board in the budget currency. It fails with
+// store.ErrNoExchangeRate when a currency of the board is missing from rates.
+func ConvertBoard(board *store.BoardDataPayload, rates map[store.Currency]float64) (*BaseCurrencyBoard, error) {
+	base := store.BudgetCurrency()
+	used := make(map[store.Currency]float64)
+	convert := func(amount store.Money, c store.Currency) (store.Money, error) {
+		if !isForeign(c) {
+			return amount, nil
+		}
+		rate, ok := rates[c]
+		if !ok {
+			return 0, fmt.Errorf("%w for %s in %s %d", store.ErrNoExchangeRate, c, board.MonthName, board.Year)
+		}
+		used[c] = rate
+		return amount.Convert(c, base, rate), nil
+	}
+
+	converted := *board
+	if board.BudgetLines != nil {
+		converted.BudgetLines = make([]store.BudgetLineWithActual, len(board.BudgetLines))
+	}
+	for i, line := range board.BudgetLines {
+		var err error
+		if line.ExpectedAmount, err = convert(line.ExpectedAmount, line.Currency); err != nil {
+			return nil, err
+		}
+		if line.ActualAmount, err = convert(line.ActualAmount, line.ActualCurrency); err != nil {
+			return nil, err
+		}
+		line.Currency, line.ActualCurrency = base, base
+		converted.BudgetLines[i] = line
+	}
+	return &BaseCurrencyBoard{BoardDataPayload: &converted, BaseCurrency: base, ExchangeRates: used}, nil
+}
+
+// isForeign reports whether amounts in c need converting. An empty code means
+// the budget currency.
+func isForeign(c store.Currency) bool {
+	return c != "" && c != store.BudgetCurrency()
+}
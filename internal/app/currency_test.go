@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"gandalf-budget/internal/store"
+)
+
+func TestConvertBoard(t *testing.T) {
+	board := &store.BoardDataPayload{
+		MonthID: 3, Year: 2024, MonthName: "March",
+		BudgetLines: []store.BudgetLineWithActual{
+			{ID: 1, Label: "Rent", ExpectedAmount: 900_00, ActualAmount: 900_00, Currency: "USD", ActualCurrency: "USD"},
+			{ID: 2, Label: "Hotel", ExpectedAmount: 200_00, ActualAmount: 150_50, Currency: "EUR", ActualCurrency: "EUR"},
+			{ID: 3, Label: "Streaming", ExpectedAmount: 10_00, ActualAmount: 12_99, Currency: "EUR", ActualCurrency: "USD"},
+			{ID: 4, Label: "Imported before currencies", ExpectedAmount: 5_00},
+			{ID: 5, Label: "Tokyo", ExpectedAmount: 15_000, ActualAmount: 0, Currency: "JPY", ActualCurrency: "USD"},
+		},
+	}
+
+	converted, err := ConvertBoard(board, map[store.Currency]float64{"EUR": 1.1, "GBP": 1.3, "JPY": 0.0067})
+	if err != nil {
+		t.Fatalf("ConvertBoard failed: %v", err)
+	}
+	lines := converted.BudgetLines
+	if lines[0].ExpectedAmount != 900_00 || lines[1].ExpectedAmount != 220_00 || lines[1].ActualAmount != 165_55 {
+		t.Errorf("Unexpected converted amounts: %+v", lines)
+	}
+	if lines[2].ExpectedAmount != 11_00 || lines[2].ActualAmount != 12_99 || lines[3].ExpectedAmount != 5_00 {
+		t.Errorf("Each amount should be converted from its own currency: %+v", lines)
+	}
+	if lines[4].ExpectedAmount != 100_50 {
+		t.Errorf("Yen have no minor unit, so 15000 yen should be 100.50 dollars, got %s", lines[4].ExpectedAmount)
+	}
+	for _, line := range lines {
+		if line.Currency != "USD" || line.ActualCurrency != "USD" {
+			t.Errorf("Converted line %d should be labelled with the budget currency: %+v", line.ID, line)
+		}
+	}
+	if len(converted.ExchangeRates) != 2 || converted.ExchangeRates["EUR"] != 1.1 || converted.ExchangeRates["GBP"] != 0 {
+		t.Errorf("Only the rates used should be kept, got %v", converted.ExchangeRates)
+	}
+	if board.BudgetLines[1].ExpectedAmount != 200_00 {
+		t.Error("ConvertBoard must not modify the original board")
+	}
+
+	snap, err := json.Marshal(converted)
+	if err != nil {
+		t.Fatalf("Failed to encode converted board: %v", err)
+	}
+	var decoded struct {
+		MonthName     string             `json:"month_name"`
+		BaseCurrency  string             `json:"base_currency"`
+		ExchangeRates map[string]float64 `json:"exchange_rates"`
+	}
+	if err := json.Unmarshal(snap, &decoded); err != nil || decoded.MonthName != "March" || decoded.BaseCurrency != "USD" || decoded.ExchangeRates["EUR"] != 1.1 {
+		t.Errorf("Snapshot JSON should hold the board, base currency and rates: %s", snap)
+	}
+
+	if _, err := ConvertBoard(board, nil); !errors.Is(err, store.ErrNoExchangeRate) {
+		t.Errorf("Expected ErrNoExchangeRate without rates, got %v", err)
+	}
+}
+
+func TestLoadBoardInBaseCurrency_SkipsRatesForBaseCurrencyBoards(t *testing.T) {
+	mockStore := &store.ReusableMockStore{
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
+			return &store.BoardDataPayload{MonthID: int64(monthID), BudgetLines: []store.BudgetLineWithActual{
+				{ExpectedAmount: 10_00, Currency: "USD", ActualCurrency: "USD"},
+			}}, nil
+		},
+	}
+	board, err := LoadBoardInBaseCurrency(context.Background(), mockStore, 1)
+	if err != nil {
+		t.Fatalf("Rates should not be looked up for a board in the budget currency: %v", err)
+	}
+	if board.BudgetLines[0].ExpectedAmount != 10_00 {
+		t.Errorf("Unexpected board: %+v", board.BudgetLines)
+	}
+}
+
+func TestParseExchangeRatesCSV(t *testing.T) {
+	rates, err := ParseExchangeRatesCSV([]byte("Currency;Rate;Date\nEUR;1,087;2024-03-15\n\ngbp;1.27;Apr 2024\n"))
+	if err != nil {
+		t.Fatalf("ParseExchangeRatesCSV failed: %v", err)
+	}
+	want := []store.ExchangeRate{
+		{Currency: "EUR", Year: 2024, Month: 3, Rate: 1.087},
+		{Currency: "GBP", Year: 2024, Month: 4, Rate: 1.27},
+	}
+	if len(rates) != 2 || rates[0] != want[0] || rates[1] != want[1] {
+		t.Errorf("Unexpected rates: %+v", rates)
+	}
+
+	for _, data := range []string{
+		"month,currency\n2024-03,EUR\n",
+		"month,currency,rate\n2024-03,EURO,1.1\n",
+		"month,currency,rate\nsoon,EUR,1.1\n",
+		"month,currency,rate\n2024-03,EUR,free\n",
+	} {
+		if _, err := ParseExchangeRatesCSV([]byte(data)); err == nil {
+			t.Errorf("Expected an error for %q", data)
+		}
+	}
+}
@@ -1,29 +1,95 @@
 package app
 
+import "gandalf-budget/internal/store"
+
 type DashboardPayload struct {
 	MonthID         int               `json:"month_id"`
 	Year            int               `json:"year"`
 	Month           string            `json:"month"`
-	TotalExpected   float64           `json:"total_expected"`
-	TotalActual     float64           `json:"total_actual"`
-	TotalDifference float64           `json:"total_difference"`
+	TotalExpected   store.Money       `json:"total_expected"`
+	TotalActual     store.Money       `json:"total_actual"`
+	TotalDifference store.Money       `json:"total_difference"`
 	CategorySummaries []CategorySummary `json:"category_summaries"`
+	// BaseCurrency is the currency of every amount; ExchangeRates are the rates
+	// used to convert lines kept in other currencies.
+	BaseCurrency  store.Currency             `json:"base_currency,omitempty"`
+	ExchangeRates map[store.Currency]float64 `json:"exchange_rates,omitempty"`
 }
 
 type CategorySummary struct {
 	CategoryID    int                `json:"category_id"`
 	CategoryName  string             `json:"category_name"`
 	CategoryColor string             `json:"category_color"`
-	TotalExpected float64            `json:"total_expected"`
-	TotalActual   float64            `json:"total_actual"`
-	Difference    float64            `json:"difference"`
+	TotalExpected store.Money        `json:"total_expected"`
+	TotalActual   store.Money        `json:"total_actual"`
+	Difference    store.Money        `json:"difference"`
 	BudgetLines   []BudgetLineDetail `json:"budget_lines"`
 }
 
 type BudgetLineDetail struct {
-	BudgetLineID   int     `json:"budget_line_id"`
-	Label          string  `json:"label"`
-	ExpectedAmount float64 `json:"expected_amount"`
-	ActualAmount   float64 `json:"actual_amount"`
-	Difference     float64 `json:"difference"`
+	BudgetLineID   int         `json:"budget_line_id"`
+	Label          string      `json:"label"`
+	ExpectedAmount store.Money `json:"expected_amount"`
+	ActualAmount   store.Money `json:"actual_amount"`
+	Difference     store.Money `json:"difference"`
+}
+
+// BuildDashboardPayload aggregates a month's board per category. Every category is
+// listed, in the order given, even when it has no budget lines this month.
+func BuildDashboardPayload(boardData *store.BoardDataPayload, allCategories []store.Category) *DashboardPayload {
+	payload := &DashboardPayload{
+		MonthID: int(boardData.MonthID),
+		Year:    boardData.Year,
+		Month:   boardData.MonthName,
+	}
+
+	var order []int64
+	categorySummariesMap := make(map[int64]*CategorySummary)
+	for _, cat := range allCategories {
+		order = append(order, cat.ID)
+		categorySummariesMap[cat.ID] = &CategorySummary{
+			CategoryID:    int(cat.ID),
+			CategoryName:  cat.Name,
+			CategoryColor: cat.Color,
+			BudgetLines:   []BudgetLineDetail{},
+		}
+	}
+
+	for _, line := range boardData.BudgetLines {
+		payload.TotalExpected += line.ExpectedAmount
+		payload.TotalActual += line.ActualAmount
+
+		summary, ok := categorySummariesMap[line.CategoryID]
+		if !ok {
+			summary = &CategorySummary{
+				CategoryID:    int(line.CategoryID),
+				CategoryName:  line.CategoryName,
+				CategoryColor: line.CategoryColor,
+				BudgetLines:   []BudgetLineDetail{},
+			}
+			order = append(order, line.CategoryID)
+			categorySummariesMap[line.CategoryID] = summary
+		}
+
+		summary.TotalExpected += line.ExpectedAmount
+		summary.TotalActual += line.ActualAmount
+
+		summary.BudgetLines = append(summary.BudgetLines, BudgetLineDetail{
+			BudgetLineID:   int(line.ID),
+			Label:          line.Label,
+			ExpectedAmount: line.ExpectedAmount,
+			ActualAmount:   line.ActualAmount,
+			Difference:     line.ExpectedAmount - line.ActualAmount,
+		})
+	}
+
+	payload.CategorySummaries = []CategorySummary{}
+	for _, id := range order {
+		summary := categorySummariesMap[id]
+		summary.Difference = summary.TotalExpected - summary.TotalActual
+		payload.CategorySummaries = append(payload.CategorySummaries, *summary)
+	}
+
+	payload.TotalDifference = payload.TotalExpected - payload.TotalActual
+	return payload
 }
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gandalf-budget/internal/store"
+)
+
+// demoLine is a budget line of the demo budget with the payments made against it
+// each month; those of the current month stop at today's date.
+type demoLine struct {
+	category string
+	label    string
+	expected float64
+	payments []demoPayment
+}
+
+type demoPayment struct {
+	day    int
+	amount float64
+	payee  string
+}
+
+var demoCategories = []store.Category{
+	{ID: 1, Name: "Housing", Color: "bg-blue-500"},
+	{ID: 2, Name: "Food", Color: "bg-green-500"},
+	{ID: 3, Name: "Transport", Color: "bg-yellow-500"},
+	{ID: 4, Name: "Leisure", Color: "bg-purple-500"},
+}
+
+var demoLines = []demoLine{
+	{"Housing", "Rent", 1200, []demoPayment{{1, 1200, "Landlord"}}},
+	{"Housing", "Electricity", 80, []demoPayment{{12, 74.35, "Power Co"}}},
+	{"Food", "Groceries", 450, []demoPayment{{3, 86.20, "Market"}, {10, 112.45, "Market"}, {17, 95.10, "Market"}, {24, 120.80, "Market"}}},
+	{"Food", "Restaurants", 120, []demoPayment{{8, 42.00, "Trattoria"}, {21, 58.50, "Sushi Bar"}}},
+	{"Transport", "Public transport", 60, []demoPayment{{2, 60, "City Transit"}}},
+	{"Transport", "Fuel", 90, []demoPayment{{6, 48.90, "Gas Station"}, {20, 51.30, "Gas Station"}}},
+	{"Leisure", "Cinema", 30, []demoPayment{{14, 24.00, "Cinema"}}},
+	{"Leisure", "Books", 40, []demoPayment{{27, 18.90, "Bookshop"}}},
+}
+
+// SeedDemoData fills an empty store with a small sample budget: last month,
+// finalized with its snapshot, and the current month rolled over from it with
+// the payments made so far. It is meant for the in-memory store of demo mode.
+func SeedDemoData(ctx context.Context, s store.Store, now time.Time) error {
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	previous := current.AddDate(0, -1, 0)
+
+	dump := &store.DatabaseDump{
+		Months:     []store.Month{{ID: 1, Year: previous.Year(), Month: int(previous.Month())}},
+		Categories: demoCategories,
+	}
+	categoryIDs := make(map[string]int)
+	for _, c := range demoCategories {
+		categoryIDs[c.Name] = int(c.ID)
+	}
+	for i, line := range demoLines {
+		dump.BudgetLines = append(dump.BudgetLines, store.BudgetLine{
+			ID: i + 1, MonthID: 1, CategoryID: categoryIDs[line.category], Label: line.label, Expected: store.MoneyFromFloat(line.expected),
+		})
+	}
+	if _, err := s.ImportAll(ctx, dump, store.ImportModeReplace, false); err != nil {
+		return fmt.Errorf("failed to load demo budget: %w", err)
+	}
+
+	if err := addDemoPayments(ctx, s, 1, previous, 31); err != nil {
+		return err
+	}
+	board, err := s.GetBoardData(ctx, 1)
+	if err != nil {
+		return fmt.Errorf("failed to load demo board for snapshot: %w", err)
+	}
+	board.IsFinalized = true
+	snapJSON, err := json.Marshal(board)
+	if err != nil {
+		return fmt.Errorf("failed to encode demo snapshot: %w", err)
+	}
+	currentID, err := s.FinalizeMonth(ctx, 1, string(snapJSON))
+	if err != nil {
+		return fmt.Errorf("failed to finalize demo month: %w", err)
+	}
+	return addDemoPayments(ctx, s, int(currentID), current, now.Day())
+}
+
+// addDemoPayments books the payments due up to lastDay against the month's lines.
+func addDemoPayments(ctx context.Context, s store.Store, monthID int, month time.Time, lastDay int) error {
+	lines, err := s.GetBudgetLinesByMonthID(ctx, monthID)
+	if err != nil {
+		return fmt.Errorf("failed to load demo budget lines: %w", err)
+	}
+	daysInMonth := month.AddDate(0, 1, -1).Day()
+	for _, bl := range lines {
+		for _, line := range demoLines {
+			if line.label != bl.Label {
+				continue
+			}
+			for _, p := range line.payments {
+				if p.day > lastDay || p.day > daysInMonth {
+					continue
+				}
+				t := &store.Transaction{
+					BudgetLineID: int64(bl.ID),
+					Date:         month.AddDate(0, 0, p.day-1),
+					Amount:       store.MoneyFromFloat(p.amount),
+					Payee:        p.payee,
+				}
+				if _, err := s.CreateTransaction(ctx, t); err != nil {
+					return fmt.Errorf("failed to add demo payment to %s: %w", bl.Label, err)
+				}
+			}
+		}
+	}
+	return nil
+}
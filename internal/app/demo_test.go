@@ -0,0 +1,46 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedDemoData(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	require.NoError(t, SeedDemoData(ctx, s, time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)))
+
+	months, err := s.GetMonthsByYear(ctx, 2024)
+	require.NoError(t, err)
+	require.Len(t, months, 2)
+	assert.Equal(t, store.Month{ID: 1, Year: 2024, Month: 2, Finalized: true}, months[0])
+	assert.Equal(t, store.Month{ID: 2, Year: 2024, Month: 3}, months[1])
+
+	snaps, err := s.GetAnnualSnapshotsMetadataByYear(ctx, 2024)
+	require.NoError(t, err)
+	require.Len(t, snaps, 1)
+	assert.Equal(t, int64(1), snaps[0].MonthID)
+
+	actuals := func(monthID int) map[string]store.Money {
+		lines, err := s.GetBudgetLinesByMonthID(ctx, monthID)
+		require.NoError(t, err)
+		byLabel := make(map[string]store.Money)
+		for _, bl := range lines {
+			byLabel[bl.Label] = *bl.ActualAmount
+		}
+		return byLabel
+	}
+	february := actuals(1)
+	assert.Len(t, february, len(demoLines))
+	assert.Equal(t, store.Money(414_55), february["Groceries"])
+	assert.Equal(t, store.Money(18_90), february["Books"], "payments on the 27th fit in February")
+
+	march := actuals(2)
+	assert.Equal(t, store.Money(198_65), march["Groceries"], "only payments up to today are booked")
+	assert.Equal(t, store.Money(0), march["Books"])
+}
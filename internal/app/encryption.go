@@ -0,0 +1,270 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encrypted backups are written as a header followed by AES-256-GCM sealed chunks:
+//
+//	magic (6) | salt (16) | scrypt log2(N) (1) | key check tag (16) | chunk...
+//
+// The key check tag lets a wrong passphrase be told apart from a tampered body.
+// Each chunk's nonce carries its sequence number and whether it is the last one,
+// so reordered, dropped or truncated chunks fail authentication.
+const (
+	encryptionMagic     = "GBENC\x01"
+	encryptionSaltSize  = 16
+	encryptionChunkSize = 64 << 10
+	scryptLogN          = 15
+	scryptR             = 8
+	scryptP             = 1
+)
+
+var (
+	ErrPassphraseRequired = errors.New("backup is encrypted: a passphrase is required")
+	ErrWrongPassphrase    = errors.New("wrong passphrase for encrypted backup")
+	ErrBackupTampered     = errors.New("encrypted backup failed authentication: the file was modified or is incomplete")
+)
+
+// IsEncryptedBackup reports whether the buffered input starts with the encrypted backup header.
+func IsEncryptedBackup(br *bufio.Reader) bool {
+	magic, err := br.Peek(len(encryptionMagic))
+	return err == nil && string(magic) == encryptionMagic
+}
+
+// NewEncryptWriter returns a writer that encrypts everything written to it into w.
+// Close must be called to write the final chunk; it does not close w.
+func NewEncryptWriter(w io.Writer, passphrase string) (io.WriteCloser, error) {
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	header := append([]byte(encryptionMagic), salt...)
+	header = append(header, scryptLogN)
+
+	aead, err := deriveBackupCipher(passphrase, salt, scryptLogN)
+	if err != nil {
+		return nil, err
+	}
+	header = aead.Seal(header, keyCheckNonce(), nil, header)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{aead: aead, w: w}, nil
+}
+
+type encryptWriter struct {
+	aead    cipher.AEAD
+	w       io.Writer
+	buf     []byte
+	counter uint64
+	closed  bool
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("write to closed encrypted backup")
+	}
+	e.buf = append(e.buf, p...)
+	// Hold back a full chunk until more data arrives: only Close knows which chunk is last.
+	for len(e.buf) > encryptionChunkSize {
+		if err := e.flush(e.buf[:encryptionChunkSize], false); err != nil {
+			return 0, err
+		}
+		e.buf = e.buf[encryptionChunkSize:]
+	}
+	return len(p), nil
+}
+
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.flush(e.buf, true)
+}
+
+func (e *encryptWriter) flush(chunk []byte, final bool) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.counter, final), chunk, nil)
+	e.counter++
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+// NewDecryptReader checks the passphrase against the header of an encrypted
+// backup and returns a reader of the decrypted content.
+func NewDecryptReader(r io.Reader, passphrase string) (io.Reader, error) {
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	headerSize := len(encryptionMagic) + encryptionSaltSize + 1
+	header := make([]byte, headerSize+16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrBackupTampered
+	}
+	if string(header[:len(encryptionMagic)]) != encryptionMagic {
+		return nil, errors.New("not an encrypted backup")
+	}
+	salt := header[len(encryptionMagic) : len(encryptionMagic)+encryptionSaltSize]
+	logN := header[headerSize-1]
+	if logN < 10 || logN > 20 {
+		return nil, ErrBackupTampered
+	}
+
+	aead, err := deriveBackupCipher(passphrase, salt, logN)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := aead.Open(nil, keyCheckNonce(), header[headerSize:], header[:headerSize]); err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return &decryptReader{aead: aead, r: bufio.NewReader(r)}, nil
+}
+
+type decryptReader struct {
+	aead    cipher.AEAD
+	r       *bufio.Reader
+	plain   bytes.Buffer
+	counter uint64
+	done    bool
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for d.plain.Len() == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.nextChunk(); err != nil {
+			return 0, err
+		}
+	}
+	return d.plain.Read(p)
+}
+
+func (d *decryptReader) nextChunk() error {
+	sealed := make([]byte, encryptionChunkSize+d.aead.Overhead())
+	n, err := io.ReadFull(d.r, sealed)
+	final := false
+	switch {
+	case err == io.ErrUnexpectedEOF:
+		final = true
+	case err == io.EOF:
+		// The stream ended without a chunk marked as final.
+		return ErrBackupTampered
+	case err != nil:
+		return err
+	default:
+		if _, peekErr := d.r.Peek(1); peekErr == io.EOF {
+			final = true
+		}
+	}
+
+	chunk, err := d.aead.Open(nil, chunkNonce(d.counter, final), sealed[:n], nil)
+	if err != nil {
+		return ErrBackupTampered
+	}
+	d.counter++
+	d.done = final
+	d.plain.Write(chunk)
+	return nil
+}
+
+func deriveBackupCipher(passphrase string, salt []byte, logN byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<logN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive backup key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(counter uint64, final bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, counter)
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+func keyCheckNonce() []byte {
+	nonce := make([]byte, 12)
+	nonce[11] = 2
+	return nonce
+}
+
+// EncryptBackupFile writes an encrypted copy of src to dst, going through a
+// temporary file so a failed run never leaves a partial dst behind.
+func EncryptBackupFile(src, dst, passphrase string) error {
+	return transformBackupFile(src, dst, func(w io.Writer, r io.Reader) error {
+		enc, err := NewEncryptWriter(w, passphrase)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(enc, r); err != nil {
+			return err
+		}
+		return enc.Close()
+	})
+}
+
+// DecryptBackupFile writes the decrypted content of the encrypted backup src to dst.
+func DecryptBackupFile(src, dst, passphrase string) error {
+	return transformBackupFile(src, dst, func(w io.Writer, r io.Reader) error {
+		br := bufio.NewReader(r)
+		if !IsEncryptedBackup(br) {
+			return fmt.Errorf("%s is not an encrypted backup", src)
+		}
+		dec, err := NewDecryptReader(br, passphrase)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, dec)
+		return err
+	})
+}
+
+func transformBackupFile(src, dst string, transform func(w io.Writer, r io.Reader) error) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	tmpPath := dst + ".tmp"
+	out, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmpPath, err)
+	}
+	if err := transform(out, in); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, dst); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to move %s into place: %w", dst, err)
+	}
+	return nil
+}
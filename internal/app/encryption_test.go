@@ -0,0 +1,95 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func encryptForTest(t *testing.T, plaintext []byte, passphrase string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	enc, err := NewEncryptWriter(&out, passphrase)
+	if err != nil {
+		t.Fatalf("NewEncryptWriter failed: %v", err)
+	}
+	if _, err := enc.Write(plaintext); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	return out.Bytes()
+}
+
+func decryptForTest(ciphertext []byte, passphrase string) ([]byte, error) {
+	dec, err := NewDecryptReader(bytes.NewReader(ciphertext), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(dec)
+}
+
+func TestEncryption_RoundTrip(t *testing.T) {
+	sizes := map[string]int{
+		"empty":             0,
+		"small":             100,
+		"exactly one chunk": encryptionChunkSize,
+		"several chunks":    3*encryptionChunkSize + 17,
+	}
+	for name, size := range sizes {
+		t.Run(name, func(t *testing.T) {
+			plaintext := make([]byte, size)
+			rand.Read(plaintext)
+
+			ciphertext := encryptForTest(t, plaintext, "correct horse")
+			if !IsEncryptedBackup(bufio.NewReader(bytes.NewReader(ciphertext))) {
+				t.Fatal("Encrypted output is not recognised as an encrypted backup")
+			}
+			got, err := decryptForTest(ciphertext, "correct horse")
+			if err != nil {
+				t.Fatalf("Decrypt failed: %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Fatalf("Round trip changed the data (%d bytes in, %d out)", len(plaintext), len(got))
+			}
+		})
+	}
+}
+
+func TestEncryption_Failures(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("budget "), encryptionChunkSize/3)
+	ciphertext := encryptForTest(t, plaintext, "correct horse")
+	headerSize := len(encryptionMagic) + encryptionSaltSize + 1 + 16
+
+	tampered := append([]byte(nil), ciphertext...)
+	tampered[headerSize+10] ^= 0x01
+
+	// Dropping the final chunk leaves a stream that ends on a full, non-final chunk.
+	firstChunkOnly := ciphertext[:headerSize+encryptionChunkSize+16]
+
+	tests := []struct {
+		name       string
+		ciphertext []byte
+		passphrase string
+		expected   error
+	}{
+		{"Missing passphrase", ciphertext, "", ErrPassphraseRequired},
+		{"Wrong passphrase", ciphertext, "battery staple", ErrWrongPassphrase},
+		{"Flipped body byte", tampered, "correct horse", ErrBackupTampered},
+		{"Truncated mid-chunk", ciphertext[:len(ciphertext)-5], "correct horse", ErrBackupTampered},
+		{"Final chunk dropped", firstChunkOnly, "correct horse", ErrBackupTampered},
+		{"Truncated header", ciphertext[:10], "correct horse", ErrBackupTampered},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := decryptForTest(tc.ciphertext, tc.passphrase)
+			if !errors.Is(err, tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
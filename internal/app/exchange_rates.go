@@ -0,0 +1,79 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gandalf-budget/internal/store"
+)
+
+// ParseExchangeRatesCSV reads exchange rates from a CSV file with a header row
+// naming the month (or date), currency and rate columns, in any order, e.g.
+//
+//	month,currency,rate
+//	2024-03,EUR,1.087
+//
+// Months take the same formats as the legacy import. A rate is the number of
+// budget currency units one unit of the currency buys. Any bad row fails the
+// whole file, so an import is never applied halfway.
+func ParseExchangeRatesCSV(data []byte) ([]store.ExchangeRate, error) {
+	records, lines, err := readLegacyCSV(data, "")
+	if err != nil {
+		return nil, err
+	}
+
+	headerIndex := -1
+	for i, record := range records {
+		if !isBlankRecord(record) {
+			headerIndex = i
+			break
+		}
+	}
+	if headerIndex < 0 {
+		return nil, errors.New("the file has no header row")
+	}
+	columns := map[string]int{}
+	for i, name := range records[headerIndex] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "date" {
+			name = "month"
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"month", "currency", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("the header has no %q column", name)
+		}
+	}
+
+	rates := []store.ExchangeRate{}
+	for i := headerIndex + 1; i < len(records); i++ {
+		record := records[i]
+		if isBlankRecord(record) {
+			continue
+		}
+		field := func(name string) string {
+			if j := columns[name]; j < len(record) {
+				return strings.TrimSpace(record[j])
+			}
+			return ""
+		}
+
+		year, month, err := parseLegacyPeriod(field("month"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lines[i], err)
+		}
+		currency, err := store.ParseCurrency(field("currency"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lines[i], err)
+		}
+		rate, err := strconv.ParseFloat(strings.Replace(field("rate"), ",", ".", 1), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("line %d: invalid rate %q", lines[i], field("rate"))
+		}
+		rates = append(rates, store.ExchangeRate{Currency: currency, Year: year, Month: month, Rate: rate})
+	}
+	return rates, nil
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gandalf-budget/internal/store"
+)
+
+// Codes of the issues that block finalizing a month.
+const (
+	FinalizeIssueFinalized      = "finalized"        // the month is already finalized
+	FinalizeIssueZeroActual     = "zero_actual"      // a line has no actual yet
+	FinalizeIssueNoExchangeRate = "no_exchange_rate" // a line's currency has no rate for the month
+)
+
+// FinalizeIssue is one reason a month cannot be finalized yet. BudgetLineID and
+// Label are empty for an issue with the whole month.
+type FinalizeIssue struct {
+	BudgetLineID int64  `json:"budget_line_id,omitempty"`
+	Label        string `json:"label,omitempty"`
+	Code         string `json:"code"`
+	Message      string `json:"message"`
+}
+
+// FinalizePreview is what finalizing a month would do. Snapshot is exactly what
+// would be stored, or null when it cannot be built; the rest comes from
+// store.FinalizePlan and is empty when the month is already finalized.
+type FinalizePreview struct {
+	MonthID          int64              `json:"month_id"`
+	CanFinalize      bool               `json:"can_finalize"`
+	Issues           []FinalizeIssue    `json:"issues"`
+	Snapshot         json.RawMessage    `json:"snapshot"`
+	SnapshotVersion  int                `json:"snapshot_version"`
+	NextMonth        *store.Month       `json:"next_month"`
+	CreatesNextMonth bool               `json:"creates_next_month"`
+	ClonedLines      []store.BudgetLine `json:"cloned_lines"`
+}
+
+// FinalizeSnapshot builds the snapshot stored when a month is finalized: its
+// board in the budget currency, as JSON.
+func FinalizeSnapshot(ctx context.Context, s store.Store, monthID int) (string, error) {
+	board, err := LoadBoardInBaseCurrency(ctx, s, monthID)
+	if err != nil {
+		return "", err
+	}
+	snapJSON, err := json.Marshal(board)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode snapshot of month %d: %w", monthID, err)
+	}
+	return string(snapJSON), nil
+}
+
+// PreviewFinalize works out what finalizing a month would do without changing
+// anything, and lists every issue that would block it, line by line. It
+// returns sql.ErrNoRows when the month does not exist.
+func PreviewFinalize(ctx context.Context, s store.Store, monthID int) (*FinalizePreview, error) {
+	month, err := s.GetMonthByID(ctx, int64(monthID))
+	if err != nil {
+		return nil, err
+	}
+	preview := &FinalizePreview{MonthID: month.ID, Issues: []FinalizeIssue{}, ClonedLines: []store.BudgetLine{}}
+	finalized := month.Finalized
+	if !finalized {
+		// The month may get finalized between the two reads.
+		plan, err := s.PlanFinalizeMonth(ctx, monthID)
+		switch {
+		case errors.Is(err, store.ErrMonthFinalized):
+			finalized = true
+		case err != nil:
+			return nil, err
+		default:
+			preview.SnapshotVersion, preview.NextMonth = plan.SnapshotVersion, &plan.NextMonth
+			preview.CreatesNextMonth, preview.ClonedLines = plan.CreatesNextMonth, plan.ClonedLines
+		}
+	}
+	if finalized {
+		preview.Issues = append(preview.Issues, FinalizeIssue{Code: FinalizeIssueFinalized, Message: "The month is already finalized"})
+	}
+
+	issues, err := lineIssues(ctx, s, monthID)
+	if err != nil {
+		return nil, err
+	}
+	preview.Issues = append(preview.Issues, issues...)
+
+	snapJSON, err := FinalizeSnapshot(ctx, s, monthID)
+	switch {
+	case err == nil:
+		preview.Snapshot = json.RawMessage(snapJSON)
+	case !errors.Is(err, store.ErrNoExchangeRate):
+		return nil, err
+	}
+	preview.CanFinalize = len(preview.Issues) == 0
+	return preview, nil
+}
+
+// lineIssues checks each budget line of a month the way finalizing does: a line
+// whose actual is still zero holds it back (store.CanFinalizeMonth), and so
+// does an amount in a currency without an exchange rate for the month
+// (ConvertBoard).
+func lineIssues(ctx context.Context, s store.Store, monthID int) ([]FinalizeIssue, error) {
+	lines, err := s.GetBudgetLinesByMonthID(ctx, monthID)
+	if err != nil {
+		return nil, err
+	}
+	var rates map[store.Currency]float64
+	for _, bl := range lines {
+		if isForeign(bl.Currency) || (bl.ActualCurrency != nil && isForeign(*bl.ActualCurrency)) {
+			if rates, err = s.GetExchangeRatesForMonth(ctx, monthID); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	var issues []FinalizeIssue
+	for _, bl := range lines {
+		if bl.ActualAmount != nil && *bl.ActualAmount == 0 {
+			issues = append(issues, FinalizeIssue{
+				BudgetLineID: int64(bl.ID), Label: bl.Label, Code: FinalizeIssueZeroActual,
+				Message: fmt.Sprintf("%s has no actual amount yet", bl.Label),
+			})
+		}
+		currencies := []store.Currency{bl.Currency}
+		if bl.ActualCurrency != nil && *bl.ActualCurrency != bl.Currency {
+			currencies = append(currencies, *bl.ActualCurrency)
+		}
+		for _, c := range currencies {
+			if _, ok := rates[c]; isForeign(c) && !ok {
+				issues = append(issues, FinalizeIssue{
+					BudgetLineID: int64(bl.ID), Label: bl.Label, Code: FinalizeIssueNoExchangeRate,
+					Message: fmt.Sprintf("%s is kept in %s, which has no exchange rate for the month", bl.Label, c),
+				})
+			}
+		}
+	}
+	return issues, nil
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewFinalize(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	march := &store.Month{Year: 2024, Month: 3}
+	require.NoError(t, s.CreateMonth(ctx, march))
+	food := &store.Category{Name: "Food", Color: "#0f0"}
+	require.NoError(t, s.CreateCategory(ctx, food))
+	groceriesID, err := s.CreateBudgetLine(ctx, &store.BudgetLine{MonthID: int(march.ID), CategoryID: int(food.ID), Label: "Groceries", Expected: 400_00})
+	require.NoError(t, err)
+	hotelID, err := s.CreateBudgetLine(ctx, &store.BudgetLine{MonthID: int(march.ID), CategoryID: int(food.ID), Label: "Hotel", Expected: 200_00, Currency: "EUR"})
+	require.NoError(t, err)
+	lines, err := s.GetBudgetLinesByMonthID(ctx, int(march.ID))
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateActualLine(ctx, &store.ActualLine{ID: *lines[1].ActualID, Actual: 150_00, Currency: "EUR"}))
+
+	preview, err := PreviewFinalize(ctx, s, int(march.ID))
+	require.NoError(t, err)
+	assert.False(t, preview.CanFinalize)
+	assert.Equal(t, []FinalizeIssue{
+		{BudgetLineID: groceriesID, Label: "Groceries", Code: FinalizeIssueZeroActual, Message: "Groceries has no actual amount yet"},
+		{BudgetLineID: hotelID, Label: "Hotel", Code: FinalizeIssueNoExchangeRate, Message: "Hotel is kept in EUR, which has no exchange rate for the month"},
+	}, preview.Issues)
+	assert.Nil(t, preview.Snapshot, "the board cannot be converted without the rate")
+	assert.Equal(t, 1, preview.SnapshotVersion)
+	assert.Equal(t, &store.Month{Year: 2024, Month: 4}, preview.NextMonth)
+	assert.True(t, preview.CreatesNextMonth)
+	assert.Equal(t, []store.BudgetLine{
+		{CategoryID: int(food.ID), Label: "Groceries", Expected: 400_00, Currency: "USD"},
+		{CategoryID: int(food.ID), Label: "Hotel", Expected: 200_00, Currency: "EUR"},
+	}, preview.ClonedLines)
+	months, err := s.GetMonths(ctx, store.MonthFilter{})
+	require.NoError(t, err)
+	assert.Len(t, months, 1, "a preview changes nothing")
+
+	require.NoError(t, s.SetExchangeRates(ctx, []store.ExchangeRate{{Currency: "EUR", Year: 2024, Month: 3, Rate: 1.1}}))
+	require.NoError(t, s.UpdateActualLine(ctx, &store.ActualLine{ID: *lines[0].ActualID, Actual: 380_00}))
+	preview, err = PreviewFinalize(ctx, s, int(march.ID))
+	require.NoError(t, err)
+	assert.True(t, preview.CanFinalize)
+	assert.Empty(t, preview.Issues)
+	snapJSON, err := FinalizeSnapshot(ctx, s, int(march.ID))
+	require.NoError(t, err)
+	assert.JSONEq(t, snapJSON, string(preview.Snapshot))
+
+	_, err = s.FinalizeMonth(ctx, int(march.ID), snapJSON)
+	require.NoError(t, err)
+	snaps, err := s.GetMonthSnapshots(ctx, march.ID)
+	require.NoError(t, err)
+	require.Len(t, snaps, 1)
+	stored, err := s.GetAnnualSnapshotJSONByID(ctx, snaps[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(preview.Snapshot), stored, "the preview showed exactly what was stored")
+
+	preview, err = PreviewFinalize(ctx, s, int(march.ID))
+	require.NoError(t, err)
+	assert.False(t, preview.CanFinalize)
+	assert.Equal(t, []FinalizeIssue{{Code: FinalizeIssueFinalized, Message: "The month is already finalized"}}, preview.Issues)
+	assert.Nil(t, preview.NextMonth)
+	assert.Empty(t, preview.ClonedLines)
+
+	_, err = PreviewFinalize(ctx, s, 99)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+// staleMonthStore reports months as they were before being finalized, as if
+// they were finalized right after the read.
+type staleMonthStore struct {
+	store.Store
+}
+
+func (s staleMonthStore) GetMonthByID(ctx context.Context, id int64) (*store.Month, error) {
+	month, err := s.Store.GetMonthByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	month.Finalized = false
+	return month, nil
+}
+
+func TestPreviewFinalizeRacesFinalize(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	march := &store.Month{Year: 2024, Month: 3}
+	require.NoError(t, s.CreateMonth(ctx, march))
+	_, err := s.FinalizeMonth(ctx, int(march.ID), "{}")
+	require.NoError(t, err)
+
+	preview, err := PreviewFinalize(ctx, staleMonthStore{s}, int(march.ID))
+	require.NoError(t, err)
+	assert.False(t, preview.CanFinalize)
+	assert.Equal(t, []FinalizeIssue{{Code: FinalizeIssueFinalized, Message: "The month is already finalized"}}, preview.Issues)
+	assert.Nil(t, preview.NextMonth)
+}
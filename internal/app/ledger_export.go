@@ -0,0 +1,274 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"gandalf-budget/internal/store"
+)
+
+type LedgerFormat string
+
+const (
+	LedgerFormatHledger   LedgerFormat = "hledger"
+	LedgerFormatLedger    LedgerFormat = "ledger"
+	LedgerFormatBeancount LedgerFormat = "beancount"
+)
+
+// FileExtension is the conventional journal file extension for the format.
+func (f LedgerFormat) FileExtension() string {
+	switch f {
+	case LedgerFormatHledger:
+		return "journal"
+	case LedgerFormatBeancount:
+		return "beancount"
+	default:
+		return "ledger"
+	}
+}
+
+// LedgerOptions controls the account names and commodity of a journal export.
+// Each category becomes the account ExpensePrefix:<category name> unless
+// Accounts maps the category name (case-insensitively) to a full account name.
+type LedgerOptions struct {
+	Format         LedgerFormat
+	ExpensePrefix  string
+	FundingAccount string
+	Commodity      string
+	Accounts       map[string]string
+}
+
+// DefaultLedgerOptions returns the options for a format in the budget currency;
+// beancount needs a currency code rather than a symbol such as $.
+func DefaultLedgerOptions(format LedgerFormat) LedgerOptions {
+	opts := LedgerOptions{Format: format, ExpensePrefix: "Expenses", FundingAccount: "Assets:Checking", Commodity: string(store.BudgetCurrency())}
+	if format != LedgerFormatBeancount && opts.Commodity == "USD" {
+		opts.Commodity = "$"
+	}
+	return opts
+}
+
+func (o LedgerOptions) Validate() error {
+	switch o.Format {
+	case LedgerFormatHledger, LedgerFormatLedger:
+	case LedgerFormatBeancount:
+		for _, r := range o.Commodity {
+			if !unicode.IsUpper(r) && !unicode.IsDigit(r) {
+				return fmt.Errorf("beancount commodity %q must be an upper-case code such as USD", o.Commodity)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported ledger format %q: use hledger, ledger or beancount", o.Format)
+	}
+	if strings.TrimSpace(o.ExpensePrefix) == "" || strings.TrimSpace(o.FundingAccount) == "" {
+		return fmt.Errorf("expense prefix and funding account are required")
+	}
+	if strings.TrimSpace(o.Commodity) == "" {
+		return fmt.Errorf("commodity is required")
+	}
+	return nil
+}
+
+// ParseLedgerAccounts reads category-to-account overrides written as
+// "Food=Expenses:Groceries,Home=Expenses:Housing".
+func ParseLedgerAccounts(spec string) (map[string]string, error) {
+	accounts := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		category, account, ok := strings.Cut(pair, "=")
+		category, account = strings.TrimSpace(category), strings.TrimSpace(account)
+		if !ok || category == "" || account == "" {
+			return nil, fmt.Errorf("invalid account mapping %q: use Category=Account:Name", pair)
+		}
+		accounts[strings.ToLower(category)] = account
+	}
+	return accounts, nil
+}
+
+// WriteLedger writes a year of budget as a plain-text accounting journal. Each
+// month's expected amounts become a periodic budget transaction limited to that
+// month (a custom "budget" directive for beancount), so the tools' budget
+// reports compare against the same figures as the app. Actuals become one
+// transaction per month, dated its last day and cleared once the month is
+// finalized, with the budget line labels as posting comments.
+func WriteLedger(ctx context.Context, w io.Writer, s store.Store, year int, opts LedgerOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	months, err := s.GetMonthsByYear(ctx, year)
+	if err != nil {
+		return err
+	}
+	boards := make([]*store.BoardDataPayload, 0, len(months))
+	for _, month := range months {
+		board, err := LoadBoardInBaseCurrency(ctx, s, int(month.ID))
+		if err != nil {
+			return fmt.Errorf("failed to load board for %d-%02d: %w", month.Year, month.Month, err)
+		}
+		boards = append(boards, board.BoardDataPayload)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "; Gandalf Budget export for %d\n", year)
+
+	if opts.Format == LedgerFormatBeancount && len(months) > 0 {
+		// Beancount rejects postings to accounts that were never opened.
+		opened := make(map[string]bool)
+		openOn := firstDay(months[0])
+		fmt.Fprintln(bw)
+		for _, account := range append([]string{opts.FundingAccount}, ledgerAccountsOf(boards, opts)...) {
+			if !opened[account] {
+				opened[account] = true
+				fmt.Fprintf(bw, "%s open %s\n", openOn.Format("2006-01-02"), account)
+			}
+		}
+	}
+
+	for i, month := range months {
+		categories := ledgerCategories(boards[i], opts)
+		if len(categories) == 0 {
+			continue
+		}
+		title := fmt.Sprintf("%s %d", time.Month(month.Month), month.Year)
+
+		fmt.Fprintln(bw)
+		writeLedgerBudget(bw, opts, month, title, categories)
+		writeLedgerActuals(bw, opts, month, title, categories)
+	}
+	return bw.Flush()
+}
+
+type ledgerCategory struct {
+	account  string
+	expected store.Money
+	lines    []store.BudgetLineWithActual
+}
+
+// ledgerCategories groups a month's lines by account, in board order.
+func ledgerCategories(board *store.BoardDataPayload, opts LedgerOptions) []*ledgerCategory {
+	var categories []*ledgerCategory
+	byAccount := make(map[string]*ledgerCategory)
+	for _, line := range board.BudgetLines {
+		account := ledgerAccount(line.CategoryName, opts)
+		c, ok := byAccount[account]
+		if !ok {
+			c = &ledgerCategory{account: account}
+			byAccount[account] = c
+			categories = append(categories, c)
+		}
+		c.expected += line.ExpectedAmount
+		c.lines = append(c.lines, line)
+	}
+	return categories
+}
+
+func ledgerAccountsOf(boards []*store.BoardDataPayload, opts LedgerOptions) []string {
+	var accounts []string
+	for _, board := range boards {
+		for _, c := range ledgerCategories(board, opts) {
+			accounts = append(accounts, c.account)
+		}
+	}
+	return accounts
+}
+
+func writeLedgerBudget(w io.Writer, opts LedgerOptions, month store.Month, title string, categories []*ledgerCategory) {
+	start := firstDay(month)
+	if opts.Format == LedgerFormatBeancount {
+		for _, c := range categories {
+			fmt.Fprintf(w, "%s custom \"budget\" %s \"monthly\" %s ; %s\n",
+				start.Format("2006-01-02"), c.account, ledgerAmount(c.expected, opts), title)
+		}
+		return
+	}
+
+	fmt.Fprintf(w, "~ monthly from %s to %s\n", start.Format("2006-01-02"), start.AddDate(0, 1, 0).Format("2006-01-02"))
+	fmt.Fprintf(w, "    ; Budget %s\n", title)
+	for _, c := range categories {
+		fmt.Fprintf(w, "    %-40s  %s\n", c.account, ledgerAmount(c.expected, opts))
+	}
+	fmt.Fprintf(w, "    %s\n", opts.FundingAccount)
+}
+
+func writeLedgerActuals(w io.Writer, opts LedgerOptions, month store.Month, title string, categories []*ledgerCategory) {
+	var postings []string
+	for _, c := range categories {
+		for _, line := range c.lines {
+			if line.ActualAmount == 0 {
+				continue
+			}
+			postings = append(postings, fmt.Sprintf("    %-40s  %s ; %s",
+				c.account, ledgerAmount(line.ActualAmount, opts), ledgerComment(line.Label)))
+		}
+	}
+	if len(postings) == 0 {
+		return
+	}
+
+	flag := "!"
+	if month.Finalized {
+		flag = "*"
+	}
+	date := firstDay(month).AddDate(0, 1, -1).Format("2006-01-02")
+	if opts.Format == LedgerFormatBeancount {
+		fmt.Fprintf(w, "\n%s %s \"%s actuals\"\n", date, flag, title)
+	} else {
+		fmt.Fprintf(w, "\n%s %s %s actuals\n", date, flag, title)
+	}
+	for _, p := range postings {
+		fmt.Fprintln(w, p)
+	}
+	fmt.Fprintf(w, "    %s\n", opts.FundingAccount)
+}
+
+func firstDay(month store.Month) time.Time {
+	return time.Date(month.Year, time.Month(month.Month), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ledgerAccount derives the account for a category. Beancount only allows
+// capitalised components of letters, digits and dashes, so names are reduced to
+// that; the other tools accept spaces but not the ':' separator or runs of spaces.
+func ledgerAccount(category string, opts LedgerOptions) string {
+	if account, ok := opts.Accounts[strings.ToLower(category)]; ok {
+		return account
+	}
+	var component string
+	if opts.Format == LedgerFormatBeancount {
+		var b strings.Builder
+		for _, word := range strings.FieldsFunc(category, func(r rune) bool {
+			return r > unicode.MaxASCII || (!unicode.IsLetter(r) && !unicode.IsDigit(r))
+		}) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+		component = b.String()
+	} else {
+		component = strings.Join(strings.Fields(strings.ReplaceAll(category, ":", "-")), " ")
+	}
+	if component == "" {
+		component = "Uncategorized"
+	}
+	return opts.ExpensePrefix + ":" + component
+}
+
+// ledgerAmount puts a one-character symbol before the number ($12.50) and a
+// commodity code after it (12.50 EUR), as the tools write them.
+func ledgerAmount(amount store.Money, opts LedgerOptions) string {
+	if opts.Format != LedgerFormatBeancount && len([]rune(opts.Commodity)) == 1 {
+		if amount < 0 {
+			return fmt.Sprintf("-%s%s", opts.Commodity, -amount)
+		}
+		return fmt.Sprintf("%s%s", opts.Commodity, amount)
+	}
+	return fmt.Sprintf("%s %s", amount, opts.Commodity)
+}
+
+func ledgerComment(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,112 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"gandalf-budget/internal/store"
+)
+
+func ledgerTestStore() *store.ReusableMockStore {
+	boards := map[int]*store.BoardDataPayload{
+		1: {BudgetLines: []store.BudgetLineWithActual{
+			{CategoryName: "Food & Drink", Label: "Groceries", ExpectedAmount: 300_00, ActualAmount: 280_50},
+			{CategoryName: "Food & Drink", Label: "Take  away", ExpectedAmount: 50_00, ActualAmount: 0},
+			{CategoryName: "Home", Label: "Rent", ExpectedAmount: 900_00, ActualAmount: 900_00},
+		}},
+		2: {BudgetLines: []store.BudgetLineWithActual{
+			{CategoryName: "Home", Label: "Rent", ExpectedAmount: 950_00},
+		}},
+		3: {BudgetLines: []store.BudgetLineWithActual{}},
+	}
+	return &store.ReusableMockStore{
+		MockGetMonthsByYear: func(ctx context.Context, year int) ([]store.Month, error) {
+			return []store.Month{
+				{ID: 1, Year: year, Month: 1, Finalized: true},
+				{ID: 2, Year: year, Month: 2},
+				{ID: 3, Year: year, Month: 3},
+			}, nil
+		},
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
+			return boards[monthID], nil
+		},
+	}
+}
+
+func TestWriteLedger_Hledger(t *testing.T) {
+	opts := DefaultLedgerOptions(LedgerFormatHledger)
+	opts.Accounts = map[string]string{"home": "Expenses:Housing"}
+
+	var buf bytes.Buffer
+	if err := WriteLedger(context.Background(), &buf, ledgerTestStore(), 2024, opts); err != nil {
+		t.Fatalf("WriteLedger failed: %v", err)
+	}
+	expected := `; Gandalf Budget export for 2024
+
+~ monthly from 2024-01-01 to 2024-02-01
+    ; Budget January 2024
+    Expenses:Food & Drink                     $350.00
+    Expenses:Housing                          $900.00
+    Assets:Checking
+
+2024-01-31 * January 2024 actuals
+    Expenses:Food & Drink                     $280.50 ; Groceries
+    Expenses:Housing                          $900.00 ; Rent
+    Assets:Checking
+
+~ monthly from 2024-02-01 to 2024-03-01
+    ; Budget February 2024
+    Expenses:Housing                          $950.00
+    Assets:Checking
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected journal:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
+
+func TestWriteLedger_Beancount(t *testing.T) {
+	ctx := context.Background()
+	opts := DefaultLedgerOptions(LedgerFormatBeancount)
+	opts.Commodity = "EUR"
+
+	var buf bytes.Buffer
+	if err := WriteLedger(ctx, &buf, ledgerTestStore(), 2024, opts); err != nil {
+		t.Fatalf("WriteLedger failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"2024-01-01 open Assets:Checking\n",
+		"2024-01-01 open Expenses:FoodDrink\n",
+		"2024-01-01 open Expenses:Home\n",
+		`2024-01-01 custom "budget" Expenses:FoodDrink "monthly" 350.00 EUR ; January 2024`,
+		`2024-02-01 custom "budget" Expenses:Home "monthly" 950.00 EUR ; February 2024`,
+		"2024-01-31 * \"January 2024 actuals\"\n    Expenses:FoodDrink                        280.50 EUR ; Groceries\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the journal to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "open Expenses:Home") != 1 {
+		t.Errorf("Each account should be opened once:\n%s", out)
+	}
+
+	opts.Commodity = "$"
+	if err := WriteLedger(ctx, &buf, ledgerTestStore(), 2024, opts); err == nil {
+		t.Error("Expected an error for a beancount commodity symbol")
+	}
+}
+
+func TestParseLedgerAccounts(t *testing.T) {
+	accounts, err := ParseLedgerAccounts(" Food = Expenses:Groceries ,Home=Expenses:Housing,")
+	if err != nil {
+		t.Fatalf("ParseLedgerAccounts failed: %v", err)
+	}
+	if len(accounts) != 2 || accounts["food"] != "Expenses:Groceries" || accounts["home"] != "Expenses:Housing" {
+		t.Errorf("Unexpected accounts: %v", accounts)
+	}
+	if _, err := ParseLedgerAccounts("Food"); err == nil {
+		t.Error("Expected an error for a mapping without an account")
+	}
+}
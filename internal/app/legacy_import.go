@@ -0,0 +1,319 @@
+package app
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gandalf-budget/internal/store"
+)
+
+// LegacyColumnMapping names the spreadsheet columns (by header text, case-insensitive)
+// that hold each field. Month holds the period ("2023-01", "01/2023", "Jan 2023" or
+// a date); when Year is set, Month holds just the month (1-12 or a month name).
+type LegacyColumnMapping struct {
+	Month    string `json:"month"`
+	Year     string `json:"year,omitempty"`
+	Category string `json:"category"`
+	Label    string `json:"label"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+
+	// Sheet selects the XLSX worksheet by name; the first sheet is used by default.
+	Sheet string `json:"sheet,omitempty"`
+	// Delimiter and DecimalSeparator only apply to CSV input. The delimiter is
+	// detected from the header row when empty; the decimal separator defaults to ".".
+	Delimiter        string `json:"delimiter,omitempty"`
+	DecimalSeparator string `json:"decimal_separator,omitempty"`
+}
+
+type LegacyFileFormat string
+
+const (
+	LegacyFormatCSV  LegacyFileFormat = "csv"
+	LegacyFormatXLSX LegacyFileFormat = "xlsx"
+)
+
+// ParseLegacySpreadsheet reads the rows of an old budget spreadsheet using the
+// mapping. Rows that cannot be understood are returned as rejections alongside
+// the good ones; an error means the file or mapping as a whole is unusable.
+func ParseLegacySpreadsheet(data []byte, format LegacyFileFormat, mapping LegacyColumnMapping) ([]store.LegacyImportRow, []store.LegacyImportRejection, error) {
+	var records [][]string
+	var lines []int
+	var err error
+	switch format {
+	case LegacyFormatCSV:
+		records, lines, err = readLegacyCSV(data, mapping.Delimiter)
+	case LegacyFormatXLSX:
+		records, err = readXLSXSheet(bytes.NewReader(data), int64(len(data)), mapping.Sheet)
+	default:
+		return nil, nil, fmt.Errorf("unsupported file format %q: use csv or xlsx", format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decimal := '.'
+	switch mapping.DecimalSeparator {
+	case "", ".", "dot":
+	case ",", "comma":
+		decimal = ','
+	default:
+		return nil, nil, fmt.Errorf("invalid decimal separator %q: use dot or comma", mapping.DecimalSeparator)
+	}
+
+	if format == LegacyFormatXLSX {
+		// Numeric cells are stored with a '.' whatever the locale of the spreadsheet.
+		decimal = '.'
+	}
+
+	headerIndex := -1
+	for i, record := range records {
+		if !isBlankRecord(record) {
+			headerIndex = i
+			break
+		}
+	}
+	if headerIndex < 0 {
+		return nil, nil, errors.New("the file has no header row")
+	}
+	columns, err := mapLegacyColumns(records[headerIndex], mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := []store.LegacyImportRow{}
+	rejected := []store.LegacyImportRejection{}
+	for i := headerIndex + 1; i < len(records); i++ {
+		record := records[i]
+		if isBlankRecord(record) {
+			continue
+		}
+		line := i + 1
+		if lines != nil {
+			line = lines[i]
+		}
+		row, err := parseLegacyRecord(record, columns, decimal)
+		if err != nil {
+			rejected = append(rejected, store.LegacyImportRejection{Line: line, Reason: err.Error()})
+			continue
+		}
+		row.Line = line
+		rows = append(rows, *row)
+	}
+	return rows, rejected, nil
+}
+
+// readLegacyCSV also returns the file line each record starts on, since the
+// CSV reader skips blank lines and records may span several lines.
+func readLegacyCSV(data []byte, delimiter string) ([][]string, []int, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	switch delimiter {
+	case "":
+		// Spreadsheets saved in decimal-comma locales use semicolons.
+		firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+		if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+			r.Comma = ';'
+		}
+	case ",", "comma":
+	case ";", "semicolon":
+		r.Comma = ';'
+	case "\t", "tab":
+		r.Comma = '\t'
+	case "|", "pipe":
+		r.Comma = '|'
+	default:
+		return nil, nil, fmt.Errorf("invalid delimiter %q: use comma, semicolon, tab or pipe", delimiter)
+	}
+
+	var records [][]string
+	var lines []int
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records, lines, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+}
+
+type legacyColumns struct {
+	month, year, category, label, expected, actual int
+}
+
+func mapLegacyColumns(header []string, mapping LegacyColumnMapping) (*legacyColumns, error) {
+	find := func(field, name string, required bool) (int, error) {
+		if name == "" {
+			if required {
+				return -1, fmt.Errorf("column mapping for %s is required", field)
+			}
+			return -1, nil
+		}
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(name)) {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("column %q (mapped to %s) not found in the header row", name, field)
+	}
+
+	var cols legacyColumns
+	var err error
+	if cols.month, err = find("month", mapping.Month, true); err != nil {
+		return nil, err
+	}
+	if cols.year, err = find("year", mapping.Year, false); err != nil {
+		return nil, err
+	}
+	if cols.category, err = find("category", mapping.Category, true); err != nil {
+		return nil, err
+	}
+	if cols.label, err = find("label", mapping.Label, true); err != nil {
+		return nil, err
+	}
+	if cols.expected, err = find("expected", mapping.Expected, true); err != nil {
+		return nil, err
+	}
+	if cols.actual, err = find("actual", mapping.Actual, false); err != nil {
+		return nil, err
+	}
+	return &cols, nil
+}
+
+func parseLegacyRecord(record []string, cols *legacyColumns, decimal rune) (*store.LegacyImportRow, error) {
+	cell := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var row store.LegacyImportRow
+	var err error
+	if cols.year >= 0 {
+		if row.Year, err = strconv.Atoi(cell(cols.year)); err != nil {
+			return nil, fmt.Errorf("invalid year %q", cell(cols.year))
+		}
+		if row.Month, err = parseMonthNumber(cell(cols.month)); err != nil {
+			return nil, err
+		}
+	} else if row.Year, row.Month, err = parseLegacyPeriod(cell(cols.month)); err != nil {
+		return nil, err
+	}
+	if row.Year < 1900 || row.Year > 2999 {
+		return nil, fmt.Errorf("year %d is out of range", row.Year)
+	}
+
+	row.Category = cell(cols.category)
+	row.Label = cell(cols.label)
+	if row.Category == "" {
+		return nil, errors.New("category is empty")
+	}
+	if row.Label == "" {
+		return nil, errors.New("label is empty")
+	}
+
+	if row.Expected, err = parseLegacyAmount(cell(cols.expected), decimal); err != nil {
+		return nil, fmt.Errorf("invalid expected amount: %w", err)
+	}
+	if actual := cell(cols.actual); actual != "" {
+		if row.Actual, err = parseLegacyAmount(actual, decimal); err != nil {
+			return nil, fmt.Errorf("invalid actual amount: %w", err)
+		}
+	}
+	if row.Expected < 0 || row.Actual < 0 {
+		return nil, errors.New("amounts cannot be negative")
+	}
+	return &row, nil
+}
+
+var legacyPeriodLayouts = []string{
+	"2006-01", "2006/01", "2006-1", "2006/1",
+	"01/2006", "1/2006", "01-2006", "1-2006", "01.2006", "1.2006",
+	"2006-01-02", "2006/01/02", "02/01/2006", "02.01.2006", "01/02/2006",
+	"Jan 2006", "January 2006", "Jan-2006", "January-2006", "Jan-06",
+}
+
+// parseLegacyPeriod reads a year and month from text or an Excel date serial.
+func parseLegacyPeriod(value string) (int, int, error) {
+	if value == "" {
+		return 0, 0, errors.New("month is empty")
+	}
+	for _, layout := range legacyPeriodLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Year(), int(t.Month()), nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 60 {
+		// Excel counts days from 1899-12-30 once its 1900 leap-year bug is accounted for.
+		t := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(math.Floor(serial)))
+		return t.Year(), int(t.Month()), nil
+	}
+	return 0, 0, fmt.Errorf("unrecognised month %q", value)
+}
+
+func parseMonthNumber(value string) (int, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 1 || n > 12 {
+			return 0, fmt.Errorf("month %d is out of range", n)
+		}
+		return n, nil
+	}
+	for _, layout := range []string{"Jan", "January"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return int(t.Month()), nil
+		}
+	}
+	return 0, fmt.Errorf("unrecognised month %q", value)
+}
+
+// parseLegacyAmount accepts amounts such as "1.234,50", "$1,234.50" or "1 234".
+func parseLegacyAmount(value string, decimal rune) (store.Money, error) {
+	if value == "" {
+		return 0, errors.New("amount is empty")
+	}
+	thousands := ','
+	if decimal == ',' {
+		thousands = '.'
+	}
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case unicode.IsDigit(r), r == '-':
+			b.WriteRune(r)
+		case r == decimal:
+			b.WriteRune('.')
+		case r == thousands, unicode.IsSpace(r), unicode.Is(unicode.Sc, r):
+		default:
+			return 0, fmt.Errorf("%q is not a number", value)
+		}
+	}
+	amount, err := store.ParseMoney(b.String())
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	return amount, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
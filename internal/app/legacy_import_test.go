@@ -0,0 +1,147 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var legacyMapping = LegacyColumnMapping{
+	Month:    "Mes",
+	Category: "Categoría",
+	Label:    "Detalle",
+	Expected: "Presupuesto",
+	Actual:   "Real",
+}
+
+func TestParseLegacySpreadsheet_CSV(t *testing.T) {
+	csvData := "\xef\xbb\xbfMes;Categoría;Detalle;Presupuesto;Real\n" +
+		"2022-01;Hogar;Arriendo;450.000;450.000\n" +
+		"Feb 2022;Comida;Supermercado;$ 120.000,50;\n" +
+		"\n" +
+		"13/2022;Comida;Feria;10;10\n" +
+		"2022-03;;Luz;10;10\n" +
+		"2022-03;Hogar;Agua;-5;0\n" +
+		"2022-03;Hogar;Gas;abc;0\n"
+
+	mapping := legacyMapping
+	mapping.DecimalSeparator = "comma"
+	rows, rejected, err := ParseLegacySpreadsheet([]byte(csvData), LegacyFormatCSV, mapping)
+	if err != nil {
+		t.Fatalf("ParseLegacySpreadsheet failed: %v", err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d: %+v", len(rows), rows)
+	}
+	if rows[0].Year != 2022 || rows[0].Month != 1 || rows[0].Expected != 450000_00 || rows[0].Line != 2 {
+		t.Errorf("Unexpected first row: %+v", rows[0])
+	}
+	if rows[1].Month != 2 || rows[1].Expected != 120000_50 || rows[1].Actual != 0 {
+		t.Errorf("Unexpected second row: %+v", rows[1])
+	}
+
+	reasons := map[int]string{}
+	for _, r := range rejected {
+		reasons[r.Line] = r.Reason
+	}
+	expected := map[int]string{
+		5: "unrecognised month",
+		6: "category is empty",
+		7: "cannot be negative",
+		8: "invalid expected amount",
+	}
+	if len(reasons) != len(expected) {
+		t.Errorf("Expected %d rejections, got %+v", len(expected), rejected)
+	}
+	for line, want := range expected {
+		if !strings.Contains(reasons[line], want) {
+			t.Errorf("Line %d: expected rejection containing %q, got %q", line, want, reasons[line])
+		}
+	}
+}
+
+func TestParseLegacySpreadsheet_SeparateYearColumn(t *testing.T) {
+	csvData := "Year,Month,Category,Item,Budget,Spent\n2021,March,Food,Groceries,\"1,200.00\",1100\n2021,11,Food,Groceries,10,9\n"
+	mapping := LegacyColumnMapping{Year: "year", Month: "month", Category: "category", Label: "item", Expected: "budget", Actual: "spent"}
+
+	rows, rejected, err := ParseLegacySpreadsheet([]byte(csvData), LegacyFormatCSV, mapping)
+	if err != nil {
+		t.Fatalf("ParseLegacySpreadsheet failed: %v", err)
+	}
+	if len(rejected) != 0 || len(rows) != 2 {
+		t.Fatalf("Expected 2 rows and no rejections, got %+v / %+v", rows, rejected)
+	}
+	if rows[0].Month != 3 || rows[0].Expected != 1200_00 || rows[1].Month != 11 {
+		t.Errorf("Unexpected rows: %+v", rows)
+	}
+}
+
+func TestParseLegacySpreadsheet_MappingErrors(t *testing.T) {
+	_, _, err := ParseLegacySpreadsheet([]byte("Mes,Categoría\n"), LegacyFormatCSV, legacyMapping)
+	if err == nil || !strings.Contains(err.Error(), `"Detalle"`) {
+		t.Errorf("Expected a missing column error, got %v", err)
+	}
+	_, _, err = ParseLegacySpreadsheet([]byte("a\n"), LegacyFormatCSV, LegacyColumnMapping{})
+	if err == nil || !strings.Contains(err.Error(), "required") {
+		t.Errorf("Expected a required mapping error, got %v", err)
+	}
+	_, _, err = ParseLegacySpreadsheet([]byte("a\n"), "ods", legacyMapping)
+	if err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
+
+func TestParseLegacySpreadsheet_XLSX(t *testing.T) {
+	wb := &xlsxWorkbook{}
+	wb.AddSheet("Notes").AddRow(xlsxText("ignore me"))
+	sheet := wb.AddSheet("History")
+	sheet.AddRow(xlsxText("Mes"), xlsxText("Categoría"), xlsxText("Detalle"), xlsxText("Presupuesto"), xlsxText("Real"))
+	// 44593 is the Excel serial for 2022-02-01.
+	sheet.AddRow(xlsxAmount(44593), xlsxText("Hogar"), xlsxText("Arriendo & gastos"), xlsxAmount(450.5), xlsxAmount(450))
+	var buf bytes.Buffer
+	if err := wb.Write(&buf); err != nil {
+		t.Fatalf("Failed to build workbook: %v", err)
+	}
+
+	mapping := legacyMapping
+	mapping.Sheet = "history"
+	rows, rejected, err := ParseLegacySpreadsheet(buf.Bytes(), LegacyFormatXLSX, mapping)
+	if err != nil {
+		t.Fatalf("ParseLegacySpreadsheet failed: %v", err)
+	}
+	if len(rejected) != 0 || len(rows) != 1 {
+		t.Fatalf("Expected 1 row and no rejections, got %+v / %+v", rows, rejected)
+	}
+	if rows[0].Year != 2022 || rows[0].Month != 2 || rows[0].Label != "Arriendo & gastos" || rows[0].Expected != 450_50 {
+		t.Errorf("Unexpected row: %+v", rows[0])
+	}
+}
+
+func TestReadXLSXSheet_SharedStrings(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"xl/workbook.xml":            `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Hoja1" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Target="/xl/worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":       `<sst><si><t>Mes</t></si><si><r><t>Rich </t></r><r><t>text</t></r><rPh><t>ignored</t></rPh></si></sst>`,
+		"xl/worksheets/sheet1.xml":   `<worksheet><sheetData><row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row><row r="3"><c r="B3"><v>42</v></c></row></sheetData></worksheet>`,
+	}
+	for name, content := range parts {
+		fw, _ := zw.Create(name)
+		fw.Write([]byte(content))
+	}
+	zw.Close()
+
+	rows, err := readXLSXSheet(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "")
+	if err != nil {
+		t.Fatalf("readXLSXSheet failed: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows including the gap, got %d: %q", len(rows), rows)
+	}
+	if strings.Join(rows[0], "|") != "Mes||Rich text" || len(rows[1]) != 0 || strings.Join(rows[2], "|") != "|42" {
+		t.Errorf("Unexpected cells: %q", rows)
+	}
+}
@@ -0,0 +1,284 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"gandalf-budget/internal/store"
+)
+
+const (
+	pdfMargin     = 15.0
+	pdfPageWidth  = 210.0
+	pdfPageHeight = 297.0
+	pdfBodyWidth  = pdfPageWidth - 2*pdfMargin
+)
+
+type pdfColor struct{ r, g, b int }
+
+var (
+	pdfColorUnder    = pdfColor{22, 163, 74}   // spent less than expected
+	pdfColorOver     = pdfColor{220, 38, 38}   // spent more than expected
+	pdfColorExpected = pdfColor{203, 213, 225} // expected bars
+	pdfColorHeader   = pdfColor{241, 245, 249}
+	pdfColorText     = pdfColor{15, 23, 42}
+)
+
+// pdfRow is one line of a report table and of its bar chart.
+type pdfRow struct {
+	label            string
+	expected, actual store.Money
+}
+
+// WriteMonthPDF renders a one-page month-end summary: totals, a table per
+// category with differences coloured by over/under budget, and a bar chart of
+// expected against actual spending.
+func WriteMonthPDF(w io.Writer, payload *DashboardPayload) error {
+	rows := make([]pdfRow, 0, len(payload.CategorySummaries))
+	for _, c := range payload.CategorySummaries {
+		rows = append(rows, pdfRow{label: c.CategoryName, expected: c.TotalExpected, actual: c.TotalActual})
+	}
+
+	doc := newPDFReport(fmt.Sprintf("Budget summary - %s %d", payload.Month, payload.Year))
+	doc.totals(payload.TotalExpected, payload.TotalActual)
+	doc.table("Category", rows)
+	doc.barChart("Expected vs actual by category", rows)
+	return doc.output(w)
+}
+
+// WriteYearPDF renders the annual summary from the finalized months' snapshots:
+// a table per month, a table per category and a bar chart per month.
+func WriteYearPDF(ctx context.Context, w io.Writer, s store.Store, year int) error {
+	snaps, err := s.GetAnnualSnapshotsMetadataByYear(ctx, year)
+	if err != nil {
+		return err
+	}
+
+	var monthRows, categoryRows []pdfRow
+	categoryIndex := make(map[string]int)
+	var totalExpected, totalActual store.Money
+	for _, meta := range snaps {
+		snapJSON, err := s.GetAnnualSnapshotJSONByID(ctx, meta.ID)
+		if err != nil {
+			return err
+		}
+		lines, err := snapshotLines(snapJSON)
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot for %s %d: %w", meta.Month, meta.Year, err)
+		}
+
+		month := pdfRow{label: meta.Month}
+		for _, line := range lines {
+			month.expected += line.ExpectedAmount
+			month.actual += line.ActualAmount
+			i, ok := categoryIndex[line.CategoryName]
+			if !ok {
+				i = len(categoryRows)
+				categoryIndex[line.CategoryName] = i
+				categoryRows = append(categoryRows, pdfRow{label: line.CategoryName})
+			}
+			categoryRows[i].expected += line.ExpectedAmount
+			categoryRows[i].actual += line.ActualAmount
+		}
+		monthRows = append(monthRows, month)
+		totalExpected += month.expected
+		totalActual += month.actual
+	}
+
+	doc := newPDFReport(fmt.Sprintf("Annual summary %d", year))
+	if len(snaps) == 0 {
+		doc.note("No finalized months yet.")
+		return doc.output(w)
+	}
+	doc.note(fmt.Sprintf("%d finalized month(s), generated %s.", len(snaps), time.Now().Format("2006-01-02")))
+	doc.totals(totalExpected, totalActual)
+	doc.table("Month", monthRows)
+	doc.table("Category", categoryRows)
+	doc.barChart("Expected vs actual by month", monthRows)
+	return doc.output(w)
+}
+
+type pdfReport struct {
+	pdf *gofpdf.Fpdf
+	tr  func(string) string
+}
+
+func newPDFReport(title string) *pdfReport {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("Gandalf Budget", true)
+	pdf.AddPage()
+
+	// The core fonts use cp1252, which covers accented category names.
+	doc := &pdfReport{pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+	doc.setTextColor(pdfColorText)
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(pdfBodyWidth, 10, doc.tr(title), "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+	return doc
+}
+
+func (d *pdfReport) note(text string) {
+	d.pdf.SetFont("Helvetica", "", 10)
+	d.pdf.CellFormat(pdfBodyWidth, 6, d.tr(text), "", 1, "L", false, 0, "")
+	d.pdf.Ln(2)
+}
+
+func (d *pdfReport) totals(expected, actual store.Money) {
+	width := pdfBodyWidth / 3
+	d.pdf.SetFont("Helvetica", "", 9)
+	for _, label := range []string{"Expected", "Actual", "Difference"} {
+		d.pdf.CellFormat(width, 5, label, "", 0, "L", false, 0, "")
+	}
+	d.pdf.Ln(-1)
+	d.pdf.SetFont("Helvetica", "B", 14)
+	d.pdf.CellFormat(width, 8, pdfAmount(expected), "", 0, "L", false, 0, "")
+	d.pdf.CellFormat(width, 8, pdfAmount(actual), "", 0, "L", false, 0, "")
+	d.setTextColor(differenceColor(expected - actual))
+	d.pdf.CellFormat(width, 8, pdfAmount(expected-actual), "", 1, "L", false, 0, "")
+	d.setTextColor(pdfColorText)
+	d.pdf.Ln(4)
+}
+
+// table writes the rows with a bold total; differences are expected - actual,
+// green when under budget and red when over.
+func (d *pdfReport) table(firstColumn string, rows []pdfRow) {
+	widths := []float64{pdfBodyWidth - 3*35, 35, 35, 35}
+	const rowHeight = 6.5
+
+	d.pdf.SetFont("Helvetica", "B", 10)
+	d.setFillColor(pdfColorHeader)
+	for i, h := range []string{firstColumn, "Expected", "Actual", "Difference"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		d.pdf.CellFormat(widths[i], rowHeight, h, "B", 0, align, true, 0, "")
+	}
+	d.pdf.Ln(-1)
+
+	var total pdfRow
+	d.pdf.SetFont("Helvetica", "", 10)
+	for _, row := range rows {
+		d.tableRow(widths, rowHeight, row, "")
+		total.expected += row.expected
+		total.actual += row.actual
+	}
+	total.label = "Total"
+	d.pdf.SetFont("Helvetica", "B", 10)
+	d.tableRow(widths, rowHeight, total, "T")
+	d.pdf.Ln(6)
+}
+
+func (d *pdfReport) tableRow(widths []float64, height float64, row pdfRow, border string) {
+	d.pdf.CellFormat(widths[0], height, d.tr(row.label), border, 0, "L", false, 0, "")
+	d.pdf.CellFormat(widths[1], height, pdfAmount(row.expected), border, 0, "R", false, 0, "")
+	d.pdf.CellFormat(widths[2], height, pdfAmount(row.actual), border, 0, "R", false, 0, "")
+	d.setTextColor(differenceColor(row.expected - row.actual))
+	d.pdf.CellFormat(widths[3], height, pdfAmount(row.expected-row.actual), border, 1, "R", false, 0, "")
+	d.setTextColor(pdfColorText)
+}
+
+// barChart draws a horizontal bar pair per row in the space left on the page:
+// a grey bar for the expected amount and a green or red one for the actual.
+func (d *pdfReport) barChart(title string, rows []pdfRow) {
+	if len(rows) == 0 {
+		return
+	}
+	d.pdf.SetFont("Helvetica", "B", 11)
+	d.pdf.CellFormat(pdfBodyWidth, 7, title, "", 1, "L", false, 0, "")
+
+	const labelWidth, valueWidth = 45.0, 25.0
+	barWidth := pdfBodyWidth - labelWidth - valueWidth
+	_, top := d.pdf.GetXY()
+	rowHeight := math.Min(12, math.Max(5, (pdfPageHeight-pdfMargin-top)/float64(len(rows))))
+
+	scale := 0.0
+	for _, row := range rows {
+		scale = math.Max(scale, math.Max(row.expected.Float64(), row.actual.Float64()))
+	}
+	if scale == 0 {
+		scale = 1
+	}
+
+	d.pdf.SetFont("Helvetica", "", 8)
+	for _, row := range rows {
+		if d.pdf.GetY()+rowHeight > pdfPageHeight-pdfMargin {
+			d.pdf.AddPage()
+		}
+		y := d.pdf.GetY()
+		bar := rowHeight * 0.35
+		d.pdf.CellFormat(labelWidth, rowHeight, d.tr(truncate(row.label, 28)), "", 0, "L", false, 0, "")
+
+		x := pdfMargin + labelWidth
+		d.setFillColor(pdfColorExpected)
+		d.pdf.Rect(x, y+rowHeight*0.1, barWidth*math.Max(row.expected.Float64(), 0)/scale, bar, "F")
+		d.setFillColor(differenceColor(row.expected - row.actual))
+		d.pdf.Rect(x, y+rowHeight*0.1+bar, barWidth*math.Max(row.actual.Float64(), 0)/scale, bar, "F")
+
+		d.pdf.SetXY(x+barWidth, y)
+		d.pdf.CellFormat(valueWidth, rowHeight, pdfAmount(row.actual), "", 1, "R", false, 0, "")
+	}
+
+	d.pdf.Ln(2)
+	for _, legend := range []struct {
+		color pdfColor
+		text  string
+	}{{pdfColorExpected, "Expected"}, {pdfColorUnder, "Actual (within budget)"}, {pdfColorOver, "Actual (over budget)"}} {
+		x, y := d.pdf.GetXY()
+		d.setFillColor(legend.color)
+		d.pdf.Rect(x, y+1, 4, 3, "F")
+		d.pdf.SetX(x + 5)
+		d.pdf.CellFormat(40, 5, legend.text, "", 0, "L", false, 0, "")
+	}
+	d.pdf.Ln(-1)
+}
+
+func (d *pdfReport) output(w io.Writer) error {
+	return d.pdf.Output(w)
+}
+
+func (d *pdfReport) setTextColor(c pdfColor) { d.pdf.SetTextColor(c.r, c.g, c.b) }
+func (d *pdfReport) setFillColor(c pdfColor) { d.pdf.SetFillColor(c.r, c.g, c.b) }
+
+func differenceColor(difference store.Money) pdfColor {
+	if difference < 0 {
+		return pdfColorOver
+	}
+	return pdfColorUnder
+}
+
+// pdfAmount formats an amount with thousands separators, e.g. "-1,234.50".
+func pdfAmount(v store.Money) string {
+	whole, frac, hasFrac := strings.Cut(v.Abs().String(), ".")
+	var b strings.Builder
+	if v < 0 {
+		b.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if hasFrac {
+		b.WriteString("." + frac)
+	}
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "..."
+}
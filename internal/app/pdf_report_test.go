@@ -0,0 +1,79 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"gandalf-budget/internal/store"
+)
+
+func TestWriteMonthPDF(t *testing.T) {
+	payload := &DashboardPayload{
+		Year:          2024,
+		Month:         "March",
+		TotalExpected: 1400_00,
+		TotalActual:   1480_00,
+		CategorySummaries: []CategorySummary{
+			{CategoryName: "Comida y bebida", TotalExpected: 400_00, TotalActual: 380_00},
+			{CategoryName: "Hogar", TotalExpected: 1000_00, TotalActual: 1100_00},
+			{CategoryName: "Sin líneas"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMonthPDF(&buf, payload); err != nil {
+		t.Fatalf("WriteMonthPDF failed: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) || !bytes.Contains(buf.Bytes(), []byte("%%EOF")) {
+		t.Errorf("Output is not a complete PDF document")
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("/Type /Page\n")); n != 1 {
+		t.Errorf("Expected a one-page summary, got %d pages", n)
+	}
+}
+
+func TestWriteYearPDF(t *testing.T) {
+	ctx := context.Background()
+	snaps := map[int64]string{
+		1: `{"budget_lines": [{"category_name": "Food", "expected_amount": 100, "actual_amount": 120}]}`,
+		2: `{"category_summaries": [{"category_name": "Food", "budget_lines": [{"expected_amount": 100, "actual_amount": 90}]}]}`,
+	}
+	mockStore := &store.ReusableMockStore{
+		MockGetAnnualSnapshotsMetadataByYear: func(ctx context.Context, year int) ([]store.AnnualSnapMeta, error) {
+			return []store.AnnualSnapMeta{
+				{ID: 1, Year: year, Month: "January", SnapCreatedAt: time.Now()},
+				{ID: 2, Year: year, Month: "February", SnapCreatedAt: time.Now()},
+			}, nil
+		},
+		MockGetAnnualSnapshotJSONByID: func(ctx context.Context, snapID int64) (string, error) { return snaps[snapID], nil },
+	}
+
+	var buf bytes.Buffer
+	if err := WriteYearPDF(ctx, &buf, mockStore, 2024); err != nil {
+		t.Fatalf("WriteYearPDF failed: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Errorf("Output is not a PDF document")
+	}
+
+	snaps[2] = "not json"
+	if err := WriteYearPDF(ctx, &bytes.Buffer{}, mockStore, 2024); err == nil {
+		t.Error("Expected an error for an unreadable snapshot")
+	}
+}
+
+func TestPDFAmount(t *testing.T) {
+	for v, want := range map[store.Money]string{
+		0:           "0.00",
+		-1:          "-0.01",
+		999_50:      "999.50",
+		1234_50:     "1,234.50",
+		-1234567_80: "-1,234,567.80",
+	} {
+		if got := pdfAmount(v); got != want {
+			t.Errorf("pdfAmount(%v) = %q, want %q", v, got, want)
+		}
+	}
+}
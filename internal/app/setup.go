@@ -29,7 +29,7 @@ func SeedInitialMonth(db *sqlx.DB) error {
 		currentMonth := int(time.Now().Month())
 
 		query := `INSERT INTO months (year, month, finalized) VALUES (?, ?, ?)`
-		_, err = db.Exec(query, currentYear, currentMonth, 0)
+		_, err = db.Exec(db.Rebind(query), currentYear, currentMonth, false)
 		if err != nil {
 			return fmt.Errorf("failed to insert initial month: %w", err)
 		}
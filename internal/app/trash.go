@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gandalf-budget/internal/store"
+)
+
+// DefaultTrashRetentionDays is how long deleted categories and budget lines
+// stay in the trash before they are purged.
+const DefaultTrashRetentionDays = 30
+
+// trashPurgeInterval is how often the trash is checked for expired items.
+const trashPurgeInterval = 24 * time.Hour
+
+// TrashPurger permanently deletes the categories and budget lines that have
+// been in the trash for longer than the retention period.
+type TrashPurger struct {
+	store     store.Store
+	retention time.Duration
+
+	now func() time.Time
+}
+
+func NewTrashPurger(s store.Store, retentionDays int) *TrashPurger {
+	return &TrashPurger{
+		store:     s,
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+		now:       time.Now,
+	}
+}
+
+// Start purges the trash straight away and then once a day until ctx is
+// cancelled. A zero retention keeps deleted items forever.
+func (p *TrashPurger) Start(ctx context.Context) {
+	if p.retention <= 0 {
+		log.Println("Trash purging is disabled; deleted items are kept until restored.")
+		return
+	}
+	log.Printf("Purging items deleted more than %s ago from the trash", p.retention)
+
+	go func() {
+		p.runScheduled(ctx)
+
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.runScheduled(ctx)
+			}
+		}
+	}()
+}
+
+func (p *TrashPurger) runScheduled(ctx context.Context) {
+	if _, err := p.PurgeNow(ctx); err != nil {
+		log.Printf("Scheduled trash purge failed: %v", err)
+	}
+}
+
+// PurgeNow deletes the items that have been in the trash longer than the
+// retention period.
+func (p *TrashPurger) PurgeNow(ctx context.Context) (*store.TrashCounts, error) {
+	counts, err := p.store.PurgeTrash(ctx, p.now().Add(-p.retention))
+	if err != nil {
+		return nil, err
+	}
+	if counts.Categories > 0 || counts.BudgetLines > 0 {
+		log.Printf("Purged %d categories and %d budget lines from the trash", counts.Categories, counts.BudgetLines)
+	}
+	return counts, nil
+}
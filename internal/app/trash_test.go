@@ -0,0 +1,32 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashPurger_PurgeNow(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	category := &store.Category{Name: "Travel", Color: "#00f"}
+	require.NoError(t, s.CreateCategory(ctx, category))
+	require.NoError(t, s.DeleteCategory(ctx, category.ID))
+
+	purger := NewTrashPurger(s, 30)
+	counts, err := purger.PurgeNow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &store.TrashCounts{}, counts, "deleted today, kept for 30 days")
+
+	purger.now = func() time.Time { return time.Now().AddDate(0, 0, 31) }
+	counts, err = purger.PurgeNow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &store.TrashCounts{Categories: 1}, counts)
+	trash, err := s.GetTrash(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, trash.Categories)
+}
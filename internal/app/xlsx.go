@@ -0,0 +1,216 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A minimal SpreadsheetML (XLSX) writer: enough for text, numbers, formulas
+// with cached values, bold and number styles, and column widths.
+
+type xlsxStyle int
+
+const (
+	xlsxStyleDefault xlsxStyle = iota
+	xlsxStyleBold
+	xlsxStyleAmount
+	xlsxStyleBoldAmount
+)
+
+type xlsxCell struct {
+	text    string
+	number  float64
+	numeric bool
+	formula string
+	style   xlsxStyle
+}
+
+func xlsxText(s string) xlsxCell { return xlsxCell{text: s} }
+
+func xlsxBold(s string) xlsxCell { return xlsxCell{text: s, style: xlsxStyleBold} }
+
+func xlsxAmount(v float64) xlsxCell {
+	return xlsxCell{number: v, numeric: true, style: xlsxStyleAmount}
+}
+
+// xlsxFormula is a formula cell; cached is written as its value so viewers
+// that do not recalculate still show the right number.
+func xlsxFormula(formula string, cached float64, style xlsxStyle) xlsxCell {
+	return xlsxCell{formula: formula, number: cached, numeric: true, style: style}
+}
+
+type xlsxSheet struct {
+	name      string
+	colWidths []float64
+	rows      [][]xlsxCell
+}
+
+// AddRow appends a row and returns its 1-based row number for use in formulas.
+func (s *xlsxSheet) AddRow(cells ...xlsxCell) int {
+	s.rows = append(s.rows, cells)
+	return len(s.rows)
+}
+
+type xlsxWorkbook struct {
+	sheets []*xlsxSheet
+}
+
+// AddSheet appends a sheet; a name already in use gets a " (2)", " (3)"... suffix
+// because spreadsheet apps refuse workbooks with duplicate sheet names.
+func (wb *xlsxWorkbook) AddSheet(name string, colWidths ...float64) *xlsxSheet {
+	unique := name
+	for n := 2; wb.hasSheet(unique); n++ {
+		unique = fmt.Sprintf("%s (%d)", name, n)
+	}
+	sheet := &xlsxSheet{name: unique, colWidths: colWidths}
+	wb.sheets = append(wb.sheets, sheet)
+	return sheet
+}
+
+func (wb *xlsxWorkbook) hasSheet(name string) bool {
+	for _, sheet := range wb.sheets {
+		if strings.EqualFold(sheet.name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// xlsxCellRef converts a 0-based column and 1-based row to an A1 reference.
+func xlsxCellRef(col, row int) string {
+	return xlsxColumnName(col) + strconv.Itoa(row)
+}
+
+func xlsxColumnName(col int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name
+}
+
+func (wb *xlsxWorkbook) Write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"[Content_Types].xml", wb.contentTypes()},
+		{"_rels/.rels", []byte(xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`)},
+		{"xl/workbook.xml", wb.workbookXML()},
+		{"xl/_rels/workbook.xml.rels", wb.workbookRels()},
+		{"xl/styles.xml", []byte(xlsxStylesXML)},
+	}
+	for i, sheet := range wb.sheets {
+		files = append(files, struct {
+			name    string
+			content []byte
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet.xml()})
+	}
+
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (wb *xlsxWorkbook) contentTypes() []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+	return b.Bytes()
+}
+
+func (wb *xlsxWorkbook) workbookXML() []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range wb.sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.name), i+1, i+1)
+	}
+	// Ask spreadsheet apps to recalculate on open rather than trust the cached values.
+	b.WriteString(`</sheets><calcPr fullCalcOnLoad="1"/></workbook>`)
+	return b.Bytes()
+}
+
+func (wb *xlsxWorkbook) workbookRels() []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(wb.sheets)+1)
+	b.WriteString(`</Relationships>`)
+	return b.Bytes()
+}
+
+func (s *xlsxSheet) xml() []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(s.colWidths) > 0 {
+		b.WriteString(`<cols>`)
+		for i, width := range s.colWidths {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width)
+		}
+		b.WriteString(`</cols>`)
+	}
+	b.WriteString(`<sheetData>`)
+	for r, row := range s.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := xlsxCellRef(c, r+1)
+			switch {
+			case cell.formula != "":
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><f>%s</f><v>%s</v></c>`, ref, cell.style, xmlEscape(cell.formula), strconv.FormatFloat(cell.number, 'f', -1, 64))
+			case cell.numeric:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cell.style, strconv.FormatFloat(cell.number, 'f', -1, 64))
+			case cell.text != "":
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t>%s</t></is></c>`, ref, cell.style, xmlEscape(cell.text))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.Bytes()
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Cell style indexes match xlsxStyle; numFmtId 4 is the built-in "#,##0.00".
+const xlsxStylesXML = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="4" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1" applyNumberFormat="1"/>` +
+	`</cellXfs></styleSheet>`
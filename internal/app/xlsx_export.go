@@ -0,0 +1,213 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gandalf-budget/internal/store"
+)
+
+// WriteYearXLSX writes a workbook for the year: a summary sheet built from the
+// finalized months' snapshots, then one sheet per month with category sections
+// and SUM formulas.
+func WriteYearXLSX(ctx context.Context, w io.Writer, s store.Store, year int) error {
+	wb := &xlsxWorkbook{}
+
+	snaps, err := s.GetAnnualSnapshotsMetadataByYear(ctx, year)
+	if err != nil {
+		return err
+	}
+	if err := addSummarySheet(ctx, wb, s, year, snaps); err != nil {
+		return err
+	}
+
+	months, err := s.GetMonthsByYear(ctx, year)
+	if err != nil {
+		return err
+	}
+	for _, month := range months {
+		board, err := LoadBoardInBaseCurrency(ctx, s, int(month.ID))
+		if err != nil {
+			return fmt.Errorf("failed to load board for %d-%02d: %w", month.Year, month.Month, err)
+		}
+		addMonthSheet(wb, board.BoardDataPayload)
+	}
+
+	return wb.Write(w)
+}
+
+func addMonthSheet(wb *xlsxWorkbook, board *store.BoardDataPayload) {
+	sheet := wb.AddSheet(board.MonthName, 32, 14, 14, 14)
+	title := fmt.Sprintf("%s %d", board.MonthName, board.Year)
+	if board.IsFinalized {
+		title += " (finalized)"
+	}
+	sheet.AddRow(xlsxBold(title))
+	sheet.AddRow(xlsxBold("Category / Label"), xlsxBold("Expected"), xlsxBold("Actual"), xlsxBold("Difference"))
+
+	var subtotalRows []int
+	var totalExpected, totalActual store.Money
+	lines := board.BudgetLines
+	for start := 0; start < len(lines); {
+		category := lines[start].CategoryName
+		end := start
+		for end < len(lines) && lines[end].CategoryName == category {
+			end++
+		}
+
+		sheet.AddRow(xlsxBold(category))
+		firstRow := len(sheet.rows) + 1
+		var expected, actual store.Money
+		for _, line := range lines[start:end] {
+			row := len(sheet.rows) + 1
+			sheet.AddRow(
+				xlsxText("  "+line.Label),
+				xlsxAmount(line.ExpectedAmount.Float64()),
+				xlsxAmount(line.ActualAmount.Float64()),
+				xlsxFormula(fmt.Sprintf("B%d-C%d", row, row), (line.ExpectedAmount-line.ActualAmount).Float64(), xlsxStyleAmount),
+			)
+			expected += line.ExpectedAmount
+			actual += line.ActualAmount
+		}
+		lastRow := len(sheet.rows)
+		subtotalRows = append(subtotalRows, sheet.AddRow(
+			xlsxBold("Total "+category),
+			xlsxFormula(fmt.Sprintf("SUM(B%d:B%d)", firstRow, lastRow), expected.Float64(), xlsxStyleBoldAmount),
+			xlsxFormula(fmt.Sprintf("SUM(C%d:C%d)", firstRow, lastRow), actual.Float64(), xlsxStyleBoldAmount),
+			xlsxFormula(fmt.Sprintf("SUM(D%d:D%d)", firstRow, lastRow), (expected-actual).Float64(), xlsxStyleBoldAmount),
+		))
+		totalExpected += expected
+		totalActual += actual
+		start = end
+	}
+
+	sheet.AddRow()
+	sheet.AddRow(
+		xlsxBold("Total"),
+		sumOfCells("B", subtotalRows, totalExpected),
+		sumOfCells("C", subtotalRows, totalActual),
+		sumOfCells("D", subtotalRows, totalExpected-totalActual),
+	)
+}
+
+// sumOfCells sums the given rows of a column, or is a plain zero when there are none.
+func sumOfCells(col string, rows []int, cached store.Money) xlsxCell {
+	if len(rows) == 0 {
+		return xlsxCell{number: 0, numeric: true, style: xlsxStyleBoldAmount}
+	}
+	refs := make([]string, len(rows))
+	for i, row := range rows {
+		refs[i] = fmt.Sprintf("%s%d", col, row)
+	}
+	return xlsxFormula("SUM("+strings.Join(refs, ",")+")", cached.Float64(), xlsxStyleBoldAmount)
+}
+
+func addSummarySheet(ctx context.Context, wb *xlsxWorkbook, s store.Store, year int, snaps []store.AnnualSnapMeta) error {
+	sheet := wb.AddSheet("Summary", 24, 14, 14, 14, 14)
+	sheet.AddRow(xlsxBold(fmt.Sprintf("Summary %d (finalized months)", year)))
+	sheet.AddRow(xlsxBold("Month"), xlsxBold("Finalized on"), xlsxBold("Expected"), xlsxBold("Actual"), xlsxBold("Difference"))
+
+	type categoryTotal struct{ expected, actual store.Money }
+	var categoryOrder []string
+	categoryTotals := make(map[string]*categoryTotal)
+
+	firstRow := len(sheet.rows) + 1
+	var totalExpected, totalActual store.Money
+	for _, meta := range snaps {
+		snapJSON, err := s.GetAnnualSnapshotJSONByID(ctx, meta.ID)
+		if err != nil {
+			return err
+		}
+		lines, err := snapshotLines(snapJSON)
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot for %s %d: %w", meta.Month, meta.Year, err)
+		}
+
+		var expected, actual store.Money
+		for _, line := range lines {
+			expected += line.ExpectedAmount
+			actual += line.ActualAmount
+			total, ok := categoryTotals[line.CategoryName]
+			if !ok {
+				total = &categoryTotal{}
+				categoryTotals[line.CategoryName] = total
+				categoryOrder = append(categoryOrder, line.CategoryName)
+			}
+			total.expected += line.ExpectedAmount
+			total.actual += line.ActualAmount
+		}
+		row := len(sheet.rows) + 1
+		sheet.AddRow(
+			xlsxText(meta.Month),
+			xlsxText(meta.SnapCreatedAt.Format("2006-01-02")),
+			xlsxAmount(expected.Float64()),
+			xlsxAmount(actual.Float64()),
+			xlsxFormula(fmt.Sprintf("C%d-D%d", row, row), (expected-actual).Float64(), xlsxStyleAmount),
+		)
+		totalExpected += expected
+		totalActual += actual
+	}
+	lastRow := len(sheet.rows)
+	if len(snaps) == 0 {
+		sheet.AddRow(xlsxText("No finalized months yet"))
+	} else {
+		sheet.AddRow(
+			xlsxBold("Total"),
+			xlsxText(""),
+			xlsxFormula(fmt.Sprintf("SUM(C%d:C%d)", firstRow, lastRow), totalExpected.Float64(), xlsxStyleBoldAmount),
+			xlsxFormula(fmt.Sprintf("SUM(D%d:D%d)", firstRow, lastRow), totalActual.Float64(), xlsxStyleBoldAmount),
+			xlsxFormula(fmt.Sprintf("SUM(E%d:E%d)", firstRow, lastRow), (totalExpected-totalActual).Float64(), xlsxStyleBoldAmount),
+		)
+	}
+
+	if len(categoryOrder) > 0 {
+		sheet.AddRow()
+		sheet.AddRow(xlsxBold("Category"), xlsxText(""), xlsxBold("Expected"), xlsxBold("Actual"), xlsxBold("Difference"))
+		for _, name := range categoryOrder {
+			total := categoryTotals[name]
+			row := len(sheet.rows) + 1
+			sheet.AddRow(
+				xlsxText(name),
+				xlsxText(""),
+				xlsxAmount(total.expected.Float64()),
+				xlsxAmount(total.actual.Float64()),
+				xlsxFormula(fmt.Sprintf("C%d-D%d", row, row), (total.expected-total.actual).Float64(), xlsxStyleAmount),
+			)
+		}
+	}
+	return nil
+}
+
+// snapshotLines reads the budget lines of a finalized month's snapshot. Snapshots
+// hold a board payload; dashboard-shaped snapshots are accepted too.
+func snapshotLines(snapJSON string) ([]store.BudgetLineWithActual, error) {
+	var snap struct {
+		BudgetLines       []store.BudgetLineWithActual `json:"budget_lines"`
+		CategorySummaries []CategorySummary            `json:"category_summaries"`
+	}
+	if err := json.Unmarshal([]byte(snapJSON), &snap); err != nil {
+		return nil, err
+	}
+	if len(snap.BudgetLines) > 0 || len(snap.CategorySummaries) == 0 {
+		return snap.BudgetLines, nil
+	}
+
+	var lines []store.BudgetLineWithActual
+	for _, summary := range snap.CategorySummaries {
+		for _, line := range summary.BudgetLines {
+			lines = append(lines, store.BudgetLineWithActual{
+				ID:             int64(line.BudgetLineID),
+				CategoryID:     int64(summary.CategoryID),
+				CategoryName:   summary.CategoryName,
+				CategoryColor:  summary.CategoryColor,
+				Label:          line.Label,
+				ExpectedAmount: line.ExpectedAmount,
+				ActualAmount:   line.ActualAmount,
+			})
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gandalf-budget/internal/store"
+)
+
+func TestWriteYearXLSX(t *testing.T) {
+	mockStore := &store.ReusableMockStore{
+		MockGetAnnualSnapshotsMetadataByYear: func(ctx context.Context, year int) ([]store.AnnualSnapMeta, error) {
+			return []store.AnnualSnapMeta{{ID: 7, MonthID: 1, Year: year, Month: "January", SnapCreatedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}}, nil
+		},
+		MockGetAnnualSnapshotJSONByID: func(ctx context.Context, snapID int64) (string, error) {
+			return `{"budget_lines": [{"category_name": "Food", "label": "Groceries", "expected_amount": 100, "actual_amount": 90}]}`, nil
+		},
+		MockGetMonthsByYear: func(ctx context.Context, year int) ([]store.Month, error) {
+			return []store.Month{{ID: 1, Year: year, Month: 1, Finalized: true}, {ID: 2, Year: year, Month: 2}}, nil
+		},
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
+			if monthID == 2 {
+				return &store.BoardDataPayload{MonthID: 2, Year: 2024, MonthName: "February"}, nil
+			}
+			return &store.BoardDataPayload{
+				MonthID: 1, Year: 2024, MonthName: "January", IsFinalized: true,
+				BudgetLines: []store.BudgetLineWithActual{
+					{CategoryName: "Food", Label: "Groceries", ExpectedAmount: 100_00, ActualAmount: 90_00},
+					{CategoryName: "Food", Label: "Restaurants & bars", ExpectedAmount: 50_00, ActualAmount: 60_00},
+					{CategoryName: "Home", Label: "Rent", ExpectedAmount: 500_00, ActualAmount: 500_00},
+				},
+			}, nil
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteYearXLSX(context.Background(), &buf, mockStore, 2024); err != nil {
+		t.Fatalf("WriteYearXLSX failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Workbook is not a zip archive: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(content)
+
+		decoder := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed XML: %v", f.Name, err)
+			}
+		}
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "xl/workbook.xml", "xl/styles.xml", "xl/worksheets/sheet3.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("Workbook is missing %s", name)
+		}
+	}
+	workbook := parts["xl/workbook.xml"]
+	for _, sheet := range []string{`name="Summary"`, `name="January"`, `name="February"`} {
+		if !strings.Contains(workbook, sheet) {
+			t.Errorf("Workbook is missing sheet %s", sheet)
+		}
+	}
+
+	january := parts["xl/worksheets/sheet2.xml"]
+	for _, expected := range []string{
+		"<f>SUM(B4:B5)</f><v>150</v>",
+		"<f>B5-C5</f><v>-10</v>",
+		"<f>SUM(B6,B9)</f><v>650</v>",
+		"Restaurants &amp; bars",
+	} {
+		if !strings.Contains(january, expected) {
+			t.Errorf("January sheet is missing %q", expected)
+		}
+	}
+	if !strings.Contains(parts["xl/worksheets/sheet1.xml"], "<f>SUM(C3:C3)</f><v>100</v>") {
+		t.Errorf("Summary sheet should total the snapshot's expected amounts")
+	}
+}
+
+func TestSnapshotLines_DashboardShape(t *testing.T) {
+	lines, err := snapshotLines(`{"category_summaries": [{"category_name": "Food", "budget_lines": [{"label": "Groceries", "expected_amount": 10, "actual_amount": 8}]}]}`)
+	if err != nil {
+		t.Fatalf("snapshotLines failed: %v", err)
+	}
+	if len(lines) != 1 || lines[0].CategoryName != "Food" || lines[0].ActualAmount != 8_00 {
+		t.Errorf("Unexpected lines from a dashboard snapshot: %+v", lines)
+	}
+}
+
+func TestXLSXWorkbook_DuplicateSheetNames(t *testing.T) {
+	wb := &xlsxWorkbook{}
+	wb.AddSheet("March")
+	if second := wb.AddSheet("march"); second.name != "march (2)" {
+		t.Errorf("Expected a suffixed sheet name, got %q", second.name)
+	}
+}
@@ -0,0 +1,208 @@
+package app
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// readXLSXSheet returns the cell text of one worksheet, row by row, with empty
+// cells filled in. An empty sheetName selects the first sheet. Numbers are
+// returned as stored, so dates come back as Excel serial numbers.
+func readXLSXSheet(r io.ReaderAt, size int64, sheetName string) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid XLSX file: %w", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := findXLSXSheet(files, sheetName)
+	if err != nil {
+		return nil, err
+	}
+	sharedStrings, err := readXLSXSharedStrings(files["xl/sharedStrings.xml"])
+	if err != nil {
+		return nil, err
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Index int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline struct {
+					Text string `xml:",innerxml"`
+				} `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodeZipXML(files[sheetPath], &sheet); err != nil {
+		return nil, fmt.Errorf("failed to read worksheet %s: %w", sheetPath, err)
+	}
+
+	var rows [][]string
+	for i, row := range sheet.Rows {
+		rowIndex := row.Index
+		if rowIndex == 0 {
+			rowIndex = i + 1
+		}
+		for len(rows) < rowIndex-1 {
+			rows = append(rows, nil)
+		}
+		var values []string
+		for j, cell := range row.Cells {
+			col := j
+			if cell.Ref != "" {
+				if col, err = xlsxColumnIndex(cell.Ref); err != nil {
+					return nil, err
+				}
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			switch cell.Type {
+			case "s":
+				idx, err := strconv.Atoi(cell.Value)
+				if err != nil || idx < 0 || idx >= len(sharedStrings) {
+					return nil, fmt.Errorf("cell %s references an invalid shared string", cell.Ref)
+				}
+				values[col] = sharedStrings[idx]
+			case "inlineStr":
+				values[col] = xmlInnerText(cell.Inline.Text)
+			default:
+				values[col] = cell.Value
+			}
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+func findXLSXSheet(files map[string]*zip.File, sheetName string) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeZipXML(files["xl/workbook.xml"], &workbook); err != nil {
+		return "", fmt.Errorf("failed to read workbook: %w", err)
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return "", fmt.Errorf("failed to read workbook relationships: %w", err)
+	}
+
+	for _, sheet := range workbook.Sheets {
+		if sheetName != "" && !strings.EqualFold(sheet.Name, sheetName) {
+			continue
+		}
+		for _, rel := range rels.Relationships {
+			if rel.ID != sheet.RID {
+				continue
+			}
+			target := strings.TrimPrefix(rel.Target, "/")
+			if !strings.HasPrefix(target, "xl/") {
+				target = path.Join("xl", target)
+			}
+			return target, nil
+		}
+		return "", fmt.Errorf("worksheet %q has no data", sheet.Name)
+	}
+	if sheetName != "" {
+		return "", fmt.Errorf("worksheet %q not found", sheetName)
+	}
+	return "", errors.New("workbook has no worksheets")
+}
+
+func readXLSXSharedStrings(f *zip.File) ([]string, error) {
+	if f == nil {
+		return nil, nil
+	}
+	var sst struct {
+		Items []struct {
+			Text string `xml:",innerxml"`
+		} `xml:"si"`
+	}
+	if err := decodeZipXML(f, &sst); err != nil {
+		return nil, fmt.Errorf("failed to read shared strings: %w", err)
+	}
+	strs := make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		strs[i] = xmlInnerText(item.Text)
+	}
+	return strs, nil
+}
+
+// xmlInnerText concatenates the <t> runs of a string item, skipping phonetic hints.
+func xmlInnerText(inner string) string {
+	decoder := xml.NewDecoder(strings.NewReader("<x>" + inner + "</x>"))
+	var b strings.Builder
+	inText, skip := false, 0
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return b.String()
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "rPh" {
+				skip++
+			}
+			inText = t.Name.Local == "t"
+		case xml.EndElement:
+			if t.Name.Local == "rPh" {
+				skip--
+			}
+			inText = false
+		case xml.CharData:
+			if inText && skip == 0 {
+				b.Write(t)
+			}
+		}
+	}
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	if f == nil {
+		return errors.New("missing part")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// xlsxColumnIndex returns the 0-based column of an A1-style reference.
+func xlsxColumnIndex(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return col - 1, nil
+}
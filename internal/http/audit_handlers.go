@@ -0,0 +1,93 @@
+package http
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gandalf-budget/internal/store"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+var auditEntities = map[string]bool{
+	store.AuditEntityCategory:   true,
+	store.AuditEntityBudgetLine: true,
+	store.AuditEntityActualLine: true,
+	store.AuditEntityMonth:      true,
+}
+
+// ListAuditEntriesHandler returns the audit log, newest first. Optional query
+// parameters: entity (category, budget_line, actual_line or month), month_id,
+// from and to (a date, covering the whole day, or an RFC 3339 time), and limit
+// (default 100, at most 1000).
+func ListAuditEntriesHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		filter := store.AuditFilter{Entity: query.Get("entity"), Limit: defaultAuditLimit}
+		if filter.Entity != "" && !auditEntities[filter.Entity] {
+			http.Error(w, "Invalid entity: use category, budget_line, actual_line or month", http.StatusBadRequest)
+			return
+		}
+		if monthIDStr := query.Get("month_id"); monthIDStr != "" {
+			monthID, err := strconv.ParseInt(monthIDStr, 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid 'month_id' query parameter: must be an integer", http.StatusBadRequest)
+				return
+			}
+			filter.MonthID = &monthID
+		}
+		var err error
+		if filter.From, err = parseAuditTime(query.Get("from"), false); err != nil {
+			http.Error(w, "Invalid 'from' query parameter: use YYYY-MM-DD or an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		if filter.To, err = parseAuditTime(query.Get("to"), true); err != nil {
+			http.Error(w, "Invalid 'to' query parameter: use YYYY-MM-DD or an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		if limitStr := query.Get("limit"); limitStr != "" {
+			filter.Limit, err = strconv.Atoi(limitStr)
+			if err != nil || filter.Limit < 1 || filter.Limit > maxAuditLimit {
+				http.Error(w, "Invalid 'limit' query parameter: must be between 1 and 1000", http.StatusBadRequest)
+				return
+			}
+		}
+
+		entries, err := s.GetAuditEntries(r.Context(), filter)
+		if err != nil {
+			log.Printf("Error fetching audit entries: %v", err)
+			http.Error(w, "Failed to fetch audit entries", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
+			log.Printf("Error encoding audit entries: %v", err)
+		}
+	}
+}
+
+// parseAuditTime reads a time range bound. A date given as the end of the
+// range includes that whole day.
+func parseAuditTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if day, err := time.Parse("2006-01-02", value); err == nil {
+		if end {
+			day = day.AddDate(0, 0, 1)
+		}
+		return day, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
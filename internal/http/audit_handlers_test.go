@@ -0,0 +1,60 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAuditEntriesHandler(t *testing.T) {
+	var got store.AuditFilter
+	mockStore := &store.ReusableMockStore{
+		MockGetAuditEntries: func(ctx context.Context, filter store.AuditFilter) ([]store.AuditEntry, error) {
+			got = filter
+			return []store.AuditEntry{{ID: 1, Entity: store.AuditEntityCategory, EntityID: 3, Action: store.AuditActionCreate, After: json.RawMessage(`{"id":3}`)}}, nil
+		},
+	}
+	monthID := int64(4)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantCode   int
+		wantFilter store.AuditFilter
+	}{
+		{"No filters", "", http.StatusOK, store.AuditFilter{Limit: 100}},
+		{"Entity and month", "?entity=budget_line&month_id=4&limit=20", http.StatusOK,
+			store.AuditFilter{Entity: store.AuditEntityBudgetLine, MonthID: &monthID, Limit: 20}},
+		{"Dates cover whole days", "?from=2024-03-01&to=2024-03-31", http.StatusOK, store.AuditFilter{
+			From: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), Limit: 100}},
+		{"RFC 3339 times", "?from=2024-03-01T10:00:00Z", http.StatusOK, store.AuditFilter{
+			From: time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC), Limit: 100}},
+		{"Unknown entity", "?entity=transaction", http.StatusBadRequest, store.AuditFilter{}},
+		{"Invalid month", "?month_id=march", http.StatusBadRequest, store.AuditFilter{}},
+		{"Invalid time", "?to=yesterday", http.StatusBadRequest, store.AuditFilter{}},
+		{"Limit too large", "?limit=5000", http.StatusBadRequest, store.AuditFilter{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got = store.AuditFilter{}
+			rr := httptest.NewRecorder()
+			ListAuditEntriesHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/audit"+tc.query, nil))
+			require.Equal(t, tc.wantCode, rr.Code, rr.Body.String())
+			assert.Equal(t, tc.wantFilter, got)
+			if tc.wantCode == http.StatusOK {
+				assert.JSONEq(t, `[{"id":1,"entity":"category","entity_id":3,"action":"create","month_id":null,"before":null,"after":{"id":3},"created_at":"0001-01-01T00:00:00Z"}]`, rr.Body.String())
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	ListAuditEntriesHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/audit", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
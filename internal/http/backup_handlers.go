@@ -0,0 +1,71 @@
+package http
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
+)
+
+type backupListResponse struct {
+	Directory       string           `json:"directory"`
+	IntervalSeconds int64            `json:"interval_seconds"`
+	LastBackup      *store.BackupRun `json:"last_backup"`
+	Backups         []app.BackupFile `json:"backups"`
+}
+
+// ListBackupsHandler reports the last successful backup of any kind and the on-disk backups.
+func ListBackupsHandler(s store.Store, backups *app.BackupScheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		lastBackup, err := s.GetLastBackupRun(r.Context())
+		if err != nil {
+			log.Printf("Error fetching last backup run: %v", err)
+			http.Error(w, "Failed to retrieve last backup", http.StatusInternalServerError)
+			return
+		}
+		files, err := backups.List()
+		if err != nil {
+			log.Printf("Error listing backups: %v", err)
+			http.Error(w, "Failed to list backups", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(backupListResponse{
+			Directory:       backups.Dir(),
+			IntervalSeconds: int64(backups.Interval().Seconds()),
+			LastBackup:      lastBackup,
+			Backups:         files,
+		})
+	}
+}
+
+// TriggerBackupHandler takes an on-disk backup right away.
+func TriggerBackupHandler(backups *app.BackupScheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		file, err := backups.RunNow(r.Context())
+		if err != nil {
+			log.Printf("Error running manual backup: %v", err)
+			http.Error(w, "Failed to create backup", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(file); err != nil {
+			log.Printf("Error encoding new backup to JSON: %v", err)
+		}
+	}
+}
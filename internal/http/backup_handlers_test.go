@@ -0,0 +1,141 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBackupTestScheduler(t *testing.T, s store.Store, passphrase string) *app.BackupScheduler {
+	t.Helper()
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("CREATE TABLE months (id INTEGER PRIMARY KEY, year INT, month INT)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	return app.NewBackupScheduler(db, s, t.TempDir(), 0, app.DefaultRetentionPolicy, passphrase)
+}
+
+func TestBackupHandlers_TriggerThenList(t *testing.T) {
+	var recorded []*store.BackupRun
+	mockStore := &store.ReusableMockStore{
+		MockRecordBackupRun: func(ctx context.Context, run *store.BackupRun) error {
+			recorded = append(recorded, run)
+			return nil
+		},
+		MockGetLastBackupRun: func(ctx context.Context) (*store.BackupRun, error) {
+			if len(recorded) == 0 {
+				return nil, nil
+			}
+			return recorded[len(recorded)-1], nil
+		},
+	}
+	backups := newBackupTestScheduler(t, mockStore, "")
+
+	rr := httptest.NewRecorder()
+	TriggerBackupHandler(backups).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/backups", nil))
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	var created app.BackupFile
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Name)
+	assert.Greater(t, created.SizeBytes, int64(0))
+	if assert.Len(t, recorded, 1) {
+		assert.Equal(t, store.BackupKindSnapshot, recorded[0].Kind)
+	}
+
+	rr = httptest.NewRecorder()
+	ListBackupsHandler(mockStore, backups).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/backups", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var listed struct {
+		LastBackup *store.BackupRun `json:"last_backup"`
+		Backups    []app.BackupFile `json:"backups"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	if assert.Len(t, listed.Backups, 1) {
+		assert.Equal(t, created.Name, listed.Backups[0].Name)
+	}
+	if assert.NotNil(t, listed.LastBackup) {
+		assert.Equal(t, store.BackupKindSnapshot, listed.LastBackup.Kind)
+	}
+}
+
+func TestTriggerBackupHandler_Encrypted(t *testing.T) {
+	mockStore := &store.ReusableMockStore{
+		MockRecordBackupRun: func(ctx context.Context, run *store.BackupRun) error { return nil },
+	}
+	backups := newBackupTestScheduler(t, mockStore, "s3cret")
+
+	rr := httptest.NewRecorder()
+	TriggerBackupHandler(backups).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/backups", nil))
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	var created app.BackupFile
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.True(t, created.Encrypted)
+	assert.True(t, strings.HasSuffix(created.Name, ".db.enc"), created.Name)
+
+	files, err := backups.List()
+	assert.NoError(t, err)
+	if assert.Len(t, files, 1, "the unencrypted copy must not be left behind") {
+		assert.Equal(t, created.Name, files[0].Name)
+	}
+
+	encrypted := filepath.Join(backups.Dir(), created.Name)
+	assert.ErrorIs(t, app.DecryptBackupFile(encrypted, encrypted+".db", "guess"), app.ErrWrongPassphrase)
+	assert.NoError(t, app.DecryptBackupFile(encrypted, encrypted+".db", "s3cret"))
+	restored, err := sqlx.Connect("sqlite3", encrypted+".db")
+	if assert.NoError(t, err) {
+		defer restored.Close()
+		var tables int
+		assert.NoError(t, restored.Get(&tables, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'months'"))
+		assert.Equal(t, 1, tables)
+	}
+}
+
+func TestListBackupsHandler_NoBackupsYet(t *testing.T) {
+	mockStore := &store.ReusableMockStore{
+		MockGetLastBackupRun: func(ctx context.Context) (*store.BackupRun, error) { return nil, nil },
+	}
+	backups := newBackupTestScheduler(t, mockStore, "")
+
+	rr := httptest.NewRecorder()
+	ListBackupsHandler(mockStore, backups).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/backups", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"directory": "`+backups.Dir()+`", "interval_seconds": 0, "last_backup": null, "backups": []}`, rr.Body.String())
+}
+
+func TestExportJSONHandler_RecordsLastBackup(t *testing.T) {
+	mockStore := exportMockStore()
+	var recorded *store.BackupRun
+	mockStore.MockRecordBackupRun = func(ctx context.Context, run *store.BackupRun) error {
+		recorded = run
+		return nil
+	}
+
+	rr := httptest.NewRecorder()
+	ExportJSONHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/export/json", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	if assert.NotNil(t, recorded) {
+		assert.Equal(t, store.BackupKindJSONExport, recorded.Kind)
+		assert.Equal(t, int64(rr.Body.Len()), recorded.SizeBytes)
+		assert.WithinDuration(t, time.Now(), recorded.CreatedAt, time.Minute)
+	}
+}
@@ -0,0 +1,294 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
+)
+
+const maxBankStatementBytes = 16 << 20
+
+type bankImportResponse struct {
+	Added      int `json:"added"`
+	Duplicates int `json:"duplicates"`
+	*app.BankImportResult
+}
+
+// ImportBankStatementHandler queues the spending in an uploaded OFX/QFX or CSV
+// statement for review (multipart "file", plus a JSON "mapping" for CSV). Each
+// transaction gets a proposed budget line where one matches; nothing is added to
+// the actuals until the transactions are confirmed.
+func ImportBankStatementHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBankStatementBytes)
+		if err := r.ParseMultipartForm(maxBankStatementBytes); err != nil {
+			http.Error(w, "Invalid upload: expected multipart form data with a statement file", http.StatusBadRequest)
+			return
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing statement file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, "Failed to read uploaded file", http.StatusBadRequest)
+			return
+		}
+
+		format := app.BankStatementFormat(strings.ToLower(r.URL.Query().Get("format")))
+		if format == "" {
+			format = app.BankStatementFormat(strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), "."))
+		}
+		var mapping app.BankCSVMapping
+		if format == app.BankFormatCSV {
+			if err := json.Unmarshal([]byte(r.FormValue("mapping")), &mapping); err != nil {
+				http.Error(w, "Invalid mapping JSON: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		statement, rejected, err := app.ParseBankStatement(data, format, mapping)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err := app.PrepareBankTransactions(r.Context(), s, statement)
+		if err != nil {
+			log.Printf("Error matching bank statement %s: %v", header.Filename, err)
+			http.Error(w, "Failed to match transactions to budget lines", http.StatusInternalServerError)
+			return
+		}
+		result.Rejected = rejected
+
+		added, err := s.AddBankTransactions(r.Context(), result.Transactions)
+		if err != nil {
+			log.Printf("Error saving bank statement %s: %v", header.Filename, err)
+			http.Error(w, "Failed to save transactions", http.StatusInternalServerError)
+			return
+		}
+
+		resp := bankImportResponse{Added: added, Duplicates: len(result.Transactions) - added, BankImportResult: result}
+		// Only report what was queued now; duplicates are already in the queue or applied.
+		queued := make([]store.BankTransaction, 0, added)
+		for _, txn := range result.Transactions {
+			if txn.ID != 0 {
+				queued = append(queued, txn)
+			}
+		}
+		resp.Transactions = queued
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Printf("Error encoding bank import response: %v", err)
+		}
+	}
+}
+
+// ListBankTransactionsHandler serves the review queue. ?status= selects pending
+// (the default), applied, ignored or all transactions.
+func ListBankTransactionsHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		status := r.URL.Query().Get("status")
+		switch status {
+		case "":
+			status = store.BankTransactionPending
+		case "all":
+			status = ""
+		case store.BankTransactionPending, store.BankTransactionApplied, store.BankTransactionIgnored:
+		default:
+			http.Error(w, "Invalid status: use pending, applied, ignored or all", http.StatusBadRequest)
+			return
+		}
+
+		txns, err := s.GetBankTransactions(r.Context(), status)
+		if err != nil {
+			log.Printf("Error fetching bank transactions (status %q): %v", status, err)
+			http.Error(w, "Failed to fetch bank transactions", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(txns); err != nil {
+			log.Printf("Error encoding bank transactions: %v", err)
+		}
+	}
+}
+
+// ConfirmBankTransactionsHandler adds reviewed transactions to their budget
+// lines' actuals. The body is {"confirmations": [{"transaction_id", "budget_line_id"}]}.
+// Lines in a finalized month need override_finalized=1, as other edits do.
+func ConfirmBankTransactionsHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			Confirmations []store.BankConfirmation `json:"confirmations"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(req.Confirmations) == 0 {
+			http.Error(w, "No confirmations given", http.StatusBadRequest)
+			return
+		}
+		for _, c := range req.Confirmations {
+			if c.TransactionID <= 0 || c.BudgetLineID <= 0 {
+				http.Error(w, "Each confirmation needs a transaction_id and a budget_line_id", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := s.ConfirmBankTransactions(editContext(r), req.Confirmations); err != nil {
+			writeBankTransactionError(w, "confirm bank transactions", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// IgnoreBankTransactionHandler drops a transaction from the review queue
+// (POST /api/v1/bank-transactions/{id}/ignore).
+func IgnoreBankTransactionHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/bank-transactions/"), "/ignore")
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid bank transaction ID in path", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.IgnoreBankTransaction(r.Context(), id); err != nil {
+			writeBankTransactionError(w, "ignore bank transaction", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeBankTransactionError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Bank transaction or budget line not found", http.StatusNotFound)
+	case errors.Is(err, store.ErrMonthFinalized):
+		http.Error(w, finalizedMonthMessage, http.StatusConflict)
+	case errors.Is(err, store.ErrTransactionNotPending), errors.Is(err, store.ErrCurrencyMismatch):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Failed to %s: %v", action, err)
+		http.Error(w, "Failed to "+action, http.StatusInternalServerError)
+	}
+}
+
+func ListMatchRulesHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		rules, err := s.GetMatchRules(r.Context())
+		if err != nil {
+			log.Printf("Error fetching match rules: %v", err)
+			http.Error(w, "Failed to fetch match rules", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rules); err != nil {
+			log.Printf("Error encoding match rules: %v", err)
+		}
+	}
+}
+
+// CreateMatchRuleHandler adds a rule proposing the budget line labelled "label"
+// for transactions whose description contains "pattern".
+func CreateMatchRuleHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var rule store.MatchRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		rule.Pattern = strings.TrimSpace(rule.Pattern)
+		rule.Label = strings.TrimSpace(rule.Label)
+		if rule.Pattern == "" || rule.Label == "" {
+			http.Error(w, "Pattern and label are required", http.StatusBadRequest)
+			return
+		}
+		if rule.Amount != nil && *rule.Amount <= 0 {
+			http.Error(w, "Amount must be positive when set", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.CreateMatchRule(r.Context(), &rule); err != nil {
+			log.Printf("Error creating match rule %q: %v", rule.Pattern, err)
+			http.Error(w, "Failed to create match rule", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(rule); err != nil {
+			log.Printf("Error encoding created match rule: %v", err)
+		}
+	}
+}
+
+func DeleteMatchRuleHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		pathParts := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/")
+		id, err := strconv.ParseInt(pathParts[len(pathParts)-1], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid match rule ID in path", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.DeleteMatchRule(r.Context(), id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Match rule not found", http.StatusNotFound)
+			} else {
+				log.Printf("Error deleting match rule %d: %v", id, err)
+				http.Error(w, "Failed to delete match rule", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
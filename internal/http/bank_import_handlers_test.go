@@ -0,0 +1,186 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportBankStatementHandler_CSV(t *testing.T) {
+	var saved []store.BankTransaction
+	mockStore := &store.ReusableMockStore{
+		MockGetMatchRules: func(ctx context.Context) ([]store.MatchRule, error) { return nil, nil },
+		MockGetMonthsByYear: func(ctx context.Context, year int) ([]store.Month, error) {
+			return []store.Month{{ID: 4, Year: year, Month: 3}}, nil
+		},
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
+			return &store.BoardDataPayload{BudgetLines: []store.BudgetLineWithActual{{ID: 11, Label: "Rent", ExpectedAmount: 900_00}}}, nil
+		},
+		MockAddBankTransactions: func(ctx context.Context, txns []store.BankTransaction) (int, error) {
+			saved = txns
+			// The second transaction was imported by an earlier upload.
+			txns[0].ID = 1
+			return 1, nil
+		},
+	}
+
+	csvData := "Date,Description,Amount\n2024-03-01,Landlord rent,-900\n2024-03-02,Coffee,-3.5\n2024-03-03,Salary,2500\nbad,x,1\n"
+	mapping := `{"date": "Date", "description": "Description", "amount": "Amount"}`
+	req := legacyUploadRequest(t, "/api/v1/import/bank", "march.csv", csvData, mapping)
+	rr := httptest.NewRecorder()
+	ImportBankStatementHandler(mockStore).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Len(t, saved, 2)
+
+	var resp struct {
+		Added        int                     `json:"added"`
+		Duplicates   int                     `json:"duplicates"`
+		Credits      int                     `json:"credits"`
+		Transactions []store.BankTransaction `json:"transactions"`
+		Rejected     []struct {
+			Line int `json:"line"`
+		} `json:"rejected"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Added)
+	assert.Equal(t, 1, resp.Duplicates)
+	assert.Equal(t, 1, resp.Credits)
+	if assert.Len(t, resp.Transactions, 1) {
+		assert.Equal(t, int64(11), *resp.Transactions[0].BudgetLineID)
+		assert.Equal(t, "label", resp.Transactions[0].MatchReason)
+	}
+	if assert.Len(t, resp.Rejected, 1) {
+		assert.Equal(t, 5, resp.Rejected[0].Line)
+	}
+}
+
+func TestImportBankStatementHandler_Rejections(t *testing.T) {
+	tests := []struct {
+		name         string
+		filename     string
+		content      string
+		mapping      string
+		expectedBody string
+	}{
+		{"Missing file", "", "", "", "Missing statement file"},
+		{"Unknown format", "a.pdf", "x", "", "unsupported statement format"},
+		{"CSV without mapping", "a.csv", "Date\n", "", "Invalid mapping JSON"},
+		{"Not OFX", "a.ofx", "Date,Amount\n", "", "not an OFX statement"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := legacyUploadRequest(t, "/api/v1/import/bank", tc.filename, tc.content, tc.mapping)
+			rr := httptest.NewRecorder()
+			ImportBankStatementHandler(&store.ReusableMockStore{}).ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), tc.expectedBody)
+		})
+	}
+}
+
+func TestListBankTransactionsHandler(t *testing.T) {
+	var gotStatus string
+	mockStore := &store.ReusableMockStore{
+		MockGetBankTransactions: func(ctx context.Context, status string) ([]store.BankTransaction, error) {
+			gotStatus = status
+			return []store.BankTransaction{{ID: 1, Status: store.BankTransactionPending}}, nil
+		},
+	}
+
+	tests := []struct {
+		query      string
+		wantCode   int
+		wantStatus string
+	}{
+		{"", http.StatusOK, store.BankTransactionPending},
+		{"?status=all", http.StatusOK, ""},
+		{"?status=ignored", http.StatusOK, store.BankTransactionIgnored},
+		{"?status=nope", http.StatusBadRequest, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			gotStatus = "unset"
+			rr := httptest.NewRecorder()
+			ListBankTransactionsHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/bank-transactions"+tc.query, nil))
+
+			assert.Equal(t, tc.wantCode, rr.Code)
+			if tc.wantCode == http.StatusOK {
+				assert.Equal(t, tc.wantStatus, gotStatus)
+			}
+		})
+	}
+}
+
+func TestConfirmBankTransactionsHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		storeErr error
+		wantCode int
+	}{
+		{"Success", `{"confirmations": [{"transaction_id": 1, "budget_line_id": 2}]}`, nil, http.StatusNoContent},
+		{"Empty", `{"confirmations": []}`, nil, http.StatusBadRequest},
+		{"Missing line", `{"confirmations": [{"transaction_id": 1}]}`, nil, http.StatusBadRequest},
+		{"Not pending", `{"confirmations": [{"transaction_id": 1, "budget_line_id": 2}]}`, fmt.Errorf("tx 1: %w", store.ErrTransactionNotPending), http.StatusConflict},
+		{"Finalized month", `{"confirmations": [{"transaction_id": 1, "budget_line_id": 2}]}`, fmt.Errorf("line 2: %w", store.ErrMonthFinalized), http.StatusConflict},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStore := &store.ReusableMockStore{
+				MockConfirmBankTransactions: func(ctx context.Context, confirmations []store.BankConfirmation) error { return tc.storeErr },
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/bank-transactions/confirm", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			ConfirmBankTransactionsHandler(mockStore).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.wantCode, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestIgnoreBankTransactionHandler(t *testing.T) {
+	var gotID int64
+	mockStore := &store.ReusableMockStore{
+		MockIgnoreBankTransaction: func(ctx context.Context, id int64) error {
+			gotID = id
+			return nil
+		},
+	}
+	rr := httptest.NewRecorder()
+	IgnoreBankTransactionHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/bank-transactions/42/ignore", nil))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, int64(42), gotID)
+}
+
+func TestCreateMatchRuleHandler(t *testing.T) {
+	mockStore := &store.ReusableMockStore{
+		MockCreateMatchRule: func(ctx context.Context, rule *store.MatchRule) error {
+			rule.ID = 3
+			return nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	CreateMatchRuleHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/match-rules",
+		strings.NewReader(`{"pattern": " netflix ", "label": "Streaming"}`)))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var rule store.MatchRule
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rule))
+	assert.Equal(t, int64(3), rule.ID)
+	assert.Equal(t, "netflix", rule.Pattern)
+
+	rr = httptest.NewRecorder()
+	CreateMatchRuleHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/match-rules",
+		strings.NewReader(`{"pattern": "netflix"}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
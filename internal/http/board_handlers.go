@@ -31,7 +31,7 @@ func GetBoardDataHandler(s store.Store) http.HandlerFunc {
 			return
 		}
 
-		boardData, err := s.GetBoardData(monthID)
+		boardData, err := s.GetBoardData(r.Context(), monthID)
 		if err != nil {
 			http.Error(w, "Failed to fetch board data", http.StatusInternalServerError)
 			return
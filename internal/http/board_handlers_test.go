@@ -1,12 +1,14 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	"gandalf-budget/internal/store" // For store types
@@ -26,7 +28,7 @@ func TestGetBoardDataHandler(t *testing.T) {
 			name:         "Successful fetch",
 			monthIDParam: "1",
 			setupMock: func(ms *store.ReusableMockStore) {
-				ms.MockGetBoardData = func(monthID int) (*store.BoardDataPayload, error) {
+				ms.MockGetBoardData = func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
 					if monthID != 1 {
 						return nil, fmt.Errorf("unexpected monthID: %d", monthID)
 					}
@@ -35,8 +37,8 @@ func TestGetBoardDataHandler(t *testing.T) {
 						Year:      2024,
 						MonthName: "January",
 						BudgetLines: []store.BudgetLineWithActual{
-							{ID: 1, MonthID: 1, CategoryID: 1, CategoryName: "Food", CategoryColor: "", Label: "Line 1", ExpectedAmount: 100.0, ActualAmount: 50.0},
-							{ID: 2, MonthID: 1, CategoryID: 2, CategoryName: "Rent", CategoryColor: "", Label: "Line 2", ExpectedAmount: 150.0, ActualAmount: 75.0},
+							{ID: 1, MonthID: 1, CategoryID: 1, CategoryName: "Food", CategoryColor: "", Label: "Line 1", ExpectedAmount: 100_00, ActualAmount: 50_00},
+							{ID: 2, MonthID: 1, CategoryID: 2, CategoryName: "Rent", CategoryColor: "", Label: "Line 2", ExpectedAmount: 150_00, ActualAmount: 75_00},
 						},
 					}, nil
 				}
@@ -47,8 +49,8 @@ func TestGetBoardDataHandler(t *testing.T) {
 				Year:      2024,
 				MonthName: "January",
 				BudgetLines: []store.BudgetLineWithActual{
-					{ID: 1, MonthID: 1, CategoryID: 1, CategoryName: "Food", CategoryColor: "", Label: "Line 1", ExpectedAmount: 100.0, ActualAmount: 50.0},
-					{ID: 2, MonthID: 1, CategoryID: 2, CategoryName: "Rent", CategoryColor: "", Label: "Line 2", ExpectedAmount: 150.0, ActualAmount: 75.0},
+					{ID: 1, MonthID: 1, CategoryID: 1, CategoryName: "Food", CategoryColor: "", Label: "Line 1", ExpectedAmount: 100_00, ActualAmount: 50_00},
+					{ID: 2, MonthID: 1, CategoryID: 2, CategoryName: "Rent", CategoryColor: "", Label: "Line 2", ExpectedAmount: 150_00, ActualAmount: 75_00},
 				},
 			},
 		},
@@ -56,7 +58,7 @@ func TestGetBoardDataHandler(t *testing.T) {
 			name:         "Empty board data",
 			monthIDParam: "2",
 			setupMock: func(ms *store.ReusableMockStore) {
-				ms.MockGetBoardData = func(monthID int) (*store.BoardDataPayload, error) {
+				ms.MockGetBoardData = func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
 					if monthID != 2 {
 						return nil, fmt.Errorf("unexpected monthID: %d", monthID)
 					}
@@ -80,26 +82,26 @@ func TestGetBoardDataHandler(t *testing.T) {
 			name:         "Store error on GetBoardData",
 			monthIDParam: "3",
 			setupMock: func(ms *store.ReusableMockStore) {
-				ms.MockGetBoardData = func(monthID int) (*store.BoardDataPayload, error) {
+				ms.MockGetBoardData = func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
 					return nil, errors.New("database is down")
 				}
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBody:       map[string]string{"error": "Failed to fetch board data"},
+			expectedBody:       "Failed to fetch board data",
 		},
 		{
 			name:               "Invalid monthId in path (non-integer)",
 			monthIDParam:       "abc",
 			setupMock:          func(ms *store.ReusableMockStore) {},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedBody:       map[string]string{"error": "Invalid Month ID format"},
+			expectedBody:       "Invalid Month ID format",
 		},
 		{
 			name:               "Missing monthId in path",
 			monthIDParam:       "",
 			setupMock:          func(ms *store.ReusableMockStore) {},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedBody:       map[string]string{"error": "Month ID is required"},
+			expectedBody:       "Month ID is required",
 		},
 	}
 
@@ -130,18 +132,8 @@ func TestGetBoardDataHandler(t *testing.T) {
 					expectedJSON, _ := json.Marshal(tc.expectedBody)
 					t.Errorf("Expected body %s, got %s", string(expectedJSON), rr.Body.String())
 				}
-			} else {
-				var actualErrorBody map[string]string
-				if err := json.Unmarshal(rr.Body.Bytes(), &actualErrorBody); err != nil {
-					if expectedStr, ok := tc.expectedBody.(string); ok && rr.Body.String() == expectedStr {
-					} else {
-						t.Logf("Raw error body: %s", rr.Body.String())
-						t.Fatalf("Could not unmarshal error response body: %v. Body: %s", err, rr.Body.String())
-					}
-				} else if !reflect.DeepEqual(actualErrorBody, tc.expectedBody) {
-					expectedJSON, _ := json.Marshal(tc.expectedBody)
-					t.Errorf("Expected error body %s, got %s", string(expectedJSON), rr.Body.String())
-				}
+			} else if body := strings.TrimSpace(rr.Body.String()); body != tc.expectedBody {
+				t.Errorf("Expected error body %q, got %q", tc.expectedBody, body)
 			}
 		})
 	}
@@ -158,7 +150,7 @@ func TestGetBoardDataHandler(t *testing.T) {
 		if rr.Code != http.StatusMethodNotAllowed {
 			t.Errorf("Expected status code %d for wrong method, got %d", http.StatusMethodNotAllowed, rr.Code)
 		}
-		if rr.Body.String() != "Method not allowed" {
+		if strings.TrimSpace(rr.Body.String()) != "Method not allowed" {
 			t.Errorf("Expected body 'Method not allowed', got '%s'", rr.Body.String())
 		}
 	})
@@ -167,7 +159,3 @@ func TestGetBoardDataHandler(t *testing.T) {
 func ptrToInt64(v int64) *int64 {
 	return &v
 }
-
-func ptrToFloat64(v float64) *float64 {
-	return &v
-}
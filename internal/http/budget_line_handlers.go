@@ -2,6 +2,7 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"gandalf-budget/internal/store"
 	"log"
@@ -18,7 +19,8 @@ func CreateBudgetLineHandler(s store.Store) http.HandlerFunc {
 		}
 
 		var bl store.BudgetLine
-		if err := json.NewDecoder(r.Body).Decode(&bl); err != nil {
+		err := json.NewDecoder(r.Body).Decode(&bl)
+		if err != nil {
 			log.Printf("Error decoding request body for create budget line: %v", err)
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
@@ -29,8 +31,22 @@ func CreateBudgetLineHandler(s store.Store) http.HandlerFunc {
 			http.Error(w, "Missing required fields: month_id, category_id, label", http.StatusBadRequest)
 			return
 		}
+		if bl.Currency != "" {
+			if bl.Currency, err = store.ParseCurrency(string(bl.Currency)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
 
-		budgetLineID, err := s.CreateBudgetLine(&bl)
+		budgetLineID, err := s.CreateBudgetLine(editContext(r), &bl)
+		if errors.Is(err, store.ErrCategoryDeleted) {
+			http.Error(w, "The category is in the trash; restore it first", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, store.ErrMonthFinalized) {
+			http.Error(w, finalizedMonthMessage, http.StatusConflict)
+			return
+		}
 		if err != nil {
 			log.Printf("Error creating budget line: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to create budget line: %v", err), http.StatusInternalServerError)
@@ -63,7 +79,8 @@ func UpdateActualLineHandler(s store.Store) http.HandlerFunc {
 		}
 
 		var reqBody struct {
-			Actual *float64 `json:"actual"`
+			Actual   *store.Decimal `json:"actual"`
+			Currency *string        `json:"currency"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
 			log.Printf("Error decoding request body for update actual line ID %d: %v", actualLineID, err)
@@ -76,12 +93,8 @@ func UpdateActualLineHandler(s store.Store) http.HandlerFunc {
 			http.Error(w, "Missing 'actual' field in request body", http.StatusBadRequest)
 			return
 		}
-		if *reqBody.Actual < 0 {
-			http.Error(w, "Invalid 'actual' amount: must be non-negative", http.StatusBadRequest)
-			return
-		}
 
-		al, err := s.GetActualLineByID(actualLineID)
+		al, err := s.GetActualLineByID(r.Context(), actualLineID)
 		if err != nil {
 			log.Printf("Error fetching actual line ID %d for update: %v", actualLineID, err)
 			http.Error(w, "Failed to retrieve actual line for update", http.StatusInternalServerError)
@@ -92,9 +105,31 @@ func UpdateActualLineHandler(s store.Store) http.HandlerFunc {
 			return
 		}
 
-		al.Actual = *reqBody.Actual
+		if reqBody.Currency != nil {
+			if al.Currency, err = store.ParseCurrency(*reqBody.Currency); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if al.Actual, err = reqBody.Actual.In(al.Currency); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if al.Actual < 0 {
+			http.Error(w, "Invalid 'actual' amount: must be non-negative", http.StatusBadRequest)
+			return
+		}
 
-		if err := s.UpdateActualLine(al); err != nil {
+		err = s.UpdateActualLine(editContext(r), al)
+		if errors.Is(err, store.ErrMonthFinalized) {
+			http.Error(w, finalizedMonthMessage, http.StatusConflict)
+			return
+		}
+		if errors.Is(err, store.ErrActualCurrencyInUse) {
+			http.Error(w, "The line has transactions in its current currency; delete them before changing the currency", http.StatusConflict)
+			return
+		}
+		if err != nil {
 			log.Printf("Error updating actual line ID %d: %v", actualLineID, err)
 			http.Error(w, fmt.Sprintf("Failed to update actual line: %v", err), http.StatusInternalServerError)
 			return
@@ -124,8 +159,9 @@ func UpdateBudgetLineHandler(s store.Store) http.HandlerFunc {
 		}
 
 		var reqBody struct {
-			Label    *string  `json:"label"`
-			Expected *float64 `json:"expected"`
+			Label    *string        `json:"label"`
+			Expected *store.Decimal `json:"expected"`
+			Currency *string        `json:"currency"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
 			log.Printf("Error decoding request body for update budget line ID %d: %v", budgetLineID, err)
@@ -134,7 +170,7 @@ func UpdateBudgetLineHandler(s store.Store) http.HandlerFunc {
 		}
 		defer r.Body.Close()
 
-		bl, err := s.GetBudgetLineByID(budgetLineID)
+		bl, err := s.GetBudgetLineByID(r.Context(), budgetLineID)
 		if err != nil {
 			log.Printf("Error fetching budget line ID %d for update: %v", budgetLineID, err)
 			http.Error(w, "Failed to retrieve budget line for update", http.StatusInternalServerError)
@@ -148,11 +184,28 @@ func UpdateBudgetLineHandler(s store.Store) http.HandlerFunc {
 		if reqBody.Label != nil {
 			bl.Label = *reqBody.Label
 		}
+		if reqBody.Currency != nil {
+			currency, err := store.ParseCurrency(*reqBody.Currency)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			// Without a new amount, the expected keeps its value in the new currency.
+			bl.Expected, bl.Currency = bl.Expected.Convert(bl.Currency, currency, 1), currency
+		}
 		if reqBody.Expected != nil {
-			bl.Expected = *reqBody.Expected
+			if bl.Expected, err = reqBody.Expected.In(bl.Currency); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 		}
 
-		if err := s.UpdateBudgetLine(bl); err != nil {
+		err = s.UpdateBudgetLine(editContext(r), bl)
+		if errors.Is(err, store.ErrMonthFinalized) {
+			http.Error(w, finalizedMonthMessage, http.StatusConflict)
+			return
+		}
+		if err != nil {
 			log.Printf("Error updating budget line ID %d: %v", budgetLineID, err)
 			http.Error(w, fmt.Sprintf("Failed to update budget line: %v", err), http.StatusInternalServerError)
 			return
@@ -181,7 +234,11 @@ func DeleteBudgetLineHandler(s store.Store) http.HandlerFunc {
 			return
 		}
 
-		err = s.DeleteBudgetLine(budgetLineID)
+		err = s.DeleteBudgetLine(editContext(r), budgetLineID)
+		if errors.Is(err, store.ErrMonthFinalized) {
+			http.Error(w, finalizedMonthMessage, http.StatusConflict)
+			return
+		}
 		if err != nil {
 			log.Printf("Error deleting budget line ID %d: %v", budgetLineID, err)
 			http.Error(w, fmt.Sprintf("Failed to delete budget line: %v", err), http.StatusInternalServerError)
@@ -211,7 +268,7 @@ func GetBudgetLinesByMonthIDHandler(s store.Store) http.HandlerFunc {
 			return
 		}
 
-		budgetLines, err := s.GetBudgetLinesByMonthID(monthID)
+		budgetLines, err := s.GetBudgetLinesByMonthID(r.Context(), monthID)
 		if err != nil {
 			log.Printf("Error getting budget lines for month ID %d: %v", monthID, err)
 			http.Error(w, fmt.Sprintf("Failed to get budget lines: %v", err), http.StatusInternalServerError)
@@ -2,6 +2,7 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"gandalf-budget/internal/store"
@@ -11,133 +12,8 @@ import (
 	"testing"
 )
 
-type MockStore struct {
-	MockGetAllCategories func() ([]store.Category, error)
-	MockCreateCategory   func(category *store.Category) error
-	MockGetCategoryByID  func(id int64) (*store.Category, error)
-	MockUpdateCategory   func(category *store.Category) error
-	MockDeleteCategory   func(id int64) error
-
-	MockCreateBudgetLine        func(b *store.BudgetLine) (int64, error)
-	MockGetBudgetLinesByMonthID func(monthID int) ([]store.BudgetLine, error)
-	MockUpdateBudgetLine        func(b *store.BudgetLine) error
-	MockDeleteBudgetLine        func(id int64) error
-	MockUpdateActualLine        func(a *store.ActualLine) error
-	MockGetActualLineByID       func(id int64) (*store.ActualLine, error)
-	MockGetBudgetLineByID       func(id int64) (*store.BudgetLine, error)
-
-	MockGetBoardData     func(monthID int) (*store.BoardDataPayload, error)
-	MockCanFinalizeMonth func(monthID int) (bool, string, error)
-	MockFinalizeMonth    func(monthID int, snapJSON string) (int64, error)
-}
-
-func (m *MockStore) GetAllCategories() ([]store.Category, error) {
-	if m.MockGetAllCategories != nil {
-		return m.MockGetAllCategories()
-	}
-	return nil, fmt.Errorf("MockGetAllCategories not implemented")
-}
-
-func (m *MockStore) CreateCategory(category *store.Category) error {
-	if m.MockCreateCategory != nil {
-		return m.MockCreateCategory(category)
-	}
-	return fmt.Errorf("MockCreateCategory not implemented")
-}
-
-func (m *MockStore) GetCategoryByID(id int64) (*store.Category, error) {
-	if m.MockGetCategoryByID != nil {
-		return m.MockGetCategoryByID(id)
-	}
-	return nil, fmt.Errorf("MockGetCategoryByID not implemented")
-}
-
-func (m *MockStore) UpdateCategory(category *store.Category) error {
-	if m.MockUpdateCategory != nil {
-		return m.MockUpdateCategory(category)
-	}
-	return fmt.Errorf("MockUpdateCategory not implemented")
-}
-
-func (m *MockStore) DeleteCategory(id int64) error {
-	if m.MockDeleteCategory != nil {
-		return m.MockDeleteCategory(id)
-	}
-	return fmt.Errorf("MockDeleteCategory not implemented")
-}
-
-func (m *MockStore) CreateBudgetLine(b *store.BudgetLine) (int64, error) {
-	if m.MockCreateBudgetLine != nil {
-		return m.MockCreateBudgetLine(b)
-	}
-	return 0, fmt.Errorf("MockCreateBudgetLine not implemented")
-}
-
-func (m *MockStore) GetBudgetLinesByMonthID(monthID int) ([]store.BudgetLine, error) {
-	if m.MockGetBudgetLinesByMonthID != nil {
-		return m.MockGetBudgetLinesByMonthID(monthID)
-	}
-	return nil, fmt.Errorf("MockGetBudgetLinesByMonthID not implemented")
-}
-
-func (m *MockStore) UpdateBudgetLine(b *store.BudgetLine) error {
-	if m.MockUpdateBudgetLine != nil {
-		return m.MockUpdateBudgetLine(b)
-	}
-	return fmt.Errorf("MockUpdateBudgetLine not implemented")
-}
-
-func (m *MockStore) DeleteBudgetLine(id int64) error {
-	if m.MockDeleteBudgetLine != nil {
-		return m.MockDeleteBudgetLine(id)
-	}
-	return fmt.Errorf("MockDeleteBudgetLine not implemented")
-}
-
-func (m *MockStore) UpdateActualLine(a *store.ActualLine) error {
-	if m.MockUpdateActualLine != nil {
-		return m.MockUpdateActualLine(a)
-	}
-	return fmt.Errorf("MockUpdateActualLine not implemented")
-}
-
-func (m *MockStore) GetActualLineByID(id int64) (*store.ActualLine, error) {
-	if m.MockGetActualLineByID != nil {
-		return m.MockGetActualLineByID(id)
-	}
-	return nil, fmt.Errorf("MockGetActualLineByID not implemented")
-}
-
-func (m *MockStore) GetBudgetLineByID(id int64) (*store.BudgetLine, error) {
-	if m.MockGetBudgetLineByID != nil {
-		return m.MockGetBudgetLineByID(id)
-	}
-	return nil, fmt.Errorf("MockGetBudgetLineByID not implemented")
-}
-
-func (m *MockStore) GetBoardData(monthID int) (*store.BoardDataPayload, error) {
-	if m.MockGetBoardData != nil {
-		return m.MockGetBoardData(monthID)
-	}
-	return nil, fmt.Errorf("MockGetBoardData not implemented")
-}
-
-func (m *MockStore) CanFinalizeMonth(monthID int) (bool, string, error) {
-	if m.MockCanFinalizeMonth != nil {
-		return m.MockCanFinalizeMonth(monthID)
-	}
-	return false, "", fmt.Errorf("MockCanFinalizeMonth not implemented")
-}
-
-func (m *MockStore) FinalizeMonth(monthID int, snapJSON string) (int64, error) {
-	if m.MockFinalizeMonth != nil {
-		return m.MockFinalizeMonth(monthID, snapJSON)
-	}
-	return 0, fmt.Errorf("MockFinalizeMonth not implemented")
-}
-
 func TestCreateBudgetLineHandler(t *testing.T) {
-	mockStore := &MockStore{}
+	mockStore := &store.ReusableMockStore{}
 	handler := CreateBudgetLineHandler(mockStore)
 
 	t.Run("successful creation", func(t *testing.T) {
@@ -146,7 +22,7 @@ func TestCreateBudgetLineHandler(t *testing.T) {
 			MonthID:    1,
 			CategoryID: 1,
 			Label:      "Test Groceries",
-			Expected:   150.00,
+			Expected:   150_00,
 		}
 		createdBudgetLine := store.BudgetLine{
 			ID:         int(expectedID),
@@ -156,7 +32,7 @@ func TestCreateBudgetLineHandler(t *testing.T) {
 			Expected:   inputBudgetLine.Expected,
 		}
 
-		mockStore.MockCreateBudgetLine = func(bl *store.BudgetLine) (int64, error) {
+		mockStore.MockCreateBudgetLine = func(ctx context.Context, bl *store.BudgetLine) (int64, error) {
 			if bl.Label != inputBudgetLine.Label || bl.Expected != inputBudgetLine.Expected || bl.MonthID != inputBudgetLine.MonthID || bl.CategoryID != inputBudgetLine.CategoryID {
 				t.Errorf("MockCreateBudgetLine called with unexpected data: got %+v, want label %s", bl, inputBudgetLine.Label)
 			}
@@ -206,7 +82,7 @@ func TestCreateBudgetLineHandler(t *testing.T) {
 	})
 
 	t.Run("store error on creation", func(t *testing.T) {
-		mockStore.MockCreateBudgetLine = func(bl *store.BudgetLine) (int64, error) {
+		mockStore.MockCreateBudgetLine = func(ctx context.Context, bl *store.BudgetLine) (int64, error) {
 			return 0, fmt.Errorf("database error")
 		}
 		budgetLineJSON := `{"label":"Test","expected":100,"month_id":1,"category_id":1}`
@@ -220,22 +96,22 @@ func TestCreateBudgetLineHandler(t *testing.T) {
 }
 
 func TestGetBudgetLinesByMonthIDHandler(t *testing.T) {
-	mockStore := &MockStore{}
+	mockStore := &store.ReusableMockStore{}
 	handler := GetBudgetLinesByMonthIDHandler(mockStore)
 
 	t.Run("successful retrieval", func(t *testing.T) {
 		monthID := 1
 		actualID1 := int64(101)
-		actualAmount1 := 150.0
+		actualAmount1 := store.Money(150_00)
 		actualID2 := int64(102)
-		actualAmount2 := 25.0
+		actualAmount2 := store.Money(25_00)
 
 		expectedBudgetLines := []store.BudgetLine{
-			{ID: 1, MonthID: monthID, CategoryID: 1, Label: "Food", Expected: 200, ActualID: &actualID1, ActualAmount: &actualAmount1},
-			{ID: 2, MonthID: monthID, CategoryID: 2, Label: "Gas", Expected: 50, ActualID: &actualID2, ActualAmount: &actualAmount2},
-			{ID: 3, MonthID: monthID, CategoryID: 3, Label: "Rent", Expected: 1000, ActualID: nil, ActualAmount: nil},
+			{ID: 1, MonthID: monthID, CategoryID: 1, Label: "Food", Expected: 200_00, ActualID: &actualID1, ActualAmount: &actualAmount1},
+			{ID: 2, MonthID: monthID, CategoryID: 2, Label: "Gas", Expected: 50_00, ActualID: &actualID2, ActualAmount: &actualAmount2},
+			{ID: 3, MonthID: monthID, CategoryID: 3, Label: "Rent", Expected: 1000_00, ActualID: nil, ActualAmount: nil},
 		}
-		mockStore.MockGetBudgetLinesByMonthID = func(mID int) ([]store.BudgetLine, error) {
+		mockStore.MockGetBudgetLinesByMonthID = func(ctx context.Context, mID int) ([]store.BudgetLine, error) {
 			if mID != monthID {
 				t.Errorf("expected month ID %d, got %d", monthID, mID)
 			}
@@ -297,7 +173,7 @@ func TestGetBudgetLinesByMonthIDHandler(t *testing.T) {
 
 	t.Run("store error on retrieval", func(t *testing.T) {
 		monthID := 2
-		mockStore.MockGetBudgetLinesByMonthID = func(mID int) ([]store.BudgetLine, error) {
+		mockStore.MockGetBudgetLinesByMonthID = func(ctx context.Context, mID int) ([]store.BudgetLine, error) {
 			return nil, fmt.Errorf("database error")
 		}
 		req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/budget-lines?month_id=%d", monthID), nil)
@@ -310,7 +186,7 @@ func TestGetBudgetLinesByMonthIDHandler(t *testing.T) {
 
 	t.Run("no budget lines found", func(t *testing.T) {
 		monthID := 3
-		mockStore.MockGetBudgetLinesByMonthID = func(mID int) ([]store.BudgetLine, error) {
+		mockStore.MockGetBudgetLinesByMonthID = func(ctx context.Context, mID int) ([]store.BudgetLine, error) {
 			return []store.BudgetLine{}, nil
 		}
 		req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/budget-lines?month_id=%d", monthID), nil)
@@ -330,7 +206,7 @@ func TestGetBudgetLinesByMonthIDHandler(t *testing.T) {
 }
 
 func TestUpdateBudgetLineHandler(t *testing.T) {
-	mockStore := &MockStore{}
+	mockStore := &store.ReusableMockStore{}
 	handler := UpdateBudgetLineHandler(mockStore)
 
 	t.Run("successful update", func(t *testing.T) {
@@ -340,14 +216,14 @@ func TestUpdateBudgetLineHandler(t *testing.T) {
 			MonthID:    1,
 			CategoryID: 1,
 			Label:      "Old Label",
-			Expected:   100.00,
+			Expected:   100_00,
 		}
 		updatePayload := struct {
-			Label    *string  `json:"label"`
-			Expected *float64 `json:"expected"`
+			Label    *string      `json:"label"`
+			Expected *store.Money `json:"expected"`
 		}{
 			Label:    pointy.String("New Label"),
-			Expected: pointy.Float64(200.00),
+			Expected: pointy.Money(200_00),
 		}
 		updatedBudgetLine := store.BudgetLine{
 			ID:         int(budgetLineID),
@@ -357,13 +233,13 @@ func TestUpdateBudgetLineHandler(t *testing.T) {
 			Expected:   *updatePayload.Expected,
 		}
 
-		mockStore.MockGetBudgetLineByID = func(id int64) (*store.BudgetLine, error) {
+		mockStore.MockGetBudgetLineByID = func(ctx context.Context, id int64) (*store.BudgetLine, error) {
 			if id != budgetLineID {
 				t.Errorf("expected GetBudgetLineByID with ID %d, got %d", budgetLineID, id)
 			}
 			return originalBudgetLine, nil
 		}
-		mockStore.MockUpdateBudgetLine = func(bl *store.BudgetLine) error {
+		mockStore.MockUpdateBudgetLine = func(ctx context.Context, bl *store.BudgetLine) error {
 			if bl.ID != int(budgetLineID) || bl.Label != *updatePayload.Label || bl.Expected != *updatePayload.Expected {
 				t.Errorf("UpdateBudgetLine called with unexpected data: got %+v", bl)
 			}
@@ -388,9 +264,34 @@ func TestUpdateBudgetLineHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("currency change reads the amount in the new currency", func(t *testing.T) {
+		var saved *store.BudgetLine
+		mockStore.MockGetBudgetLineByID = func(ctx context.Context, id int64) (*store.BudgetLine, error) {
+			return &store.BudgetLine{ID: int(id), Label: "Tokyo", Expected: 120_50, Currency: "USD"}, nil
+		}
+		mockStore.MockUpdateBudgetLine = func(ctx context.Context, bl *store.BudgetLine) error {
+			saved = bl
+			return nil
+		}
+		for payload, want := range map[string]store.Money{
+			`{"currency": "jpy"}`:                    121,
+			`{"currency": "JPY", "expected": 15000}`: 15_000,
+		} {
+			saved = nil
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("PUT", "/api/v1/budget-lines/5", strings.NewReader(payload)))
+			if rr.Code != http.StatusOK || saved == nil {
+				t.Fatalf("expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
+			}
+			if saved.Currency != "JPY" || saved.Expected != want {
+				t.Errorf("%s: expected %d JPY, got %d %s", payload, want, saved.Expected, saved.Currency)
+			}
+		}
+	})
+
 	t.Run("budget line not found", func(t *testing.T) {
 		budgetLineID := int64(2)
-		mockStore.MockGetBudgetLineByID = func(id int64) (*store.BudgetLine, error) {
+		mockStore.MockGetBudgetLineByID = func(ctx context.Context, id int64) (*store.BudgetLine, error) {
 			return nil, nil
 		}
 		payload := `{"label":"Any Label"}`
@@ -423,7 +324,7 @@ func TestUpdateBudgetLineHandler(t *testing.T) {
 
 	t.Run("store error on GetBudgetLineByID", func(t *testing.T) {
 		budgetLineID := int64(3)
-		mockStore.MockGetBudgetLineByID = func(id int64) (*store.BudgetLine, error) {
+		mockStore.MockGetBudgetLineByID = func(ctx context.Context, id int64) (*store.BudgetLine, error) {
 			return nil, fmt.Errorf("database error on get")
 		}
 		payload := `{"label":"Any Label"}`
@@ -438,10 +339,10 @@ func TestUpdateBudgetLineHandler(t *testing.T) {
 	t.Run("store error on UpdateBudgetLine", func(t *testing.T) {
 		budgetLineID := int64(4)
 		originalBudgetLine := &store.BudgetLine{ID: int(budgetLineID), Label: "Old"}
-		mockStore.MockGetBudgetLineByID = func(id int64) (*store.BudgetLine, error) {
+		mockStore.MockGetBudgetLineByID = func(ctx context.Context, id int64) (*store.BudgetLine, error) {
 			return originalBudgetLine, nil
 		}
-		mockStore.MockUpdateBudgetLine = func(bl *store.BudgetLine) error {
+		mockStore.MockUpdateBudgetLine = func(ctx context.Context, bl *store.BudgetLine) error {
 			return fmt.Errorf("database error on update")
 		}
 		payload := `{"label":"New Label"}`
@@ -455,23 +356,23 @@ func TestUpdateBudgetLineHandler(t *testing.T) {
 }
 
 var pointy = struct {
-	String  func(s string) *string
-	Float64 func(f float64) *float64
-	Int     func(i int) *int
+	String func(s string) *string
+	Money  func(m store.Money) *store.Money
+	Int    func(i int) *int
 }{
-	String:  func(s string) *string { return &s },
-	Float64: func(f float64) *float64 { return &f },
-	Int:     func(i int) *int { return &i },
+	String: func(s string) *string { return &s },
+	Money:  func(m store.Money) *store.Money { return &m },
+	Int:    func(i int) *int { return &i },
 }
 
 func TestDeleteBudgetLineHandler(t *testing.T) {
-	mockStore := &MockStore{}
+	mockStore := &store.ReusableMockStore{}
 	handler := DeleteBudgetLineHandler(mockStore)
 
 	t.Run("successful deletion", func(t *testing.T) {
 		budgetLineID := int64(1)
 		deleteCalled := false
-		mockStore.MockDeleteBudgetLine = func(id int64) error {
+		mockStore.MockDeleteBudgetLine = func(ctx context.Context, id int64) error {
 			if id != budgetLineID {
 				t.Errorf("expected DeleteBudgetLine with ID %d, got %d", budgetLineID, id)
 			}
@@ -502,7 +403,7 @@ func TestDeleteBudgetLineHandler(t *testing.T) {
 
 	t.Run("store error on deletion", func(t *testing.T) {
 		budgetLineID := int64(2)
-		mockStore.MockDeleteBudgetLine = func(id int64) error {
+		mockStore.MockDeleteBudgetLine = func(ctx context.Context, id int64) error {
 			return fmt.Errorf("database error")
 		}
 		req := httptest.NewRequest("DELETE", fmt.Sprintf("/api/v1/budget-lines/%d", budgetLineID), nil)
@@ -516,7 +417,7 @@ func TestDeleteBudgetLineHandler(t *testing.T) {
 }
 
 func TestUpdateActualLineHandler(t *testing.T) {
-	mockStore := &MockStore{}
+	mockStore := &store.ReusableMockStore{}
 	handler := UpdateActualLineHandler(mockStore)
 
 	t.Run("successful update", func(t *testing.T) {
@@ -524,25 +425,25 @@ func TestUpdateActualLineHandler(t *testing.T) {
 		originalActualLine := &store.ActualLine{
 			ID:           actualLineID,
 			BudgetLineID: 10,
-			Actual:       50.00,
+			Actual:       50_00,
 		}
 		updatePayload := struct {
-			Actual *float64 `json:"actual"`
+			Actual *store.Money `json:"actual"`
 		}{
-			Actual: pointy.Float64(75.50),
+			Actual: pointy.Money(75_50),
 		}
 
 		var capturedActualLine store.ActualLine
-		mockStore.MockGetActualLineByID = func(id int64) (*store.ActualLine, error) {
+		mockStore.MockGetActualLineByID = func(ctx context.Context, id int64) (*store.ActualLine, error) {
 			if id != actualLineID {
 				t.Errorf("expected GetActualLineByID with ID %d, got %d", actualLineID, id)
 			}
 			return originalActualLine, nil
 		}
-		mockStore.MockUpdateActualLine = func(al *store.ActualLine) error {
+		mockStore.MockUpdateActualLine = func(ctx context.Context, al *store.ActualLine) error {
 			capturedActualLine = *al
 			if al.ID != actualLineID || al.Actual != *updatePayload.Actual {
-				t.Errorf("UpdateActualLine called with unexpected data: got %+v, want actual %f", al, *updatePayload.Actual)
+				t.Errorf("UpdateActualLine called with unexpected data: got %+v, want actual %s", al, *updatePayload.Actual)
 			}
 			return nil
 		}
@@ -561,30 +462,26 @@ func TestUpdateActualLineHandler(t *testing.T) {
 			t.Fatalf("Failed to unmarshal response body: %v", err)
 		}
 		if respBody.Actual != *updatePayload.Actual {
-			t.Errorf("expected updated actual %.2f, got %.2f", *updatePayload.Actual, respBody.Actual)
+			t.Errorf("expected updated actual %s, got %s", *updatePayload.Actual, respBody.Actual)
 		}
-		if capturedActualLine.Actual != 75.50 {
-			t.Errorf("expected actual amount %f in mock store, got %f", 75.50, capturedActualLine.Actual)
+		if capturedActualLine.Actual != 75_50 {
+			t.Errorf("expected actual amount %s in mock store, got %s", store.Money(75_50), capturedActualLine.Actual)
 		}
 	})
 
 	t.Run("update with amount needing rounding", func(t *testing.T) {
 		actualLineID := int64(5)
-		originalActualLine := &store.ActualLine{ID: actualLineID, BudgetLineID: 11, Actual: 10.0}
-		updatePayload := struct {
-			Actual *float64 `json:"actual"`
-		}{Actual: pointy.Float64(123.456)}
-		expectedRounded := 123.46
+		originalActualLine := &store.ActualLine{ID: actualLineID, BudgetLineID: 11, Actual: 10_00}
+		expectedRounded := store.Money(123_46)
 
 		var capturedActualLine store.ActualLine
-		mockStore.MockGetActualLineByID = func(id int64) (*store.ActualLine, error) { return originalActualLine, nil }
-		mockStore.MockUpdateActualLine = func(al *store.ActualLine) error {
+		mockStore.MockGetActualLineByID = func(ctx context.Context, id int64) (*store.ActualLine, error) { return originalActualLine, nil }
+		mockStore.MockUpdateActualLine = func(ctx context.Context, al *store.ActualLine) error {
 			capturedActualLine = *al
 			return nil
 		}
 
-		payloadBytes, _ := json.Marshal(updatePayload)
-		req := httptest.NewRequest("PUT", fmt.Sprintf("/api/v1/actual-lines/%d", actualLineID), bytes.NewReader(payloadBytes))
+		req := httptest.NewRequest("PUT", fmt.Sprintf("/api/v1/actual-lines/%d", actualLineID), strings.NewReader(`{"actual":123.456}`))
 		rr := httptest.NewRecorder()
 		handler.ServeHTTP(rr, req)
 
@@ -592,22 +489,22 @@ func TestUpdateActualLineHandler(t *testing.T) {
 			t.Errorf("expected status %d, got %d. Body: %s", http.StatusOK, rr.Code, rr.Body.String())
 		}
 		if capturedActualLine.Actual != expectedRounded {
-			t.Errorf("expected rounded actual amount %f in mock store, got %f", expectedRounded, capturedActualLine.Actual)
+			t.Errorf("expected rounded actual amount %s in mock store, got %s", expectedRounded, capturedActualLine.Actual)
 		}
 		var respBody store.ActualLine
 		if err := json.Unmarshal(rr.Body.Bytes(), &respBody); err != nil {
 			t.Fatalf("Failed to unmarshal response body: %v", err)
 		}
 		if respBody.Actual != expectedRounded {
-			t.Errorf("expected response actual %.2f, got %.2f", expectedRounded, respBody.Actual)
+			t.Errorf("expected response actual %s, got %s", expectedRounded, respBody.Actual)
 		}
 	})
 
 	t.Run("update with negative amount - handler validation", func(t *testing.T) {
 		actualLineID := int64(6)
 		updatePayload := struct {
-			Actual *float64 `json:"actual"`
-		}{Actual: pointy.Float64(-10.50)}
+			Actual *store.Money `json:"actual"`
+		}{Actual: pointy.Money(-10_50)}
 
 		payloadBytes, _ := json.Marshal(updatePayload)
 		req := httptest.NewRequest("PUT", fmt.Sprintf("/api/v1/actual-lines/%d", actualLineID), bytes.NewReader(payloadBytes))
@@ -624,7 +521,7 @@ func TestUpdateActualLineHandler(t *testing.T) {
 
 	t.Run("actual line not found", func(t *testing.T) {
 		actualLineID := int64(2)
-		mockStore.MockGetActualLineByID = func(id int64) (*store.ActualLine, error) {
+		mockStore.MockGetActualLineByID = func(ctx context.Context, id int64) (*store.ActualLine, error) {
 			return nil, nil
 		}
 		payload := `{"actual":100.0}`
@@ -666,7 +563,7 @@ func TestUpdateActualLineHandler(t *testing.T) {
 
 	t.Run("store error on GetActualLineByID", func(t *testing.T) {
 		actualLineID := int64(3)
-		mockStore.MockGetActualLineByID = func(id int64) (*store.ActualLine, error) {
+		mockStore.MockGetActualLineByID = func(ctx context.Context, id int64) (*store.ActualLine, error) {
 			return nil, fmt.Errorf("database error on get")
 		}
 		payload := `{"actual":100.0}`
@@ -680,11 +577,11 @@ func TestUpdateActualLineHandler(t *testing.T) {
 
 	t.Run("store error on UpdateActualLine", func(t *testing.T) {
 		actualLineID := int64(4)
-		originalActualLine := &store.ActualLine{ID: actualLineID, Actual: 50.0}
-		mockStore.MockGetActualLineByID = func(id int64) (*store.ActualLine, error) {
+		originalActualLine := &store.ActualLine{ID: actualLineID, Actual: 50_00}
+		mockStore.MockGetActualLineByID = func(ctx context.Context, id int64) (*store.ActualLine, error) {
 			return originalActualLine, nil
 		}
-		mockStore.MockUpdateActualLine = func(al *store.ActualLine) error {
+		mockStore.MockUpdateActualLine = func(ctx context.Context, al *store.ActualLine) error {
 			return fmt.Errorf("database error on update")
 		}
 		payload := `{"actual":100.0}`
@@ -695,4 +592,21 @@ func TestUpdateActualLineHandler(t *testing.T) {
 			t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rr.Code)
 		}
 	})
+
+	t.Run("currency change on a line with transactions", func(t *testing.T) {
+		actualLineID := int64(5)
+		mockStore.MockGetActualLineByID = func(ctx context.Context, id int64) (*store.ActualLine, error) {
+			return &store.ActualLine{ID: actualLineID, Actual: 50_00, Currency: "USD"}, nil
+		}
+		mockStore.MockUpdateActualLine = func(ctx context.Context, al *store.ActualLine) error {
+			return fmt.Errorf("actual line %d: %w", al.ID, store.ErrActualCurrencyInUse)
+		}
+		payload := `{"actual":50.0,"currency":"EUR"}`
+		req := httptest.NewRequest("PUT", fmt.Sprintf("/api/v1/actual-lines/%d", actualLineID), strings.NewReader(payload))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status %d, got %d", http.StatusConflict, rr.Code)
+		}
+	})
 }
@@ -3,6 +3,7 @@ package http
 import (
 	"database/sql" // For sql.ErrNoRows
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv" // For parsing ID from path
@@ -17,7 +18,7 @@ func HandleGetCategories(storage store.Store) http.HandlerFunc {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		categories, err := storage.GetAllCategories()
+		categories, err := storage.GetAllCategories(r.Context())
 		if err != nil {
 			log.Printf("Error in HandleGetCategories calling store.GetAllCategories: %v", err)
 			http.Error(w, "Failed to retrieve categories", http.StatusInternalServerError)
@@ -44,7 +45,11 @@ func HandleCreateCategory(storage store.Store) http.HandlerFunc {
 			http.Error(w, "Category name and color are required", http.StatusBadRequest)
 			return
 		}
-		err := storage.CreateCategory(&newCategory)
+		err := storage.CreateCategory(r.Context(), &newCategory)
+		if errors.Is(err, store.ErrCategoryNameInTrash) {
+			http.Error(w, "A category with this name is in the trash; restore it from the trash instead", http.StatusConflict)
+			return
+		}
 		if err != nil {
 			http.Error(w, "Failed to create category", http.StatusInternalServerError)
 			return
@@ -87,10 +92,12 @@ func HandleUpdateCategory(storage store.Store) http.HandlerFunc {
 			return
 		}
 
-		err = storage.UpdateCategory(&categoryToUpdate)
+		err = storage.UpdateCategory(r.Context(), &categoryToUpdate)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				http.Error(w, "Category not found or no changes needed", http.StatusNotFound)
+			} else if errors.Is(err, store.ErrCategoryNameInTrash) {
+				http.Error(w, "A category with this name is in the trash; restore it from the trash instead", http.StatusConflict)
 			} else {
 				log.Printf("Error in HandleUpdateCategory calling store.UpdateCategory for ID %d: %v", id, err)
 				http.Error(w, "Failed to update category", http.StatusInternalServerError)
@@ -98,7 +105,7 @@ func HandleUpdateCategory(storage store.Store) http.HandlerFunc {
 			return
 		}
 
-		updatedCategory, err := storage.GetCategoryByID(id)
+		updatedCategory, err := storage.GetCategoryByID(r.Context(), id)
 		if err != nil || updatedCategory == nil {
 			log.Printf("Error fetching updated category ID %d after update: %v", id, err)
 			http.Error(w, "Failed to retrieve category after update", http.StatusInternalServerError)
@@ -130,10 +137,12 @@ func HandleDeleteCategory(storage store.Store) http.HandlerFunc {
 			return
 		}
 
-		err = storage.DeleteCategory(id)
+		err = storage.DeleteCategory(r.Context(), id)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				http.Error(w, "Category not found", http.StatusNotFound)
+			} else if errors.Is(err, store.ErrCategoryInUse) {
+				http.Error(w, "Category is still used by budget lines", http.StatusConflict)
 			} else {
 				log.Printf("Error in HandleDeleteCategory calling store.DeleteCategory for ID %d: %v", id, err)
 				http.Error(w, "Failed to delete category", http.StatusInternalServerError)
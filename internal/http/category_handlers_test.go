@@ -2,42 +2,26 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"database/sql" // Used by store, and sql.ErrNoRows is relevant for handler logic
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sort"    // For sorting slices in tests, if needed for robust comparison
 	"strconv" // For converting int64 to string for URL paths
 	"testing"
-	"sort" // For sorting slices in tests, if needed for robust comparison
 
 	"gandalf-budget/internal/store" // Adjust to your module path
-	"github.com/jmoiron/sqlx"
-	_ "github.com/mattn/go-sqlite3"
 )
 
-func setupInMemoryDB(t *testing.T) *sqlx.DB {
-	db, err := sqlx.Open("sqlite3", ":memory:")
-	if err != nil {
-		t.Fatalf("Failed to open in-memory database: %v", err)
-	}
-
-	schema := `
-	CREATE TABLE categories (
-	  id INTEGER PRIMARY KEY AUTOINCREMENT,
-	  name TEXT UNIQUE NOT NULL,
-	  color TEXT NOT NULL
-	);`
-	_, err = db.Exec(schema)
-	if err != nil {
-		db.Close()
-		t.Fatalf("Failed to create schema: %v", err)
-	}
-	return db
+// newCategoryTestStore returns an empty in-memory store for the category handlers.
+func newCategoryTestStore(t *testing.T) store.Store {
+	t.Helper()
+	return store.NewMemoryStore()
 }
 
 func TestHandleGetCategories(t *testing.T) {
-	db := setupInMemoryDB(t)
-	defer db.Close()
+	s := newCategoryTestStore(t)
 
 	initialCategories := []store.Category{
 		{Name: "Food", Color: "bg-red-500"},
@@ -45,28 +29,24 @@ func TestHandleGetCategories(t *testing.T) {
 		{Name: "Entertainment", Color: "bg-green-500"},
 	}
 	for _, cat := range initialCategories {
-		_, err := db.Exec(`INSERT INTO categories (name, color) VALUES (?, ?)`, cat.Name, cat.Color)
-		if err != nil {
-			t.Fatalf("Failed to seed category '%s': %v", cat.Name, err)
-		}
+		seedCategory(t, s, cat)
 	}
-	
+
 	expectedCategories := []store.Category{
 		{ID: 3, Name: "Entertainment", Color: "bg-green-500"},
 		{ID: 1, Name: "Food", Color: "bg-red-500"},
 		{ID: 2, Name: "Travel", Color: "bg-blue-500"},
 	}
 	sort.Slice(expectedCategories, func(i, j int) bool {
-        return expectedCategories[i].Name < expectedCategories[j].Name
-    })
-
+		return expectedCategories[i].Name < expectedCategories[j].Name
+	})
 
 	req, err := http.NewRequest("GET", "/api/v1/categories", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	handler := HandleGetCategories(db)
+	handler := HandleGetCategories(s)
 	handler.ServeHTTP(rr, req)
 
 	if status := rr.Code; status != http.StatusOK {
@@ -81,23 +61,21 @@ func TestHandleGetCategories(t *testing.T) {
 	if len(actual) != len(expectedCategories) {
 		t.Fatalf("handler returned unexpected body size: got %d want %d categories. Body: %s", len(actual), len(expectedCategories), rr.Body.String())
 	}
-	
+
 	for i := range actual {
 		if actual[i].Name != expectedCategories[i].Name || actual[i].Color != expectedCategories[i].Color {
 			t.Errorf("Mismatch at index %d. Got Name: %s, Color: %s. Expected Name: %s, Color: %s",
 				i, actual[i].Name, actual[i].Color, expectedCategories[i].Name, expectedCategories[i].Color)
 		}
 		if actual[i].ID != expectedCategories[i].ID {
-             t.Errorf("Mismatch ID at index %d. Got ID: %d. Expected ID: %d for Name: %s",
-                i, actual[i].ID, expectedCategories[i].ID, actual[i].Name)
-        }
+			t.Errorf("Mismatch ID at index %d. Got ID: %d. Expected ID: %d for Name: %s",
+				i, actual[i].ID, expectedCategories[i].ID, actual[i].Name)
+		}
 	}
 }
 
-
 func TestHandleCreateCategory(t *testing.T) {
-	db := setupInMemoryDB(t)
-	defer db.Close()
+	s := newCategoryTestStore(t)
 
 	categoryPayload := store.Category{Name: "Electronics", Color: "bg-gray-500"}
 	payloadBytes, _ := json.Marshal(categoryPayload)
@@ -109,7 +87,7 @@ func TestHandleCreateCategory(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := HandleCreateCategory(db)
+	handler := HandleCreateCategory(s)
 	handler.ServeHTTP(rr, req)
 
 	if status := rr.Code; status != http.StatusCreated {
@@ -131,10 +109,9 @@ func TestHandleCreateCategory(t *testing.T) {
 		t.Errorf("handler returned category with zero ID")
 	}
 
-	var dbCategory store.Category
-	err = db.Get(&dbCategory, "SELECT id, name, color FROM categories WHERE id = ?", createdCategory.ID)
-	if err != nil {
-		t.Fatalf("Could not fetch created category from DB: %v", err)
+	dbCategory, err := s.GetCategoryByID(context.Background(), createdCategory.ID)
+	if err != nil || dbCategory == nil {
+		t.Fatalf("Could not fetch created category from the store: %v", err)
 	}
 	if dbCategory.Name != categoryPayload.Name {
 		t.Errorf("DB category name mismatch: got '%s' want '%s'", dbCategory.Name, categoryPayload.Name)
@@ -142,14 +119,13 @@ func TestHandleCreateCategory(t *testing.T) {
 }
 
 func TestHandleCreateCategory_Validation(t *testing.T) {
-	db := setupInMemoryDB(t) 
-	defer db.Close()
+	s := newCategoryTestStore(t)
 
 	tests := []struct {
 		name           string
-		payload        map[string]string 
+		payload        map[string]string
 		expectedStatus int
-		expectedBody   string 
+		expectedBody   string
 	}{
 		{"MissingName", map[string]string{"color": "bg-red-500"}, http.StatusBadRequest, "Category name and color are required"},
 		{"MissingColor", map[string]string{"name": "Test"}, http.StatusBadRequest, "Category name and color are required"},
@@ -161,9 +137,9 @@ func TestHandleCreateCategory_Validation(t *testing.T) {
 			payloadBytes, _ := json.Marshal(tt.payload)
 			req, _ := http.NewRequest("POST", "/api/v1/categories", bytes.NewBuffer(payloadBytes))
 			req.Header.Set("Content-Type", "application/json")
-			
+
 			rr := httptest.NewRecorder()
-			handler := HandleCreateCategory(db) 
+			handler := HandleCreateCategory(s)
 			handler.ServeHTTP(rr, req)
 
 			if status := rr.Code; status != tt.expectedStatus {
@@ -176,25 +152,18 @@ func TestHandleCreateCategory_Validation(t *testing.T) {
 	}
 }
 
-func seedCategory(t *testing.T, db *sqlx.DB, category store.Category) store.Category {
+func seedCategory(t *testing.T, s store.Store, category store.Category) store.Category {
 	t.Helper()
-	_, err := db.Exec(`INSERT INTO categories (name, color) VALUES (?, ?)`, category.Name, category.Color)
-	if err != nil {
+	if err := s.CreateCategory(context.Background(), &category); err != nil {
 		t.Fatalf("Failed to seed category '%s': %v", category.Name, err)
 	}
-	var seededCategory store.Category
-	err = db.Get(&seededCategory, "SELECT id, name, color FROM categories WHERE name = ?", category.Name)
-	if err != nil {
-		t.Fatalf("Failed to retrieve seeded category '%s': %v", category.Name, err)
-	}
-	return seededCategory
+	return category
 }
 
 func TestHandleUpdateCategory_Success(t *testing.T) {
-	db := setupInMemoryDB(t)
-	defer db.Close()
+	s := newCategoryTestStore(t)
 
-	initialCategory := seedCategory(t, db, store.Category{Name: "Initial Name", Color: "bg-initial-500"})
+	initialCategory := seedCategory(t, s, store.Category{Name: "Initial Name", Color: "bg-initial-500"})
 
 	updatePayload := store.Category{Name: "Updated Name", Color: "bg-updated-500"}
 	payloadBytes, _ := json.Marshal(updatePayload)
@@ -206,7 +175,7 @@ func TestHandleUpdateCategory_Success(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := HandleUpdateCategory(db)
+	handler := HandleUpdateCategory(s)
 	handler.ServeHTTP(rr, req)
 
 	if status := rr.Code; status != http.StatusOK {
@@ -228,7 +197,7 @@ func TestHandleUpdateCategory_Success(t *testing.T) {
 		t.Errorf("handler returned unexpected category ID: got %d want %d", updatedCategory.ID, initialCategory.ID)
 	}
 
-	dbCategory, err := store.GetCategoryByID(db, initialCategory.ID)
+	dbCategory, err := s.GetCategoryByID(context.Background(), initialCategory.ID)
 	if err != nil {
 		t.Fatalf("Could not fetch category from DB after update: %v", err)
 	}
@@ -244,8 +213,7 @@ func TestHandleUpdateCategory_Success(t *testing.T) {
 }
 
 func TestHandleUpdateCategory_NotFound(t *testing.T) {
-	db := setupInMemoryDB(t)
-	defer db.Close()
+	s := newCategoryTestStore(t)
 
 	updatePayload := store.Category{Name: "Updated Name", Color: "bg-updated-500"}
 	payloadBytes, _ := json.Marshal(updatePayload)
@@ -258,7 +226,7 @@ func TestHandleUpdateCategory_NotFound(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
-	handler := HandleUpdateCategory(db)
+	handler := HandleUpdateCategory(s)
 	handler.ServeHTTP(rr, req)
 
 	if status := rr.Code; status != http.StatusNotFound {
@@ -266,11 +234,35 @@ func TestHandleUpdateCategory_NotFound(t *testing.T) {
 	}
 }
 
+func TestHandleUpdateCategory_NameInTrash(t *testing.T) {
+	s := newCategoryTestStore(t)
+
+	trashed := seedCategory(t, s, store.Category{Name: "Travel", Color: "bg-blue-500"})
+	if err := s.DeleteCategory(context.Background(), trashed.ID); err != nil {
+		t.Fatalf("Failed to move category to the trash: %v", err)
+	}
+	food := seedCategory(t, s, store.Category{Name: "Food", Color: "bg-red-500"})
+
+	payloadBytes, _ := json.Marshal(store.Category{Name: "Travel", Color: "bg-red-500"})
+	req, err := http.NewRequest("PUT", "/api/v1/categories/"+strconv.FormatInt(food.ID, 10), bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := HandleUpdateCategory(s)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusConflict {
+		t.Errorf("handler returned wrong status code: got %v want %v. Body: %s", status, http.StatusConflict, rr.Body.String())
+	}
+}
+
 func TestHandleUpdateCategory_Validation(t *testing.T) {
-	db := setupInMemoryDB(t)
-	defer db.Close()
+	s := newCategoryTestStore(t)
 
-	initialCategory := seedCategory(t, db, store.Category{Name: "Initial Name", Color: "bg-initial-500"})
+	initialCategory := seedCategory(t, s, store.Category{Name: "Initial Name", Color: "bg-initial-500"})
 
 	tests := []struct {
 		name           string
@@ -291,7 +283,7 @@ func TestHandleUpdateCategory_Validation(t *testing.T) {
 			req.Header.Set("Content-Type", "application/json")
 
 			rr := httptest.NewRecorder()
-			handler := HandleUpdateCategory(db)
+			handler := HandleUpdateCategory(s)
 			handler.ServeHTTP(rr, req)
 
 			if status := rr.Code; status != tt.expectedStatus {
@@ -305,10 +297,9 @@ func TestHandleUpdateCategory_Validation(t *testing.T) {
 }
 
 func TestHandleDeleteCategory_Success(t *testing.T) {
-	db := setupInMemoryDB(t)
-	defer db.Close()
+	s := newCategoryTestStore(t)
 
-	categoryToDelete := seedCategory(t, db, store.Category{Name: "To Delete", Color: "bg-delete-500"})
+	categoryToDelete := seedCategory(t, s, store.Category{Name: "To Delete", Color: "bg-delete-500"})
 
 	req, err := http.NewRequest("DELETE", "/api/v1/categories/"+strconv.FormatInt(categoryToDelete.ID, 10), nil)
 	if err != nil {
@@ -316,14 +307,14 @@ func TestHandleDeleteCategory_Success(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := HandleDeleteCategory(db)
+	handler := HandleDeleteCategory(s)
 	handler.ServeHTTP(rr, req)
 
 	if status := rr.Code; status != http.StatusNoContent {
 		t.Errorf("handler returned wrong status code: got %v want %v. Body: %s", status, http.StatusNoContent, rr.Body.String())
 	}
 
-	deletedCategory, err := store.GetCategoryByID(db, categoryToDelete.ID)
+	deletedCategory, err := s.GetCategoryByID(context.Background(), categoryToDelete.ID)
 	if err != nil && err != sql.ErrNoRows {
 		t.Fatalf("Error fetching category from DB after delete: %v", err)
 	}
@@ -333,8 +324,7 @@ func TestHandleDeleteCategory_Success(t *testing.T) {
 }
 
 func TestHandleDeleteCategory_NotFound(t *testing.T) {
-	db := setupInMemoryDB(t)
-	defer db.Close()
+	s := newCategoryTestStore(t)
 
 	nonExistentID := int64(999)
 	req, err := http.NewRequest("DELETE", "/api/v1/categories/"+strconv.FormatInt(nonExistentID, 10), nil)
@@ -343,7 +333,7 @@ func TestHandleDeleteCategory_NotFound(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := HandleDeleteCategory(db)
+	handler := HandleDeleteCategory(s)
 	handler.ServeHTTP(rr, req)
 
 	if status := rr.Code; status != http.StatusNotFound {
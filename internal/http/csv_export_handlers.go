@@ -0,0 +1,196 @@
+package http
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
+)
+
+var csvDelimiters = map[string]rune{
+	",": ',', "comma": ',',
+	";": ';', "semicolon": ';',
+	"\t": '\t', "tab": '\t',
+	"|": '|', "pipe": '|',
+}
+
+var csvDecimalSeparators = map[string]rune{
+	".": '.', "dot": '.',
+	",": ',', "comma": ',',
+}
+
+// parseCSVFormat reads ?delimiter= and ?decimal= (e.g. delimiter=semicolon&decimal=comma).
+func parseCSVFormat(r *http.Request) (app.CSVFormat, error) {
+	format := app.DefaultCSVFormat
+	if v := r.URL.Query().Get("delimiter"); v != "" {
+		d, ok := csvDelimiters[v]
+		if !ok {
+			return format, fmt.Errorf("invalid delimiter %q: use comma, semicolon, tab or pipe", v)
+		}
+		format.Delimiter = d
+	}
+	if v := r.URL.Query().Get("decimal"); v != "" {
+		d, ok := csvDecimalSeparators[v]
+		if !ok {
+			return format, fmt.Errorf("invalid decimal separator %q: use dot or comma", v)
+		}
+		format.DecimalSeparator = d
+	}
+	return format, format.Validate()
+}
+
+func parseMonthIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	monthIDStr := r.URL.Query().Get("month_id")
+	if monthIDStr == "" {
+		http.Error(w, "month_id query parameter is required", http.StatusBadRequest)
+		return 0, false
+	}
+	monthID, err := strconv.Atoi(monthIDStr)
+	if err != nil {
+		http.Error(w, "Invalid month_id: must be an integer", http.StatusBadRequest)
+		return 0, false
+	}
+	return monthID, true
+}
+
+func parseYearParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	yearStr := r.URL.Query().Get("year")
+	if yearStr == "" {
+		http.Error(w, "year query parameter is required", http.StatusBadRequest)
+		return 0, false
+	}
+	year, err := strconv.Atoi(yearStr)
+	if err != nil {
+		http.Error(w, "Invalid year format: must be an integer", http.StatusBadRequest)
+		return 0, false
+	}
+	if year < 2000 || year > time.Now().Year()+5 {
+		http.Error(w, "Year out of reasonable range", http.StatusBadRequest)
+		return 0, false
+	}
+	return year, true
+}
+
+// writeCSVAttachment renders the CSV into memory first so that a failure can
+// still be reported with a proper status code.
+func writeCSVAttachment(w http.ResponseWriter, filename string, render func(*bytes.Buffer) error) {
+	var buf bytes.Buffer
+	if err := render(&buf); err != nil {
+		log.Printf("Error rendering CSV %s: %v", filename, err)
+		http.Error(w, "Failed to generate CSV", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("Error writing CSV %s: %v", filename, err)
+	}
+}
+
+// ExportBoardCSVHandler serves a month's budget lines as CSV (?month_id=).
+func ExportBoardCSVHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		format, err := parseCSVFormat(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		monthID, ok := parseMonthIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		boardData, err := s.GetBoardData(r.Context(), monthID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Month not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to fetch board data: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		filename := fmt.Sprintf("board_%d-%02d.csv", boardData.Year, monthNumber(boardData.MonthName))
+		writeCSVAttachment(w, filename, func(buf *bytes.Buffer) error {
+			return app.WriteBoardCSV(buf, boardData, format)
+		})
+	}
+}
+
+// ExportDashboardCSVHandler serves a month's per-category totals as CSV (?month_id=).
+func ExportDashboardCSVHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		format, err := parseCSVFormat(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		monthID, ok := parseMonthIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		payload, ok := loadDashboardPayload(w, r, s, monthID)
+		if !ok {
+			return
+		}
+		filename := fmt.Sprintf("dashboard_%d-%02d.csv", payload.Year, monthNumber(payload.Month))
+		writeCSVAttachment(w, filename, func(buf *bytes.Buffer) error {
+			return app.WriteDashboardCSV(buf, payload, format)
+		})
+	}
+}
+
+// ExportYearCSVHandler serves every budget line of a year by month as CSV (?year=).
+func ExportYearCSVHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		format, err := parseCSVFormat(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		year, ok := parseYearParam(w, r)
+		if !ok {
+			return
+		}
+
+		matrix, err := app.BuildYearMatrix(r.Context(), s, year)
+		if err != nil {
+			log.Printf("Error building year matrix for %d: %v", year, err)
+			http.Error(w, "Failed to fetch year data", http.StatusInternalServerError)
+			return
+		}
+		writeCSVAttachment(w, fmt.Sprintf("year_%d.csv", year), func(buf *bytes.Buffer) error {
+			return app.WriteYearMatrixCSV(buf, matrix, format)
+		})
+	}
+}
+
+// monthNumber maps the English month names used by BoardDataPayload back to 1-12, or 0 if unknown.
+func monthNumber(name string) int {
+	for m := time.January; m <= time.December; m++ {
+		if m.String() == name {
+			return int(m)
+		}
+	}
+	return 0
+}
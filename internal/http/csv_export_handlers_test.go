@@ -0,0 +1,114 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func csvMockStore() *store.ReusableMockStore {
+	return &store.ReusableMockStore{
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
+			if monthID != 1 {
+				return nil, sql.ErrNoRows
+			}
+			return &store.BoardDataPayload{
+				MonthID:   1,
+				Year:      2024,
+				MonthName: "March",
+				BudgetLines: []store.BudgetLineWithActual{
+					{ID: 1, CategoryID: 1, CategoryName: "Food", Label: "Groceries", ExpectedAmount: 100_50, ActualAmount: 80_00},
+				},
+			}, nil
+		},
+		MockGetAllCategories: func(ctx context.Context) ([]store.Category, error) {
+			return []store.Category{{ID: 1, Name: "Food"}, {ID: 2, Name: "Travel"}}, nil
+		},
+		MockGetMonthsByYear: func(ctx context.Context, year int) ([]store.Month, error) {
+			return []store.Month{{ID: 1, Year: year, Month: 3}}, nil
+		},
+	}
+}
+
+func TestCSVExportHandlers(t *testing.T) {
+	tests := []struct {
+		name         string
+		handler      http.HandlerFunc
+		url          string
+		expectedCode int
+		expectedBody string
+		expectedFile string
+	}{
+		{
+			name:         "Board with locale format",
+			handler:      ExportBoardCSVHandler(csvMockStore()),
+			url:          "/api/v1/export/csv/board?month_id=1&delimiter=semicolon&decimal=comma",
+			expectedCode: http.StatusOK,
+			expectedBody: "Category;Label;Expected;Actual;Difference\nFood;Groceries;100,50;80,00;20,50\n",
+			expectedFile: "board_2024-03.csv",
+		},
+		{
+			name:         "Dashboard lists every category and a total",
+			handler:      ExportDashboardCSVHandler(csvMockStore()),
+			url:          "/api/v1/export/csv/dashboard?month_id=1",
+			expectedCode: http.StatusOK,
+			expectedBody: "Category,Budget Lines,Expected,Actual,Difference\nFood,1,100.50,80.00,20.50\nTravel,0,0.00,0.00,0.00\nTotal,,100.50,80.00,20.50\n",
+			expectedFile: "dashboard_2024-03.csv",
+		},
+		{
+			name:         "Year matrix",
+			handler:      ExportYearCSVHandler(csvMockStore()),
+			url:          "/api/v1/export/csv/year?year=2024&delimiter=tab",
+			expectedCode: http.StatusOK,
+			expectedBody: "Food\tGroceries\t0.00\t0.00\t0.00\t0.00\t100.50\t80.00",
+			expectedFile: "year_2024.csv",
+		},
+		{
+			name:         "Same delimiter and decimal separator",
+			handler:      ExportBoardCSVHandler(csvMockStore()),
+			url:          "/api/v1/export/csv/board?month_id=1&decimal=comma",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "must differ",
+		},
+		{
+			name:         "Unknown delimiter",
+			handler:      ExportYearCSVHandler(csvMockStore()),
+			url:          "/api/v1/export/csv/year?year=2024&delimiter=colon",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid delimiter",
+		},
+		{
+			name:         "Month not found",
+			handler:      ExportDashboardCSVHandler(csvMockStore()),
+			url:          "/api/v1/export/csv/dashboard?month_id=9",
+			expectedCode: http.StatusNotFound,
+			expectedBody: "Month not found",
+		},
+		{
+			name:         "Missing month_id",
+			handler:      ExportBoardCSVHandler(csvMockStore()),
+			url:          "/api/v1/export/csv/board",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "month_id query parameter is required",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			assert.Equal(t, tc.expectedCode, rr.Code, rr.Body.String())
+			assert.Contains(t, rr.Body.String(), tc.expectedBody)
+			if tc.expectedFile != "" {
+				assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+				assert.Contains(t, rr.Header().Get("Content-Disposition"), tc.expectedFile)
+			}
+		})
+	}
+}
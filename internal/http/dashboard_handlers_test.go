@@ -1,13 +1,14 @@
 package http
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
-	"time"
 
 	"gandalf-budget/internal/app"
 	"gandalf-budget/internal/store"
@@ -15,23 +16,23 @@ import (
 
 func TestGetDashboardData_Success(t *testing.T) {
 	mockStore := &store.ReusableMockStore{
-		MockGetBoardData: func(monthID int) (*store.BoardDataPayload, error) {
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
 			if monthID == 1 {
 				return &store.BoardDataPayload{
 					MonthID:   1,
 					Year:      2023,
 					MonthName: "December",
 					BudgetLines: []store.BudgetLineWithActual{
-						{ID: 101, MonthID: 1, CategoryID: 1, CategoryName: "Food", CategoryColor: "blue", Label: "Groceries", ExpectedAmount: 500.00, ActualAmount: 480.50},
-						{ID: 102, MonthID: 1, CategoryID: 2, CategoryName: "Housing", CategoryColor: "red", Label: "Rent", ExpectedAmount: 1500.00, ActualAmount: 1500.00},
-						{ID: 103, MonthID: 1, CategoryID: 1, CategoryName: "Food", CategoryColor: "blue", Label: "Eating Out", ExpectedAmount: 150.00, ActualAmount: 180.75},
-						{ID: 104, MonthID: 1, CategoryID: 3, CategoryName: "Utilities", CategoryColor: "green", Label: "Internet", ExpectedAmount: 60.00, ActualAmount: 60.00},
+						{ID: 101, MonthID: 1, CategoryID: 1, CategoryName: "Food", CategoryColor: "blue", Label: "Groceries", ExpectedAmount: 500_00, ActualAmount: 480_50},
+						{ID: 102, MonthID: 1, CategoryID: 2, CategoryName: "Housing", CategoryColor: "red", Label: "Rent", ExpectedAmount: 1500_00, ActualAmount: 1500_00},
+						{ID: 103, MonthID: 1, CategoryID: 1, CategoryName: "Food", CategoryColor: "blue", Label: "Eating Out", ExpectedAmount: 150_00, ActualAmount: 180_75},
+						{ID: 104, MonthID: 1, CategoryID: 3, CategoryName: "Utilities", CategoryColor: "green", Label: "Internet", ExpectedAmount: 60_00, ActualAmount: 60_00},
 					},
 				}, nil
 			}
 			return nil, errors.New("board data not found for month_id")
 		},
-		MockGetAllCategories: func() ([]store.Category, error) {
+		MockGetAllCategories: func(ctx context.Context) ([]store.Category, error) {
 			return []store.Category{
 				{ID: 1, Name: "Food", Color: "blue"},
 				{ID: 2, Name: "Housing", Color: "red"},
@@ -58,19 +59,19 @@ func TestGetDashboardData_Success(t *testing.T) {
 		t.Fatalf("could not unmarshal response body: %v. Body: %s", err, rr.Body.String())
 	}
 
-	expectedTotalExpected := 500.00 + 1500.00 + 150.00 + 60.00
+	expectedTotalExpected := store.Money(500_00 + 1500_00 + 150_00 + 60_00)
 	if payload.TotalExpected != expectedTotalExpected {
-		t.Errorf("payload.TotalExpected = %f; want %f", payload.TotalExpected, expectedTotalExpected)
+		t.Errorf("payload.TotalExpected = %s; want %s", payload.TotalExpected, expectedTotalExpected)
 	}
 
-	expectedTotalActual := 480.50 + 1500.00 + 180.75 + 60.00
+	expectedTotalActual := store.Money(480_50 + 1500_00 + 180_75 + 60_00)
 	if payload.TotalActual != expectedTotalActual {
-		t.Errorf("payload.TotalActual = %f; want %f", payload.TotalActual, expectedTotalActual)
+		t.Errorf("payload.TotalActual = %s; want %s", payload.TotalActual, expectedTotalActual)
 	}
 
 	expectedTotalDifference := expectedTotalExpected - expectedTotalActual
 	if payload.TotalDifference != expectedTotalDifference {
-		t.Errorf("payload.TotalDifference = %f; want %f", payload.TotalDifference, expectedTotalDifference)
+		t.Errorf("payload.TotalDifference = %s; want %s", payload.TotalDifference, expectedTotalDifference)
 	}
 
 	if payload.MonthID != 1 {
@@ -104,16 +105,16 @@ func TestGetDashboardData_Success(t *testing.T) {
 		t.Errorf("cat1Summary.CategoryName = %s; want Food", cat1Summary.CategoryName)
 	}
 
-	expectedCat1TotalExpected := 500.00 + 150.00
+	expectedCat1TotalExpected := store.Money(500_00 + 150_00)
 	if cat1Summary.TotalExpected != expectedCat1TotalExpected {
-		t.Errorf("cat1Summary.TotalExpected = %f; want %f", cat1Summary.TotalExpected, expectedCat1TotalExpected)
+		t.Errorf("cat1Summary.TotalExpected = %s; want %s", cat1Summary.TotalExpected, expectedCat1TotalExpected)
 	}
-	expectedCat1TotalActual := 480.50 + 180.75
+	expectedCat1TotalActual := store.Money(480_50 + 180_75)
 	if cat1Summary.TotalActual != expectedCat1TotalActual {
-		t.Errorf("cat1Summary.TotalActual = %f; want %f", cat1Summary.TotalActual, expectedCat1TotalActual)
+		t.Errorf("cat1Summary.TotalActual = %s; want %s", cat1Summary.TotalActual, expectedCat1TotalActual)
 	}
 	if cat1Summary.Difference != (expectedCat1TotalExpected - expectedCat1TotalActual) {
-		t.Errorf("cat1Summary.Difference = %f; want %f", cat1Summary.Difference, (expectedCat1TotalExpected - expectedCat1TotalActual))
+		t.Errorf("cat1Summary.Difference = %s; want %s", cat1Summary.Difference, (expectedCat1TotalExpected - expectedCat1TotalActual))
 	}
 	if len(cat1Summary.BudgetLines) != 2 {
 		t.Errorf("len(cat1Summary.BudgetLines) = %d; want %d", len(cat1Summary.BudgetLines), 2)
@@ -132,14 +133,14 @@ func TestGetDashboardData_Success(t *testing.T) {
 		t.Fatalf("BudgetLineDetail for 'Groceries' not found in Food category")
 	}
 
-	if bl1_1.ExpectedAmount != 500.00 {
-		t.Errorf("Groceries ExpectedAmount = %f; want %f", bl1_1.ExpectedAmount, 500.00)
+	if bl1_1.ExpectedAmount != 500_00 {
+		t.Errorf("Groceries ExpectedAmount = %s; want %s", bl1_1.ExpectedAmount, store.Money(500_00))
 	}
-	if bl1_1.ActualAmount != 480.50 {
-		t.Errorf("Groceries ActualAmount = %f; want %f", bl1_1.ActualAmount, 480.50)
+	if bl1_1.ActualAmount != 480_50 {
+		t.Errorf("Groceries ActualAmount = %s; want %s", bl1_1.ActualAmount, store.Money(480_50))
 	}
-	if bl1_1.Difference != (500.00 - 480.50) {
-		t.Errorf("Groceries Difference = %f; want %f", bl1_1.Difference, (500.00 - 480.50))
+	if bl1_1.Difference != 500_00-480_50 {
+		t.Errorf("Groceries Difference = %s; want %s", bl1_1.Difference, store.Money(500_00-480_50))
 	}
 
 	var cat2Summary app.CategorySummary
@@ -158,13 +159,13 @@ func TestGetDashboardData_Success(t *testing.T) {
 		t.Errorf("cat2Summary.CategoryName = %s; want Housing", cat2Summary.CategoryName)
 	}
 
-	expectedCat2TotalExpected := 1500.00
+	expectedCat2TotalExpected := store.Money(1500_00)
 	if cat2Summary.TotalExpected != expectedCat2TotalExpected {
-		t.Errorf("cat2Summary.TotalExpected = %f; want %f", cat2Summary.TotalExpected, expectedCat2TotalExpected)
+		t.Errorf("cat2Summary.TotalExpected = %s; want %s", cat2Summary.TotalExpected, expectedCat2TotalExpected)
 	}
-	expectedCat2TotalActual := 1500.00
+	expectedCat2TotalActual := store.Money(1500_00)
 	if cat2Summary.TotalActual != expectedCat2TotalActual {
-		t.Errorf("cat2Summary.TotalActual = %f; want %f", cat2Summary.TotalActual, expectedCat2TotalActual)
+		t.Errorf("cat2Summary.TotalActual = %s; want %s", cat2Summary.TotalActual, expectedCat2TotalActual)
 	}
 	if len(cat2Summary.BudgetLines) != 1 {
 		t.Errorf("len(cat2Summary.BudgetLines) = %d; want %d", len(cat2Summary.BudgetLines), 1)
@@ -209,7 +210,7 @@ func TestGetDashboardData_MonthIDRequired(t *testing.T) {
 
 func TestGetDashboardData_MonthNotFound(t *testing.T) {
 	mockStore := &store.ReusableMockStore{
-		MockGetMonthByID: func(id int) (*store.Month, error) {
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
 			return nil, sql.ErrNoRows
 		},
 	}
@@ -228,9 +229,9 @@ func TestGetDashboardData_MonthNotFound(t *testing.T) {
 	}
 }
 
-func TestGetDashboardData_ErrorGetMonthByID_Other(t *testing.T) {
+func TestGetDashboardData_ErrorGetBoardData_Other(t *testing.T) {
 	mockStore := &store.ReusableMockStore{
-		MockGetMonthByID: func(id int) (*store.Month, error) {
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
 			return nil, errors.New("some other database error")
 		},
 	}
@@ -242,7 +243,7 @@ func TestGetDashboardData_ErrorGetMonthByID_Other(t *testing.T) {
 	if status := rr.Code; status != http.StatusInternalServerError {
 		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
 	}
-	expectedErrorMsg := "Failed to fetch month details"
+	expectedErrorMsg := "Failed to fetch board data: some other database error"
 	if body := rr.Body.String(); !strings.Contains(body, expectedErrorMsg) {
 		t.Errorf("handler returned unexpected body: got %s want to contain %s", body, expectedErrorMsg)
 	}
@@ -250,10 +251,7 @@ func TestGetDashboardData_ErrorGetMonthByID_Other(t *testing.T) {
 
 func TestGetDashboardData_ErrorGetBoardData(t *testing.T) {
 	mockStore := &store.ReusableMockStore{
-		MockGetMonthByID: func(id int) (*store.Month, error) {
-			return &store.Month{ID: 1, Year: 2023, Month: 12}, nil
-		},
-		MockGetBoardData: func(monthID int) ([]store.BudgetLine, error) {
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
 			return nil, errors.New("failed to fetch board data")
 		},
 	}
@@ -274,13 +272,10 @@ func TestGetDashboardData_ErrorGetBoardData(t *testing.T) {
 
 func TestGetDashboardData_ErrorGetAllCategories(t *testing.T) {
 	mockStore := &store.ReusableMockStore{
-		MockGetMonthByID: func(id int) (*store.Month, error) {
-			return &store.Month{ID: 1, Year: 2023, Month: 12}, nil
-		},
-		MockGetBoardData: func(monthID int) ([]store.BudgetLine, error) {
-			return []store.BudgetLine{}, nil
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
+			return &store.BoardDataPayload{MonthID: 1, Year: 2023, MonthName: "December"}, nil
 		},
-		MockGetAllCategories: func() ([]store.Category, error) {
+		MockGetAllCategories: func(ctx context.Context) ([]store.Category, error) {
 			return nil, errors.New("failed to fetch categories")
 		},
 	}
@@ -299,13 +294,6 @@ func TestGetDashboardData_ErrorGetAllCategories(t *testing.T) {
 	}
 }
 
-func floatEquals(a, b, tolerance float64) bool {
-	if (a-b) < tolerance && (b-a) < tolerance {
-		return true
-	}
-	return false
-}
-
 func TestMain(m *testing.M) {
 	m.Run()
 }
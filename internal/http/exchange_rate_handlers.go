@@ -0,0 +1,143 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
+)
+
+const maxExchangeRatesCSVBytes = 4 << 20
+
+func ListExchangeRatesHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		rates, err := s.GetExchangeRates(r.Context())
+		if err != nil {
+			log.Printf("Error fetching exchange rates: %v", err)
+			http.Error(w, "Failed to fetch exchange rates", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rates); err != nil {
+			log.Printf("Error encoding exchange rates: %v", err)
+		}
+	}
+}
+
+// SetExchangeRateHandler sets the rate of a currency from a month on, replacing
+// the one already set for that month. The body is {"currency", "year", "month", "rate"}.
+func SetExchangeRateHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var rate store.ExchangeRate
+		if err := json.NewDecoder(r.Body).Decode(&rate); err != nil {
+			http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		rate.Currency = store.Currency(strings.ToUpper(strings.TrimSpace(string(rate.Currency))))
+		if err := rate.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.SetExchangeRates(r.Context(), []store.ExchangeRate{rate}); err != nil {
+			log.Printf("Error saving exchange rate: %v", err)
+			http.Error(w, "Failed to save exchange rate", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ImportExchangeRatesHandler sets every rate of an uploaded CSV file (multipart
+// "file"); see app.ParseExchangeRatesCSV for the format.
+func ImportExchangeRatesHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxExchangeRatesCSVBytes)
+		if err := r.ParseMultipartForm(maxExchangeRatesCSVBytes); err != nil {
+			http.Error(w, "Invalid upload: expected multipart form data with a CSV file", http.StatusBadRequest)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing CSV file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, "Failed to read uploaded file", http.StatusBadRequest)
+			return
+		}
+
+		rates, err := app.ParseExchangeRatesCSV(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, rate := range rates {
+			if err := rate.Validate(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := s.SetExchangeRates(r.Context(), rates); err != nil {
+			log.Printf("Error importing exchange rates: %v", err)
+			http.Error(w, "Failed to save exchange rates", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]int{"imported": len(rates)}); err != nil {
+			log.Printf("Error encoding exchange rate import response: %v", err)
+		}
+	}
+}
+
+// DeleteExchangeRateHandler handles DELETE /api/v1/exchange-rates/{id}.
+func DeleteExchangeRateHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		pathParts := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/")
+		id, err := strconv.ParseInt(pathParts[len(pathParts)-1], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid exchange rate ID in path", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.DeleteExchangeRate(r.Context(), id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Exchange rate not found", http.StatusNotFound)
+			} else {
+				log.Printf("Error deleting exchange rate %d: %v", id, err)
+				http.Error(w, "Failed to delete exchange rate", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetExchangeRateHandler(t *testing.T) {
+	var saved []store.ExchangeRate
+	mockStore := &store.ReusableMockStore{
+		MockSetExchangeRates: func(ctx context.Context, rates []store.ExchangeRate) error {
+			saved = rates
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"Valid", `{"currency": "eur", "year": 2024, "month": 3, "rate": 1.08}`, http.StatusNoContent},
+		{"Budget currency", `{"currency": "USD", "year": 2024, "month": 3, "rate": 1}`, http.StatusBadRequest},
+		{"Zero rate", `{"currency": "EUR", "year": 2024, "month": 3, "rate": 0}`, http.StatusBadRequest},
+		{"Invalid month", `{"currency": "EUR", "year": 2024, "month": 13, "rate": 1.08}`, http.StatusBadRequest},
+		{"Invalid JSON", `{`, http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			saved = nil
+			rr := httptest.NewRecorder()
+			SetExchangeRateHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/exchange-rates", strings.NewReader(tc.body)))
+			assert.Equal(t, tc.wantCode, rr.Code, rr.Body.String())
+			if tc.wantCode == http.StatusNoContent {
+				assert.Equal(t, []store.ExchangeRate{{Currency: "EUR", Year: 2024, Month: 3, Rate: 1.08}}, saved)
+			} else {
+				assert.Nil(t, saved)
+			}
+		})
+	}
+}
+
+func TestImportExchangeRatesHandler(t *testing.T) {
+	var saved []store.ExchangeRate
+	mockStore := &store.ReusableMockStore{
+		MockSetExchangeRates: func(ctx context.Context, rates []store.ExchangeRate) error {
+			saved = rates
+			return nil
+		},
+	}
+
+	req := legacyUploadRequest(t, "/api/v1/exchange-rates/import", "rates.csv", "month,currency,rate\n2024-01,EUR,1.09\n2024-03,gbp,1.27\n", "")
+	rr := httptest.NewRecorder()
+	ImportExchangeRatesHandler(mockStore).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"imported": 2}`, rr.Body.String())
+	assert.Len(t, saved, 2)
+
+	saved = nil
+	req = legacyUploadRequest(t, "/api/v1/exchange-rates/import", "rates.csv", "month,currency,rate\n2024-01,EUR,1.09\n2024-02,EUR,-1\n", "")
+	rr = httptest.NewRecorder()
+	ImportExchangeRatesHandler(mockStore).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "line 3")
+	assert.Nil(t, saved, "nothing is saved from a file with a bad row")
+}
+
+func TestDeleteExchangeRateHandler(t *testing.T) {
+	mockStore := &store.ReusableMockStore{
+		MockDeleteExchangeRate: func(ctx context.Context, id int64) error {
+			if id != 4 {
+				return sql.ErrNoRows
+			}
+			return nil
+		},
+	}
+	for path, wantCode := range map[string]int{
+		"/api/v1/exchange-rates/4": http.StatusNoContent,
+		"/api/v1/exchange-rates/5": http.StatusNotFound,
+		"/api/v1/exchange-rates/x": http.StatusBadRequest,
+	} {
+		rr := httptest.NewRecorder()
+		DeleteExchangeRateHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, path, nil))
+		assert.Equal(t, wantCode, rr.Code, path)
+	}
+}
@@ -1,15 +1,22 @@
 package http
 
 import (
-	"encoding/json"
+	"compress/gzip"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"time"
 
-	"gandalf-budget/internal/store" // Assuming store is needed, though placeholder won't use it extensively
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
 )
 
-// ExportJSONHandler is a placeholder for the actual JSON export functionality.
+// passphraseHeader carries the backup passphrase; it is kept out of the URL so it never lands in logs or history.
+const passphraseHeader = "X-Backup-Passphrase"
+
+// ExportJSONHandler streams a full-database JSON backup, gzip-compressed when ?gzip=1
+// and encrypted with the X-Backup-Passphrase header when ?encrypt=1.
 func ExportJSONHandler(s store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -17,17 +24,79 @@ func ExportJSONHandler(s store.Store) http.HandlerFunc {
 			return
 		}
 
-		// Set headers for file download
-		filename := fmt.Sprintf("gandalf_backup_%s.json", time.Now().Format("20060102"))
+		now := time.Now()
+		compress := r.URL.Query().Get("gzip") == "1"
+		encrypt := r.URL.Query().Get("encrypt") == "1"
+		passphrase := r.Header.Get(passphraseHeader)
+		if encrypt && passphrase == "" {
+			http.Error(w, "Encrypted export requires the "+passphraseHeader+" header", http.StatusBadRequest)
+			return
+		}
+
+		filename := fmt.Sprintf("gandalf_backup_%s.json", now.Format("20060102"))
+		contentType := "application/json"
+		if compress {
+			filename += ".gz"
+			contentType = "application/gzip"
+		}
+		if encrypt {
+			filename += ".enc"
+			contentType = "application/octet-stream"
+		}
 		w.Header().Set("Content-Disposition", "attachment; filename="+filename)
-		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Type", contentType)
 
-		// Placeholder response
-		response := map[string]string{"message": "Export functionality is pending implementation. This is a placeholder file."}
-		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			// Log error, but headers are already sent, so can't change status code easily
-			fmt.Printf("Error encoding placeholder JSON response: %v\n", err)
+		counter := &countingWriter{w: w}
+		var out io.Writer = counter
+		// Closed innermost first, so each layer flushes into the next.
+		var closers []io.Closer
+		if encrypt {
+			enc, err := app.NewEncryptWriter(out, passphrase)
+			if err != nil {
+				log.Printf("Error starting encrypted JSON export: %v", err)
+				http.Error(w, "Failed to start encrypted export", http.StatusInternalServerError)
+				return
+			}
+			closers = append([]io.Closer{enc}, closers...)
+			out = enc
+		}
+		if compress {
+			gz := gzip.NewWriter(out)
+			closers = append([]io.Closer{gz}, closers...)
+			out = gz
+		}
+
+		// Headers are already sent once streaming starts, so failures can only be logged;
+		// the truncated file will not parse and is rejected on restore.
+		if err := app.WriteJSONBackup(r.Context(), out, s, now); err != nil {
+			log.Printf("Error streaming JSON export: %v", err)
+			return
+		}
+		for _, c := range closers {
+			if err := c.Close(); err != nil {
+				log.Printf("Error finishing JSON export: %v", err)
+				return
+			}
+		}
+
+		if err := s.RecordBackupRun(r.Context(), &store.BackupRun{
+			Kind:      store.BackupKindJSONExport,
+			Location:  filename,
+			SizeBytes: counter.n,
+			CreatedAt: now,
+		}); err != nil {
+			log.Printf("Error recording JSON export as last backup: %v", err)
 		}
 	}
 }
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
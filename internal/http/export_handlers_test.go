@@ -0,0 +1,124 @@
+package http
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func exportMockStore() *store.ReusableMockStore {
+	return &store.ReusableMockStore{
+		MockExportAll: func(ctx context.Context, sink store.ExportSink) error {
+			tables := map[string][]interface{}{
+				"months":       {&store.Month{ID: 1, Year: 2024, Month: 5}},
+				"categories":   {&store.Category{ID: 1, Name: "Food", Color: "bg-red-500"}, &store.Category{ID: 2, Name: "Rent", Color: "bg-blue-500"}},
+				"budget_lines": {},
+			}
+			for _, name := range []string{"months", "categories", "budget_lines"} {
+				if err := sink.BeginTable(name); err != nil {
+					return err
+				}
+				for _, row := range tables[name] {
+					if err := sink.WriteRow(row); err != nil {
+						return err
+					}
+				}
+				if err := sink.EndTable(); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type exportedBackup struct {
+	SchemaVersion int                `json:"schema_version"`
+	ExportedAt    string             `json:"exported_at"`
+	Currency      string             `json:"currency"`
+	Months        []store.Month      `json:"months"`
+	Categories    []store.Category   `json:"categories"`
+	BudgetLines   []store.BudgetLine `json:"budget_lines"`
+}
+
+func TestExportJSONHandler_Plain(t *testing.T) {
+	handler := ExportJSONHandler(exportMockStore())
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/export/json", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), ".json")
+
+	var backup exportedBackup
+	if err := json.Unmarshal(rr.Body.Bytes(), &backup); err != nil {
+		t.Fatalf("Export is not valid JSON: %v. Body: %s", err, rr.Body.String())
+	}
+	assert.Equal(t, 1, backup.SchemaVersion)
+	assert.NotEmpty(t, backup.ExportedAt)
+	assert.Equal(t, "USD", backup.Currency)
+	assert.Len(t, backup.Months, 1)
+	assert.Len(t, backup.Categories, 2)
+	assert.NotNil(t, backup.BudgetLines, "empty tables should be exported as empty arrays")
+	assert.Empty(t, backup.BudgetLines)
+}
+
+func TestExportJSONHandler_Gzip(t *testing.T) {
+	handler := ExportJSONHandler(exportMockStore())
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/export/json?gzip=1", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/gzip", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), ".json.gz")
+
+	gz, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("Export is not gzip-compressed: %v", err)
+	}
+	var backup exportedBackup
+	if err := json.NewDecoder(gz).Decode(&backup); err != nil {
+		t.Fatalf("Decompressed export is not valid JSON: %v", err)
+	}
+	assert.Len(t, backup.Categories, 2)
+}
+
+func TestExportJSONHandler_Encrypted(t *testing.T) {
+	handler := ExportJSONHandler(exportMockStore())
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/export/json?gzip=1&encrypt=1", nil)
+	req.Header.Set("X-Backup-Passphrase", "s3cret")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), ".json.gz.enc")
+
+	backup, err := app.ReadJSONBackup(rr.Body, "s3cret")
+	if err != nil {
+		t.Fatalf("Encrypted export does not restore: %v", err)
+	}
+	assert.Len(t, backup.Categories, 2)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/export/json?encrypt=1", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "encrypting without a passphrase must be rejected")
+}
+
+func TestExportJSONHandler_MethodNotAllowed(t *testing.T) {
+	handler := ExportJSONHandler(exportMockStore())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/export/json", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
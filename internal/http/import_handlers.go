@@ -0,0 +1,67 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
+)
+
+const maxImportBodyBytes = 64 << 20
+
+// ImportJSONHandler restores a JSON backup (plain, gzip or encrypted) sent as the request body.
+// ?mode=replace|merge selects how it is loaded and ?dry_run=1 only reports the changes.
+// Encrypted backups need their passphrase in the X-Backup-Passphrase header.
+func ImportJSONHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		mode := store.ImportMode(r.URL.Query().Get("mode"))
+		if mode == "" {
+			mode = store.ImportModeMerge
+		}
+		if mode != store.ImportModeReplace && mode != store.ImportModeMerge {
+			http.Error(w, "Invalid mode: must be 'replace' or 'merge'", http.StatusBadRequest)
+			return
+		}
+		dryRun := r.URL.Query().Get("dry_run") == "1"
+
+		defer r.Body.Close()
+		backup, err := app.ReadJSONBackup(http.MaxBytesReader(w, r.Body, maxImportBodyBytes), r.Header.Get(passphraseHeader))
+		if err != nil {
+			if errors.Is(err, app.ErrPassphraseRequired) || errors.Is(err, app.ErrWrongPassphrase) {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if errors.Is(err, app.ErrBackupTooLarge) {
+				http.Error(w, app.ErrBackupTooLarge.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			log.Printf("Rejected JSON import: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := s.ImportAll(r.Context(), &backup.DatabaseDump, mode, dryRun)
+		if err != nil {
+			if errors.Is(err, store.ErrDatabaseNotEmpty) {
+				http.Error(w, "Database already holds budget data; use mode=replace to overwrite it", http.StatusConflict)
+				return
+			}
+			log.Printf("Error importing JSON backup (mode %s, dry run %v): %v", mode, dryRun, err)
+			http.Error(w, "Failed to import backup: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Printf("Error encoding import report: %v", err)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+const validBackupJSON = `{
+  "schema_version": 1,
+  "exported_at": "2024-03-01T10:00:00Z",
+  "currency": "USD",
+  "months": [{"id": 1, "year": 2024, "month": 2, "finalized": true}],
+  "categories": [{"id": 1, "name": "Food", "color": "bg-red-500"}],
+  "budget_lines": [{"id": 1, "month_id": 1, "category_id": 1, "label": "Groceries", "expected": 100}],
+  "actual_lines": [{"id": 1, "budget_line_id": 1, "actual": 90}],
+  "annual_snaps": [{"id": 1, "month_id": 1, "snap_json": "{}", "created_at": "2024-03-01T09:00:00Z"}]
+}`
+
+func TestImportJSONHandler_Success(t *testing.T) {
+	var gotDump *store.DatabaseDump
+	var gotMode store.ImportMode
+	var gotDryRun bool
+	mockStore := &store.ReusableMockStore{
+		MockImportAll: func(ctx context.Context, dump *store.DatabaseDump, mode store.ImportMode, dryRun bool) (*store.ImportReport, error) {
+			gotDump, gotMode, gotDryRun = dump, mode, dryRun
+			return &store.ImportReport{Mode: mode, DryRun: dryRun, Inserted: store.ImportTableCounts{Months: 1}}, nil
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import/json?mode=replace&dry_run=1", bytes.NewBufferString(validBackupJSON))
+	rr := httptest.NewRecorder()
+	ImportJSONHandler(mockStore).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, store.ImportModeReplace, gotMode)
+	assert.True(t, gotDryRun)
+	if assert.NotNil(t, gotDump) {
+		assert.Len(t, gotDump.BudgetLines, 1)
+		assert.Equal(t, "2024-03-01 09:00:00", gotDump.AnnualSnaps[0].CreatedAt)
+	}
+
+	var report store.ImportReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Inserted.Months)
+}
+
+func TestImportJSONHandler_GzipBodyDefaultsToMerge(t *testing.T) {
+	var gotMode store.ImportMode
+	mockStore := &store.ReusableMockStore{
+		MockImportAll: func(ctx context.Context, dump *store.DatabaseDump, mode store.ImportMode, dryRun bool) (*store.ImportReport, error) {
+			gotMode = mode
+			return &store.ImportReport{Mode: mode}, nil
+		},
+	}
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	gz.Write([]byte(validBackupJSON))
+	gz.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import/json", &body)
+	rr := httptest.NewRecorder()
+	ImportJSONHandler(mockStore).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, store.ImportModeMerge, gotMode)
+}
+
+func TestImportJSONHandler_Rejections(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		body         string
+		importErr    error
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Unsupported schema version",
+			body:         `{"schema_version": 99, "months": []}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "unsupported schema_version 99",
+		},
+		{
+			name:         "Dangling budget line reference",
+			body:         `{"schema_version": 1, "months": [], "categories": [], "budget_lines": [{"id": 1, "month_id": 7, "category_id": 1, "label": "x", "expected": 1}]}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "budget line 1 references missing month 7",
+		},
+		{
+			name:         "Dangling match rule reference",
+			body:         `{"schema_version": 1, "months": [], "categories": [], "match_rules": [{"id": 1, "pattern": "market", "category_id": 4, "label": "x"}]}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "match rule 1 references missing category 4",
+		},
+		{
+			name:         "Backup kept in another currency",
+			body:         `{"schema_version": 1, "currency": "CLP", "months": []}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "backup amounts are in CLP but this budget is kept in USD",
+		},
+		{
+			name:         "Malformed JSON",
+			body:         `{"schema_version": 1,`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "failed to decode backup JSON",
+		},
+		{
+			name:         "Invalid mode",
+			query:        "?mode=append",
+			body:         validBackupJSON,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid mode",
+		},
+		{
+			name:         "Merge into non-empty database",
+			body:         validBackupJSON,
+			importErr:    store.ErrDatabaseNotEmpty,
+			expectedCode: http.StatusConflict,
+			expectedBody: "mode=replace",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStore := &store.ReusableMockStore{
+				MockImportAll: func(ctx context.Context, dump *store.DatabaseDump, mode store.ImportMode, dryRun bool) (*store.ImportReport, error) {
+					return nil, tc.importErr
+				},
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/import/json"+tc.query, bytes.NewBufferString(tc.body))
+			rr := httptest.NewRecorder()
+			ImportJSONHandler(mockStore).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code)
+			assert.Contains(t, rr.Body.String(), tc.expectedBody)
+		})
+	}
+}
+
+func TestImportJSONHandler_EncryptedBody(t *testing.T) {
+	mockStore := &store.ReusableMockStore{
+		MockImportAll: func(ctx context.Context, dump *store.DatabaseDump, mode store.ImportMode, dryRun bool) (*store.ImportReport, error) {
+			return &store.ImportReport{Mode: mode, Inserted: store.ImportTableCounts{Months: len(dump.Months)}}, nil
+		},
+	}
+
+	var body bytes.Buffer
+	enc, err := app.NewEncryptWriter(&body, "s3cret")
+	assert.NoError(t, err)
+	enc.Write([]byte(validBackupJSON))
+	assert.NoError(t, enc.Close())
+
+	tests := []struct {
+		name         string
+		passphrase   string
+		expectedCode int
+		expectedBody string
+	}{
+		{"Correct passphrase", "s3cret", http.StatusOK, `"months":1`},
+		{"Missing passphrase", "", http.StatusUnauthorized, "passphrase is required"},
+		{"Wrong passphrase", "guess", http.StatusUnauthorized, "wrong passphrase"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/import/json", bytes.NewReader(body.Bytes()))
+			if tc.passphrase != "" {
+				req.Header.Set("X-Backup-Passphrase", tc.passphrase)
+			}
+			rr := httptest.NewRecorder()
+			ImportJSONHandler(mockStore).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code, rr.Body.String())
+			assert.Contains(t, rr.Body.String(), tc.expectedBody)
+		})
+	}
+
+	tampered := append([]byte(nil), body.Bytes()...)
+	tampered[len(tampered)-1] ^= 0x01
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import/json", bytes.NewReader(tampered))
+	req.Header.Set("X-Backup-Passphrase", "s3cret")
+	rr := httptest.NewRecorder()
+	ImportJSONHandler(mockStore).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "modified or is incomplete")
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
+)
+
+// ExportLedgerHandler serves a year as a plain-text accounting journal
+// (?year=&format=hledger|ledger|beancount). The account names can be tuned with
+// ?prefix=, ?funding=, ?commodity= and ?accounts=Food=Expenses:Groceries,...
+func ExportLedgerHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		year, ok := parseYearParam(w, r)
+		if !ok {
+			return
+		}
+
+		q := r.URL.Query()
+		format := app.LedgerFormat(q.Get("format"))
+		if format == "" {
+			format = app.LedgerFormatHledger
+		}
+		opts := app.DefaultLedgerOptions(format)
+		if v := q.Get("prefix"); v != "" {
+			opts.ExpensePrefix = v
+		}
+		if v := q.Get("funding"); v != "" {
+			opts.FundingAccount = v
+		}
+		if v := q.Get("commodity"); v != "" {
+			opts.Commodity = v
+		}
+		accounts, err := app.ParseLedgerAccounts(q.Get("accounts"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Accounts = accounts
+		if err := opts.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := app.WriteLedger(r.Context(), &buf, s, year, opts); err != nil {
+			log.Printf("Error exporting %s journal for %d: %v", format, year, err)
+			http.Error(w, "Failed to generate journal", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=gandalf_budget_%d.%s", year, format.FileExtension()))
+		if _, err := buf.WriteTo(w); err != nil {
+			log.Printf("Error writing %s journal: %v", format, err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportLedgerHandler(t *testing.T) {
+	mockStore := &store.ReusableMockStore{
+		MockGetMonthsByYear: func(ctx context.Context, year int) ([]store.Month, error) {
+			return []store.Month{{ID: 1, Year: year, Month: 5}}, nil
+		},
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
+			return &store.BoardDataPayload{BudgetLines: []store.BudgetLineWithActual{
+				{CategoryName: "Food", Label: "Groceries", ExpectedAmount: 100_00, ActualAmount: 40_00},
+			}}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	ExportLedgerHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodGet,
+		"/api/v1/export/ledger?year=2024&format=ledger&funding=Liabilities:Visa&commodity=EUR&accounts=food=Expenses:Groceries", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "gandalf_budget_2024.ledger")
+	assert.Contains(t, rr.Body.String(), "~ monthly from 2024-05-01 to 2024-06-01")
+	assert.Contains(t, rr.Body.String(), "Expenses:Groceries                        100.00 EUR")
+	assert.Contains(t, rr.Body.String(), "    Liabilities:Visa\n")
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"Unknown format", "?year=2024&format=gnucash"},
+		{"Bad account mapping", "?year=2024&accounts=Food"},
+		{"Missing year", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			ExportLedgerHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/export/ledger"+tc.query, nil))
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+	}
+}
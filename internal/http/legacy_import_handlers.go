@@ -0,0 +1,85 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
+)
+
+const maxLegacyImportBytes = 32 << 20
+
+// ImportLegacyHandler imports an old budget spreadsheet sent as multipart form
+// data: the CSV or XLSX in "file" and the column mapping as JSON in "mapping".
+// ?preview=1 reports the rows that would be created and rejected without saving,
+// and ?finalize_past=1 finalizes imported months before the current one.
+func ImportLegacyHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxLegacyImportBytes)
+		if err := r.ParseMultipartForm(maxLegacyImportBytes); err != nil {
+			http.Error(w, "Invalid upload: expected multipart form data with a file and a mapping", http.StatusBadRequest)
+			return
+		}
+
+		var mapping app.LegacyColumnMapping
+		if err := json.Unmarshal([]byte(r.FormValue("mapping")), &mapping); err != nil {
+			http.Error(w, "Invalid mapping JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing spreadsheet file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, "Failed to read uploaded file", http.StatusBadRequest)
+			return
+		}
+
+		format := app.LegacyFileFormat(strings.ToLower(r.URL.Query().Get("format")))
+		if format == "" {
+			format = app.LegacyFileFormat(strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), "."))
+		}
+
+		rows, rejected, err := app.ParseLegacySpreadsheet(data, format, mapping)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		opts := store.LegacyImportOptions{Preview: r.URL.Query().Get("preview") == "1"}
+		if r.URL.Query().Get("finalize_past") == "1" {
+			now := time.Now()
+			opts.FinalizeBeforeYear, opts.FinalizeBeforeMonth = now.Year(), int(now.Month())
+		}
+
+		report, err := s.ImportLegacyRows(r.Context(), rows, opts)
+		if err != nil {
+			log.Printf("Error importing legacy spreadsheet %s (preview %v): %v", header.Filename, opts.Preview, err)
+			http.Error(w, "Failed to import spreadsheet: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		report.Rejected = append(rejected, report.Rejected...)
+		sort.SliceStable(report.Rejected, func(i, j int) bool { return report.Rejected[i].Line < report.Rejected[j].Line })
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Printf("Error encoding legacy import report: %v", err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func legacyUploadRequest(t *testing.T, url, filename, content, mapping string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if mapping != "" {
+		mw.WriteField("mapping", mapping)
+	}
+	if filename != "" {
+		fw, err := mw.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatalf("Failed to create form file: %v", err)
+		}
+		fw.Write([]byte(content))
+	}
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, url, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestImportLegacyHandler_Preview(t *testing.T) {
+	var gotRows []store.LegacyImportRow
+	var gotOpts store.LegacyImportOptions
+	mockStore := &store.ReusableMockStore{
+		MockImportLegacyRows: func(ctx context.Context, rows []store.LegacyImportRow, opts store.LegacyImportOptions) (*store.LegacyImportReport, error) {
+			gotRows, gotOpts = rows, opts
+			return &store.LegacyImportReport{
+				Preview:  opts.Preview,
+				Imported: rows,
+				Rejected: []store.LegacyImportRejection{{Line: 2, Reason: "duplicate line"}},
+			}, nil
+		},
+	}
+
+	csvData := "Month,Category,Label,Expected,Actual\n2022-01,Food,Groceries,100,90\n2022-01,Food,Groceries,100,90\nnope,Food,Rent,1,1\n"
+	mapping := `{"month": "Month", "category": "Category", "label": "Label", "expected": "Expected", "actual": "Actual"}`
+	req := legacyUploadRequest(t, "/api/v1/import/legacy?preview=1&finalize_past=1", "history.CSV", csvData, mapping)
+	rr := httptest.NewRecorder()
+	ImportLegacyHandler(mockStore).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Len(t, gotRows, 2)
+	assert.True(t, gotOpts.Preview)
+	assert.NotZero(t, gotOpts.FinalizeBeforeYear)
+
+	var report store.LegacyImportReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.True(t, report.Preview)
+	if assert.Len(t, report.Rejected, 2) {
+		assert.Equal(t, 2, report.Rejected[0].Line)
+		assert.Equal(t, 4, report.Rejected[1].Line)
+		assert.Contains(t, report.Rejected[1].Reason, "unrecognised month")
+	}
+}
+
+func TestImportLegacyHandler_Rejections(t *testing.T) {
+	mapping := `{"month": "Month", "category": "Category", "label": "Label", "expected": "Expected"}`
+	tests := []struct {
+		name         string
+		filename     string
+		content      string
+		mapping      string
+		expectedBody string
+	}{
+		{"Missing file", "", "", mapping, "Missing spreadsheet file"},
+		{"Invalid mapping", "a.csv", "Month\n", "{", "Invalid mapping JSON"},
+		{"Unknown format", "a.ods", "Month\n", mapping, "unsupported file format"},
+		{"Unmapped column", "a.csv", "Month,Category\n", mapping, `column "Label"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := legacyUploadRequest(t, "/api/v1/import/legacy", tc.filename, tc.content, tc.mapping)
+			rr := httptest.NewRecorder()
+			ImportLegacyHandler(&store.ReusableMockStore{}).ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), tc.expectedBody)
+		})
+	}
+}
@@ -1,15 +1,157 @@
 package http
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log" // For server-side logging
 	"net/http"
 	"strconv"
 	"strings" // For TrimPrefix
+	"time"
 
+	"gandalf-budget/internal/app"
 	"gandalf-budget/internal/store"
 )
 
+// ListMonthsHandler returns the months in calendar order, optionally filtered
+// by year and finalized. With period=YYYY-MM it returns that one month instead.
+func ListMonthsHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		if periodStr := query.Get("period"); periodStr != "" {
+			year, month, err := parsePeriod(periodStr)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			m, err := s.GetMonthByPeriod(r.Context(), year, month)
+			writeMonth(w, m, err, periodStr)
+			return
+		}
+
+		var filter store.MonthFilter
+		if yearStr := query.Get("year"); yearStr != "" {
+			year, err := strconv.Atoi(yearStr)
+			if err != nil {
+				http.Error(w, "Invalid 'year' query parameter: must be an integer", http.StatusBadRequest)
+				return
+			}
+			filter.Year = &year
+		}
+		if finalizedStr := query.Get("finalized"); finalizedStr != "" {
+			finalized, err := strconv.ParseBool(finalizedStr)
+			if err != nil {
+				http.Error(w, "Invalid 'finalized' query parameter: must be true or false", http.StatusBadRequest)
+				return
+			}
+			filter.Finalized = &finalized
+		}
+
+		months, err := s.GetMonths(r.Context(), filter)
+		if err != nil {
+			log.Printf("Error fetching months: %v", err)
+			http.Error(w, "Failed to fetch months", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(months); err != nil {
+			log.Printf("Error encoding months: %v", err)
+		}
+	}
+}
+
+// GetMonthHandler returns one month: GET /api/v1/months/:id.
+func GetMonthHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/months/"), "/")
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid Month ID format", http.StatusBadRequest)
+			return
+		}
+		m, err := s.GetMonthByID(r.Context(), id)
+		writeMonth(w, m, err, idStr)
+	}
+}
+
+// CreateMonthHandler adds an open month without budget lines. The body is
+// {"year": 2026, "month": 10}.
+func CreateMonthHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var m store.Month
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if m.Year < 1 || m.Month < 1 || m.Month > 12 {
+			http.Error(w, "Missing or invalid fields: year, month (1-12)", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.CreateMonth(r.Context(), &m); err != nil {
+			if errors.Is(err, store.ErrMonthExists) {
+				http.Error(w, fmt.Sprintf("Month %d-%02d already exists", m.Year, m.Month), http.StatusConflict)
+				return
+			}
+			log.Printf("Error creating month %d-%02d: %v", m.Year, m.Month, err)
+			http.Error(w, "Failed to create month", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(m); err != nil {
+			log.Printf("Error encoding created month: %v", err)
+		}
+	}
+}
+
+// writeMonth sends a month looked up by ref, or 404 when there is none.
+func writeMonth(w http.ResponseWriter, m *store.Month, err error, ref string) {
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, fmt.Sprintf("Month %s not found", ref), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching month %s: %v", ref, err)
+		http.Error(w, "Failed to fetch month", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		log.Printf("Error encoding month %s: %v", ref, err)
+	}
+}
+
+// parsePeriod reads a YYYY-MM period.
+func parsePeriod(value string) (year, month int, err error) {
+	t, err := time.Parse("2006-01", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid 'period' %q: expected YYYY-MM", value)
+	}
+	return t.Year(), int(t.Month()), nil
+}
+
+// FinalizeMonthHandler finalizes a month: PUT /api/v1/months/:id/finalize. A
+// client that may retry sends an Idempotency-Key header; a repeat with the same
+// key gets the first response back instead of a conflict.
 func FinalizeMonthHandler(s store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPut {
@@ -30,7 +172,40 @@ func FinalizeMonthHandler(s store.Store) http.HandlerFunc {
 			return
 		}
 
-		canFinalize, reason, err := s.CanFinalizeMonth(monthID)
+		ctx := r.Context()
+		if key := r.Header.Get("Idempotency-Key"); key != "" {
+			prior, err := s.GetFinalizeRequest(ctx, key)
+			switch {
+			case err == nil && prior.MonthID != int64(monthID):
+				http.Error(w, fmt.Sprintf("Idempotency-Key %q was already used to finalize month %d", key, prior.MonthID), http.StatusConflict)
+				return
+			case err == nil:
+				writeFinalized(w, prior.NextMonthID)
+				return
+			case !errors.Is(err, sql.ErrNoRows):
+				log.Printf("Error looking up finalize request %q: %v", key, err)
+				http.Error(w, "Failed to finalize month", http.StatusInternalServerError)
+				return
+			}
+			ctx = store.WithIdempotencyKey(ctx, key)
+		}
+
+		month, err := s.GetMonthByID(ctx, int64(monthID))
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, fmt.Sprintf("Month %d not found", monthID), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Error fetching month %d to finalize: %v", monthID, err)
+			http.Error(w, "Failed to finalize month", http.StatusInternalServerError)
+			return
+		}
+		if month.Finalized {
+			http.Error(w, "Month is already finalized", http.StatusConflict)
+			return
+		}
+
+		canFinalize, reason, err := s.CanFinalizeMonth(ctx, monthID)
 		if err != nil {
 			log.Printf("Error checking if month %d can be finalized: %v", monthID, err)
 			http.Error(w, "Failed to check finalization status", http.StatusInternalServerError)
@@ -41,31 +216,160 @@ func FinalizeMonthHandler(s store.Store) http.HandlerFunc {
 			return
 		}
 
-		boardData, err := s.GetBoardData(monthID)
-		if err != nil {
-			log.Printf("Error fetching board data for snapshot (month %d): %v", monthID, err)
-			http.Error(w, "Failed to generate snapshot data", http.StatusInternalServerError)
+		snapJSON, err := app.FinalizeSnapshot(ctx, s, monthID)
+		if errors.Is(err, store.ErrNoExchangeRate) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		snapJSONBytes, err := json.Marshal(boardData)
 		if err != nil {
-			log.Printf("Error marshalling snapshot data for month %d: %v", monthID, err)
-			http.Error(w, "Failed to prepare snapshot data", http.StatusInternalServerError)
+			log.Printf("Error building snapshot of month %d: %v", monthID, err)
+			http.Error(w, "Failed to generate snapshot data", http.StatusInternalServerError)
 			return
 		}
-		snapJSON := string(snapJSONBytes)
 
-		newMonthID, err := s.FinalizeMonth(monthID, snapJSON)
+		newMonthID, err := s.FinalizeMonth(ctx, monthID, snapJSON)
+		if errors.Is(err, store.ErrMonthFinalized) {
+			http.Error(w, "Month is already finalized", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, store.ErrIdempotencyKeyReused) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		if err != nil {
 			log.Printf("Error finalizing month %d: %v", monthID, err)
 			http.Error(w, "Failed to finalize month", http.StatusInternalServerError)
 			return
 		}
+		writeFinalized(w, newMonthID)
+	}
+}
+
+// FinalizePreviewHandler shows what finalizing a month would do, without doing
+// it: GET /api/v1/months/:id/finalize/preview. It returns the snapshot that
+// would be stored, the lines that would roll over into the next month and every
+// issue that blocks finalizing.
+func FinalizePreviewHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		monthID, err := monthIDFromPath(r.URL.Path)
+		if err != nil {
+			http.Error(w, "Invalid Month ID format", http.StatusBadRequest)
+			return
+		}
+		preview, err := app.PreviewFinalize(r.Context(), s, int(monthID))
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, fmt.Sprintf("Month %d not found", monthID), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Error previewing finalize of month %d: %v", monthID, err)
+			http.Error(w, "Failed to preview finalizing the month", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(preview); err != nil {
+			log.Printf("Error encoding finalize preview of month %d: %v", monthID, err)
+		}
+	}
+}
+
+// writeFinalized answers a finalize request with the month its budget rolled
+// over into.
+func writeFinalized(w http.ResponseWriter, newMonthID int64) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":      "Month finalized successfully",
+		"new_month_id": newMonthID,
+	})
+}
+
+// ReopenMonthHandler makes a finalized month editable again:
+// POST /api/v1/months/:id/reopen?confirm=1. The month's snapshot is kept as a
+// prior version and the next month is left alone; without confirm=1 nothing
+// changes.
+func ReopenMonthHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		monthID, err := monthIDFromPath(r.URL.Path)
+		if err != nil {
+			http.Error(w, "Invalid Month ID format", http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("confirm") != "1" {
+			http.Error(w, "Reopening a finalized month lets its budget change after the fact; repeat the request with confirm=1", http.StatusBadRequest)
+			return
+		}
 
+		if err := s.ReopenMonth(r.Context(), int(monthID)); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				http.Error(w, fmt.Sprintf("Month %d not found", monthID), http.StatusNotFound)
+			case errors.Is(err, store.ErrMonthNotFinalized):
+				http.Error(w, "Month is not finalized", http.StatusConflict)
+			default:
+				log.Printf("Error reopening month %d: %v", monthID, err)
+				http.Error(w, "Failed to reopen month", http.StatusInternalServerError)
+			}
+			return
+		}
+		m, err := s.GetMonthByID(r.Context(), monthID)
+		writeMonth(w, m, err, strconv.FormatInt(monthID, 10))
+	}
+}
+
+// ListMonthSnapshotsHandler returns every snapshot version of a month, newest
+// first: GET /api/v1/months/:id/snapshots.
+func ListMonthSnapshotsHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		monthID, err := monthIDFromPath(r.URL.Path)
+		if err != nil {
+			http.Error(w, "Invalid Month ID format", http.StatusBadRequest)
+			return
+		}
+		snaps, err := s.GetMonthSnapshots(r.Context(), monthID)
+		if err != nil {
+			log.Printf("Error fetching snapshots of month %d: %v", monthID, err)
+			http.Error(w, "Failed to fetch snapshots", http.StatusInternalServerError)
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"message":      "Month finalized successfully",
-			"new_month_id": newMonthID,
-		})
+		if err := json.NewEncoder(w).Encode(snaps); err != nil {
+			log.Printf("Error encoding snapshots of month %d: %v", monthID, err)
+		}
+	}
+}
+
+// finalizedMonthMessage answers a change refused because its month is finalized.
+const finalizedMonthMessage = "The month is finalized and read-only; reopen it to change it"
+
+// editContext is the context for a change to budget lines, actuals or
+// transactions. With override_finalized=1, on a server started with
+// -allow-finalized-override, the change may touch a finalized month; the store
+// records the override in the audit log.
+func editContext(r *http.Request) context.Context {
+	if allowed, _ := r.Context().Value(finalizedOverrideAllowedKey{}).(bool); allowed && r.URL.Query().Get("override_finalized") == "1" {
+		log.Printf("%s %s overrides the finalized month lock", r.Method, r.URL.Path)
+		return store.WithFinalizedOverride(r.Context())
 	}
+	return r.Context()
+}
+
+// monthIDFromPath reads the ID of /api/v1/months/:id/...
+func monthIDFromPath(path string) (int64, error) {
+	pathParts := strings.Split(strings.TrimPrefix(path, "/api/v1/months/"), "/")
+	return strconv.ParseInt(pathParts[0], 10, 64)
 }
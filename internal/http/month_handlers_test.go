@@ -1,7 +1,7 @@
 package http
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,24 +11,32 @@ import (
 	"strings"
 	"testing"
 
+	"gandalf-budget/internal/app"
 	"gandalf-budget/internal/store" // For store types
 )
 
 func TestFinalizeMonthHandler(t *testing.T) {
-	mockStore := &MockStore{}
+	mockStore := &store.ReusableMockStore{}
 
-	sampleBoardData := []store.BudgetLine{
-		{ID: 1, MonthID: 1, CategoryID: 1, Label: "Item 1", Expected: 100, ActualAmount: ptrToFloat64(90)},
+	sampleBoardData := &store.BoardDataPayload{
+		MonthID: 1, Year: 2024, MonthName: "March",
+		BudgetLines: []store.BudgetLineWithActual{
+			{ID: 1, MonthID: 1, CategoryID: 1, Label: "Item 1", ExpectedAmount: 100_00, ActualAmount: 90_00},
+		},
 	}
-	expectedSnapJSONBytes, _ := json.Marshal(sampleBoardData)
+	expectedBoard, _ := app.ConvertBoard(sampleBoardData, nil)
+	expectedSnapJSONBytes, _ := json.Marshal(expectedBoard)
 	expectedSnapJSON := string(expectedSnapJSONBytes)
+	openMonth := func(ctx context.Context, id int64) (*store.Month, error) {
+		return &store.Month{ID: id, Year: 2024, Month: 3}, nil
+	}
 
 	tests := []struct {
 		name               string
 		monthIDParam       string
 		pathSuffix         string
 		httpMethod         string
-		setupMock          func(ms *MockStore)
+		setupMock          func(ms *store.ReusableMockStore)
 		expectedStatusCode int
 		expectedBody       interface{}
 	}{
@@ -37,20 +45,20 @@ func TestFinalizeMonthHandler(t *testing.T) {
 			monthIDParam: "1",
 			pathSuffix:   "/finalize",
 			httpMethod:   http.MethodPut,
-			setupMock: func(ms *MockStore) {
-				ms.CanFinalizeMonthFunc = func(monthID int) (bool, string, error) {
+			setupMock: func(ms *store.ReusableMockStore) {
+				ms.MockCanFinalizeMonth = func(ctx context.Context, monthID int) (bool, string, error) {
 					if monthID != 1 {
 						return false, "mock error: unexpected monthID for CanFinalizeMonth", fmt.Errorf("unexpected monthID: %d", monthID)
 					}
 					return true, "", nil
 				}
-				ms.GetBoardDataFunc = func(monthID int) ([]store.BudgetLine, error) {
+				ms.MockGetBoardData = func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
 					if monthID != 1 {
 						return nil, fmt.Errorf("mock error: unexpected monthID for GetBoardData: %d", monthID)
 					}
 					return sampleBoardData, nil
 				}
-				ms.FinalizeMonthFunc = func(monthID int, snapJSON string) (int64, error) {
+				ms.MockFinalizeMonth = func(ctx context.Context, monthID int, snapJSON string) (int64, error) {
 					if monthID != 1 {
 						return 0, fmt.Errorf("mock error: unexpected monthID for FinalizeMonth: %d", monthID)
 					}
@@ -68,100 +76,100 @@ func TestFinalizeMonthHandler(t *testing.T) {
 			monthIDParam: "2",
 			pathSuffix:   "/finalize",
 			httpMethod:   http.MethodPut,
-			setupMock: func(ms *MockStore) {
-				ms.CanFinalizeMonthFunc = func(monthID int) (bool, string, error) {
+			setupMock: func(ms *store.ReusableMockStore) {
+				ms.MockCanFinalizeMonth = func(ctx context.Context, monthID int) (bool, string, error) {
 					return false, "Actuals not set for all lines", nil
 				}
-				ms.GetBoardDataFunc = nil
-				ms.FinalizeMonthFunc = nil
+				ms.MockGetBoardData = nil
+				ms.MockFinalizeMonth = nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedBody:       map[string]string{"error": "Actuals not set for all lines"},
+			expectedBody:       "Actuals not set for all lines",
 		},
 		{
 			name:         "Error in CanFinalizeMonth",
 			monthIDParam: "3",
 			pathSuffix:   "/finalize",
 			httpMethod:   http.MethodPut,
-			setupMock: func(ms *MockStore) {
-				ms.CanFinalizeMonthFunc = func(monthID int) (bool, string, error) {
+			setupMock: func(ms *store.ReusableMockStore) {
+				ms.MockCanFinalizeMonth = func(ctx context.Context, monthID int) (bool, string, error) {
 					return false, "", errors.New("DB error checking finalization")
 				}
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBody:       map[string]string{"error": "Failed to check finalization status"},
+			expectedBody:       "Failed to check finalization status",
 		},
 		{
 			name:         "Error in GetBoardData for snapshot",
 			monthIDParam: "4",
 			pathSuffix:   "/finalize",
 			httpMethod:   http.MethodPut,
-			setupMock: func(ms *MockStore) {
-				ms.CanFinalizeMonthFunc = func(monthID int) (bool, string, error) { return true, "", nil }
-				ms.GetBoardDataFunc = func(monthID int) ([]store.BudgetLine, error) {
+			setupMock: func(ms *store.ReusableMockStore) {
+				ms.MockCanFinalizeMonth = func(ctx context.Context, monthID int) (bool, string, error) { return true, "", nil }
+				ms.MockGetBoardData = func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
 					return nil, errors.New("DB error fetching board data")
 				}
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBody:       map[string]string{"error": "Failed to generate snapshot data"},
+			expectedBody:       "Failed to generate snapshot data",
 		},
 		{
 			name:         "Error in FinalizeMonth store method",
 			monthIDParam: "5",
 			pathSuffix:   "/finalize",
 			httpMethod:   http.MethodPut,
-			setupMock: func(ms *MockStore) {
-				ms.CanFinalizeMonthFunc = func(monthID int) (bool, string, error) { return true, "", nil }
-				ms.GetBoardDataFunc = func(monthID int) ([]store.BudgetLine, error) { return sampleBoardData, nil }
-				ms.FinalizeMonthFunc = func(monthID int, snapJSON string) (int64, error) {
+			setupMock: func(ms *store.ReusableMockStore) {
+				ms.MockCanFinalizeMonth = func(ctx context.Context, monthID int) (bool, string, error) { return true, "", nil }
+				ms.MockGetBoardData = func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) { return sampleBoardData, nil }
+				ms.MockFinalizeMonth = func(ctx context.Context, monthID int, snapJSON string) (int64, error) {
 					return 0, errors.New("DB error during finalization transaction")
 				}
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedBody:       map[string]string{"error": "Failed to finalize month"},
+			expectedBody:       "Failed to finalize month",
 		},
 		{
 			name:               "Invalid monthId in path (non-integer)",
 			monthIDParam:       "abc",
 			pathSuffix:         "/finalize",
 			httpMethod:         http.MethodPut,
-			setupMock:          func(ms *MockStore) {},
+			setupMock:          func(ms *store.ReusableMockStore) {},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedBody:       map[string]string{"error": "Invalid Month ID format"},
+			expectedBody:       "Invalid Month ID format",
 		},
 		{
 			name:               "Malformed path (missing /finalize)",
 			monthIDParam:       "1",
 			pathSuffix:         "/somethingelse",
 			httpMethod:         http.MethodPut,
-			setupMock:          func(ms *MockStore) {},
+			setupMock:          func(ms *store.ReusableMockStore) {},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedBody:       map[string]string{"error": "Invalid path structure for finalize endpoint"},
+			expectedBody:       "Invalid path structure for finalize endpoint",
 		},
 		{
 			name:               "Malformed path (too short)",
-			monthIDParam:       "",
-			pathSuffix:         "/finalize",
+			monthIDParam:       "1",
+			pathSuffix:         "",
 			httpMethod:         http.MethodPut,
-			setupMock:          func(ms *MockStore) {},
+			setupMock:          func(ms *store.ReusableMockStore) {},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedBody:       map[string]string{"error": "Invalid path structure for finalize endpoint"},
+			expectedBody:       "Invalid path structure for finalize endpoint",
 		},
-        {
+		{
 			name:               "Malformed path (no monthID)",
 			monthIDParam:       "",
 			pathSuffix:         "/finalize",
 			httpMethod:         http.MethodPut,
-			setupMock:          func(ms *MockStore) {},
+			setupMock:          func(ms *store.ReusableMockStore) {},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedBody:       map[string]string{"error": "Invalid path structure for finalize endpoint"},
+			expectedBody:       "Invalid Month ID format",
 		},
 		{
-			name:         "Wrong HTTP method (GET)",
-			monthIDParam: "1",
-			pathSuffix:   "/finalize",
-			httpMethod:   http.MethodGet,
-			setupMock:    func(ms *MockStore) {},
+			name:               "Wrong HTTP method (GET)",
+			monthIDParam:       "1",
+			pathSuffix:         "/finalize",
+			httpMethod:         http.MethodGet,
+			setupMock:          func(ms *store.ReusableMockStore) {},
 			expectedStatusCode: http.StatusMethodNotAllowed,
 			expectedBody:       "Method not allowed",
 		},
@@ -169,9 +177,10 @@ func TestFinalizeMonthHandler(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			mockStore.CanFinalizeMonthFunc = nil
-			mockStore.GetBoardDataFunc = nil
-			mockStore.FinalizeMonthFunc = nil
+			mockStore.MockGetMonthByID = openMonth
+			mockStore.MockCanFinalizeMonth = nil
+			mockStore.MockGetBoardData = nil
+			mockStore.MockFinalizeMonth = nil
 			tc.setupMock(mockStore)
 
 			path := fmt.Sprintf("/api/v1/months/%s%s", tc.monthIDParam, tc.pathSuffix)
@@ -187,7 +196,7 @@ func TestFinalizeMonthHandler(t *testing.T) {
 			if rr.Code != tc.expectedStatusCode {
 				t.Errorf("Expected status code %d, got %d. Body: %s", tc.expectedStatusCode, rr.Code, rr.Body.String())
 			}
-			
+
 			if contentType := rr.Header().Get("Content-Type"); strings.Contains(contentType, "application/json") {
 				var actualBodyMap map[string]interface{}
 				if err := json.Unmarshal(rr.Body.Bytes(), &actualBodyMap); err != nil {
@@ -197,7 +206,7 @@ func TestFinalizeMonthHandler(t *testing.T) {
 				expectedBodyBytes, _ := json.Marshal(tc.expectedBody)
 				var expectedBodyMap map[string]interface{}
 				_ = json.Unmarshal(expectedBodyBytes, &expectedBodyMap)
-				
+
 				if !reflect.DeepEqual(actualBodyMap, expectedBodyMap) {
 					t.Errorf("Expected JSON body %+v, got %+v", expectedBodyMap, actualBodyMap)
 				}
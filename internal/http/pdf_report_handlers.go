@@ -0,0 +1,62 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
+)
+
+// GetReportPDFHandler serves a printable report: the month-end summary for
+// ?month_id= or the annual summary of finalized months for ?year=.
+func GetReportPDFHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var buf bytes.Buffer
+		var filename string
+		if r.URL.Query().Get("month_id") != "" {
+			monthID, ok := parseMonthIDParam(w, r)
+			if !ok {
+				return
+			}
+			payload, ok := loadDashboardPayload(w, r, s, monthID)
+			if !ok {
+				return
+			}
+			if err := app.WriteMonthPDF(&buf, payload); err != nil {
+				log.Printf("Error rendering PDF for month %d: %v", monthID, err)
+				http.Error(w, "Failed to generate PDF", http.StatusInternalServerError)
+				return
+			}
+			filename = fmt.Sprintf("report_%d-%02d.pdf", payload.Year, monthNumber(payload.Month))
+		} else {
+			if r.URL.Query().Get("year") == "" {
+				http.Error(w, "month_id or year query parameter is required", http.StatusBadRequest)
+				return
+			}
+			year, ok := parseYearParam(w, r)
+			if !ok {
+				return
+			}
+			if err := app.WriteYearPDF(r.Context(), &buf, s, year); err != nil {
+				log.Printf("Error rendering annual PDF for %d: %v", year, err)
+				http.Error(w, "Failed to generate PDF", http.StatusInternalServerError)
+				return
+			}
+			filename = fmt.Sprintf("report_%d.pdf", year)
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+		if _, err := buf.WriteTo(w); err != nil {
+			log.Printf("Error writing PDF %s: %v", filename, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetReportPDFHandler(t *testing.T) {
+	mockStore := &store.ReusableMockStore{
+		MockGetBoardData: func(ctx context.Context, monthID int) (*store.BoardDataPayload, error) {
+			if monthID != 7 {
+				return nil, sql.ErrNoRows
+			}
+			return &store.BoardDataPayload{MonthID: 7, Year: 2024, MonthName: "May", BudgetLines: []store.BudgetLineWithActual{
+				{CategoryID: 1, CategoryName: "Food", Label: "Groceries", ExpectedAmount: 100_00, ActualAmount: 120_00},
+			}}, nil
+		},
+		MockGetAllCategories: func(ctx context.Context) ([]store.Category, error) {
+			return []store.Category{{ID: 1, Name: "Food"}}, nil
+		},
+		MockGetAnnualSnapshotsMetadataByYear: func(ctx context.Context, year int) ([]store.AnnualSnapMeta, error) { return nil, nil },
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		wantCode     int
+		wantFilename string
+	}{
+		{"Month", "?month_id=7", http.StatusOK, "report_2024-05.pdf"},
+		{"Year", "?year=2024", http.StatusOK, "report_2024.pdf"},
+		{"Unknown month", "?month_id=8", http.StatusNotFound, ""},
+		{"Invalid month", "?month_id=x", http.StatusBadRequest, ""},
+		{"No parameters", "", http.StatusBadRequest, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			GetReportPDFHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/reports/pdf"+tc.query, nil))
+
+			assert.Equal(t, tc.wantCode, rr.Code, rr.Body.String())
+			if tc.wantCode == http.StatusOK {
+				assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+				assert.Contains(t, rr.Header().Get("Content-Disposition"), tc.wantFilename)
+				assert.Equal(t, "%PDF-", rr.Body.String()[:5])
+			}
+		})
+	}
+}
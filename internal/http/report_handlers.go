@@ -32,7 +32,7 @@ func GetAnnualReport(s store.Store) http.HandlerFunc {
 			return
 		}
 
-		snapshotsMeta, err := s.GetAnnualSnapshotsMetadataByYear(year)
+		snapshotsMeta, err := s.GetAnnualSnapshotsMetadataByYear(r.Context(), year)
 		if err != nil {
 			http.Error(w, "Failed to retrieve annual report data: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -65,7 +65,7 @@ func GetSnapshotDetail(s store.Store) http.HandlerFunc {
 			return
 		}
 
-		snapJSON, err := s.GetAnnualSnapshotJSONByID(snapID)
+		snapJSON, err := s.GetAnnualSnapshotJSONByID(r.Context(), snapID)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				http.Error(w, "Snapshot not found", http.StatusNotFound)
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -22,7 +23,7 @@ func TestGetAnnualReport_Success(t *testing.T) {
 	}
 
 	mockStore := &store.ReusableMockStore{
-		MockGetAnnualSnapshotsMetadataByYear: func(year int) ([]store.AnnualSnapMeta, error) {
+		MockGetAnnualSnapshotsMetadataByYear: func(ctx context.Context, year int) ([]store.AnnualSnapMeta, error) {
 			if year == 2023 {
 				return expectedSnapshots, nil
 			}
@@ -45,7 +46,7 @@ func TestGetAnnualReport_Success(t *testing.T) {
 
 func TestGetAnnualReport_NoData(t *testing.T) {
 	mockStore := &store.ReusableMockStore{
-		MockGetAnnualSnapshotsMetadataByYear: func(year int) ([]store.AnnualSnapMeta, error) {
+		MockGetAnnualSnapshotsMetadataByYear: func(ctx context.Context, year int) ([]store.AnnualSnapMeta, error) {
 			if year == 2024 {
 				return []store.AnnualSnapMeta{}, nil
 			}
@@ -90,7 +91,7 @@ func TestGetAnnualReport_InvalidYearParameter(t *testing.T) {
 
 func TestGetAnnualReport_StoreError(t *testing.T) {
 	mockStore := &store.ReusableMockStore{
-		MockGetAnnualSnapshotsMetadataByYear: func(year int) ([]store.AnnualSnapMeta, error) {
+		MockGetAnnualSnapshotsMetadataByYear: func(ctx context.Context, year int) ([]store.AnnualSnapMeta, error) {
 			return nil, errors.New("database connection failed")
 		},
 	}
@@ -108,7 +109,7 @@ func TestGetAnnualReport_StoreError(t *testing.T) {
 func TestGetSnapshotDetail_Success(t *testing.T) {
 	expectedJSON := `{"month_id":1,"year":2023,"month":"January","total_expected":1000,"total_actual":950,"categories":[]}`
 	mockStore := &store.ReusableMockStore{
-		MockGetAnnualSnapshotJSONByID: func(snapID int64) (string, error) {
+		MockGetAnnualSnapshotJSONByID: func(ctx context.Context, snapID int64) (string, error) {
 			if snapID == 42 {
 				return expectedJSON, nil
 			}
@@ -128,7 +129,7 @@ func TestGetSnapshotDetail_Success(t *testing.T) {
 
 func TestGetSnapshotDetail_NotFound(t *testing.T) {
 	mockStore := &store.ReusableMockStore{
-		MockGetAnnualSnapshotJSONByID: func(snapID int64) (string, error) {
+		MockGetAnnualSnapshotJSONByID: func(ctx context.Context, snapID int64) (string, error) {
 			if snapID == 404 {
 				return "", sql.ErrNoRows
 			}
@@ -165,7 +166,7 @@ func TestGetSnapshotDetail_InvalidID(t *testing.T) {
 
 func TestGetSnapshotDetail_StoreError(t *testing.T) {
 	mockStore := &store.ReusableMockStore{
-		MockGetAnnualSnapshotJSONByID: func(snapID int64) (string, error) {
+		MockGetAnnualSnapshotJSONByID: func(ctx context.Context, snapID int64) (string, error) {
 			return "", errors.New("internal database error")
 		},
 	}
@@ -182,7 +183,7 @@ func TestGetSnapshotDetail_StoreError(t *testing.T) {
 
 func TestGetSnapshotDetail_NotFound_WithSqlErrNoRows(t *testing.T) {
 	mockStore := &store.ReusableMockStore{
-		MockGetAnnualSnapshotJSONByID: func(snapID int64) (string, error) {
+		MockGetAnnualSnapshotJSONByID: func(ctx context.Context, snapID int64) (string, error) {
 			if snapID == 404 {
 				return "", sql.ErrNoRows
 			}
@@ -1,36 +1,125 @@
 package http
 
 import (
+	"context"
 	"io"
 	"io/fs"
 	"log"
 	"net/http"
 	"path"
 	"strings" // Required for path manipulation
+	"time"
 
-	"github.com/jmoiron/sqlx"
-
+	"gandalf-budget/internal/app"
 	"gandalf-budget/internal/store"
 )
 
-func NewRouter(staticFS fs.FS, db *sqlx.DB) *http.ServeMux {
+// Options tunes the API routes. A zero timeout leaves requests unbounded.
+type Options struct {
+	// QueryTimeout bounds the database work of an ordinary API request.
+	QueryTimeout time.Duration
+	// ExportTimeout bounds exports, imports, PDF reports and backups, which
+	// read or write whole tables.
+	ExportTimeout time.Duration
+	// AllowFinalizedOverride lets override_finalized=1 change finalized
+	// months. Without it such requests are refused with 403.
+	AllowFinalizedOverride bool
+}
+
+var DefaultOptions = Options{
+	QueryTimeout:  10 * time.Second,
+	ExportTimeout: 2 * time.Minute,
+}
+
+// NewRouter serves the API from appStore. backups may be nil when the data is
+// not kept on disk, as in demo mode; the backups endpoint then reports 404.
+func NewRouter(staticFS fs.FS, appStore store.Store, backups *app.BackupScheduler, opts Options) *http.ServeMux {
 	mux := http.NewServeMux()
-	appStore := store.NewSQLStore(db)
 
-	mux.HandleFunc("/api/v1/health", func(w http.ResponseWriter, r *http.Request) {
+	// Store calls use the request's context, so a client disconnect or an
+	// expired timeout cancels the query in flight.
+	handle := func(pattern string, h http.HandlerFunc) {
+		timeout := opts.QueryTimeout
+		if isLongRunningAPIPath(pattern) {
+			timeout = opts.ExportTimeout
+		}
+		mux.HandleFunc(pattern, withTimeout(timeout, withFinalizedOverride(opts.AllowFinalizedOverride, h)))
+	}
+
+	handle("/api/v1/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		io.WriteString(w, `{"status": "ok"}`)
 		log.Println("HIT: /api/v1/health")
 	})
 
-	mux.HandleFunc("/api/v1/dashboard", GetDashboardData(appStore))
-	mux.HandleFunc("/api/v1/export/json", ExportJSONHandler(appStore)) // New route
-	mux.HandleFunc("/api/v1/reports/annual", GetAnnualReport(appStore))
+	handle("/api/v1/dashboard", GetDashboardData(appStore))
+	handle("/api/v1/export/json", ExportJSONHandler(appStore)) // New route
+	handle("/api/v1/import/json", ImportJSONHandler(appStore))
+	handle("/api/v1/import/legacy", ImportLegacyHandler(appStore))
+	handle("/api/v1/export/csv/board", ExportBoardCSVHandler(appStore))
+	handle("/api/v1/export/csv/dashboard", ExportDashboardCSVHandler(appStore))
+	handle("/api/v1/export/csv/year", ExportYearCSVHandler(appStore))
+	handle("/api/v1/export/xlsx", ExportXLSXHandler(appStore))
+	handle("/api/v1/export/ledger", ExportLedgerHandler(appStore))
+
+	handle("/api/v1/import/bank", ImportBankStatementHandler(appStore))
+	handle("/api/v1/bank-transactions", ListBankTransactionsHandler(appStore))
+	handle("/api/v1/bank-transactions/confirm", ConfirmBankTransactionsHandler(appStore))
+	handle("/api/v1/bank-transactions/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/ignore") {
+			IgnoreBankTransactionHandler(appStore)(w, r)
+		} else {
+			http.NotFound(w, r)
+		}
+	})
+	handle("/api/v1/match-rules", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ListMatchRulesHandler(appStore)(w, r)
+		case http.MethodPost:
+			CreateMatchRuleHandler(appStore)(w, r)
+		default:
+			http.Error(w, "Method not allowed for /api/v1/match-rules", http.StatusMethodNotAllowed)
+		}
+	})
+	handle("/api/v1/match-rules/", DeleteMatchRuleHandler(appStore))
+	handle("/api/v1/exchange-rates", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ListExchangeRatesHandler(appStore)(w, r)
+		case http.MethodPost:
+			SetExchangeRateHandler(appStore)(w, r)
+		default:
+			http.Error(w, "Method not allowed for /api/v1/exchange-rates", http.StatusMethodNotAllowed)
+		}
+	})
+	handle("/api/v1/exchange-rates/import", ImportExchangeRatesHandler(appStore))
+	handle("/api/v1/exchange-rates/", DeleteExchangeRateHandler(appStore))
+
+	handle("/api/v1/backups", func(w http.ResponseWriter, r *http.Request) {
+		if backups == nil {
+			http.Error(w, "On-disk backups are not available", http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			ListBackupsHandler(appStore, backups)(w, r)
+		case http.MethodPost:
+			TriggerBackupHandler(backups)(w, r)
+		default:
+			http.Error(w, "Method not allowed for /api/v1/backups", http.StatusMethodNotAllowed)
+		}
+	})
+	handle("/api/v1/audit", ListAuditEntriesHandler(appStore))
+	handle("/api/v1/trash", GetTrashHandler(appStore))
+	handle("/api/v1/trash/", RestoreFromTrashHandler(appStore))
+	handle("/api/v1/reports/annual", GetAnnualReport(appStore))
+	handle("/api/v1/reports/pdf", GetReportPDFHandler(appStore))
 
-	mux.HandleFunc("/api/v1/reports/snapshots/", GetSnapshotDetail(appStore))
+	handle("/api/v1/reports/snapshots/", GetSnapshotDetail(appStore))
 
-	mux.HandleFunc("/api/v1/categories", func(w http.ResponseWriter, r *http.Request) {
+	handle("/api/v1/categories", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			HandleGetCategories(appStore)(w, r)
@@ -41,7 +130,7 @@ func NewRouter(staticFS fs.FS, db *sqlx.DB) *http.ServeMux {
 		}
 	})
 
-	mux.HandleFunc("/api/v1/categories/", func(w http.ResponseWriter, r *http.Request) {
+	handle("/api/v1/categories/", func(w http.ResponseWriter, r *http.Request) {
 		idStr := strings.TrimPrefix(r.URL.Path, "/api/v1/categories/")
 		idStr = strings.TrimSuffix(idStr, "/")
 
@@ -60,7 +149,7 @@ func NewRouter(staticFS fs.FS, db *sqlx.DB) *http.ServeMux {
 		}
 	})
 
-	mux.HandleFunc("/api/v1/budget-lines", func(w http.ResponseWriter, r *http.Request) {
+	handle("/api/v1/budget-lines", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
 			CreateBudgetLineHandler(appStore)(w, r)
@@ -71,7 +160,18 @@ func NewRouter(staticFS fs.FS, db *sqlx.DB) *http.ServeMux {
 		}
 	})
 
-	mux.HandleFunc("/api/v1/budget-lines/", func(w http.ResponseWriter, r *http.Request) {
+	handle("/api/v1/budget-lines/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/transactions") {
+			switch r.Method {
+			case http.MethodGet:
+				ListTransactionsHandler(appStore)(w, r)
+			case http.MethodPost:
+				CreateTransactionHandler(appStore)(w, r)
+			default:
+				http.Error(w, "Method not allowed for /api/v1/budget-lines/:id/transactions", http.StatusMethodNotAllowed)
+			}
+			return
+		}
 		switch r.Method {
 		case http.MethodPut:
 			UpdateBudgetLineHandler(appStore)(w, r)
@@ -82,7 +182,18 @@ func NewRouter(staticFS fs.FS, db *sqlx.DB) *http.ServeMux {
 		}
 	})
 
-	mux.HandleFunc("/api/v1/actual-lines/", func(w http.ResponseWriter, r *http.Request) {
+	handle("/api/v1/transactions/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			UpdateTransactionHandler(appStore)(w, r)
+		case http.MethodDelete:
+			DeleteTransactionHandler(appStore)(w, r)
+		default:
+			http.Error(w, "Method not allowed for /api/v1/transactions/:id", http.StatusMethodNotAllowed)
+		}
+	})
+
+	handle("/api/v1/actual-lines/", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPut:
 			UpdateActualLineHandler(appStore)(w, r)
@@ -91,16 +202,34 @@ func NewRouter(staticFS fs.FS, db *sqlx.DB) *http.ServeMux {
 		}
 	})
 
-	mux.HandleFunc("/api/v1/months/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPut {
-			pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/months/"), "/")
-			if len(pathParts) >= 2 && pathParts[1] == "finalize" {
-				FinalizeMonthHandler(appStore)(w, r)
-			} else {
-				http.NotFound(w, r)
-			}
-		} else {
+	handle("/api/v1/months", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			ListMonthsHandler(appStore)(w, r)
+		case http.MethodPost:
+			CreateMonthHandler(appStore)(w, r)
+		default:
+			http.Error(w, "Method not allowed for /api/v1/months collection", http.StatusMethodNotAllowed)
+		}
+	})
+
+	handle("/api/v1/months/", func(w http.ResponseWriter, r *http.Request) {
+		pathParts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/months/"), "/"), "/")
+		switch {
+		case len(pathParts) == 1 && r.Method == http.MethodGet:
+			GetMonthHandler(appStore)(w, r)
+		case len(pathParts) == 2 && pathParts[1] == "finalize" && r.Method == http.MethodPut:
+			FinalizeMonthHandler(appStore)(w, r)
+		case len(pathParts) == 2 && pathParts[1] == "reopen":
+			ReopenMonthHandler(appStore)(w, r)
+		case len(pathParts) == 2 && pathParts[1] == "snapshots":
+			ListMonthSnapshotsHandler(appStore)(w, r)
+		case len(pathParts) == 3 && pathParts[1] == "finalize" && pathParts[2] == "preview":
+			FinalizePreviewHandler(appStore)(w, r)
+		case len(pathParts) == 1, len(pathParts) == 2 && pathParts[1] == "finalize":
 			http.Error(w, "Method not allowed for /api/v1/months/", http.StatusMethodNotAllowed)
+		default:
+			http.NotFound(w, r)
 		}
 	})
 
@@ -151,22 +280,75 @@ func serveIndexHTML(w http.ResponseWriter, r *http.Request, staticFS fs.FS) {
 	http.ServeContent(w, r, "index.html", fi.ModTime(), rs)
 }
 
+func isLongRunningAPIPath(path string) bool {
+	for _, prefix := range []string{"/api/v1/export/", "/api/v1/import/", "/api/v1/exchange-rates/import", "/api/v1/reports/pdf", "/api/v1/backups"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// withTimeout runs h with a request context that expires after d.
+func withTimeout(d time.Duration, h http.HandlerFunc) http.HandlerFunc {
+	if d <= 0 {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		h(w, r.WithContext(ctx))
+	}
+}
+
+type finalizedOverrideAllowedKey struct{}
+
+// withFinalizedOverride refuses override_finalized=1 unless the server allows
+// it, and otherwise marks the request so editContext honours it.
+func withFinalizedOverride(allowed bool, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("override_finalized") == "1" {
+			if !allowed {
+				http.Error(w, "Changing finalized months is disabled on this server", http.StatusForbidden)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), finalizedOverrideAllowedKey{}, true))
+		}
+		h(w, r)
+	}
+}
+
 func isKnownAPIPath(path string) bool {
 	knownAPIPrefixes := []string{
 		"/api/v1/health",
 		"/api/v1/dashboard",
 		"/api/v1/reports/annual",
+		"/api/v1/reports/pdf",
 		"/api/v1/reports/snapshots/",
 		"/api/v1/categories",
 		"/api/v1/budget-lines",
 		"/api/v1/actual-lines/",
-		"/api/v1/months/",
+		"/api/v1/transactions/",
+		"/api/v1/months",
 		"/api/v1/export/json", // Add this line
+		"/api/v1/import/json",
+		"/api/v1/import/legacy",
+		"/api/v1/import/bank",
+		"/api/v1/bank-transactions",
+		"/api/v1/match-rules",
+		"/api/v1/exchange-rates",
+		"/api/v1/export/csv/",
+		"/api/v1/export/xlsx",
+		"/api/v1/export/ledger",
+		"/api/v1/backups",
+		"/api/v1/audit",
+		"/api/v1/trash",
 	}
 	for _, prefix := range knownAPIPrefixes {
 		if strings.HasPrefix(path, prefix) {
 			if (prefix == "/api/v1/categories" && path != "/api/v1/categories" && !strings.HasPrefix(path, "/api/v1/categories/")) ||
-				(prefix == "/api/v1/budget-lines" && path != "/api/v1/budget-lines" && !strings.HasPrefix(path, "/api/v1/budget-lines/")) {
+				(prefix == "/api/v1/budget-lines" && path != "/api/v1/budget-lines" && !strings.HasPrefix(path, "/api/v1/budget-lines/")) ||
+				(prefix == "/api/v1/months" && path != "/api/v1/months" && !strings.HasPrefix(path, "/api/v1/months/")) {
 				continue
 			}
 			return true
@@ -0,0 +1,370 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTimeout(t *testing.T) {
+	var deadline time.Time
+	var hasDeadline bool
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		deadline, hasDeadline = r.Context().Deadline()
+	}
+
+	withTimeout(time.Minute, handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/dashboard", nil))
+	assert.True(t, hasDeadline)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+
+	withTimeout(0, handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/dashboard", nil))
+	assert.False(t, hasDeadline, "a zero timeout leaves the request unbounded")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var err error
+	withTimeout(time.Minute, func(w http.ResponseWriter, r *http.Request) {
+		err = r.Context().Err()
+	}).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/dashboard", nil).WithContext(ctx))
+	assert.ErrorIs(t, err, context.Canceled, "the client's cancellation still reaches the handler")
+}
+
+func TestIsLongRunningAPIPath(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/api/v1/export/json":           true,
+		"/api/v1/import/bank":           true,
+		"/api/v1/exchange-rates/import": true,
+		"/api/v1/backups":               true,
+		"/api/v1/dashboard":             false,
+		"/api/v1/exchange-rates/3":      false,
+	} {
+		assert.Equal(t, want, isLongRunningAPIPath(path), path)
+	}
+}
+
+func TestRouter_DemoStore(t *testing.T) {
+	s := store.NewMemoryStore()
+	require.NoError(t, app.SeedDemoData(context.Background(), s, time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC)))
+	router := NewRouter(fstest.MapFS{"index.html": {Data: []byte("<html></html>")}}, s, nil, DefaultOptions)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	rr := serve(http.MethodGet, "/api/v1/budget-lines?month_id=2", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var lines []store.BudgetLine
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &lines))
+	require.NotEmpty(t, lines)
+	var rent store.BudgetLine
+	for _, bl := range lines {
+		if bl.Label == "Rent" {
+			rent = bl
+		}
+	}
+	require.NotZero(t, rent.ID)
+
+	rr = serve(http.MethodPost, fmt.Sprintf("/api/v1/budget-lines/%d/transactions", rent.ID), `{"date": "2024-03-16", "amount": -200, "memo": "Partial refund"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	rr = serve(http.MethodPost, fmt.Sprintf("/api/v1/budget-lines/%d/transactions", rent.ID), `{"date": "2024-03-16", "amount": -2000}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "the actual cannot go below zero")
+
+	rr = serve(http.MethodGet, "/api/v1/dashboard?month_id=2", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"Housing"`)
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/backups", "").Code, "demo mode keeps no backups")
+	rr = serve(http.MethodPut, "/api/v1/months/2/finalize", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "still have zero actuals", "March has lines without payments yet")
+}
+
+func TestRouter_Months(t *testing.T) {
+	router := NewRouter(fstest.MapFS{"index.html": {Data: []byte("<html></html>")}}, store.NewMemoryStore(), nil, DefaultOptions)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	rr := serve(http.MethodPost, "/api/v1/months", `{"year": 2026, "month": 10}`)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"id":1,"year":2026,"month":10,"finalized":false}`, rr.Body.String())
+	require.Equal(t, http.StatusCreated, serve(http.MethodPost, "/api/v1/months", `{"year": 2025, "month": 12}`).Code)
+	rr = serve(http.MethodPost, "/api/v1/months", `{"year": 2026, "month": 10}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "2026-10 already exists")
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/v1/months", `{"year": 2026, "month": 13}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/api/v1/months", `not json`).Code)
+
+	rr = serve(http.MethodGet, "/api/v1/months", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `[{"id":2,"year":2025,"month":12,"finalized":false},{"id":1,"year":2026,"month":10,"finalized":false}]`, rr.Body.String())
+	rr = serve(http.MethodGet, "/api/v1/months?year=2026&finalized=false", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `[{"id":1,"year":2026,"month":10,"finalized":false}]`, rr.Body.String())
+	rr = serve(http.MethodGet, "/api/v1/months?finalized=true", "")
+	assert.JSONEq(t, `[]`, rr.Body.String())
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/api/v1/months?year=soon", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/api/v1/months?finalized=maybe", "").Code)
+
+	rr = serve(http.MethodGet, "/api/v1/months?period=2025-12", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"id":2,"year":2025,"month":12,"finalized":false}`, rr.Body.String())
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/months?period=2024-01", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/api/v1/months?period=2024-1x", "").Code)
+
+	rr = serve(http.MethodGet, "/api/v1/months/1", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"id":1,"year":2026,"month":10,"finalized":false}`, rr.Body.String())
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/months/9", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/api/v1/months/october", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodDelete, "/api/v1/months/1", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodDelete, "/api/v1/months", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/months/1/unknown", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/monthsxyz", "").Code)
+}
+
+func TestRouter_CreateCategoryNameInTrash(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	food := &store.Category{Name: "Food", Color: "#0f0"}
+	require.NoError(t, s.CreateCategory(ctx, food))
+	require.NoError(t, s.DeleteCategory(ctx, food.ID))
+
+	router := NewRouter(fstest.MapFS{"index.html": {Data: []byte("<html></html>")}}, s, nil, DefaultOptions)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	rr := serve(http.MethodPost, "/api/v1/categories", `{"name":"Food","color":"#00f"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "restore it from the trash")
+
+	_, err := s.PurgeTrash(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	rr = serve(http.MethodPost, "/api/v1/categories", `{"name":"Food","color":"#00f"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code, "a purged category frees its name")
+}
+
+func TestRouter_ReopenMonth(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	march := &store.Month{Year: 2024, Month: 3}
+	require.NoError(t, s.CreateMonth(ctx, march))
+	food := &store.Category{Name: "Food", Color: "#0f0"}
+	require.NoError(t, s.CreateCategory(ctx, food))
+	_, err := s.CreateBudgetLine(ctx, &store.BudgetLine{MonthID: int(march.ID), CategoryID: int(food.ID), Label: "Groceries", Expected: 400_00})
+	require.NoError(t, err)
+	lines, err := s.GetBudgetLinesByMonthID(ctx, int(march.ID))
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateActualLine(ctx, &store.ActualLine{ID: *lines[0].ActualID, Actual: 380_00}))
+
+	router := NewRouter(fstest.MapFS{"index.html": {Data: []byte("<html></html>")}}, s, nil, DefaultOptions)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, serve(http.MethodPut, "/api/v1/months/1/finalize").Code)
+	assert.Equal(t, http.StatusConflict, serve(http.MethodPut, "/api/v1/months/1/finalize").Code, "already finalized")
+
+	rr := serve(http.MethodPost, "/api/v1/months/1/reopen")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "confirm=1")
+	rr = serve(http.MethodPost, "/api/v1/months/1/reopen?confirm=1")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"id":1,"year":2024,"month":3,"finalized":false}`, rr.Body.String())
+	assert.Equal(t, http.StatusConflict, serve(http.MethodPost, "/api/v1/months/1/reopen?confirm=1").Code, "already open")
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/api/v1/months/9/reopen?confirm=1").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodGet, "/api/v1/months/1/reopen").Code)
+
+	rr = serve(http.MethodPut, "/api/v1/months/1/finalize")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"new_month_id":2`, "the month cloned the first time")
+
+	rr = serve(http.MethodGet, "/api/v1/months/1/snapshots")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var snaps []store.AnnualSnapMeta
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &snaps))
+	require.Len(t, snaps, 2)
+	assert.Equal(t, 2, snaps[0].Version)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/api/v1/months/x/snapshots").Code)
+}
+
+func TestRouter_FinalizedMonthIsReadOnly(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	march := &store.Month{Year: 2024, Month: 3}
+	require.NoError(t, s.CreateMonth(ctx, march))
+	food := &store.Category{Name: "Food", Color: "#0f0"}
+	require.NoError(t, s.CreateCategory(ctx, food))
+	lineID, err := s.CreateBudgetLine(ctx, &store.BudgetLine{MonthID: int(march.ID), CategoryID: int(food.ID), Label: "Groceries", Expected: 400_00})
+	require.NoError(t, err)
+	_, err = s.CreateTransaction(ctx, &store.Transaction{BudgetLineID: lineID, Date: time.Date(2024, time.March, 12, 0, 0, 0, 0, time.UTC), Amount: 45_25})
+	require.NoError(t, err)
+	_, err = s.AddBankTransactions(ctx, []store.BankTransaction{{ExternalID: "ofx:1:a", PostedOn: time.Date(2024, time.March, 14, 0, 0, 0, 0, time.UTC), Amount: 4_75, Description: "Kiosk"}})
+	require.NoError(t, err)
+	_, err = s.FinalizeMonth(ctx, int(march.ID), `{}`)
+	require.NoError(t, err)
+
+	router := NewRouter(fstest.MapFS{"index.html": {Data: []byte("<html></html>")}}, s, nil, DefaultOptions)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	tests := []struct {
+		name, method, path, body string
+	}{
+		{"Create budget line", http.MethodPost, "/api/v1/budget-lines", `{"month_id":1,"category_id":1,"label":"Snacks"}`},
+		{"Update budget line", http.MethodPut, "/api/v1/budget-lines/1", `{"label":"Food"}`},
+		{"Update actual", http.MethodPut, "/api/v1/actual-lines/1", `{"actual":10}`},
+		{"Create transaction", http.MethodPost, "/api/v1/budget-lines/1/transactions", `{"date":"2024-03-20","amount":5}`},
+		{"Delete transaction", http.MethodDelete, "/api/v1/transactions/1", ``},
+		{"Delete budget line", http.MethodDelete, "/api/v1/budget-lines/1", ``},
+		{"Confirm bank transaction", http.MethodPost, "/api/v1/bank-transactions/confirm", `{"confirmations":[{"transaction_id":1,"budget_line_id":1}]}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := serve(tc.method, tc.path, tc.body)
+			assert.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+			assert.Contains(t, rr.Body.String(), "month is finalized")
+		})
+	}
+	board, err := s.GetBoardData(ctx, int(march.ID))
+	require.NoError(t, err)
+	require.Len(t, board.BudgetLines, 1)
+	assert.Equal(t, store.Money(45_25), board.BudgetLines[0].ActualAmount)
+
+	rr := serve(http.MethodDelete, "/api/v1/budget-lines/1", ``)
+	assert.NotContains(t, rr.Body.String(), "override_finalized", "the refusal does not advertise the override")
+	rr = serve(http.MethodDelete, "/api/v1/budget-lines/1?override_finalized=1", ``)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "the override is off unless the server allows it")
+
+	opts := DefaultOptions
+	opts.AllowFinalizedOverride = true
+	router = NewRouter(fstest.MapFS{"index.html": {Data: []byte("<html></html>")}}, s, nil, opts)
+	rr = serve(http.MethodPost, "/api/v1/bank-transactions/confirm?override_finalized=1", `{"confirmations":[{"transaction_id":1,"budget_line_id":1}]}`)
+	require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	board, err = s.GetBoardData(ctx, int(march.ID))
+	require.NoError(t, err)
+	assert.Equal(t, store.Money(50_00), board.BudgetLines[0].ActualAmount, "the override lets a statement correct the month")
+
+	rr = serve(http.MethodDelete, "/api/v1/budget-lines/1?override_finalized=1", ``)
+	require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	entries, err := s.GetAuditEntries(ctx, store.AuditFilter{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, store.AuditActionDelete, entries[0].Action)
+	assert.Equal(t, store.AuditActionOverride, entries[1].Action, "the override is audited")
+}
+
+func TestRouter_FinalizeMonthIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	march := &store.Month{Year: 2024, Month: 3}
+	require.NoError(t, s.CreateMonth(ctx, march))
+	april := &store.Month{Year: 2024, Month: 4}
+	require.NoError(t, s.CreateMonth(ctx, april))
+	food := &store.Category{Name: "Food", Color: "#0f0"}
+	require.NoError(t, s.CreateCategory(ctx, food))
+	for _, monthID := range []int64{march.ID, april.ID} {
+		_, err := s.CreateBudgetLine(ctx, &store.BudgetLine{MonthID: int(monthID), CategoryID: int(food.ID), Label: "Groceries", Expected: 400_00})
+		require.NoError(t, err)
+	}
+	lines, err := s.GetBudgetLinesByMonthID(ctx, int(march.ID))
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateActualLine(ctx, &store.ActualLine{ID: *lines[0].ActualID, Actual: 380_00}))
+
+	router := NewRouter(fstest.MapFS{"index.html": {Data: []byte("<html></html>")}}, s, nil, DefaultOptions)
+	finalize := func(monthID int64, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/months/%d/finalize", monthID), nil)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := finalize(march.ID, "tab-1")
+	require.Equal(t, http.StatusOK, first.Code, first.Body.String())
+	assert.JSONEq(t, `{"message":"Month finalized successfully","new_month_id":2}`, first.Body.String(), "the existing April is reused")
+	repeat := finalize(march.ID, "tab-1")
+	require.Equal(t, http.StatusOK, repeat.Code, repeat.Body.String())
+	assert.Equal(t, first.Body.String(), repeat.Body.String())
+
+	assert.Equal(t, http.StatusConflict, finalize(march.ID, "").Code, "already finalized")
+	assert.Equal(t, http.StatusConflict, finalize(march.ID, "tab-2").Code)
+	rr := finalize(april.ID, "tab-1")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "already used")
+	assert.Equal(t, http.StatusNotFound, finalize(9, "").Code)
+
+	aprilLines, err := s.GetBudgetLinesByMonthID(ctx, int(april.ID))
+	require.NoError(t, err)
+	assert.Len(t, aprilLines, 1, "Groceries is not cloned twice")
+	snaps, err := s.GetMonthSnapshots(ctx, march.ID)
+	require.NoError(t, err)
+	assert.Len(t, snaps, 1)
+}
+
+func TestRouter_FinalizePreview(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	march := &store.Month{Year: 2024, Month: 3}
+	require.NoError(t, s.CreateMonth(ctx, march))
+	food := &store.Category{Name: "Food", Color: "#0f0"}
+	require.NoError(t, s.CreateCategory(ctx, food))
+	_, err := s.CreateBudgetLine(ctx, &store.BudgetLine{MonthID: int(march.ID), CategoryID: int(food.ID), Label: "Groceries", Expected: 400_00})
+	require.NoError(t, err)
+
+	router := NewRouter(fstest.MapFS{"index.html": {Data: []byte("<html></html>")}}, s, nil, DefaultOptions)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+
+	rr := serve(http.MethodGet, "/api/v1/months/1/finalize/preview")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var preview app.FinalizePreview
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &preview))
+	assert.False(t, preview.CanFinalize)
+	require.Len(t, preview.Issues, 1)
+	assert.Equal(t, app.FinalizeIssueZeroActual, preview.Issues[0].Code)
+	assert.Equal(t, int64(1), preview.Issues[0].BudgetLineID)
+	assert.JSONEq(t, `[{"id":0,"month_id":0,"category_id":1,"label":"Groceries","expected":400,"currency":"USD"}]`, mustJSON(t, preview.ClonedLines))
+	assert.Contains(t, string(preview.Snapshot), `"month_name":"March"`)
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/v1/months/9/finalize/preview").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/api/v1/months/x/finalize/preview").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodPost, "/api/v1/months/1/finalize/preview").Code)
+	months, err := s.GetMonths(ctx, store.MonthFilter{})
+	require.NoError(t, err)
+	assert.Len(t, months, 1)
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
@@ -0,0 +1,228 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gandalf-budget/internal/store"
+)
+
+// transactionRequest is the body of the transaction create and update
+// endpoints. Fields left out of an update keep their value.
+type transactionRequest struct {
+	Date   *string        `json:"date"`
+	Amount *store.Decimal `json:"amount"`
+	Payee  *string        `json:"payee"`
+	Memo   *string        `json:"memo"`
+}
+
+// apply copies the fields set in the request onto t, reading the amount in
+// t's currency.
+func (req transactionRequest) apply(t *store.Transaction) error {
+	if req.Date != nil {
+		date, err := parseTransactionDate(*req.Date)
+		if err != nil {
+			return err
+		}
+		t.Date = date
+	}
+	if req.Amount != nil {
+		amount, err := req.Amount.In(t.Currency)
+		if err != nil {
+			return fmt.Errorf("invalid 'amount': %w", err)
+		}
+		t.Amount = amount
+	}
+	if req.Payee != nil {
+		t.Payee = strings.TrimSpace(*req.Payee)
+	}
+	if req.Memo != nil {
+		t.Memo = strings.TrimSpace(*req.Memo)
+	}
+	return nil
+}
+
+// parseTransactionDate accepts a plain date (2024-03-15) or an RFC 3339 timestamp.
+func parseTransactionDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	ts, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid 'date' %q: expected YYYY-MM-DD", value)
+	}
+	return time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// budgetLineIDFromTransactionsPath extracts the ID from /api/v1/budget-lines/{id}/transactions.
+func budgetLineIDFromTransactionsPath(path string) (int64, error) {
+	pathParts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(pathParts) < 2 || pathParts[len(pathParts)-1] != "transactions" {
+		return 0, errors.New("invalid path")
+	}
+	return strconv.ParseInt(pathParts[len(pathParts)-2], 10, 64)
+}
+
+// ListTransactionsHandler handles GET /api/v1/budget-lines/{id}/transactions.
+func ListTransactionsHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		budgetLineID, err := budgetLineIDFromTransactionsPath(r.URL.Path)
+		if err != nil {
+			http.Error(w, "Invalid budget line ID in path", http.StatusBadRequest)
+			return
+		}
+
+		transactions, err := s.GetTransactionsByBudgetLineID(r.Context(), budgetLineID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Budget line not found", http.StatusNotFound)
+			} else {
+				log.Printf("Error fetching transactions of budget line %d: %v", budgetLineID, err)
+				http.Error(w, "Failed to fetch transactions", http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(transactions); err != nil {
+			log.Printf("Error encoding transactions: %v", err)
+		}
+	}
+}
+
+// CreateTransactionHandler handles POST /api/v1/budget-lines/{id}/transactions.
+// The body is {"date", "amount", "payee", "memo"}; date and amount are required.
+func CreateTransactionHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		budgetLineID, err := budgetLineIDFromTransactionsPath(r.URL.Path)
+		if err != nil {
+			http.Error(w, "Invalid budget line ID in path", http.StatusBadRequest)
+			return
+		}
+
+		var req transactionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Date == nil || req.Amount == nil {
+			http.Error(w, "Missing 'date' or 'amount' field in request body", http.StatusBadRequest)
+			return
+		}
+		line, err := s.GetBudgetLineByID(r.Context(), budgetLineID)
+		if err != nil {
+			writeTransactionError(w, err, "load budget line")
+			return
+		}
+		t := &store.Transaction{BudgetLineID: budgetLineID, Currency: line.TransactionCurrency()}
+		if err := req.apply(t); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := s.CreateTransaction(editContext(r), t); err != nil {
+			writeTransactionError(w, err, "create transaction")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(t); err != nil {
+			log.Printf("Error encoding created transaction: %v", err)
+		}
+	}
+}
+
+// UpdateTransactionHandler handles PUT /api/v1/transactions/{id}.
+func UpdateTransactionHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		pathParts := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/")
+		id, err := strconv.ParseInt(pathParts[len(pathParts)-1], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid transaction ID in path", http.StatusBadRequest)
+			return
+		}
+
+		var req transactionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		t, err := s.GetTransactionByID(r.Context(), id)
+		if err != nil {
+			writeTransactionError(w, err, "load transaction")
+			return
+		}
+		if err := req.apply(t); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.UpdateTransaction(editContext(r), t); err != nil {
+			writeTransactionError(w, err, "update transaction")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(t); err != nil {
+			log.Printf("Error encoding updated transaction: %v", err)
+		}
+	}
+}
+
+// DeleteTransactionHandler handles DELETE /api/v1/transactions/{id}.
+func DeleteTransactionHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		pathParts := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/")
+		id, err := strconv.ParseInt(pathParts[len(pathParts)-1], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid transaction ID in path", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.DeleteTransaction(editContext(r), id); err != nil {
+			writeTransactionError(w, err, "delete transaction")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeTransactionError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Transaction or budget line not found", http.StatusNotFound)
+	case errors.Is(err, store.ErrNegativeActual):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, store.ErrMonthFinalized):
+		http.Error(w, finalizedMonthMessage, http.StatusConflict)
+	default:
+		log.Printf("Failed to %s: %v", action, err)
+		http.Error(w, "Failed to "+action, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTransactionHandler(t *testing.T) {
+	var created *store.Transaction
+	yen := store.Currency("JPY")
+	mockStore := &store.ReusableMockStore{
+		MockGetBudgetLineByID: func(ctx context.Context, id int64) (*store.BudgetLine, error) {
+			switch id {
+			case 7:
+				return &store.BudgetLine{ID: 7, Currency: "USD"}, nil
+			case 9:
+				return &store.BudgetLine{ID: 9, Currency: "USD", ActualCurrency: &yen}, nil
+			}
+			return nil, fmt.Errorf("failed to get budget line with ID %d: %w", id, sql.ErrNoRows)
+		},
+		MockCreateTransaction: func(ctx context.Context, tr *store.Transaction) (int64, error) {
+			if tr.BudgetLineID == 9 {
+				created = tr
+				tr.ID = 4
+				return 4, nil
+			}
+			if tr.BudgetLineID != 7 {
+				return 0, sql.ErrNoRows
+			}
+			if tr.Amount < -10_00 {
+				return 0, fmt.Errorf("budget line 7 would total -20.00: %w", store.ErrNegativeActual)
+			}
+			created = tr
+			tr.ID = 3
+			return 3, nil
+		},
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{"Valid", "/api/v1/budget-lines/7/transactions", `{"date": "2024-03-15", "amount": 12.5, "payee": " Market ", "memo": "Fruit"}`, http.StatusCreated},
+		{"RFC 3339 date", "/api/v1/budget-lines/7/transactions", `{"date": "2024-03-15T18:30:00+02:00", "amount": 12.5, "payee": "Market", "memo": "Fruit"}`, http.StatusCreated},
+		{"Missing amount", "/api/v1/budget-lines/7/transactions", `{"date": "2024-03-15"}`, http.StatusBadRequest},
+		{"Invalid date", "/api/v1/budget-lines/7/transactions", `{"date": "15/03/2024", "amount": 1}`, http.StatusBadRequest},
+		{"Negative total", "/api/v1/budget-lines/7/transactions", `{"date": "2024-03-15", "amount": -20}`, http.StatusBadRequest},
+		{"Unknown budget line", "/api/v1/budget-lines/8/transactions", `{"date": "2024-03-15", "amount": 1}`, http.StatusNotFound},
+		{"Invalid budget line ID", "/api/v1/budget-lines/x/transactions", `{"date": "2024-03-15", "amount": 1}`, http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			created = nil
+			rr := httptest.NewRecorder()
+			CreateTransactionHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body)))
+			assert.Equal(t, tc.wantCode, rr.Code, rr.Body.String())
+			if tc.wantCode == http.StatusCreated {
+				require.NotNil(t, created)
+				assert.Equal(t, store.Transaction{ID: 3, BudgetLineID: 7, Date: time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC), Amount: 12_50, Currency: "USD", Payee: "Market", Memo: "Fruit"}, *created)
+				assert.Contains(t, rr.Body.String(), `"amount":12.50`)
+			}
+		})
+	}
+	t.Run("Amount in the currency of the line's actual", func(t *testing.T) {
+		created = nil
+		rr := httptest.NewRecorder()
+		CreateTransactionHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/budget-lines/9/transactions", strings.NewReader(`{"date": "2024-03-15", "amount": "1500.4"}`)))
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		require.NotNil(t, created)
+		assert.Equal(t, store.Money(1500), created.Amount, "yen have no minor unit")
+		assert.Equal(t, yen, created.Currency)
+		assert.Contains(t, rr.Body.String(), `"amount":1500}`)
+	})
+}
+
+func TestUpdateTransactionHandler(t *testing.T) {
+	var updated *store.Transaction
+	mockStore := &store.ReusableMockStore{
+		MockGetTransactionByID: func(ctx context.Context, id int64) (*store.Transaction, error) {
+			if id != 3 {
+				return nil, sql.ErrNoRows
+			}
+			return &store.Transaction{ID: 3, BudgetLineID: 7, Date: time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC), Amount: 12_50, Payee: "Market"}, nil
+		},
+		MockUpdateTransaction: func(ctx context.Context, tr *store.Transaction) error {
+			updated = tr
+			return nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	UpdateTransactionHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/api/v1/transactions/3", strings.NewReader(`{"amount": 14, "memo": "Fruit and bread"}`)))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NotNil(t, updated)
+	assert.Equal(t, store.Money(14_00), updated.Amount)
+	assert.Equal(t, "Market", updated.Payee, "fields left out keep their value")
+	assert.Equal(t, "Fruit and bread", updated.Memo)
+
+	rr = httptest.NewRecorder()
+	UpdateTransactionHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/api/v1/transactions/4", strings.NewReader(`{"amount": 14}`)))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestDeleteTransactionHandler(t *testing.T) {
+	mockStore := &store.ReusableMockStore{
+		MockDeleteTransaction: func(ctx context.Context, id int64) error {
+			if id != 3 {
+				return sql.ErrNoRows
+			}
+			return nil
+		},
+	}
+	for path, wantCode := range map[string]int{
+		"/api/v1/transactions/3": http.StatusNoContent,
+		"/api/v1/transactions/4": http.StatusNotFound,
+		"/api/v1/transactions/x": http.StatusBadRequest,
+	} {
+		rr := httptest.NewRecorder()
+		DeleteTransactionHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, path, nil))
+		assert.Equal(t, wantCode, rr.Code, path)
+	}
+}
+
+func TestListTransactionsHandler(t *testing.T) {
+	mockStore := &store.ReusableMockStore{
+		MockGetTransactionsByBudgetLineID: func(ctx context.Context, budgetLineID int64) ([]store.Transaction, error) {
+			if budgetLineID != 7 {
+				return nil, sql.ErrNoRows
+			}
+			return []store.Transaction{{ID: 3, BudgetLineID: 7, Date: time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC), Amount: 12_50, Currency: "USD"}}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	ListTransactionsHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/budget-lines/7/transactions", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[{"id": 3, "budget_line_id": 7, "date": "2024-03-15T00:00:00Z", "amount": 12.50, "currency": "USD", "payee": "", "memo": ""}]`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	ListTransactionsHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/budget-lines/8/transactions/", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gandalf-budget/internal/store"
+)
+
+// GetTrashHandler lists the categories and budget lines in the trash, most
+// recently deleted first.
+func GetTrashHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		trash, err := s.GetTrash(r.Context())
+		if err != nil {
+			log.Printf("Error fetching trash: %v", err)
+			http.Error(w, "Failed to fetch trash", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(trash); err != nil {
+			log.Printf("Error encoding trash: %v", err)
+		}
+	}
+}
+
+// RestoreFromTrashHandler takes an item out of the trash:
+// POST /api/v1/trash/categories/:id/restore or
+// POST /api/v1/trash/budget-lines/:id/restore.
+func RestoreFromTrashHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		pathParts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/trash/"), "/"), "/")
+		if len(pathParts) != 3 || pathParts[2] != "restore" {
+			http.NotFound(w, r)
+			return
+		}
+		id, err := strconv.ParseInt(pathParts[1], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID in path", http.StatusBadRequest)
+			return
+		}
+
+		var restore func(ctx context.Context, id int64) error
+		var kind string
+		switch pathParts[0] {
+		case "categories":
+			restore, kind = s.RestoreCategory, "Category"
+		case "budget-lines":
+			restore, kind = s.RestoreBudgetLine, "Budget line"
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		if err := restore(editContext(r), id); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				http.Error(w, kind+" not found in the trash", http.StatusNotFound)
+			case errors.Is(err, store.ErrCategoryDeleted):
+				http.Error(w, "The budget line's category is in the trash; restore the category first", http.StatusConflict)
+			case errors.Is(err, store.ErrMonthFinalized):
+				http.Error(w, finalizedMonthMessage, http.StatusConflict)
+			default:
+				log.Printf("Error restoring %s %d: %v", strings.ToLower(kind), id, err)
+				http.Error(w, "Failed to restore "+strings.ToLower(kind), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
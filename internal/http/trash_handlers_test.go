@@ -0,0 +1,82 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTrashHandler(t *testing.T) {
+	deletedAt := time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC)
+	mockStore := &store.ReusableMockStore{
+		MockGetTrash: func(ctx context.Context) (*store.Trash, error) {
+			return &store.Trash{
+				Categories:  []store.Category{{ID: 2, Name: "Bills", Color: "#f00", DeletedAt: &deletedAt}},
+				BudgetLines: []store.BudgetLine{},
+			}, nil
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	GetTrashHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/trash", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"categories":[{"id":2,"name":"Bills","color":"#f00","deleted_at":"2024-03-02T10:00:00Z"}],"budget_lines":[]}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	GetTrashHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/api/v1/trash", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestRestoreFromTrashHandler(t *testing.T) {
+	var restored string
+	mockStore := &store.ReusableMockStore{
+		MockRestoreCategory: func(ctx context.Context, id int64) error {
+			if id == 9 {
+				return sql.ErrNoRows
+			}
+			restored = fmt.Sprintf("category %d", id)
+			return nil
+		},
+		MockRestoreBudgetLine: func(ctx context.Context, id int64) error {
+			if id == 5 {
+				return fmt.Errorf("budget line 5: %w", store.ErrCategoryDeleted)
+			}
+			restored = fmt.Sprintf("budget line %d", id)
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		wantCode     int
+		wantRestored string
+	}{
+		{"Category", http.MethodPost, "/api/v1/trash/categories/2/restore", http.StatusNoContent, "category 2"},
+		{"Budget line", http.MethodPost, "/api/v1/trash/budget-lines/4/restore", http.StatusNoContent, "budget line 4"},
+		{"Not in the trash", http.MethodPost, "/api/v1/trash/categories/9/restore", http.StatusNotFound, ""},
+		{"Category still in the trash", http.MethodPost, "/api/v1/trash/budget-lines/5/restore", http.StatusConflict, ""},
+		{"Invalid ID", http.MethodPost, "/api/v1/trash/categories/abc/restore", http.StatusBadRequest, ""},
+		{"Unknown kind", http.MethodPost, "/api/v1/trash/months/1/restore", http.StatusNotFound, ""},
+		{"Missing action", http.MethodPost, "/api/v1/trash/categories/2", http.StatusNotFound, ""},
+		{"Wrong method", http.MethodGet, "/api/v1/trash/categories/2/restore", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			restored = ""
+			rr := httptest.NewRecorder()
+			RestoreFromTrashHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+			assert.Equal(t, tc.wantCode, rr.Code, rr.Body.String())
+			assert.Equal(t, tc.wantRestored, restored)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+
+	"gandalf-budget/internal/app"
+	"gandalf-budget/internal/store"
+)
+
+// ExportXLSXHandler serves a year's workbook (?year=): a summary of the finalized
+// months plus one sheet per month.
+func ExportXLSXHandler(s store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		year, ok := parseYearParam(w, r)
+		if !ok {
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := app.WriteYearXLSX(r.Context(), &buf, s, year); err != nil {
+			log.Printf("Error generating XLSX workbook for %d: %v", year, err)
+			http.Error(w, "Failed to generate workbook", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=gandalf_budget_%d.xlsx", year))
+		if _, err := buf.WriteTo(w); err != nil {
+			log.Printf("Error writing XLSX workbook for %d: %v", year, err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gandalf-budget/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportXLSXHandler(t *testing.T) {
+	mockStore := &store.ReusableMockStore{
+		MockGetAnnualSnapshotsMetadataByYear: func(ctx context.Context, year int) ([]store.AnnualSnapMeta, error) { return nil, nil },
+		MockGetMonthsByYear:                  func(ctx context.Context, year int) ([]store.Month, error) { return nil, nil },
+	}
+
+	rr := httptest.NewRecorder()
+	ExportXLSXHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/export/xlsx?year=2024", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "gandalf_budget_2024.xlsx")
+	assert.Equal(t, "PK", rr.Body.String()[:2], "an XLSX file is a zip archive")
+
+	rr = httptest.NewRecorder()
+	ExportXLSXHandler(mockStore).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/export/xlsx?year=abc", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// timestampLayout is how the store writes DATETIME and TIMESTAMP values, in UTC.
+const timestampLayout = "2006-01-02 15:04:05"
+
+// auditRow is an audit_log row as stored; the JSON columns may be NULL.
+type auditRow struct {
+	ID         int64     `db:"id"`
+	Entity     string    `db:"entity"`
+	EntityID   int64     `db:"entity_id"`
+	Action     string    `db:"action"`
+	MonthID    *int64    `db:"month_id"`
+	BeforeJSON *string   `db:"before_json"`
+	AfterJSON  *string   `db:"after_json"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// auditJSON encodes a row for the audit log; nil stays NULL.
+func auditJSON(row interface{}) (*string, error) {
+	if row == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+	s := string(encoded)
+	return &s, nil
+}
+
+// recordAudit logs a change to a row in tx, so the entry is kept exactly when
+// the change is. before is nil for a created row and after for a deleted one.
+func recordAudit(ctx context.Context, tx *sqlx.Tx, entity string, entityID int64, action string, monthID *int64, before, after interface{}) error {
+	beforeJSON, err := auditJSON(before)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s %d for the audit log: %w", entity, entityID, err)
+	}
+	afterJSON, err := auditJSON(after)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s %d for the audit log: %w", entity, entityID, err)
+	}
+	_, err = tx.ExecContext(ctx, tx.Rebind(`
+		INSERT INTO audit_log (entity, entity_id, action, month_id, before_json, after_json, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		entity, entityID, action, monthID, beforeJSON, afterJSON, time.Now().UTC().Format(timestampLayout))
+	if err != nil {
+		return fmt.Errorf("failed to record %s of %s %d in the audit log: %w", action, entity, entityID, err)
+	}
+	return nil
+}
+
+// GetAuditEntries returns the audit entries matching filter, newest first.
+func (s *sqlStore) GetAuditEntries(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	query := `
+		SELECT id, entity, entity_id, action, month_id, before_json, after_json, created_at
+		FROM audit_log WHERE 1 = 1`
+	args := []interface{}{}
+	if filter.Entity != "" {
+		query += ` AND entity = ?`
+		args = append(args, filter.Entity)
+	}
+	if filter.MonthID != nil {
+		query += ` AND month_id = ?`
+		args = append(args, *filter.MonthID)
+	}
+	if !filter.From.IsZero() {
+		query += ` AND created_at >= ?`
+		args = append(args, filter.From.UTC().Format(timestampLayout))
+	}
+	if !filter.To.IsZero() {
+		query += ` AND created_at < ?`
+		args = append(args, filter.To.UTC().Format(timestampLayout))
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	var rows []auditRow
+	if err := s.DB.SelectContext(ctx, &rows, s.DB.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("error fetching audit entries: %w", err)
+	}
+	entries := make([]AuditEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = AuditEntry{
+			ID: row.ID, Entity: row.Entity, EntityID: row.EntityID, Action: row.Action, MonthID: row.MonthID,
+			CreatedAt: row.CreatedAt.UTC(),
+		}
+		if row.BeforeJSON != nil {
+			entries[i].Before = json.RawMessage(*row.BeforeJSON)
+		}
+		if row.AfterJSON != nil {
+			entries[i].After = json.RawMessage(*row.AfterJSON)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,249 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreBackends_AuditLog(t *testing.T) {
+	logs := map[string][]AuditEntry{}
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			start := time.Now().Add(-time.Second)
+
+			category := &Category{Name: "Travel", Color: "#00f"}
+			require.NoError(t, s.CreateCategory(ctx, category))
+			require.NoError(t, s.UpdateCategory(ctx, &Category{ID: category.ID, Name: "Trips", Color: "#00f"}))
+			lineID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00})
+			require.NoError(t, err)
+			require.NoError(t, s.UpdateBudgetLine(ctx, &BudgetLine{ID: int(lineID), Label: "Food", Expected: 420_00}))
+			lines, err := s.GetBudgetLinesByMonthID(ctx, 1)
+			require.NoError(t, err)
+			require.NoError(t, s.UpdateActualLine(ctx, &ActualLine{ID: *lines[0].ActualID, Actual: 12_50}))
+			assert.ErrorIs(t, s.DeleteCategory(ctx, 1), ErrCategoryInUse)
+			require.NoError(t, s.DeleteCategory(ctx, category.ID))
+			newMonthID, err := s.FinalizeMonth(ctx, 1, `{}`)
+			require.NoError(t, err)
+			assert.ErrorIs(t, s.DeleteBudgetLine(ctx, lineID), ErrMonthFinalized)
+			require.NoError(t, s.DeleteBudgetLine(WithFinalizedOverride(ctx), lineID))
+
+			all, err := s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+			require.Len(t, all, 12, "the seeding import and the changes, but not the failed deletes")
+			assert.Equal(t, AuditEntityBudgetLine, all[0].Entity, "newest first")
+			assert.Equal(t, AuditActionDelete, all[0].Action)
+			assert.Equal(t, []interface{}{AuditEntityMonth, int64(1), AuditActionOverride}, []interface{}{all[1].Entity, all[1].EntityID, all[1].Action},
+				"the override of the finalized month is logged with the delete")
+			assert.JSONEq(t, `{"id":1,"month_id":1,"category_id":1,"label":"Food","expected":420,"currency":"USD"}`, string(all[0].Before))
+			assert.Nil(t, all[0].After)
+
+			categories, err := s.GetAuditEntries(ctx, AuditFilter{Entity: AuditEntityCategory})
+			require.NoError(t, err)
+			require.Len(t, categories, 3)
+			assert.Equal(t, []string{AuditActionDelete, AuditActionUpdate, AuditActionCreate},
+				[]string{categories[0].Action, categories[1].Action, categories[2].Action})
+			assert.JSONEq(t, `{"id":3,"name":"Travel","color":"#00f"}`, string(categories[1].Before))
+			assert.JSONEq(t, `{"id":3,"name":"Trips","color":"#00f"}`, string(categories[1].After))
+			assert.Nil(t, categories[2].Before)
+			assert.Nil(t, categories[0].MonthID)
+
+			actuals, err := s.GetAuditEntries(ctx, AuditFilter{Entity: AuditEntityActualLine})
+			require.NoError(t, err)
+			require.Len(t, actuals, 1)
+			assert.JSONEq(t, `{"id":1,"budget_line_id":1,"actual":0,"currency":"USD"}`, string(actuals[0].Before))
+			assert.JSONEq(t, `{"id":1,"budget_line_id":1,"actual":12.5,"currency":"USD"}`, string(actuals[0].After))
+
+			march := int64(1)
+			inMarch, err := s.GetAuditEntries(ctx, AuditFilter{MonthID: &march})
+			require.NoError(t, err)
+			assert.Len(t, inMarch, 6, "line create, update and delete, the actual, finalizing and the override")
+			inApril, err := s.GetAuditEntries(ctx, AuditFilter{MonthID: &newMonthID})
+			require.NoError(t, err)
+			require.Len(t, inApril, 2, "the new month and its cloned line")
+			assert.Equal(t, AuditEntityMonth, inApril[1].Entity)
+			assert.Equal(t, AuditActionCreate, inApril[1].Action)
+
+			limited, err := s.GetAuditEntries(ctx, AuditFilter{Limit: 2})
+			require.NoError(t, err)
+			assert.Equal(t, all[:2], limited)
+			later, err := s.GetAuditEntries(ctx, AuditFilter{From: time.Now().Add(time.Hour)})
+			require.NoError(t, err)
+			assert.Empty(t, later)
+			window, err := s.GetAuditEntries(ctx, AuditFilter{From: start, To: time.Now().Add(time.Hour)})
+			require.NoError(t, err)
+			assert.Len(t, window, 12)
+			for _, e := range window {
+				assert.Equal(t, time.UTC, e.CreatedAt.Location())
+			}
+
+			for i := range all {
+				all[i].CreatedAt = time.Time{}
+			}
+			logs[name] = all
+		})
+	}
+	assert.Equal(t, logs["sql"], logs["memory"], "both stores log the same changes")
+}
+
+func TestStoreBackends_AuditLogRollsBackWithTheChange(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			_, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 9, CategoryID: 1, Label: "Nowhere"})
+			assert.Error(t, err)
+			assert.Error(t, s.CreateCategory(ctx, &Category{Name: "Food", Color: "#000"}))
+			assert.Error(t, s.UpdateActualLine(ctx, &ActualLine{ID: 7, Actual: 1_00}))
+
+			entries, err := s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			assert.Equal(t, AuditActionImport, entries[0].Action, "only the seeding import is logged")
+		})
+	}
+}
+
+func TestStoreBackends_ImportReplacesTheAuditLog(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			require.NoError(t, s.CreateCategory(ctx, &Category{Name: "Travel", Color: "#00f"}))
+			_, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00})
+			require.NoError(t, err)
+			before, err := s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+			require.Len(t, before, 3)
+
+			dump := &DatabaseDump{
+				Months:     []Month{{ID: 1, Year: 2024, Month: 4}},
+				Categories: []Category{{ID: 1, Name: "Home", Color: "#fff"}},
+			}
+			_, err = s.ImportAll(ctx, dump, ImportModeReplace, true)
+			require.NoError(t, err)
+			previewed, err := s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+			assert.Equal(t, before, previewed, "a dry run leaves the log alone")
+
+			_, err = s.ImportAll(ctx, dump, ImportModeReplace, false)
+			require.NoError(t, err)
+			entries, err := s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+			require.Len(t, entries, 1, "the history of the replaced rows goes with them")
+			assert.Equal(t, AuditEntityDatabase, entries[0].Entity)
+			assert.Equal(t, AuditActionImport, entries[0].Action)
+			assert.Nil(t, entries[0].MonthID)
+			assert.JSONEq(t, `{"months":1,"categories":3,"budget_lines":1,"actual_lines":1,"transactions":0,"annual_snaps":0,"exchange_rates":0,"match_rules":0,"bank_transactions":0}`,
+				string(entries[0].Before))
+			assert.JSONEq(t, `{"months":1,"categories":1,"budget_lines":0,"actual_lines":0,"transactions":0,"annual_snaps":0,"exchange_rates":0,"match_rules":0,"bank_transactions":0}`,
+				string(entries[0].After))
+
+			require.NoError(t, s.UpdateCategory(ctx, &Category{ID: 1, Name: "House", Color: "#fff"}))
+			entries, err = s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+			assert.Equal(t, []string{AuditActionUpdate, AuditActionImport}, []string{entries[0].Action, entries[1].Action}, "changes after the import are logged after it")
+		})
+	}
+}
+
+func TestStoreBackends_AuditLogTransactions(t *testing.T) {
+	logs := map[string][]AuditEntry{}
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			lineID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00})
+			require.NoError(t, err)
+
+			marketID, err := s.CreateTransaction(ctx, &Transaction{BudgetLineID: lineID, Date: time.Date(2024, time.March, 12, 0, 0, 0, 0, time.UTC), Amount: 50_00})
+			require.NoError(t, err)
+			require.NoError(t, s.UpdateTransaction(ctx, &Transaction{ID: marketID, Date: time.Date(2024, time.March, 12, 0, 0, 0, 0, time.UTC), Amount: 45_00, Payee: "Market"}))
+			require.NoError(t, s.UpdateTransaction(ctx, &Transaction{ID: marketID, Date: time.Date(2024, time.March, 13, 0, 0, 0, 0, time.UTC), Amount: 45_00, Payee: "Market"}))
+			require.NoError(t, s.DeleteTransaction(ctx, marketID))
+
+			actuals, err := s.GetAuditEntries(ctx, AuditFilter{Entity: AuditEntityActualLine})
+			require.NoError(t, err)
+			require.Len(t, actuals, 3, "moving the transaction to another day leaves the actual alone")
+			for _, e := range actuals {
+				assert.Equal(t, AuditActionUpdate, e.Action)
+				assert.Equal(t, int64(1), *e.MonthID)
+			}
+			assert.JSONEq(t, `{"id":1,"budget_line_id":1,"actual":0,"currency":"USD"}`, string(actuals[2].Before), "created")
+			assert.JSONEq(t, `{"id":1,"budget_line_id":1,"actual":50,"currency":"USD"}`, string(actuals[2].After))
+			assert.JSONEq(t, `{"id":1,"budget_line_id":1,"actual":45,"currency":"USD"}`, string(actuals[1].After), "updated")
+			assert.JSONEq(t, `{"id":1,"budget_line_id":1,"actual":0,"currency":"USD"}`, string(actuals[0].After), "deleted")
+
+			_, err = s.AddBankTransactions(ctx, []BankTransaction{{ExternalID: "stmt-1", PostedOn: time.Date(2024, time.March, 20, 0, 0, 0, 0, time.UTC), Amount: 12_34, Description: "Corner shop"}})
+			require.NoError(t, err)
+			pending, err := s.GetBankTransactions(ctx, BankTransactionPending)
+			require.NoError(t, err)
+			require.Len(t, pending, 1)
+			require.NoError(t, s.ConfirmBankTransactions(ctx, []BankConfirmation{{TransactionID: pending[0].ID, BudgetLineID: lineID}}))
+			confirmed, err := s.GetAuditEntries(ctx, AuditFilter{Entity: AuditEntityActualLine, Limit: 1})
+			require.NoError(t, err)
+			require.Len(t, confirmed, 1)
+			assert.JSONEq(t, `{"id":1,"budget_line_id":1,"actual":0,"currency":"USD"}`, string(confirmed[0].Before), "confirmed")
+			assert.JSONEq(t, `{"id":1,"budget_line_id":1,"actual":12.34,"currency":"USD"}`, string(confirmed[0].After))
+
+			all, err := s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+			for i := range all {
+				all[i].CreatedAt = time.Time{}
+			}
+			logs[name] = all
+		})
+	}
+	assert.Equal(t, logs["sql"], logs["memory"], "both stores log the same changes")
+}
+
+func TestStoreBackends_AuditLogLegacyImport(t *testing.T) {
+	logs := map[string][]AuditEntry{}
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			require.NoError(t, s.DeleteCategory(ctx, 2))
+			rows := []LegacyImportRow{
+				{Line: 2, Year: 2024, Month: 2, Category: "Bills", Label: "Power", Expected: 80_00, Actual: 75_00},
+				{Line: 3, Year: 2024, Month: 2, Category: "Home", Label: "Rent", Expected: 500_00},
+			}
+			opts := LegacyImportOptions{FinalizeBeforeYear: 2024, FinalizeBeforeMonth: 3}
+			before, err := s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+			_, err = s.ImportLegacyRows(ctx, rows, LegacyImportOptions{FinalizeBeforeYear: 2024, FinalizeBeforeMonth: 3, Preview: true})
+			require.NoError(t, err)
+			previewed, err := s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+			assert.Len(t, previewed, len(before), "a preview logs nothing")
+
+			_, err = s.ImportLegacyRows(ctx, rows, opts)
+			require.NoError(t, err)
+			entries, err := s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+			imported := entries[:len(entries)-len(before)]
+			got := make([]string, len(imported))
+			for i, e := range imported {
+				got[len(imported)-1-i] = e.Entity + " " + e.Action
+			}
+			assert.Equal(t, []string{
+				"month create", "category restore", "budget_line create", "actual_line create",
+				"category create", "budget_line create", "actual_line create", "month update",
+			}, got)
+			assert.JSONEq(t, `{"id":2,"budget_line_id":2,"actual":0,"currency":"USD"}`, string(imported[1].After), "Rent has no actual")
+			assert.JSONEq(t, `{"id":1,"budget_line_id":1,"actual":75,"currency":"USD"}`, string(imported[4].After), "Power's actual")
+			assert.JSONEq(t, `{"id":2,"year":2024,"month":2,"finalized":true}`, string(imported[0].After))
+
+			for i := range entries {
+				entries[i].CreatedAt = time.Time{}
+			}
+			logs[name] = entries
+		})
+	}
+	assert.Equal(t, logs["sql"], logs["memory"], "both stores log the same changes")
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// BackupDatabase writes a consistent copy of the live database to destPath with
+// SQLite's online backup API, so it is safe to run while requests are served.
+// The copy is written next to destPath first and renamed into place when complete.
+func BackupDatabase(ctx context.Context, db *sqlx.DB, destPath string) error {
+	tmpPath := destPath + ".tmp"
+	os.Remove(tmpPath)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection for backup: %w", err)
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn interface{}) error {
+		srcConn, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("online backup requires a SQLite connection, got %T", driverConn)
+		}
+		destDriverConn, err := (&sqlite3.SQLiteDriver{}).Open(tmpPath)
+		if err != nil {
+			return fmt.Errorf("failed to open backup file %s: %w", tmpPath, err)
+		}
+		destConn := destDriverConn.(*sqlite3.SQLiteConn)
+		defer destConn.Close()
+
+		backup, err := destConn.Backup("main", srcConn, "main")
+		if err != nil {
+			return fmt.Errorf("failed to start online backup: %w", err)
+		}
+		for {
+			done, err := backup.Step(-1)
+			if err != nil {
+				backup.Finish()
+				return fmt.Errorf("failed to copy database pages: %w", err)
+			}
+			if done {
+				break
+			}
+			if err := ctx.Err(); err != nil {
+				backup.Finish()
+				return fmt.Errorf("online backup cancelled: %w", err)
+			}
+			// The source was busy or locked; give the writer a moment and retry.
+			time.Sleep(50 * time.Millisecond)
+		}
+		if err := backup.Finish(); err != nil {
+			return fmt.Errorf("failed to finish online backup: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to move backup into place at %s: %w", destPath, err)
+	}
+	return nil
+}
+
+func (s *sqlStore) RecordBackupRun(ctx context.Context, run *BackupRun) error {
+	if run.CreatedAt.IsZero() {
+		run.CreatedAt = time.Now()
+	}
+	var id int64
+	err := s.DB.GetContext(ctx, &id, s.DB.Rebind(`
+		INSERT INTO backup_runs (kind, location, size_bytes, created_at)
+		VALUES (?, ?, ?, ?)
+		RETURNING id;`), run.Kind, run.Location, run.SizeBytes, run.CreatedAt.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return fmt.Errorf("failed to record %s backup run: %w", run.Kind, err)
+	}
+	run.ID = id
+	return nil
+}
+
+// GetLastBackupRun returns the most recent successful backup of any kind, or nil if there is none.
+func (s *sqlStore) GetLastBackupRun(ctx context.Context) (*BackupRun, error) {
+	var run BackupRun
+	err := s.DB.GetContext(ctx, &run, `
+		SELECT id, kind, location, size_bytes, created_at
+		FROM backup_runs
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1;`)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error fetching last backup run: %w", err)
+	}
+	return &run, nil
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestBackupDatabase(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	catID := createTestCategory(t, db, "Food", "bg-red-500")
+	monthID := createTestMonth(t, db, 2024, 5, false)
+	createTestBudgetLine(t, db, monthID, catID, "Groceries", 120)
+
+	dest := filepath.Join(t.TempDir(), "copy.db")
+	if err := BackupDatabase(ctx, db, dest); err != nil {
+		t.Fatalf("BackupDatabase(ctx) failed: %v", err)
+	}
+
+	copyDB, err := sqlx.Connect("sqlite3", dest)
+	if err != nil {
+		t.Fatalf("Failed to open backup copy: %v", err)
+	}
+	defer copyDB.Close()
+
+	var label string
+	if err := copyDB.Get(&label, "SELECT label FROM budget_lines WHERE month_id = ?", monthID); err != nil {
+		t.Fatalf("Backup copy is missing data: %v", err)
+	}
+	if label != "Groceries" {
+		t.Errorf("Backup copy label = %q, want Groceries", label)
+	}
+	if matches, _ := filepath.Glob(dest + ".tmp"); len(matches) != 0 {
+		t.Errorf("Temporary backup file was left behind: %v", matches)
+	}
+}
+
+func TestRecordAndGetLastBackupRun(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	s := NewSQLStore(db).(*sqlStore)
+
+	last, err := s.GetLastBackupRun(ctx)
+	if err != nil || last != nil {
+		t.Fatalf("Expected no backup run on a fresh database, got %+v, err %v", last, err)
+	}
+
+	older := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	newer := older.Add(48 * time.Hour)
+	for _, run := range []*BackupRun{
+		{Kind: BackupKindJSONExport, Location: "gandalf_backup_20240503.json", SizeBytes: 10, CreatedAt: newer},
+		{Kind: BackupKindSnapshot, Location: "backups/budget-20240501-080000.db", SizeBytes: 4096, CreatedAt: older},
+	} {
+		if err := s.RecordBackupRun(ctx, run); err != nil {
+			t.Fatalf("RecordBackupRun() failed: %v", err)
+		}
+		if run.ID == 0 {
+			t.Errorf("RecordBackupRun() did not set the ID")
+		}
+	}
+
+	last, err = s.GetLastBackupRun(ctx)
+	if err != nil {
+		t.Fatalf("GetLastBackupRun() failed: %v", err)
+	}
+	if last == nil || last.Kind != BackupKindJSONExport || !last.CreatedAt.Equal(newer) {
+		t.Errorf("GetLastBackupRun() = %+v, want the JSON export at %v", last, newer)
+	}
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrTransactionNotPending = errors.New("bank transaction is not pending review")
+	ErrMonthFinalized        = errors.New("month is finalized")
+	ErrCurrencyMismatch      = errors.New("actuals are kept in another currency")
+)
+
+// AddBankTransactions queues statement transactions for review. Transactions
+// already imported (same external ID) are skipped, so overlapping statements can
+// be uploaded safely; the number actually added is returned and their IDs are set.
+func (s *sqlStore) AddBankTransactions(ctx context.Context, txns []BankTransaction) (int, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin bank import transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format("2006-01-02 15:04:05")
+	added := 0
+	for i := range txns {
+		t := &txns[i]
+		if t.Status == "" {
+			t.Status = BankTransactionPending
+		}
+		err := tx.GetContext(ctx, &t.ID, tx.Rebind(`
+			INSERT INTO bank_transactions
+				(external_id, posted_on, amount, description, month_id, budget_line_id, match_reason, status, imported_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (external_id) DO NOTHING
+			RETURNING id`),
+			t.ExternalID, t.PostedOn.Format("2006-01-02"), t.Amount, t.Description,
+			t.MonthID, t.BudgetLineID, t.MatchReason, t.Status, now)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert bank transaction %s: %w", t.ExternalID, err)
+		}
+		added++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit bank import: %w", err)
+	}
+	return added, nil
+}
+
+// GetBankTransactions lists transactions with the given status, or all of them
+// when status is empty, oldest first.
+func (s *sqlStore) GetBankTransactions(ctx context.Context, status string) ([]BankTransaction, error) {
+	txns := []BankTransaction{}
+	query := `
+		SELECT id, external_id, posted_on, amount, description, month_id, budget_line_id, match_reason, status, imported_at
+		FROM bank_transactions`
+	args := []interface{}{}
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY posted_on, id`
+	if err := s.DB.SelectContext(ctx, &txns, s.DB.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("error fetching bank transactions: %w", err)
+	}
+	for i := range txns {
+		txns[i].PostedOn, txns[i].ImportedAt = txns[i].PostedOn.UTC(), txns[i].ImportedAt.UTC()
+	}
+	return txns, nil
+}
+
+// ConfirmBankTransactions adds each pending transaction's amount to the actual of
+// the chosen budget line and marks it applied. All confirmations succeed or none do.
+func (s *sqlStore) ConfirmBankTransactions(ctx context.Context, confirmations []BankConfirmation) error {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin confirmation transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, c := range confirmations {
+		var txn BankTransaction
+		err := tx.GetContext(ctx, &txn, tx.Rebind(`SELECT id, posted_on, amount, description, status FROM bank_transactions WHERE id = ?`), c.TransactionID)
+		if err != nil {
+			return fmt.Errorf("failed to load bank transaction %d: %w", c.TransactionID, err)
+		}
+		if txn.Status != BankTransactionPending {
+			return fmt.Errorf("bank transaction %d: %w", c.TransactionID, ErrTransactionNotPending)
+		}
+
+		var line struct {
+			MonthID  int64    `db:"month_id"`
+			Currency Currency `db:"currency"`
+		}
+		err = tx.GetContext(ctx, &line, tx.Rebind(`SELECT month_id, currency FROM budget_lines WHERE id = ? AND deleted_at IS NULL`), c.BudgetLineID)
+		if err != nil {
+			return fmt.Errorf("failed to load budget line %d: %w", c.BudgetLineID, err)
+		}
+		if err := checkLineEditable(ctx, tx, c.BudgetLineID); err != nil {
+			return fmt.Errorf("budget line %d: %w", c.BudgetLineID, err)
+		}
+
+		var actual ActualLine
+		err = tx.GetContext(ctx, &actual, tx.Rebind(`SELECT id, budget_line_id, actual, currency FROM actual_lines WHERE budget_line_id = ? ORDER BY id LIMIT 1`), c.BudgetLineID)
+		if err == sql.ErrNoRows {
+			actual.Currency, err = line.Currency, nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load actual of budget line %d: %w", c.BudgetLineID, err)
+		}
+		// Statements are in the budget currency, so they only add to actuals kept in it.
+		if base := BudgetCurrency(); actual.Currency != "" && actual.Currency != base {
+			return fmt.Errorf("budget line %d records actuals in %s, not %s: %w", c.BudgetLineID, actual.Currency, base, ErrCurrencyMismatch)
+		}
+		booked := &Transaction{BudgetLineID: c.BudgetLineID, Date: txn.PostedOn, Amount: txn.Amount, Payee: txn.Description}
+		if _, err := insertTransaction(ctx, tx, booked); err != nil {
+			return fmt.Errorf("failed to add bank transaction %d to budget line %d: %w", c.TransactionID, c.BudgetLineID, err)
+		}
+		if err := refreshActual(ctx, tx, c.BudgetLineID); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, tx.Rebind(`UPDATE bank_transactions SET status = ?, budget_line_id = ?, month_id = ? WHERE id = ?`),
+			BankTransactionApplied, c.BudgetLineID, line.MonthID, c.TransactionID)
+		if err != nil {
+			return fmt.Errorf("failed to mark bank transaction %d applied: %w", c.TransactionID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit confirmations: %w", err)
+	}
+	return nil
+}
+
+// IgnoreBankTransaction takes a pending transaction out of the review queue
+// without touching any budget line.
+func (s *sqlStore) IgnoreBankTransaction(ctx context.Context, id int64) error {
+	var status string
+	if err := s.DB.GetContext(ctx, &status, s.DB.Rebind(`SELECT status FROM bank_transactions WHERE id = ?`), id); err != nil {
+		return fmt.Errorf("failed to load bank transaction %d: %w", id, err)
+	}
+	if status != BankTransactionPending {
+		return fmt.Errorf("bank transaction %d: %w", id, ErrTransactionNotPending)
+	}
+	if _, err := s.DB.ExecContext(ctx, s.DB.Rebind(`UPDATE bank_transactions SET status = ? WHERE id = ?`), BankTransactionIgnored, id); err != nil {
+		return fmt.Errorf("failed to ignore bank transaction %d: %w", id, err)
+	}
+	return nil
+}
+
+// GetMatchRules returns the rules in the order they are tried.
+func (s *sqlStore) GetMatchRules(ctx context.Context) ([]MatchRule, error) {
+	rules := []MatchRule{}
+	if err := s.DB.SelectContext(ctx, &rules, `SELECT id, pattern, category_id, label, amount FROM match_rules ORDER BY id`); err != nil {
+		return nil, fmt.Errorf("error fetching match rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (s *sqlStore) CreateMatchRule(ctx context.Context, rule *MatchRule) error {
+	err := s.DB.GetContext(ctx, &rule.ID, s.DB.Rebind(`INSERT INTO match_rules (pattern, category_id, label, amount) VALUES (?, ?, ?, ?) RETURNING id`),
+		rule.Pattern, rule.CategoryID, rule.Label, rule.Amount)
+	if err != nil {
+		return fmt.Errorf("failed to create match rule: %w", err)
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteMatchRule(ctx context.Context, id int64) error {
+	res, err := s.DB.ExecContext(ctx, s.DB.Rebind(`DELETE FROM match_rules WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete match rule %d: %w", id, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
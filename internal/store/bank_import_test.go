@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStoreBackends_BankTransactions(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			openID, closedID := int64(1), int64(2)
+			groceriesID, takeawayID, closedLineID := int64(1), int64(2), int64(3)
+			_, err := s.ImportAll(ctx, &DatabaseDump{
+				Months:     []Month{{ID: openID, Year: 2024, Month: 2}, {ID: closedID, Year: 2024, Month: 1, Finalized: true}},
+				Categories: []Category{{ID: 1, Name: "Food", Color: "bg-red-500"}},
+				BudgetLines: []BudgetLine{
+					{ID: int(groceriesID), MonthID: int(openID), CategoryID: 1, Label: "Groceries", Expected: 300_00},
+					{ID: int(takeawayID), MonthID: int(openID), CategoryID: 1, Label: "Takeaway", Expected: 50_00},
+					{ID: int(closedLineID), MonthID: int(closedID), CategoryID: 1, Label: "Groceries", Expected: 300_00},
+				},
+				ActualLines:  []ActualLine{{ID: 1, BudgetLineID: groceriesID, Actual: 10_10}},
+				Transactions: []Transaction{{ID: 1, BudgetLineID: groceriesID, Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Amount: 10_10}},
+			}, ImportModeReplace, false)
+			if err != nil {
+				t.Fatalf("Failed to seed the store: %v", err)
+			}
+
+			posted := time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)
+			txns := []BankTransaction{
+				{ExternalID: "ofx:1:a", PostedOn: posted, Amount: 45_25, Description: "Market", MonthID: &openID, BudgetLineID: &groceriesID, MatchReason: "label"},
+				{ExternalID: "ofx:1:b", PostedOn: posted, Amount: 12_00, Description: "Pizza", MonthID: &openID},
+				{ExternalID: "ofx:1:c", PostedOn: posted.AddDate(0, -1, 0), Amount: 5_00, Description: "Old"},
+			}
+			added, err := s.AddBankTransactions(ctx, txns)
+			if err != nil || added != 3 {
+				t.Fatalf("AddBankTransactions() = %d, %v; want 3 added", added, err)
+			}
+			again := []BankTransaction{txns[0], {ExternalID: "ofx:1:d", PostedOn: posted, Amount: 1_00, Description: "New"}}
+			again[0].ID = 0
+			if added, err := s.AddBankTransactions(ctx, again); err != nil || added != 1 || again[0].ID != 0 || again[1].ID == 0 {
+				t.Fatalf("Re-importing should only add the new transaction: added %d, err %v, %+v", added, err, again)
+			}
+
+			pending, err := s.GetBankTransactions(ctx, BankTransactionPending)
+			if err != nil || len(pending) != 4 {
+				t.Fatalf("GetBankTransactions(pending) = %+v, %v; want 4", pending, err)
+			}
+			if pending[0].ExternalID != "ofx:1:c" || pending[1].PostedOn.Format("2006-01-02") != "2024-02-03" {
+				t.Errorf("Expected transactions oldest first with dates intact, got %+v", pending)
+			}
+
+			// A failing confirmation rolls back the whole batch.
+			err = s.ConfirmBankTransactions(ctx, []BankConfirmation{
+				{TransactionID: txns[0].ID, BudgetLineID: groceriesID},
+				{TransactionID: txns[2].ID, BudgetLineID: closedLineID},
+			})
+			if !errors.Is(err, ErrMonthFinalized) {
+				t.Fatalf("Expected ErrMonthFinalized, got %v", err)
+			}
+			if got := actualOf(t, s, groceriesID); got != 10_10 {
+				t.Errorf("Failed batch must not change actuals, got %v", got)
+			}
+
+			err = s.ConfirmBankTransactions(ctx, []BankConfirmation{
+				{TransactionID: txns[0].ID, BudgetLineID: groceriesID},
+				{TransactionID: txns[1].ID, BudgetLineID: takeawayID},
+			})
+			if err != nil {
+				t.Fatalf("ConfirmBankTransactions() failed: %v", err)
+			}
+			if got := actualOf(t, s, groceriesID); got != 55_35 {
+				t.Errorf("Expected groceries actual 55.35, got %v", got)
+			}
+			// Takeaway had no actual line yet, so one is created.
+			if got := actualOf(t, s, takeawayID); got != 12_00 {
+				t.Errorf("Expected takeaway actual 12, got %v", got)
+			}
+
+			err = s.ConfirmBankTransactions(ctx, []BankConfirmation{{TransactionID: txns[0].ID, BudgetLineID: groceriesID}})
+			if !errors.Is(err, ErrTransactionNotPending) {
+				t.Errorf("Confirming twice should fail with ErrTransactionNotPending, got %v", err)
+			}
+			err = s.ConfirmBankTransactions(ctx, []BankConfirmation{{TransactionID: 999, BudgetLineID: groceriesID}})
+			if !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected sql.ErrNoRows for an unknown transaction, got %v", err)
+			}
+
+			if err := s.IgnoreBankTransaction(ctx, txns[2].ID); err != nil {
+				t.Fatalf("IgnoreBankTransaction() failed: %v", err)
+			}
+			if err := s.IgnoreBankTransaction(ctx, txns[2].ID); !errors.Is(err, ErrTransactionNotPending) {
+				t.Errorf("Ignoring twice should fail with ErrTransactionNotPending, got %v", err)
+			}
+
+			applied, _ := s.GetBankTransactions(ctx, BankTransactionApplied)
+			if len(applied) != 2 || applied[1].BudgetLineID == nil || *applied[1].BudgetLineID != takeawayID {
+				t.Errorf("Expected 2 applied transactions recording their line, got %+v", applied)
+			}
+			all, _ := s.GetBankTransactions(ctx, "")
+			if len(all) != 4 {
+				t.Errorf("Expected 4 transactions in total, got %d", len(all))
+			}
+		})
+	}
+}
+
+func TestStoreBackends_MatchRules(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			foodID := int64(1)
+
+			amount := Money(12_99)
+			rules := []MatchRule{
+				{Pattern: "netflix", Label: "Streaming", Amount: &amount},
+				{Pattern: "lider", CategoryID: &foodID, Label: "Groceries"},
+			}
+			for i := range rules {
+				if err := s.CreateMatchRule(ctx, &rules[i]); err != nil || rules[i].ID == 0 {
+					t.Fatalf("CreateMatchRule() failed: %v", err)
+				}
+			}
+
+			got, err := s.GetMatchRules(ctx)
+			if err != nil || len(got) != 2 {
+				t.Fatalf("GetMatchRules() = %+v, %v", got, err)
+			}
+			if got[0].Amount == nil || *got[0].Amount != 12_99 || got[0].CategoryID != nil {
+				t.Errorf("Unexpected first rule: %+v", got[0])
+			}
+			if got[1].CategoryID == nil || *got[1].CategoryID != foodID || got[1].Amount != nil {
+				t.Errorf("Unexpected second rule: %+v", got[1])
+			}
+
+			if err := s.DeleteMatchRule(ctx, rules[0].ID); err != nil {
+				t.Fatalf("DeleteMatchRule() failed: %v", err)
+			}
+			if err := s.DeleteMatchRule(ctx, rules[0].ID); err != sql.ErrNoRows {
+				t.Errorf("Expected sql.ErrNoRows deleting a missing rule, got %v", err)
+			}
+		})
+	}
+}
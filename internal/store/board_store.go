@@ -1,20 +1,28 @@
 package store
 
 import (
+	"context"
 	"fmt" // For error formatting
 	// Other necessary imports like "database/sql" if doing complex scanning,
 	// but sqlx should handle it.
 	"database/sql"
+
+	"github.com/jmoiron/sqlx"
 )
 
-func (s *sqlStore) GetBoardData(monthID int) (*BoardDataPayload, error) {
+func (s *sqlStore) GetBoardData(ctx context.Context, monthID int) (*BoardDataPayload, error) {
+	return getBoardData(ctx, s.DB, monthID)
+}
+
+// getBoardData runs against either the database or an open transaction.
+func getBoardData(ctx context.Context, q sqlx.ExtContext, monthID int) (*BoardDataPayload, error) {
 	var monthDetails struct {
 		Year      int  `db:"year"`
 		Month     int  `db:"month"`
 		Finalized bool `db:"finalized"`
 	}
 	monthQuery := `SELECT year, month, finalized FROM months WHERE id = ?;`
-	err := s.DB.Get(&monthDetails, monthQuery, monthID)
+	err := sqlx.GetContext(ctx, q, &monthDetails, q.Rebind(monthQuery), monthID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, sql.ErrNoRows
@@ -49,14 +57,16 @@ func (s *sqlStore) GetBoardData(monthID int) (*BoardDataPayload, error) {
 		c.color AS category_color,
 		bl.label,
 		bl.expected AS expected_amount,
-		COALESCE(al.actual, 0) AS actual_amount
+		COALESCE(al.actual, 0) AS actual_amount,
+		bl.currency,
+		COALESCE(al.currency, bl.currency) AS actual_currency
 	FROM budget_lines bl
 	JOIN categories c ON bl.category_id = c.id
 	LEFT JOIN actual_lines al ON bl.id = al.budget_line_id
-	WHERE bl.month_id = ?
+	WHERE bl.month_id = ? AND bl.deleted_at IS NULL
 	ORDER BY c.name, bl.label;
 	`
-	err = s.DB.Select(&budgetLinesWithActuals, query, monthID)
+	err = sqlx.SelectContext(ctx, q, &budgetLinesWithActuals, q.Rebind(query), monthID)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, fmt.Errorf("error fetching budget lines with actuals for month %d: %w", monthID, err)
 	}
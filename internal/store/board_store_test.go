@@ -1,6 +1,9 @@
 package store
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -36,8 +39,8 @@ func TestGetBoardData(t *testing.T) {
 			name:    "Month with budget lines and actual lines",
 			monthID: int(month1ID),
 			expectedLines: []BudgetLineWithActual{
-				{ID: bl1m1, MonthID: month1ID, CategoryID: cat1ID, CategoryName: "Food", CategoryColor: "bg-red-500", Label: "Groceries", ExpectedAmount: 500.0, ActualAmount: 480.0},
-				{ID: bl2m1, MonthID: month1ID, CategoryID: cat2ID, CategoryName: "Travel", CategoryColor: "bg-blue-500", Label: "Gas", ExpectedAmount: 100.0, ActualAmount: 110.0},
+				{ID: bl1m1, MonthID: month1ID, CategoryID: cat1ID, CategoryName: "Food", CategoryColor: "bg-red-500", Label: "Groceries", ExpectedAmount: 500_00, ActualAmount: 480_00},
+				{ID: bl2m1, MonthID: month1ID, CategoryID: cat2ID, CategoryName: "Travel", CategoryColor: "bg-blue-500", Label: "Gas", ExpectedAmount: 100_00, ActualAmount: 110_00},
 			},
 			expectError: false,
 		},
@@ -45,14 +48,14 @@ func TestGetBoardData(t *testing.T) {
 			name:          "Month with no budget lines",
 			monthID:       int(month2ID),
 			expectedLines: []BudgetLineWithActual{},
-			expectError:   true,
+			expectError:   false,
 		},
 		{
 			name:    "Month with lines but varied actuals",
 			monthID: int(month3ID),
 			expectedLines: []BudgetLineWithActual{
-				{ID: bl1m3, MonthID: month3ID, CategoryID: cat1ID, CategoryName: "Food", CategoryColor: "bg-red-500", Label: "Restaurant", ExpectedAmount: 150.0, ActualAmount: 0},
-				{ID: bl2m3, MonthID: month3ID, CategoryID: cat2ID, CategoryName: "Travel", CategoryColor: "bg-blue-500", Label: "Bus Pass", ExpectedAmount: 50.0, ActualAmount: 0},
+				{ID: bl1m3, MonthID: month3ID, CategoryID: cat1ID, CategoryName: "Food", CategoryColor: "bg-red-500", Label: "Restaurant", ExpectedAmount: 150_00, ActualAmount: 0},
+				{ID: bl2m3, MonthID: month3ID, CategoryID: cat2ID, CategoryName: "Travel", CategoryColor: "bg-blue-500", Label: "Bus Pass", ExpectedAmount: 50_00, ActualAmount: 0},
 			},
 			expectError: false,
 		},
@@ -60,17 +63,17 @@ func TestGetBoardData(t *testing.T) {
 			name:          "Invalid monthID (non-existent)",
 			monthID:       999,
 			expectedLines: []BudgetLineWithActual{},
-			expectError:   false,
+			expectError:   true,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			payload, err := s.GetBoardData(tc.monthID)
+			payload, err := s.GetBoardData(context.Background(), tc.monthID)
 
 			if tc.expectError {
-				if err == nil {
-					t.Errorf("Expected an error, but got nil")
+				if !errors.Is(err, sql.ErrNoRows) {
+					t.Errorf("Expected sql.ErrNoRows, but got %v", err)
 				}
 				return
 			}
@@ -1,20 +1,42 @@
 package store
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"math"
 )
 
-func (s *sqlStore) CreateBudgetLine(b *BudgetLine) (int64, error) {
-	tx, err := s.DB.Beginx()
+// ErrActualCurrencyInUse is returned when changing the currency of an actual
+// whose line has transactions: their amounts are in the old currency.
+var ErrActualCurrencyInUse = errors.New("the actual's transactions are in its current currency")
+
+func (s *sqlStore) CreateBudgetLine(ctx context.Context, b *BudgetLine) (int64, error) {
+	if b.Currency == "" {
+		b.Currency = BudgetCurrency()
+	}
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareNamed(`
-		INSERT INTO budget_lines (month_id, category_id, label, expected)
-		VALUES (:month_id, :category_id, :label, :expected)
+	var categoryDeleted bool
+	err = tx.GetContext(ctx, &categoryDeleted, tx.Rebind(`SELECT EXISTS (SELECT 1 FROM categories WHERE id = ? AND deleted_at IS NOT NULL)`), b.CategoryID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check category %d: %w", b.CategoryID, err)
+	}
+	if categoryDeleted {
+		return 0, fmt.Errorf("category %d: %w", b.CategoryID, ErrCategoryDeleted)
+	}
+	if err := checkMonthEditable(ctx, tx, int64(b.MonthID)); err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareNamedContext(ctx, `
+		INSERT INTO budget_lines (month_id, category_id, label, expected, currency)
+		VALUES (:month_id, :category_id, :label, :expected, :currency)
 		RETURNING id`)
 	if err != nil {
 		return 0, fmt.Errorf("failed to prepare budget_lines insert statement: %w", err)
@@ -22,26 +44,33 @@ func (s *sqlStore) CreateBudgetLine(b *BudgetLine) (int64, error) {
 	defer stmt.Close()
 
 	var budgetLineID int64
-	if err := stmt.Get(&budgetLineID, b); err != nil {
+	if err := stmt.GetContext(ctx, &budgetLineID, b); err != nil {
 		return 0, fmt.Errorf("failed to execute budget_lines insert statement: %w", err)
 	}
 
 	actualLine := &ActualLine{
 		BudgetLineID: budgetLineID,
 		Actual:       0,
+		Currency:     b.Currency,
 	}
-	stmtActual, err := tx.PrepareNamed(`
-		INSERT INTO actual_lines (budget_line_id, actual)
-		VALUES (:budget_line_id, :actual)`)
+	stmtActual, err := tx.PrepareNamedContext(ctx, `
+		INSERT INTO actual_lines (budget_line_id, actual, currency)
+		VALUES (:budget_line_id, :actual, :currency)`)
 	if err != nil {
 		return 0, fmt.Errorf("failed to prepare actual_lines insert statement: %w", err)
 	}
 	defer stmtActual.Close()
 
-	if _, err := stmtActual.Exec(actualLine); err != nil {
+	if _, err := stmtActual.ExecContext(ctx, actualLine); err != nil {
 		return 0, fmt.Errorf("failed to execute actual_lines insert statement: %w", err)
 	}
 
+	created := BudgetLine{ID: int(budgetLineID), MonthID: b.MonthID, CategoryID: b.CategoryID, Label: b.Label, Expected: b.Expected, Currency: b.Currency}
+	monthID := int64(b.MonthID)
+	if err := recordAudit(ctx, tx, AuditEntityBudgetLine, budgetLineID, AuditActionCreate, &monthID, nil, created); err != nil {
+		return 0, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -49,17 +78,17 @@ func (s *sqlStore) CreateBudgetLine(b *BudgetLine) (int64, error) {
 	return budgetLineID, nil
 }
 
-func (s *sqlStore) GetBudgetLinesByMonthID(monthID int) ([]BudgetLine, error) {
+func (s *sqlStore) GetBudgetLinesByMonthID(ctx context.Context, monthID int) ([]BudgetLine, error) {
 	var budgetLines []BudgetLine
 	query := `
 		SELECT
-			bl.id, bl.month_id, bl.category_id, bl.label, bl.expected,
-			al.id AS actual_id, al.actual AS actual_amount
+			bl.id, bl.month_id, bl.category_id, bl.label, bl.expected, bl.currency,
+			al.id AS actual_id, al.actual AS actual_amount, al.currency AS actual_currency
 		FROM budget_lines bl
 		LEFT JOIN actual_lines al ON bl.id = al.budget_line_id
-		WHERE bl.month_id = $1
+		WHERE bl.month_id = ? AND bl.deleted_at IS NULL
 		ORDER BY bl.id`
-	err := s.DB.Select(&budgetLines, query, monthID)
+	err := s.DB.SelectContext(ctx, &budgetLines, s.DB.Rebind(query), monthID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get budget lines by month ID %d: %w", monthID, err)
 	}
@@ -69,73 +98,166 @@ func (s *sqlStore) GetBudgetLinesByMonthID(monthID int) ([]BudgetLine, error) {
 	return budgetLines, nil
 }
 
-func (s *sqlStore) UpdateBudgetLine(b *BudgetLine) error {
-	_, err := s.DB.NamedExec(`
+func (s *sqlStore) UpdateBudgetLine(ctx context.Context, b *BudgetLine) error {
+	if b.Currency == "" {
+		b.Currency = BudgetCurrency()
+	}
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before BudgetLine
+	err = tx.GetContext(ctx, &before, tx.Rebind(`SELECT id, month_id, category_id, label, expected, currency FROM budget_lines WHERE id = ? AND deleted_at IS NULL`), b.ID)
+	if err == sql.ErrNoRows {
+		// Like an UPDATE matching no row: nothing changes and nothing is logged.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get budget line with ID %d: %w", b.ID, err)
+	}
+	if err := checkMonthEditable(ctx, tx, int64(before.MonthID)); err != nil {
+		return err
+	}
+	_, err = tx.NamedExecContext(ctx, `
 		UPDATE budget_lines
-		SET label = :label, expected = :expected
+		SET label = :label, expected = :expected, currency = :currency
 		WHERE id = :id`, b)
 	if err != nil {
 		return fmt.Errorf("failed to update budget line with ID %d: %w", b.ID, err)
 	}
+	after := before
+	after.Label, after.Expected, after.Currency = b.Label, b.Expected, b.Currency
+	monthID := int64(before.MonthID)
+	if err := recordAudit(ctx, tx, AuditEntityBudgetLine, int64(b.ID), AuditActionUpdate, &monthID, before, after); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit budget line with ID %d: %w", b.ID, err)
+	}
 	return nil
 }
 
-func (s *sqlStore) UpdateActualLine(a *ActualLine) error {
+func (s *sqlStore) UpdateActualLine(ctx context.Context, a *ActualLine) error {
 	if a.Actual < 0 {
-		return fmt.Errorf("actual amount must be non-negative, got %f", a.Actual)
+		return fmt.Errorf("actual amount must be non-negative, got %s", a.Actual.StringIn(a.Currency))
+	}
+	if a.Currency == "" {
+		a.Currency = BudgetCurrency()
 	}
-	a.Actual = math.Round(a.Actual*100) / 100
 
-	_, err := s.DB.NamedExec(`
-		UPDATE actual_lines
-		SET actual = :actual
-		WHERE id = :id`, a)
+	tx, err := s.DB.BeginTxx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current struct {
+		ActualLine
+		MonthID      int64 `db:"month_id"`
+		Transactions int   `db:"transactions"`
+	}
+	err = tx.GetContext(ctx, &current, tx.Rebind(`
+		SELECT al.id, al.budget_line_id, al.currency, bl.month_id, COALESCE(SUM(t.amount), 0) AS actual, COUNT(t.id) AS transactions
+		FROM actual_lines al
+		JOIN budget_lines bl ON bl.id = al.budget_line_id
+		LEFT JOIN transactions t ON t.budget_line_id = al.budget_line_id
+		WHERE al.id = ? AND bl.deleted_at IS NULL GROUP BY al.id, bl.month_id`), a.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get actual line with ID %d: %w", a.ID, err)
+	}
+	if err := checkMonthEditable(ctx, tx, current.MonthID); err != nil {
+		return err
+	}
+	if a.Currency != current.Currency && current.Transactions > 0 {
+		return fmt.Errorf("actual line %d: %w", a.ID, ErrActualCurrencyInUse)
+	}
+	// Setting the total directly books the difference as one adjustment, so
+	// the actual stays the sum of the line's transactions.
+	if diff := a.Actual - current.Actual; diff != 0 {
+		date, err := adjustmentDate(ctx, tx, current.BudgetLineID)
+		if err != nil {
+			return err
+		}
+		adjustment := &Transaction{BudgetLineID: current.BudgetLineID, Date: date, Amount: diff, Memo: "Adjustment to the actual total"}
+		if _, err := insertTransaction(ctx, tx, adjustment); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE actual_lines SET currency = ? WHERE id = ?`), a.Currency, a.ID); err != nil {
 		return fmt.Errorf("failed to update actual line with ID %d: %w", a.ID, err)
 	}
+	if err := syncActual(ctx, tx, current.BudgetLineID); err != nil {
+		return err
+	}
+	after := ActualLine{ID: a.ID, BudgetLineID: current.BudgetLineID, Actual: a.Actual, Currency: a.Currency}
+	if err := recordAudit(ctx, tx, AuditEntityActualLine, a.ID, AuditActionUpdate, &current.MonthID, current.ActualLine, after); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit actual line with ID %d: %w", a.ID, err)
+	}
+	a.BudgetLineID = current.BudgetLineID
 	return nil
 }
 
-func (s *sqlStore) GetActualLineByID(id int64) (*ActualLine, error) {
+func (s *sqlStore) GetActualLineByID(ctx context.Context, id int64) (*ActualLine, error) {
 	var actualLine ActualLine
-	err := s.DB.Get(&actualLine, "SELECT id, budget_line_id, actual FROM actual_lines WHERE id = $1", id)
+	err := s.DB.GetContext(ctx, &actualLine, s.DB.Rebind(`
+		SELECT al.id, al.budget_line_id, al.actual, al.currency
+		FROM actual_lines al JOIN budget_lines bl ON bl.id = al.budget_line_id
+		WHERE al.id = ? AND bl.deleted_at IS NULL`), id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get actual line with ID %d: %w", id, err)
 	}
 	return &actualLine, nil
 }
 
-func (s *sqlStore) GetBudgetLineByID(id int64) (*BudgetLine, error) {
+func (s *sqlStore) GetBudgetLineByID(ctx context.Context, id int64) (*BudgetLine, error) {
 	var budgetLine BudgetLine
-	err := s.DB.Get(&budgetLine, "SELECT id, month_id, category_id, label, expected FROM budget_lines WHERE id = $1", id)
+	err := s.DB.GetContext(ctx, &budgetLine, s.DB.Rebind(`
+		SELECT
+			bl.id, bl.month_id, bl.category_id, bl.label, bl.expected, bl.currency,
+			al.id AS actual_id, al.actual AS actual_amount, al.currency AS actual_currency
+		FROM budget_lines bl
+		LEFT JOIN actual_lines al ON bl.id = al.budget_line_id
+		WHERE bl.id = ? AND bl.deleted_at IS NULL
+		ORDER BY al.id LIMIT 1`), id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get budget line with ID %d: %w", id, err)
 	}
 	return &budgetLine, nil
 }
 
-func (s *sqlStore) DeleteBudgetLine(id int64) error {
-	tx, err := s.DB.Beginx()
+func (s *sqlStore) DeleteBudgetLine(ctx context.Context, id int64) error {
+	tx, err := s.DB.BeginTxx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec("DELETE FROM actual_lines WHERE budget_line_id = $1", id)
+	var before BudgetLine
+	err = tx.GetContext(ctx, &before, tx.Rebind(`SELECT id, month_id, category_id, label, expected, currency FROM budget_lines WHERE id = ? AND deleted_at IS NULL`), id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no budget line found with ID %d to delete", id)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to delete actual line for budget line ID %d: %w", id, err)
+		return fmt.Errorf("failed to get budget line with ID %d: %w", id, err)
+	}
+	if err := checkMonthEditable(ctx, tx, int64(before.MonthID)); err != nil {
+		return err
 	}
 
-	res, err := tx.Exec("DELETE FROM budget_lines WHERE id = $1", id)
-	if err != nil {
+	// The line goes to the trash with its actual and transactions; PurgeTrash
+	// deletes them for good.
+	if _, err := tx.ExecContext(ctx, tx.Rebind("UPDATE budget_lines SET deleted_at = ? WHERE id = ?"), trashTimestamp(), id); err != nil {
 		return fmt.Errorf("failed to delete budget line with ID %d: %w", id, err)
 	}
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected after deleting budget line ID %d: %w", id, err)
-	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("no budget line found with ID %d to delete", id)
+	monthID := int64(before.MonthID)
+	if err := recordAudit(ctx, tx, AuditEntityBudgetLine, id, AuditActionDelete, &monthID, before, nil); err != nil {
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -1,14 +1,30 @@
 package store
 
 import (
+	"context"
 	"database/sql" // For sql.ErrNoRows
+	"errors"
 	"fmt"
 	"log"
+
+	"github.com/jmoiron/sqlx"
 )
 
-func (s *sqlStore) GetAllCategories() ([]Category, error) {
+// ErrCategoryInUse is returned when deleting a category that budget lines still reference.
+var ErrCategoryInUse = errors.New("category is used by budget lines")
+
+// ErrCategoryDeleted is returned when adding or restoring a budget line whose
+// category is in the trash.
+var ErrCategoryDeleted = errors.New("category is in the trash")
+
+// ErrCategoryNameInTrash is returned when creating or renaming a category to a
+// name still held by a category in the trash; that one has to be restored
+// instead.
+var ErrCategoryNameInTrash = errors.New("a category with this name is in the trash")
+
+func (s *sqlStore) GetAllCategories(ctx context.Context) ([]Category, error) {
 	var categories []Category
-	err := s.DB.Select(&categories, "SELECT id, name, color FROM categories ORDER BY name ASC")
+	err := s.DB.SelectContext(ctx, &categories, "SELECT id, name, color FROM categories WHERE deleted_at IS NULL ORDER BY name ASC")
 	if err != nil {
 		log.Printf("Error getting all categories: %v", err)
 		return nil, err
@@ -19,32 +35,57 @@ func (s *sqlStore) GetAllCategories() ([]Category, error) {
 	return categories, nil
 }
 
-func (s *sqlStore) CreateCategory(category *Category) error {
+func (s *sqlStore) CreateCategory(ctx context.Context, category *Category) error {
 	if category.Name == "" {
 		return fmt.Errorf("category name cannot be empty")
 	}
 	if category.Color == "" {
 		return fmt.Errorf("category color cannot be empty")
 	}
-	query := `INSERT INTO categories (name, color) VALUES (?, ?)`
-	res, err := s.DB.Exec(query, category.Name, category.Color)
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCategoryNameInTrash(ctx, tx, category.Name); err != nil {
+		return err
+	}
+	query := `INSERT INTO categories (name, color) VALUES (?, ?) RETURNING id`
+	var id int64
+	err = tx.GetContext(ctx, &id, tx.Rebind(query), category.Name, category.Color)
 	if err != nil {
 		log.Printf("Error creating category '%s': %v", category.Name, err)
 		return fmt.Errorf("failed to insert category: %w", err)
 	}
-	id, err := res.LastInsertId()
-	if err != nil {
-		log.Printf("Error getting last insert ID for category '%s': %v", category.Name, err)
-		return fmt.Errorf("failed to get last insert ID: %w", err)
+	created := Category{ID: id, Name: category.Name, Color: category.Color}
+	if err := recordAudit(ctx, tx, AuditEntityCategory, id, AuditActionCreate, nil, nil, created); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit category: %w", err)
 	}
 	category.ID = id
 	log.Printf("Successfully created category '%s' with ID %d", category.Name, category.ID)
 	return nil
 }
 
-func (s *sqlStore) GetCategoryByID(id int64) (*Category, error) {
+// checkCategoryNameInTrash returns ErrCategoryNameInTrash when a category in
+// the trash holds name, which would otherwise fail the unique constraint.
+func checkCategoryNameInTrash(ctx context.Context, tx *sqlx.Tx, name string) error {
+	var trashed bool
+	if err := tx.GetContext(ctx, &trashed, tx.Rebind(`SELECT EXISTS (SELECT 1 FROM categories WHERE name = ? AND deleted_at IS NOT NULL)`), name); err != nil {
+		return fmt.Errorf("failed to check the trash for category %q: %w", name, err)
+	}
+	if trashed {
+		return fmt.Errorf("category %q: %w", name, ErrCategoryNameInTrash)
+	}
+	return nil
+}
+
+func (s *sqlStore) GetCategoryByID(ctx context.Context, id int64) (*Category, error) {
 	var category Category
-	err := s.DB.Get(&category, "SELECT id, name, color FROM categories WHERE id = ?", id)
+	err := s.DB.GetContext(ctx, &category, s.DB.Rebind("SELECT id, name, color FROM categories WHERE id = ? AND deleted_at IS NULL"), id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("Category with ID %d not found: %v", id, err)
@@ -56,7 +97,7 @@ func (s *sqlStore) GetCategoryByID(id int64) (*Category, error) {
 	return &category, nil
 }
 
-func (s *sqlStore) UpdateCategory(category *Category) error {
+func (s *sqlStore) UpdateCategory(ctx context.Context, category *Category) error {
 	if category.ID == 0 {
 		return fmt.Errorf("category ID cannot be zero for update")
 	}
@@ -67,46 +108,84 @@ func (s *sqlStore) UpdateCategory(category *Category) error {
 		return fmt.Errorf("category color cannot be empty for update")
 	}
 
-	query := `UPDATE categories SET name = ?, color = ? WHERE id = ?`
-	res, err := s.DB.Exec(query, category.Name, category.Color, category.ID)
+	tx, err := s.DB.BeginTxx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before Category
+	err = tx.GetContext(ctx, &before, tx.Rebind("SELECT id, name, color FROM categories WHERE id = ? AND deleted_at IS NULL"), category.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("No category found with ID %d to update.", category.ID)
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to get category %d before update: %w", category.ID, err)
+	}
+	if category.Name != before.Name {
+		if err := checkCategoryNameInTrash(ctx, tx, category.Name); err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE categories SET name = ?, color = ? WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), category.Name, category.Color, category.ID); err != nil {
 		log.Printf("Error updating category ID %d: %v", category.ID, err)
 		return fmt.Errorf("failed to update category: %w", err)
 	}
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		log.Printf("Error getting rows affected for update category ID %d: %v", category.ID, err)
-		return fmt.Errorf("failed to get rows affected: %w", err)
+	after := Category{ID: category.ID, Name: category.Name, Color: category.Color}
+	if err := recordAudit(ctx, tx, AuditEntityCategory, category.ID, AuditActionUpdate, nil, before, after); err != nil {
+		return err
 	}
-	if rowsAffected == 0 {
-		log.Printf("No category found with ID %d to update, or data was the same.", category.ID)
-		return sql.ErrNoRows
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit category update: %w", err)
 	}
 	log.Printf("Successfully updated category ID %d", category.ID)
 	return nil
 }
 
-func (s *sqlStore) DeleteCategory(id int64) error {
+func (s *sqlStore) DeleteCategory(ctx context.Context, id int64) error {
 	if id == 0 {
 		return fmt.Errorf("category ID cannot be zero for delete")
 	}
 
-	query := `DELETE FROM categories WHERE id = ?`
-	res, err := s.DB.Exec(query, id)
+	tx, err := s.DB.BeginTxx(ctx, nil)
 	if err != nil {
-		log.Printf("Error deleting category ID %d: %v", id, err)
-		return fmt.Errorf("failed to delete category: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := res.RowsAffected()
+	var before Category
+	err = tx.GetContext(ctx, &before, tx.Rebind("SELECT id, name, color FROM categories WHERE id = ? AND deleted_at IS NULL"), id)
 	if err != nil {
-		log.Printf("Error getting rows affected for delete category ID %d: %v", id, err)
-		return fmt.Errorf("failed to get rows affected on delete: %w", err)
+		if err == sql.ErrNoRows {
+			log.Printf("No category found with ID %d to delete.", id)
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to get category %d before delete: %w", id, err)
+	}
+
+	var inUse bool
+	err = tx.GetContext(ctx, &inUse, tx.Rebind(`SELECT EXISTS (SELECT 1 FROM budget_lines WHERE category_id = ? AND deleted_at IS NULL)`), id)
+	if err != nil {
+		return fmt.Errorf("failed to check budget lines of category %d: %w", id, err)
+	}
+	if inUse {
+		return ErrCategoryInUse
 	}
 
-	if rowsAffected == 0 {
-		log.Printf("No category found with ID %d to delete.", id)
-		return sql.ErrNoRows
+	// The category goes to the trash; PurgeTrash deletes it for good.
+	query := `UPDATE categories SET deleted_at = ? WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, tx.Rebind(query), trashTimestamp(), id); err != nil {
+		log.Printf("Error deleting category ID %d: %v", id, err)
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+	if err := recordAudit(ctx, tx, AuditEntityCategory, id, AuditActionDelete, nil, before, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit category delete: %w", err)
 	}
 
 	log.Printf("Successfully deleted category ID %d", id)
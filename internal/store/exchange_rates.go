@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrNoExchangeRate is returned when an amount is kept in a currency that has no
+// rate in effect for its month.
+var ErrNoExchangeRate = errors.New("no exchange rate in effect")
+
+// Validate checks that the rate converts a foreign currency from a valid month.
+func (r ExchangeRate) Validate() error {
+	if _, err := ParseCurrency(string(r.Currency)); err != nil {
+		return err
+	}
+	if r.Currency == BudgetCurrency() {
+		return fmt.Errorf("%s is the budget currency and needs no exchange rate", r.Currency)
+	}
+	if r.Year < 1 || r.Month < 1 || r.Month > 12 {
+		return fmt.Errorf("invalid month %d-%02d for the %s exchange rate", r.Year, r.Month, r.Currency)
+	}
+	if r.Rate <= 0 {
+		return fmt.Errorf("exchange rate for %s in %d-%02d must be positive, got %g", r.Currency, r.Year, r.Month, r.Rate)
+	}
+	return nil
+}
+
+func (s *sqlStore) GetExchangeRates(ctx context.Context) ([]ExchangeRate, error) {
+	rates := []ExchangeRate{}
+	err := s.DB.SelectContext(ctx, &rates, `SELECT id, currency, year, month, rate FROM exchange_rates ORDER BY currency, year, month`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange rates: %w", err)
+	}
+	return rates, nil
+}
+
+// SetExchangeRates adds the rates in a single transaction, replacing the rate a
+// currency already has for the same month.
+func (s *sqlStore) SetExchangeRates(ctx context.Context, rates []ExchangeRate) error {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, r := range rates {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, tx.Rebind(`
+			INSERT INTO exchange_rates (currency, year, month, rate) VALUES (?, ?, ?, ?)
+			ON CONFLICT (currency, year, month) DO UPDATE SET rate = excluded.rate`),
+			r.Currency, r.Year, r.Month, r.Rate)
+		if err != nil {
+			return fmt.Errorf("failed to save %s exchange rate for %d-%02d: %w", r.Currency, r.Year, r.Month, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit exchange rates: %w", err)
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteExchangeRate(ctx context.Context, id int64) error {
+	res, err := s.DB.ExecContext(ctx, s.DB.Rebind(`DELETE FROM exchange_rates WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete exchange rate %d: %w", id, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetExchangeRatesForMonth returns, per currency, the latest rate set for the
+// month or an earlier one.
+func (s *sqlStore) GetExchangeRatesForMonth(ctx context.Context, monthID int) (map[Currency]float64, error) {
+	var rows []ExchangeRate
+	err := s.DB.SelectContext(ctx, &rows, s.DB.Rebind(`
+		SELECT r.currency, r.rate
+		FROM exchange_rates r
+		JOIN months m ON m.id = ?
+		WHERE r.year * 12 + r.month = (
+			SELECT MAX(latest.year * 12 + latest.month)
+			FROM exchange_rates latest
+			WHERE latest.currency = r.currency
+			  AND latest.year * 12 + latest.month <= m.year * 12 + m.month
+		)`), monthID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange rates for month %d: %w", monthID, err)
+	}
+	rates := make(map[Currency]float64, len(rows))
+	for _, r := range rows {
+		rates[r.Currency] = r.Rate
+	}
+	return rates, nil
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreBackends_ExchangeRates(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, err := s.ImportAll(ctx, &DatabaseDump{
+				Months: []Month{{ID: 1, Year: 2024, Month: 3}, {ID: 2, Year: 2024, Month: 1}},
+			}, ImportModeReplace, false)
+			require.NoError(t, err)
+			march, january := 1, 2
+
+			require.NoError(t, s.SetExchangeRates(ctx, []ExchangeRate{
+				{Currency: "EUR", Year: 2024, Month: 1, Rate: 1.09},
+				{Currency: "EUR", Year: 2024, Month: 2, Rate: 1.08},
+				{Currency: "EUR", Year: 2024, Month: 4, Rate: 1.07},
+				{Currency: "GBP", Year: 2024, Month: 3, Rate: 1.27},
+			}))
+			require.NoError(t, s.SetExchangeRates(ctx, []ExchangeRate{{Currency: "EUR", Year: 2024, Month: 2, Rate: 1.085}}))
+
+			rates, err := s.GetExchangeRates(ctx)
+			require.NoError(t, err)
+			assert.Len(t, rates, 4, "a rate for the same month replaces the old one")
+
+			inEffect, err := s.GetExchangeRatesForMonth(ctx, march)
+			require.NoError(t, err)
+			assert.Equal(t, map[Currency]float64{"EUR": 1.085, "GBP": 1.27}, inEffect)
+
+			inEffect, err = s.GetExchangeRatesForMonth(ctx, january)
+			require.NoError(t, err)
+			assert.Equal(t, map[Currency]float64{"EUR": 1.09}, inEffect, "later rates do not apply to earlier months")
+
+			err = s.SetExchangeRates(ctx, []ExchangeRate{
+				{Currency: "CHF", Year: 2024, Month: 1, Rate: 1.1},
+				{Currency: "USD", Year: 2024, Month: 1, Rate: 1},
+			})
+			assert.Error(t, err, "the budget currency has no rate")
+			assert.Equal(t, 4, countRows(t, s, "exchange_rates"), "a rejected batch saves nothing")
+
+			require.NoError(t, s.DeleteExchangeRate(ctx, rates[0].ID))
+			assert.ErrorIs(t, s.DeleteExchangeRate(ctx, rates[0].ID), sql.ErrNoRows)
+		})
+	}
+}
+
+func TestStoreBackends_BudgetLineCurrency(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+
+			hotelID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Hotel", Expected: 200_00, Currency: "EUR"})
+			require.NoError(t, err)
+			_, err = s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Taxi", Expected: 30_00})
+			require.NoError(t, err)
+
+			lines, err := s.GetBudgetLinesByMonthID(ctx, 1)
+			require.NoError(t, err)
+			require.Len(t, lines, 2)
+			assert.Equal(t, Currency("EUR"), lines[0].Currency)
+			assert.Equal(t, Currency("EUR"), *lines[0].ActualCurrency, "the actual starts in the line's currency")
+			assert.Equal(t, Currency("USD"), lines[1].Currency, "lines default to the budget currency")
+
+			actual, err := s.GetActualLineByID(ctx, *lines[0].ActualID)
+			require.NoError(t, err)
+			actual.Actual, actual.Currency = 180_00, "GBP"
+			require.NoError(t, s.UpdateActualLine(ctx, actual))
+
+			board, err := s.GetBoardData(ctx, 1)
+			require.NoError(t, err)
+			hotel := board.BudgetLines[0]
+			assert.Equal(t, hotelID, hotel.ID)
+			assert.Equal(t, Currency("EUR"), hotel.Currency)
+			assert.Equal(t, Currency("GBP"), hotel.ActualCurrency)
+
+			err = s.UpdateActualLine(ctx, &ActualLine{ID: actual.ID, Actual: 180_00, Currency: "EUR"})
+			assert.ErrorIs(t, err, ErrActualCurrencyInUse, "the 180 GBP are booked as a transaction now")
+			nextMonthID, err := s.FinalizeMonth(ctx, 1, "{}")
+			require.NoError(t, err)
+			cloned, err := s.GetBudgetLinesByMonthID(ctx, int(nextMonthID))
+			require.NoError(t, err)
+			require.Len(t, cloned, 2)
+			assert.Equal(t, "Hotel", cloned[0].Label)
+			assert.Equal(t, Currency("EUR"), cloned[0].Currency, "the next month keeps the line's currency")
+			require.NotNil(t, cloned[0].ActualCurrency)
+			assert.Equal(t, Currency("EUR"), *cloned[0].ActualCurrency)
+		})
+	}
+}
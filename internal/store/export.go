@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ExportSink receives the full database contents from ExportAll one row at a
+// time, so callers can stream them out without holding every table in memory.
+type ExportSink interface {
+	BeginTable(name string) error
+	WriteRow(row interface{}) error
+	EndTable() error
+}
+
+type exportTable struct {
+	name   string
+	query  string
+	newRow func() interface{}
+}
+
+// exportTables lists the tables in dependency order: a table only references
+// tables that appear before it.
+var exportTables = []exportTable{
+	{"months", `SELECT id, year, month, finalized FROM months ORDER BY id`, func() interface{} { return &Month{} }},
+	{"categories", `SELECT id, name, color, deleted_at FROM categories ORDER BY id`, func() interface{} { return &Category{} }},
+	{"budget_lines", `SELECT id, month_id, category_id, label, expected, currency, deleted_at FROM budget_lines ORDER BY id`, func() interface{} { return &BudgetLine{} }},
+	{"actual_lines", `SELECT id, budget_line_id, actual, currency FROM actual_lines ORDER BY id`, func() interface{} { return &ActualLine{} }},
+	{"transactions", `
+		SELECT t.id, t.budget_line_id, t.date, t.amount, ` + transactionCurrencySQL + ` AS currency, t.payee, t.memo
+		FROM transactions t JOIN budget_lines bl ON bl.id = t.budget_line_id ORDER BY t.id`, func() interface{} { return &Transaction{} }},
+	{"annual_snaps", `SELECT id, month_id, version, snap_json, created_at FROM annual_snaps ORDER BY id`, func() interface{} { return &AnnualSnap{} }},
+	{"exchange_rates", `SELECT id, currency, year, month, rate FROM exchange_rates ORDER BY id`, func() interface{} { return &ExchangeRate{} }},
+	{"match_rules", `SELECT id, pattern, category_id, label, amount FROM match_rules ORDER BY id`, func() interface{} { return &MatchRule{} }},
+	{"bank_transactions", `
+		SELECT id, external_id, posted_on, amount, description, month_id, budget_line_id, match_reason, status, imported_at
+		FROM bank_transactions ORDER BY id`, func() interface{} { return &BankTransaction{} }},
+}
+
+func (s *sqlStore) ExportAll(ctx context.Context, sink ExportSink) error {
+	tx, err := s.DB.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin export transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range exportTables {
+		if err := sink.BeginTable(table.name); err != nil {
+			return fmt.Errorf("failed to begin export of table %s: %w", table.name, err)
+		}
+
+		rows, err := tx.QueryxContext(ctx, table.query)
+		if err != nil {
+			return fmt.Errorf("failed to query table %s for export: %w", table.name, err)
+		}
+		for rows.Next() {
+			row := table.newRow()
+			if err := rows.StructScan(row); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan row of table %s for export: %w", table.name, err)
+			}
+			if err := sink.WriteRow(row); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to write row of table %s: %w", table.name, err)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to iterate table %s for export: %w", table.name, err)
+		}
+		rows.Close()
+
+		if err := sink.EndTable(); err != nil {
+			return fmt.Errorf("failed to end export of table %s: %w", table.name, err)
+		}
+	}
+	return nil
+}
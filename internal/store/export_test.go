@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type recordingSink struct {
+	tables []string
+	rows   map[string][]interface{}
+}
+
+func (r *recordingSink) BeginTable(name string) error {
+	r.tables = append(r.tables, name)
+	return nil
+}
+
+func (r *recordingSink) WriteRow(row interface{}) error {
+	table := r.tables[len(r.tables)-1]
+	r.rows[table] = append(r.rows[table], row)
+	return nil
+}
+
+func (r *recordingSink) EndTable() error { return nil }
+
+func TestStoreBackends_ExportAll(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			catID, monthID, blID, alID := int64(1), int64(1), int64(1), int64(1)
+			_, err := s.ImportAll(context.Background(), &DatabaseDump{
+				Months:       []Month{{ID: monthID, Year: 2024, Month: 3, Finalized: true}},
+				Categories:   []Category{{ID: catID, Name: "Food", Color: "bg-red-500"}},
+				BudgetLines:  []BudgetLine{{ID: int(blID), MonthID: int(monthID), CategoryID: int(catID), Label: "Groceries", Expected: 250_50, Currency: "USD"}},
+				ActualLines:  []ActualLine{{ID: alID, BudgetLineID: blID, Actual: 240_25, Currency: "USD"}},
+				Transactions: []Transaction{{ID: 1, BudgetLineID: blID, Date: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), Amount: 240_25}},
+				AnnualSnaps:  []AnnualSnap{{ID: 1, MonthID: monthID, SnapJSON: `{"total":1}`, CreatedAt: "2024-04-01 10:00:00"}},
+			}, ImportModeReplace, false)
+			if err != nil {
+				t.Fatalf("Failed to seed the store: %v", err)
+			}
+
+			sink := &recordingSink{rows: map[string][]interface{}{}}
+			if err := s.ExportAll(context.Background(), sink); err != nil {
+				t.Fatalf("ExportAll() failed: %v", err)
+			}
+
+			wantTables := []string{"months", "categories", "budget_lines", "actual_lines", "transactions", "annual_snaps", "exchange_rates", "match_rules", "bank_transactions"}
+			if !reflect.DeepEqual(sink.tables, wantTables) {
+				t.Fatalf("Exported tables = %v, want %v", sink.tables, wantTables)
+			}
+
+			if got := sink.rows["months"]; len(got) != 1 || !reflect.DeepEqual(got[0], &Month{ID: monthID, Year: 2024, Month: 3, Finalized: true}) {
+				t.Errorf("Unexpected months export: %+v", got)
+			}
+			if got := sink.rows["categories"]; len(got) != 1 || !reflect.DeepEqual(got[0], &Category{ID: catID, Name: "Food", Color: "bg-red-500"}) {
+				t.Errorf("Unexpected categories export: %+v", got)
+			}
+			wantLine := &BudgetLine{ID: int(blID), MonthID: int(monthID), CategoryID: int(catID), Label: "Groceries", Expected: 250_50, Currency: "USD"}
+			if got := sink.rows["budget_lines"]; len(got) != 1 || !reflect.DeepEqual(got[0], wantLine) {
+				t.Errorf("Unexpected budget_lines export: %+v", got)
+			}
+			if got := sink.rows["actual_lines"]; len(got) != 1 || !reflect.DeepEqual(got[0], &ActualLine{ID: alID, BudgetLineID: blID, Actual: 240_25, Currency: "USD"}) {
+				t.Errorf("Unexpected actual_lines export: %+v", got)
+			}
+			snaps := sink.rows["annual_snaps"]
+			if len(snaps) != 1 {
+				t.Fatalf("Expected 1 annual snap, got %d", len(snaps))
+			}
+			if snap := snaps[0].(*AnnualSnap); snap.MonthID != monthID || snap.SnapJSON != `{"total":1}` || snap.CreatedAt == "" {
+				t.Errorf("Unexpected annual_snaps export: %+v", snap)
+			}
+		})
+	}
+}
+
+func TestStoreBackends_ExportAllEmptyDatabase(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			sink := &recordingSink{rows: map[string][]interface{}{}}
+			if err := s.ExportAll(context.Background(), sink); err != nil {
+				t.Fatalf("ExportAll() failed: %v", err)
+			}
+			if len(sink.tables) != 9 {
+				t.Errorf("Expected every table to be announced even when empty, got %v", sink.tables)
+			}
+			if len(sink.rows) != 0 {
+				t.Errorf("Expected no rows, got %+v", sink.rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrIdempotencyKeyReused is returned when an idempotency key that finalized
+// one month is sent to finalize another.
+var ErrIdempotencyKeyReused = errors.New("idempotency key was used to finalize another month")
+
+type idempotencyKeyKey struct{}
+
+// WithIdempotencyKey returns a context under which FinalizeMonth records its
+// result under key, and answers a repeated call with the same key with that
+// result instead of finalizing again.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+// IdempotencyKey returns the key set by WithIdempotencyKey, or "".
+func IdempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyKey{}).(string)
+	return key
+}
+
+const finalizeRequestQuery = `SELECT idempotency_key, month_id, next_month_id, created_at FROM finalize_requests WHERE idempotency_key = ?`
+
+// GetFinalizeRequest returns what finalizing returned for key. It returns
+// sql.ErrNoRows when no finalize request was made with the key.
+func (s *sqlStore) GetFinalizeRequest(ctx context.Context, key string) (*FinalizeRequest, error) {
+	var req FinalizeRequest
+	err := s.DB.GetContext(ctx, &req, s.DB.Rebind(finalizeRequestQuery), key)
+	if err == sql.ErrNoRows {
+		return nil, sql.ErrNoRows
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get finalize request %q: %w", key, err)
+	}
+	req.CreatedAt = req.CreatedAt.UTC()
+	return &req, nil
+}
+
+// priorFinalizeResult looks up an earlier finalize of monthID made with the
+// key on ctx. found is false when there is no key or it has not been used.
+func priorFinalizeResult(ctx context.Context, tx *sqlx.Tx, monthID int64) (nextMonthID int64, found bool, err error) {
+	key := IdempotencyKey(ctx)
+	if key == "" {
+		return 0, false, nil
+	}
+	var req FinalizeRequest
+	err = tx.GetContext(ctx, &req, tx.Rebind(finalizeRequestQuery), key)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get finalize request %q: %w", key, err)
+	}
+	if req.MonthID != monthID {
+		return 0, false, fmt.Errorf("key %q finalized month %d: %w", key, req.MonthID, ErrIdempotencyKeyReused)
+	}
+	return req.NextMonthID, true, nil
+}
+
+// recordFinalizeRequest stores the result of finalizing monthID under the key
+// on ctx, if there is one.
+func recordFinalizeRequest(ctx context.Context, tx *sqlx.Tx, monthID, nextMonthID int64) error {
+	key := IdempotencyKey(ctx)
+	if key == "" {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, tx.Rebind(`
+		INSERT INTO finalize_requests (idempotency_key, month_id, next_month_id, created_at)
+		VALUES (?, ?, ?, ?)`), key, monthID, nextMonthID, time.Now().UTC().Format(timestampLayout))
+	if err != nil {
+		return fmt.Errorf("failed to record finalize request %q: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type finalizedOverrideKey struct{}
+
+// WithFinalizedOverride returns a context under which the store lets the
+// budget lines, actuals and transactions of finalized months be changed. It is
+// meant for deliberate corrections; each one is audited as an override of the
+// month next to the change itself, and the month's snapshots are left as they
+// were.
+func WithFinalizedOverride(ctx context.Context) context.Context {
+	return context.WithValue(ctx, finalizedOverrideKey{}, true)
+}
+
+// FinalizedOverride reports whether ctx allows changes to finalized months.
+func FinalizedOverride(ctx context.Context) bool {
+	allowed, _ := ctx.Value(finalizedOverrideKey{}).(bool)
+	return allowed
+}
+
+// checkMonthEditable returns ErrMonthFinalized when the month is finalized,
+// unless ctx carries the override, which is then recorded in the audit log. A
+// month that does not exist is left for the caller's own query to report.
+func checkMonthEditable(ctx context.Context, tx *sqlx.Tx, monthID int64) error {
+	var finalized bool
+	err := tx.GetContext(ctx, &finalized, tx.Rebind(`SELECT finalized FROM months WHERE id = ?`), monthID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check month %d: %w", monthID, err)
+	}
+	if !finalized {
+		return nil
+	}
+	if FinalizedOverride(ctx) {
+		return recordAudit(ctx, tx, AuditEntityMonth, monthID, AuditActionOverride, &monthID, nil, nil)
+	}
+	return fmt.Errorf("month %d: %w", monthID, ErrMonthFinalized)
+}
+
+// checkLineEditable is checkMonthEditable for the month of a budget line.
+func checkLineEditable(ctx context.Context, tx *sqlx.Tx, budgetLineID int64) error {
+	var monthID int64
+	err := tx.GetContext(ctx, &monthID, tx.Rebind(`SELECT month_id FROM budget_lines WHERE id = ?`), budgetLineID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get month of budget line %d: %w", budgetLineID, err)
+	}
+	return checkMonthEditable(ctx, tx, monthID)
+}
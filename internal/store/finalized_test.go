@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreBackends_FinalizedMonthIsReadOnly(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			groceriesID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00})
+			require.NoError(t, err)
+			powerID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 2, Label: "Power", Expected: 80_00})
+			require.NoError(t, err)
+			marketID, err := s.CreateTransaction(ctx, &Transaction{BudgetLineID: groceriesID, Date: time.Date(2024, time.March, 12, 0, 0, 0, 0, time.UTC), Amount: 45_25})
+			require.NoError(t, err)
+			require.NoError(t, s.DeleteBudgetLine(ctx, powerID))
+			bank := []BankTransaction{{ExternalID: "ofx:1:a", PostedOn: time.Date(2024, time.March, 14, 0, 0, 0, 0, time.UTC), Amount: 4_75, Description: "Kiosk"}}
+			_, err = s.AddBankTransactions(ctx, bank)
+			require.NoError(t, err)
+			confirm := []BankConfirmation{{TransactionID: bank[0].ID, BudgetLineID: groceriesID}}
+			lines, err := s.GetBudgetLinesByMonthID(ctx, 1)
+			require.NoError(t, err)
+			require.Len(t, lines, 1)
+			actualID := *lines[0].ActualID
+			aprilID, err := s.FinalizeMonth(ctx, 1, `{"month_id":1}`)
+			require.NoError(t, err)
+			entries, err := s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+
+			_, err = s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Snacks"})
+			assert.ErrorIs(t, err, ErrMonthFinalized)
+			assert.ErrorIs(t, s.UpdateBudgetLine(ctx, &BudgetLine{ID: int(groceriesID), Label: "Food", Expected: 1_00}), ErrMonthFinalized)
+			assert.ErrorIs(t, s.UpdateActualLine(ctx, &ActualLine{ID: actualID, Actual: 99_00}), ErrMonthFinalized)
+			assert.ErrorIs(t, s.DeleteBudgetLine(ctx, groceriesID), ErrMonthFinalized)
+			assert.ErrorIs(t, s.RestoreBudgetLine(ctx, powerID), ErrMonthFinalized)
+			_, err = s.CreateTransaction(ctx, &Transaction{BudgetLineID: groceriesID, Date: time.Date(2024, time.March, 20, 0, 0, 0, 0, time.UTC), Amount: 5_00})
+			assert.ErrorIs(t, err, ErrMonthFinalized)
+			assert.ErrorIs(t, s.UpdateTransaction(ctx, &Transaction{ID: marketID, Date: time.Date(2024, time.March, 12, 0, 0, 0, 0, time.UTC), Amount: 1_00}), ErrMonthFinalized)
+			assert.ErrorIs(t, s.DeleteTransaction(ctx, marketID), ErrMonthFinalized)
+			assert.ErrorIs(t, s.ConfirmBankTransactions(ctx, confirm), ErrMonthFinalized)
+
+			lines, err = s.GetBudgetLinesByMonthID(ctx, 1)
+			require.NoError(t, err)
+			require.Len(t, lines, 1)
+			assert.Equal(t, "Groceries", lines[0].Label)
+			assert.Equal(t, Money(45_25), *lines[0].ActualAmount)
+			unchanged, err := s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+			assert.Len(t, unchanged, len(entries), "rejected changes are not logged")
+
+			aprilLines, err := s.GetBudgetLinesByMonthID(ctx, int(aprilID))
+			require.NoError(t, err)
+			require.Len(t, aprilLines, 1)
+			require.NoError(t, s.UpdateBudgetLine(ctx, &BudgetLine{ID: aprilLines[0].ID, Label: "Food", Expected: 420_00}), "the open month stays editable")
+
+			override := WithFinalizedOverride(ctx)
+			require.NoError(t, s.UpdateActualLine(override, &ActualLine{ID: actualID, Actual: 50_00}))
+			require.NoError(t, s.ConfirmBankTransactions(override, confirm))
+			lines, err = s.GetBudgetLinesByMonthID(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, Money(54_75), *lines[0].ActualAmount)
+			corrections, err := s.GetAuditEntries(ctx, AuditFilter{Limit: 1})
+			require.NoError(t, err)
+			require.Len(t, corrections, 1)
+			assert.Equal(t, AuditEntityActualLine, corrections[0].Entity, "an override is audited like any change")
+			snaps, err := s.GetMonthSnapshots(ctx, 1)
+			require.NoError(t, err)
+			assert.Len(t, snaps, 1, "an override leaves the snapshots alone")
+
+			require.NoError(t, s.ReopenMonth(ctx, 1))
+			require.NoError(t, s.RestoreBudgetLine(ctx, powerID), "a reopened month is editable again")
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrDatabaseNotEmpty is returned by a merge import when the target database already holds data.
+var ErrDatabaseNotEmpty = errors.New("database is not empty")
+
+// ImportAll loads a dump into the database in a single transaction, keeping the
+// dump's IDs. Replace mode wipes the existing rows first. Merge mode only loads
+// into a database without budget data; the empty months it may hold (such as the
+// one seeded on first start) are kept unless they clash with a month of the dump.
+// Replace mode also clears the audit log, whose history belonged to the rows it
+// wipes; either mode then records the import as the first entry of what
+// follows. A dry run performs every statement and then rolls back.
+func (s *sqlStore) ImportAll(ctx context.Context, dump *DatabaseDump, mode ImportMode, dryRun bool) (*ImportReport, error) {
+	if mode != ImportModeReplace && mode != ImportModeMerge {
+		return nil, fmt.Errorf("unknown import mode %q", mode)
+	}
+
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin import transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	existing, err := countTables(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Mode: mode, DryRun: dryRun}
+	if mode == ImportModeMerge {
+		if existing.Categories+existing.BudgetLines+existing.ActualLines+existing.Transactions+existing.AnnualSnaps+existing.ExchangeRates+
+			existing.MatchRules+existing.BankTransactions > 0 {
+			return nil, ErrDatabaseNotEmpty
+		}
+		for _, m := range dump.Months {
+			res, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM months WHERE id = ? OR (year = ? AND month = ?)`), m.ID, m.Year, m.Month)
+			if err != nil {
+				return nil, fmt.Errorf("failed to clear empty month clashing with %d-%02d: %w", m.Year, m.Month, err)
+			}
+			deleted, err := res.RowsAffected()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get rows affected clearing month %d-%02d: %w", m.Year, m.Month, err)
+			}
+			report.Deleted.Months += int(deleted)
+		}
+	} else {
+		// Children first, so foreign keys hold at every step when they are enforced.
+		for _, table := range []string{"audit_log", "finalize_requests", "bank_transactions", "match_rules", "exchange_rates", "annual_snaps", "transactions", "actual_lines", "budget_lines", "categories", "months"} {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+				return nil, fmt.Errorf("failed to clear table %s: %w", table, err)
+			}
+		}
+		report.Deleted = existing
+	}
+
+	for _, m := range dump.Months {
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO months (id, year, month, finalized) VALUES (?, ?, ?, ?)`),
+			m.ID, m.Year, m.Month, m.Finalized); err != nil {
+			return nil, fmt.Errorf("failed to import month %d: %w", m.ID, err)
+		}
+	}
+	for _, c := range dump.Categories {
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO categories (id, name, color, deleted_at) VALUES (?, ?, ?, ?)`),
+			c.ID, c.Name, c.Color, nullableTimestamp(c.DeletedAt)); err != nil {
+			return nil, fmt.Errorf("failed to import category %d (%s): %w", c.ID, c.Name, err)
+		}
+	}
+	// Backups from before multi-currency support have no currency codes; their
+	// amounts are in the budget currency.
+	for _, b := range dump.BudgetLines {
+		if b.Currency == "" {
+			b.Currency = BudgetCurrency()
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO budget_lines (id, month_id, category_id, label, expected, currency, deleted_at) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+			b.ID, b.MonthID, b.CategoryID, b.Label, b.Expected, b.Currency, nullableTimestamp(b.DeletedAt)); err != nil {
+			return nil, fmt.Errorf("failed to import budget line %d (%s): %w", b.ID, b.Label, err)
+		}
+	}
+	for _, a := range dump.ActualLines {
+		if a.Currency == "" {
+			a.Currency = BudgetCurrency()
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO actual_lines (id, budget_line_id, actual, currency) VALUES (?, ?, ?, ?)`),
+			a.ID, a.BudgetLineID, a.Actual, a.Currency); err != nil {
+			return nil, fmt.Errorf("failed to import actual line %d: %w", a.ID, err)
+		}
+	}
+	for _, t := range dump.Transactions {
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO transactions (id, budget_line_id, date, amount, payee, memo) VALUES (?, ?, ?, ?, ?, ?)`),
+			t.ID, t.BudgetLineID, t.Date.Format(transactionDateLayout), t.Amount, t.Payee, t.Memo); err != nil {
+			return nil, fmt.Errorf("failed to import transaction %d: %w", t.ID, err)
+		}
+	}
+	// Backups from before transactions only hold each line's actual total.
+	opened, err := recordOpeningTransactions(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record opening transactions: %w", err)
+	}
+	for _, snap := range dump.AnnualSnaps {
+		if snap.Version == 0 {
+			snap.Version = 1
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO annual_snaps (id, month_id, version, snap_json, created_at) VALUES (?, ?, ?, ?, ?)`),
+			snap.ID, snap.MonthID, snap.Version, snap.SnapJSON, snap.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to import annual snap %d: %w", snap.ID, err)
+		}
+	}
+	for _, r := range dump.ExchangeRates {
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO exchange_rates (id, currency, year, month, rate) VALUES (?, ?, ?, ?, ?)`),
+			r.ID, r.Currency, r.Year, r.Month, r.Rate); err != nil {
+			return nil, fmt.Errorf("failed to import exchange rate %d (%s): %w", r.ID, r.Currency, err)
+		}
+	}
+	for _, r := range dump.MatchRules {
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`INSERT INTO match_rules (id, pattern, category_id, label, amount) VALUES (?, ?, ?, ?, ?)`),
+			r.ID, r.Pattern, r.CategoryID, r.Label, r.Amount); err != nil {
+			return nil, fmt.Errorf("failed to import match rule %d (%s): %w", r.ID, r.Pattern, err)
+		}
+	}
+	for _, t := range dump.BankTransactions {
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`
+			INSERT INTO bank_transactions
+				(id, external_id, posted_on, amount, description, month_id, budget_line_id, match_reason, status, imported_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			t.ID, t.ExternalID, t.PostedOn.Format(transactionDateLayout), t.Amount, t.Description,
+			t.MonthID, t.BudgetLineID, t.MatchReason, t.Status, t.ImportedAt.UTC().Format(timestampLayout)); err != nil {
+			return nil, fmt.Errorf("failed to import bank transaction %d (%s): %w", t.ID, t.ExternalID, err)
+		}
+	}
+	report.Inserted = ImportTableCounts{
+		Months:           len(dump.Months),
+		Categories:       len(dump.Categories),
+		BudgetLines:      len(dump.BudgetLines),
+		ActualLines:      len(dump.ActualLines),
+		Transactions:     len(dump.Transactions) + opened,
+		AnnualSnaps:      len(dump.AnnualSnaps),
+		ExchangeRates:    len(dump.ExchangeRates),
+		MatchRules:       len(dump.MatchRules),
+		BankTransactions: len(dump.BankTransactions),
+	}
+	if err := recordAudit(ctx, tx, AuditEntityDatabase, 0, AuditActionImport, nil, report.Deleted, report.Inserted); err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	if err := resetIDSequences(ctx, tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import transaction: %w", err)
+	}
+	return report, nil
+}
+
+func countTables(ctx context.Context, tx *sqlx.Tx) (ImportTableCounts, error) {
+	var counts ImportTableCounts
+	targets := []struct {
+		table string
+		dest  *int
+	}{
+		{"months", &counts.Months},
+		{"categories", &counts.Categories},
+		{"budget_lines", &counts.BudgetLines},
+		{"actual_lines", &counts.ActualLines},
+		{"transactions", &counts.Transactions},
+		{"annual_snaps", &counts.AnnualSnaps},
+		{"exchange_rates", &counts.ExchangeRates},
+		{"match_rules", &counts.MatchRules},
+		{"bank_transactions", &counts.BankTransactions},
+	}
+	for _, target := range targets {
+		if err := tx.GetContext(ctx, target.dest, "SELECT COUNT(*) FROM "+target.table); err != nil {
+			return counts, fmt.Errorf("failed to count rows in %s: %w", target.table, err)
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,231 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleDump() *DatabaseDump {
+	return &DatabaseDump{
+		Months:      []Month{{ID: 10, Year: 2024, Month: 1, Finalized: true}, {ID: 11, Year: 2024, Month: 2}},
+		Categories:  []Category{{ID: 5, Name: "Food", Color: "bg-red-500"}},
+		BudgetLines: []BudgetLine{{ID: 20, MonthID: 10, CategoryID: 5, Label: "Groceries", Expected: 300_00}, {ID: 21, MonthID: 11, CategoryID: 5, Label: "Groceries", Expected: 300_00}},
+		ActualLines: []ActualLine{{ID: 30, BudgetLineID: 20, Actual: 310_50}, {ID: 31, BudgetLineID: 21, Actual: 0}},
+		AnnualSnaps: []AnnualSnap{{ID: 40, MonthID: 10, SnapJSON: `{}`, CreatedAt: "2024-02-01 09:00:00"}},
+	}
+}
+
+// countRows counts a table's rows in the store's export.
+func countRows(t *testing.T, s Store, table string) int {
+	t.Helper()
+	return len(exportTablesOf(t, s)[table])
+}
+
+func TestStoreBackends_ImportAllReplace(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, err := s.ImportAll(ctx, &DatabaseDump{
+				Months:       []Month{{ID: 1, Year: 2023, Month: 12}},
+				Categories:   []Category{{ID: 1, Name: "Old", Color: "bg-gray-500"}},
+				BudgetLines:  []BudgetLine{{ID: 1, MonthID: 1, CategoryID: 1, Label: "Old line", Expected: 10_00}},
+				ActualLines:  []ActualLine{{ID: 1, BudgetLineID: 1, Actual: 5_00}},
+				Transactions: []Transaction{{ID: 1, BudgetLineID: 1, Date: time.Date(2023, time.December, 1, 0, 0, 0, 0, time.UTC), Amount: 5_00}},
+			}, ImportModeReplace, false)
+			require.NoError(t, err)
+
+			report, err := s.ImportAll(ctx, sampleDump(), ImportModeReplace, false)
+			if err != nil {
+				t.Fatalf("ImportAll() failed: %v", err)
+			}
+			wantDeleted := ImportTableCounts{Months: 1, Categories: 1, BudgetLines: 1, ActualLines: 1, Transactions: 1}
+			if report.Deleted != wantDeleted {
+				t.Errorf("Deleted = %+v, want %+v", report.Deleted, wantDeleted)
+			}
+			wantInserted := ImportTableCounts{Months: 2, Categories: 1, BudgetLines: 2, ActualLines: 2, Transactions: 1, AnnualSnaps: 1}
+			if report.Inserted != wantInserted {
+				t.Errorf("Inserted = %+v, want %+v", report.Inserted, wantInserted)
+			}
+
+			if line, err := s.GetBudgetLineByID(ctx, 20); err != nil || line.Label != "Groceries" {
+				t.Errorf("Imported budget line 20 not found with its original ID: line=%+v err=%v", line, err)
+			}
+			if n := countRows(t, s, "categories"); n != 1 {
+				t.Errorf("Expected old categories to be replaced, got %d rows", n)
+			}
+
+			board, err := s.GetBoardData(ctx, 10)
+			if err != nil {
+				t.Fatalf("GetBoardData() after import failed: %v", err)
+			}
+			if !board.IsFinalized || len(board.BudgetLines) != 1 || board.BudgetLines[0].ActualAmount != 310_50 {
+				t.Errorf("Unexpected board after import: %+v", board)
+			}
+
+			category := &Category{Name: "Bills", Color: "#f00"}
+			require.NoError(t, s.CreateCategory(ctx, category))
+			assert.Equal(t, int64(6), category.ID, "new rows get IDs after the imported ones")
+			lineID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 11, CategoryID: 6, Label: "Power"})
+			require.NoError(t, err)
+			assert.Equal(t, int64(22), lineID)
+		})
+	}
+}
+
+func TestStoreBackends_ImportAllMergeIntoSeededDatabase(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, s.CreateMonth(ctx, &Month{Year: 2024, Month: 1})) // seeded month, clashes with the dump
+			require.NoError(t, s.CreateMonth(ctx, &Month{Year: 2030, Month: 6})) // unrelated empty month, kept
+
+			report, err := s.ImportAll(ctx, sampleDump(), ImportModeMerge, false)
+			if err != nil {
+				t.Fatalf("ImportAll() merge failed: %v", err)
+			}
+			if report.Deleted.Months != 1 {
+				t.Errorf("Expected the clashing seeded month to be removed, deleted=%+v", report.Deleted)
+			}
+			if n := countRows(t, s, "months"); n != 3 {
+				t.Errorf("Expected 3 months after merge, got %d", n)
+			}
+		})
+	}
+}
+
+func TestStoreBackends_ImportAllMergeRejectsNonEmptyDatabase(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, s.CreateCategory(ctx, &Category{Name: "Existing", Color: "bg-gray-500"}))
+
+			_, err := s.ImportAll(ctx, sampleDump(), ImportModeMerge, false)
+			if !errors.Is(err, ErrDatabaseNotEmpty) {
+				t.Fatalf("Expected ErrDatabaseNotEmpty, got %v", err)
+			}
+			if n := countRows(t, s, "months"); n != 0 {
+				t.Errorf("Rejected merge must not write anything, found %d months", n)
+			}
+		})
+	}
+}
+
+func TestStoreBackends_ImportAllDryRunRollsBack(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, s.CreateCategory(ctx, &Category{Name: "Existing", Color: "bg-gray-500"}))
+
+			report, err := s.ImportAll(ctx, sampleDump(), ImportModeReplace, true)
+			if err != nil {
+				t.Fatalf("ImportAll() dry run failed: %v", err)
+			}
+			if !report.DryRun || report.Deleted.Categories != 1 || report.Inserted.BudgetLines != 2 {
+				t.Errorf("Unexpected dry-run report: %+v", report)
+			}
+
+			categories, err := s.GetAllCategories(ctx)
+			if err != nil || len(categories) != 1 || categories[0].Name != "Existing" {
+				t.Errorf("Dry run changed the database: categories=%+v err=%v", categories, err)
+			}
+			if n := countRows(t, s, "budget_lines"); n != 0 {
+				t.Errorf("Dry run inserted %d budget lines", n)
+			}
+
+			category := &Category{Name: "Bills", Color: "#f00"}
+			require.NoError(t, s.CreateCategory(ctx, category))
+			assert.Equal(t, int64(2), category.ID, "a dry run leaves the IDs alone")
+		})
+	}
+}
+
+func TestStoreBackends_ImportAllConstraintViolationRollsBack(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, s.CreateCategory(ctx, &Category{Name: "Existing", Color: "bg-gray-500"}))
+
+			dump := sampleDump()
+			dump.Categories = append(dump.Categories, Category{ID: 6, Name: "Food", Color: "bg-blue-500"})
+
+			if _, err := s.ImportAll(ctx, dump, ImportModeReplace, false); err == nil {
+				t.Fatal("Expected duplicate category name to fail the import")
+			}
+			if n := countRows(t, s, "categories"); n != 1 {
+				t.Errorf("Failed import must leave the database untouched, found %d categories", n)
+			}
+		})
+	}
+}
+
+// dumpOf reads a store's export back as a dump, as restoring a JSON backup does.
+func dumpOf(t *testing.T, s Store) *DatabaseDump {
+	t.Helper()
+	var tables []string
+	for name, rows := range exportTablesOf(t, s) {
+		tables = append(tables, fmt.Sprintf("%q: [%s]", name, strings.Join(rows, ",")))
+	}
+	var dump DatabaseDump
+	require.NoError(t, json.Unmarshal([]byte("{"+strings.Join(tables, ",")+"}"), &dump))
+	return &dump
+}
+
+func TestStoreBackends_ImportAllKeepsBankReview(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			lineID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00})
+			require.NoError(t, err)
+			foodID, amount := int64(1), Money(12_00)
+			require.NoError(t, s.CreateMatchRule(ctx, &MatchRule{Pattern: "market", CategoryID: &foodID, Label: "Groceries"}))
+			require.NoError(t, s.CreateMatchRule(ctx, &MatchRule{Pattern: "corner", Label: "Snacks", Amount: &amount}))
+			monthID := int64(1)
+			_, err = s.AddBankTransactions(ctx, []BankTransaction{
+				{ExternalID: "a", PostedOn: time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC), Amount: 12_00, Description: "MARKET", MonthID: &monthID, BudgetLineID: &lineID, MatchReason: "market"},
+				{ExternalID: "b", PostedOn: time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC), Amount: 8_00, Description: "KIOSK"},
+			})
+			require.NoError(t, err)
+			pending, err := s.GetBankTransactions(ctx, BankTransactionPending)
+			require.NoError(t, err)
+			require.NoError(t, s.ConfirmBankTransactions(ctx, []BankConfirmation{{TransactionID: pending[0].ID, BudgetLineID: lineID}}))
+			rules, err := s.GetMatchRules(ctx)
+			require.NoError(t, err)
+			queue, err := s.GetBankTransactions(ctx, "")
+			require.NoError(t, err)
+
+			dump := dumpOf(t, s)
+			require.Len(t, dump.MatchRules, 2)
+			require.Len(t, dump.BankTransactions, 2)
+			report, err := s.ImportAll(ctx, dump, ImportModeReplace, false)
+			require.NoError(t, err)
+			assert.Equal(t, 2, report.Deleted.MatchRules)
+			assert.Equal(t, 2, report.Deleted.BankTransactions)
+			assert.Equal(t, 2, report.Inserted.MatchRules)
+			assert.Equal(t, 2, report.Inserted.BankTransactions)
+
+			restoredRules, err := s.GetMatchRules(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, rules, restoredRules, "replacing the categories does not cascade to the rules")
+			restoredQueue, err := s.GetBankTransactions(ctx, "")
+			require.NoError(t, err)
+			assert.Equal(t, queue, restoredQueue, "applied transactions keep their month and line")
+
+			require.NoError(t, s.CreateMatchRule(ctx, &MatchRule{Pattern: "bakery", CategoryID: &foodID, Label: "Bread"}))
+			restoredRules, err = s.GetMatchRules(ctx)
+			require.NoError(t, err)
+			require.Len(t, restoredRules, 3)
+			assert.Equal(t, int64(3), restoredRules[2].ID, "new rules get IDs after the restored ones")
+			_, err = s.ImportAll(ctx, dump, ImportModeMerge, true)
+			assert.ErrorIs(t, err, ErrDatabaseNotEmpty)
+		})
+	}
+}
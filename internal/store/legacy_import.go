@@ -0,0 +1,217 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// legacyCategoryColors are handed out in turn to categories created by a legacy import.
+var legacyCategoryColors = []string{
+	"bg-blue-500", "bg-green-500", "bg-red-500", "bg-yellow-500", "bg-purple-500",
+	"bg-pink-500", "bg-indigo-500", "bg-teal-500", "bg-orange-500", "bg-gray-500",
+}
+
+// ImportLegacyRows loads spreadsheet rows in a single transaction, creating the
+// categories and months they need. Rows for finalized months and lines already
+// present in their month are rejected rather than merged. A preview performs
+// every statement and then rolls back, so it reports exactly what would happen.
+func (s *sqlStore) ImportLegacyRows(ctx context.Context, rows []LegacyImportRow, opts LegacyImportOptions) (*LegacyImportReport, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin legacy import transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	report := &LegacyImportReport{
+		Preview:           opts.Preview,
+		Imported:          []LegacyImportRow{},
+		Rejected:          []LegacyImportRejection{},
+		CreatedCategories: []string{},
+		CreatedMonths:     []string{},
+		FinalizedMonths:   []string{},
+	}
+
+	categoryIDs := make(map[string]int64)
+	deletedCategories := make(map[int64]Category)
+	var categories []Category
+	if err := tx.SelectContext(ctx, &categories, `SELECT id, name, color, deleted_at FROM categories`); err != nil {
+		return nil, fmt.Errorf("failed to load categories: %w", err)
+	}
+	for _, c := range categories {
+		categoryIDs[strings.ToLower(c.Name)] = c.ID
+		if c.DeletedAt != nil {
+			*c.DeletedAt = c.DeletedAt.UTC()
+			deletedCategories[c.ID] = c
+		}
+	}
+
+	type monthState struct {
+		id        int64
+		finalized bool
+		created   bool
+	}
+	months := make(map[[2]int]*monthState)
+	var monthOrder [][2]int
+	seenLines := make(map[string]bool)
+
+	for _, row := range rows {
+		period := [2]int{row.Year, row.Month}
+		month, ok := months[period]
+		if !ok {
+			month = &monthState{}
+			err := tx.QueryRowxContext(ctx, tx.Rebind(`SELECT id, finalized FROM months WHERE year = ? AND month = ? ORDER BY id LIMIT 1`),
+				row.Year, row.Month).Scan(&month.id, &month.finalized)
+			if err != nil && err != sql.ErrNoRows {
+				return nil, fmt.Errorf("failed to look up month %d-%02d: %w", row.Year, row.Month, err)
+			}
+			if err == sql.ErrNoRows {
+				err := tx.GetContext(ctx, &month.id, tx.Rebind(`INSERT INTO months (year, month, finalized) VALUES (?, ?, FALSE) RETURNING id`), row.Year, row.Month)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create month %d-%02d: %w", row.Year, row.Month, err)
+				}
+				month.created = true
+				created := Month{ID: month.id, Year: row.Year, Month: row.Month}
+				if err := recordAudit(ctx, tx, AuditEntityMonth, month.id, AuditActionCreate, &month.id, nil, created); err != nil {
+					return nil, err
+				}
+				report.CreatedMonths = append(report.CreatedMonths, fmt.Sprintf("%d-%02d", row.Year, row.Month))
+			}
+			months[period] = month
+			monthOrder = append(monthOrder, period)
+		}
+		if month.finalized && !month.created {
+			report.Rejected = append(report.Rejected, LegacyImportRejection{
+				Line: row.Line, Reason: fmt.Sprintf("month %d-%02d is already finalized", row.Year, row.Month),
+			})
+			continue
+		}
+
+		categoryID, ok := categoryIDs[strings.ToLower(row.Category)]
+		if !ok {
+			color := legacyCategoryColors[len(report.CreatedCategories)%len(legacyCategoryColors)]
+			err := tx.GetContext(ctx, &categoryID, tx.Rebind(`INSERT INTO categories (name, color) VALUES (?, ?) RETURNING id`), row.Category, color)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create category %s: %w", row.Category, err)
+			}
+			created := Category{ID: categoryID, Name: row.Category, Color: color}
+			if err := recordAudit(ctx, tx, AuditEntityCategory, categoryID, AuditActionCreate, nil, nil, created); err != nil {
+				return nil, err
+			}
+			categoryIDs[strings.ToLower(row.Category)] = categoryID
+			report.CreatedCategories = append(report.CreatedCategories, row.Category)
+		}
+		// Names stay taken in the trash, so a deleted category comes back when
+		// imported lines use it.
+		if before, deleted := deletedCategories[categoryID]; deleted {
+			if _, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE categories SET deleted_at = NULL WHERE id = ?`), categoryID); err != nil {
+				return nil, fmt.Errorf("failed to restore category %s: %w", row.Category, err)
+			}
+			after := before
+			after.DeletedAt = nil
+			if err := recordAudit(ctx, tx, AuditEntityCategory, categoryID, AuditActionRestore, nil, before, after); err != nil {
+				return nil, err
+			}
+			delete(deletedCategories, categoryID)
+		}
+
+		lineKey := fmt.Sprintf("%d/%d/%s", month.id, categoryID, strings.ToLower(row.Label))
+		if seenLines[lineKey] {
+			report.Rejected = append(report.Rejected, LegacyImportRejection{
+				Line: row.Line, Reason: fmt.Sprintf("duplicate line %q for %s in %d-%02d", row.Label, row.Category, row.Year, row.Month),
+			})
+			continue
+		}
+		seenLines[lineKey] = true
+		var existing int
+		if err := tx.GetContext(ctx, &existing, tx.Rebind(`SELECT COUNT(*) FROM budget_lines WHERE month_id = ? AND category_id = ? AND LOWER(label) = LOWER(?) AND deleted_at IS NULL`),
+			month.id, categoryID, row.Label); err != nil {
+			return nil, fmt.Errorf("failed to check for existing budget line %s: %w", row.Label, err)
+		}
+		if existing > 0 {
+			report.Rejected = append(report.Rejected, LegacyImportRejection{
+				Line: row.Line, Reason: fmt.Sprintf("%d-%02d already has a %q line for %s", row.Year, row.Month, row.Label, row.Category),
+			})
+			continue
+		}
+
+		var budgetLineID int64
+		err := tx.GetContext(ctx, &budgetLineID, tx.Rebind(`INSERT INTO budget_lines (month_id, category_id, label, expected, currency) VALUES (?, ?, ?, ?, ?) RETURNING id`),
+			month.id, categoryID, row.Label, row.Expected, BudgetCurrency())
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert budget line %s (line %d): %w", row.Label, row.Line, err)
+		}
+		created := BudgetLine{ID: int(budgetLineID), MonthID: int(month.id), CategoryID: int(categoryID), Label: row.Label, Expected: row.Expected, Currency: BudgetCurrency()}
+		if err := recordAudit(ctx, tx, AuditEntityBudgetLine, budgetLineID, AuditActionCreate, &month.id, nil, created); err != nil {
+			return nil, err
+		}
+		if row.Actual != 0 {
+			opening := &Transaction{BudgetLineID: budgetLineID, Date: time.Date(row.Year, time.Month(row.Month), 1, 0, 0, 0, 0, time.UTC), Amount: row.Actual, Memo: "Imported actual"}
+			if _, err := insertTransaction(ctx, tx, opening); err != nil {
+				return nil, fmt.Errorf("failed to insert actual for %s (line %d): %w", row.Label, row.Line, err)
+			}
+		}
+		if err := refreshActual(ctx, tx, budgetLineID); err != nil {
+			return nil, fmt.Errorf("failed to insert actual for %s (line %d): %w", row.Label, row.Line, err)
+		}
+		report.Imported = append(report.Imported, row)
+	}
+
+	for _, period := range monthOrder {
+		month := months[period]
+		if month.finalized || !periodBefore(period, opts.FinalizeBeforeYear, opts.FinalizeBeforeMonth) {
+			continue
+		}
+		if err := finalizeImportedMonth(ctx, tx, month.id); err != nil {
+			return nil, err
+		}
+		report.FinalizedMonths = append(report.FinalizedMonths, fmt.Sprintf("%d-%02d", period[0], period[1]))
+	}
+
+	if opts.Preview {
+		return report, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit legacy import: %w", err)
+	}
+	return report, nil
+}
+
+func periodBefore(period [2]int, year, month int) bool {
+	if year == 0 {
+		return false
+	}
+	return period[0] < year || (period[0] == year && period[1] < month)
+}
+
+// finalizeImportedMonth marks a historical month finalized with a snapshot of its
+// board, like FinalizeMonth, but without rolling the budget over to a next month.
+func finalizeImportedMonth(ctx context.Context, tx *sqlx.Tx, monthID int64) error {
+	var before Month
+	if err := tx.GetContext(ctx, &before, tx.Rebind(`SELECT id, year, month, finalized FROM months WHERE id = ?`), monthID); err != nil {
+		return fmt.Errorf("failed to get month %d: %w", monthID, err)
+	}
+	boardData, err := getBoardData(ctx, tx, int(monthID))
+	if err != nil {
+		return err
+	}
+	boardData.IsFinalized = true
+	snapJSON, err := json.Marshal(boardData)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot for month %d: %w", monthID, err)
+	}
+	if _, err := insertSnapshot(ctx, tx, monthID, string(snapJSON)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE months SET finalized = TRUE WHERE id = ?`), monthID); err != nil {
+		return fmt.Errorf("failed to mark month %d as finalized: %w", monthID, err)
+	}
+	after := before
+	after.Finalized = true
+	return recordAudit(ctx, tx, AuditEntityMonth, monthID, AuditActionUpdate, &monthID, before, after)
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestStoreBackends_ImportLegacyRows(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, err := s.ImportAll(ctx, &DatabaseDump{
+				Months:      []Month{{ID: 1, Year: 2021, Month: 12, Finalized: true}, {ID: 2, Year: 2022, Month: 2}},
+				Categories:  []Category{{ID: 1, Name: "Food", Color: "bg-red-500"}},
+				BudgetLines: []BudgetLine{{ID: 1, MonthID: 2, CategoryID: 1, Label: "Groceries", Expected: 100_00}},
+			}, ImportModeReplace, false)
+			if err != nil {
+				t.Fatalf("Failed to seed the store: %v", err)
+			}
+
+			rows := []LegacyImportRow{
+				{Line: 2, Year: 2022, Month: 1, Category: "food", Label: "Groceries", Expected: 90_00, Actual: 95_00},
+				{Line: 3, Year: 2022, Month: 1, Category: "Home", Label: "Rent", Expected: 500_00, Actual: 500_00},
+				{Line: 4, Year: 2022, Month: 1, Category: "Home", Label: "rent", Expected: 1_00, Actual: 1_00},
+				{Line: 5, Year: 2021, Month: 12, Category: "Food", Label: "Groceries", Expected: 80_00, Actual: 80_00},
+				{Line: 6, Year: 2022, Month: 2, Category: "Food", Label: "groceries", Expected: 100_00, Actual: 100_00},
+				{Line: 7, Year: 2022, Month: 3, Category: "Food", Label: "Groceries", Expected: 100_00, Actual: 0},
+			}
+			opts := LegacyImportOptions{FinalizeBeforeYear: 2022, FinalizeBeforeMonth: 3, Preview: true}
+
+			preview, err := s.ImportLegacyRows(ctx, rows, opts)
+			if err != nil {
+				t.Fatalf("ImportLegacyRows() preview failed: %v", err)
+			}
+			if got := countRows(t, s, "budget_lines"); got != 1 {
+				t.Errorf("Preview must not write anything, found %d budget lines", got)
+			}
+
+			opts.Preview = false
+			report, err := s.ImportLegacyRows(ctx, rows, opts)
+			if err != nil {
+				t.Fatalf("ImportLegacyRows() failed: %v", err)
+			}
+			preview.Preview = false
+			previewJSON, _ := json.Marshal(preview)
+			reportJSON, _ := json.Marshal(report)
+			if string(previewJSON) != string(reportJSON) {
+				t.Errorf("Preview should report exactly what the import does:\npreview %s\nimport  %s", previewJSON, reportJSON)
+			}
+
+			if len(report.Imported) != 3 {
+				t.Errorf("Expected 3 imported rows, got %+v", report.Imported)
+			}
+			wantRejected := []int{4, 5, 6}
+			if len(report.Rejected) != len(wantRejected) {
+				t.Fatalf("Expected rejections for lines %v, got %+v", wantRejected, report.Rejected)
+			}
+			for i, line := range wantRejected {
+				if report.Rejected[i].Line != line {
+					t.Errorf("Rejection %d: expected line %d, got %+v", i, line, report.Rejected[i])
+				}
+			}
+			if len(report.CreatedCategories) != 1 || report.CreatedCategories[0] != "Home" {
+				t.Errorf("Expected only Home to be created, got %v", report.CreatedCategories)
+			}
+			if len(report.CreatedMonths) != 2 || report.CreatedMonths[0] != "2022-01" || report.CreatedMonths[1] != "2022-03" {
+				t.Errorf("Unexpected created months: %v", report.CreatedMonths)
+			}
+			if len(report.FinalizedMonths) != 2 || report.FinalizedMonths[0] != "2022-01" || report.FinalizedMonths[1] != "2022-02" {
+				t.Errorf("Expected January and February 2022 to be finalized, got %v", report.FinalizedMonths)
+			}
+
+			dump := dumpOf(t, s)
+			monthIDs := map[string]int64{}
+			var marchFinalized bool
+			for _, m := range dump.Months {
+				monthIDs[fmt.Sprintf("%04d-%02d", m.Year, m.Month)] = m.ID
+				if m.Year == 2022 && m.Month == 3 {
+					marchFinalized = m.Finalized
+				}
+			}
+			var snapJSON string
+			for _, snap := range dump.AnnualSnaps {
+				if snap.MonthID == monthIDs["2022-01"] {
+					snapJSON = snap.SnapJSON
+				}
+			}
+			if snapJSON == "" {
+				t.Fatalf("Expected a snapshot for January 2022, got %+v", dump.AnnualSnaps)
+			}
+			var snap BoardDataPayload
+			if err := json.Unmarshal([]byte(snapJSON), &snap); err != nil {
+				t.Fatalf("Snapshot is not a board payload: %v", err)
+			}
+			if !snap.IsFinalized || len(snap.BudgetLines) != 2 {
+				t.Errorf("Unexpected snapshot: %+v", snap)
+			}
+
+			if marchFinalized {
+				t.Error("March 2022 is not before the cutoff and must stay open")
+			}
+			if got := len(dump.AnnualSnaps); got != 2 {
+				t.Errorf("Expected 2 snapshots, got %d", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,1291 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errForeignKey and errUnique mirror the constraint errors SQLite reports, so the
+// in-memory store fails the same writes the database would.
+var errForeignKey = errors.New("FOREIGN KEY constraint failed")
+
+func errUnique(columns string) error {
+	return fmt.Errorf("UNIQUE constraint failed: %s", columns)
+}
+
+// errRollback makes update discard its changes without reporting an error, for
+// previews and dry runs.
+var errRollback = errors.New("roll back")
+
+// memoryStore keeps every table in memory. Writes copy the tables, change the
+// copy and publish it only when the whole operation succeeds, so a failed call
+// leaves nothing behind, like a rolled-back transaction, and readers can keep
+// using the copy they started with while a write is in progress.
+type memoryStore struct {
+	mu   sync.RWMutex
+	data *memoryData
+}
+
+type memoryData struct {
+	months           []Month
+	categories       []Category
+	budgetLines      []BudgetLine // without the actual_* fields
+	actualLines      []ActualLine
+	transactions     []Transaction
+	annualSnaps      []AnnualSnap
+	bankTransactions []BankTransaction
+	matchRules       []MatchRule
+	backupRuns       []BackupRun
+	exchangeRates    []ExchangeRate
+	auditLog         []AuditEntry
+	finalizeRequests []FinalizeRequest
+}
+
+// NewMemoryStore returns an empty Store that keeps its data in memory and
+// behaves like the SQL store: the same IDs, constraints, cascading deletes and
+// errors. It is safe for concurrent use and suits demos and tests; everything
+// is lost when the process exits.
+func NewMemoryStore() Store {
+	return &memoryStore{data: &memoryData{}}
+}
+
+// read returns the current tables, which are never changed once published.
+func (m *memoryStore) read(ctx context.Context) (*memoryData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.data, nil
+}
+
+// update runs fn on a copy of the tables and publishes the copy if fn succeeds.
+func (m *memoryStore) update(ctx context.Context, fn func(d *memoryData) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d := m.data.clone()
+	if err := fn(d); err != nil {
+		if err == errRollback {
+			return nil
+		}
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.data = d
+	return nil
+}
+
+// clone copies the tables. Rows are copied by value; the pointers they hold are
+// shared, so changes must replace a pointer rather than write through it.
+func (d *memoryData) clone() *memoryData {
+	return &memoryData{
+		months:           append([]Month(nil), d.months...),
+		categories:       append([]Category(nil), d.categories...),
+		budgetLines:      append([]BudgetLine(nil), d.budgetLines...),
+		actualLines:      append([]ActualLine(nil), d.actualLines...),
+		transactions:     append([]Transaction(nil), d.transactions...),
+		annualSnaps:      append([]AnnualSnap(nil), d.annualSnaps...),
+		bankTransactions: append([]BankTransaction(nil), d.bankTransactions...),
+		matchRules:       append([]MatchRule(nil), d.matchRules...),
+		backupRuns:       append([]BackupRun(nil), d.backupRuns...),
+		exchangeRates:    append([]ExchangeRate(nil), d.exchangeRates...),
+		auditLog:         append([]AuditEntry(nil), d.auditLog...),
+		finalizeRequests: append([]FinalizeRequest(nil), d.finalizeRequests...),
+	}
+}
+
+// findRow returns the first row matching, or nil.
+func findRow[T any](rows []T, match func(*T) bool) *T {
+	for i := range rows {
+		if match(&rows[i]) {
+			return &rows[i]
+		}
+	}
+	return nil
+}
+
+// deleteRows removes the rows matching and returns how many there were.
+func deleteRows[T any](rows *[]T, match func(*T) bool) int {
+	kept := (*rows)[:0:0]
+	for i := range *rows {
+		if !match(&(*rows)[i]) {
+			kept = append(kept, (*rows)[i])
+		}
+	}
+	deleted := len(*rows) - len(kept)
+	*rows = kept
+	return deleted
+}
+
+// nextID picks the ID SQLite gives a new row: one more than the largest in use.
+func nextID[T any](rows []T, id func(*T) int64) int64 {
+	var max int64
+	for i := range rows {
+		if rowID := id(&rows[i]); rowID > max {
+			max = rowID
+		}
+	}
+	return max + 1
+}
+
+func sortByID[T any](rows []T, id func(*T) int64) {
+	sort.SliceStable(rows, func(i, j int) bool { return id(&rows[i]) < id(&rows[j]) })
+}
+
+func monthRowID(m *Month) int64                     { return m.ID }
+func categoryRowID(c *Category) int64               { return c.ID }
+func budgetLineRowID(b *BudgetLine) int64           { return int64(b.ID) }
+func actualLineRowID(a *ActualLine) int64           { return a.ID }
+func transactionRowID(t *Transaction) int64         { return t.ID }
+func annualSnapRowID(a *AnnualSnap) int64           { return a.ID }
+func bankTransactionRowID(b *BankTransaction) int64 { return b.ID }
+func matchRuleRowID(r *MatchRule) int64             { return r.ID }
+func backupRunRowID(r *BackupRun) int64             { return r.ID }
+func exchangeRateRowID(r *ExchangeRate) int64       { return r.ID }
+func auditEntryRowID(e *AuditEntry) int64           { return e.ID }
+
+func (d *memoryData) month(id int64) *Month {
+	return findRow(d.months, func(m *Month) bool { return m.ID == id })
+}
+
+// monthOf returns the month of a year and month number.
+func (d *memoryData) monthOf(year, month int) *Month {
+	return findRow(d.months, func(m *Month) bool { return m.Year == year && m.Month == month })
+}
+
+// checkMonthEditable mirrors the SQL store: changes to a finalized month need
+// the override on ctx, and are audited as overrides.
+func (d *memoryData) checkMonthEditable(ctx context.Context, monthID int64) error {
+	if month := d.month(monthID); month == nil || !month.Finalized {
+		return nil
+	}
+	if FinalizedOverride(ctx) {
+		return d.recordAudit(AuditEntityMonth, monthID, AuditActionOverride, &monthID, nil, nil)
+	}
+	return fmt.Errorf("month %d: %w", monthID, ErrMonthFinalized)
+}
+
+func (d *memoryData) checkLineEditable(ctx context.Context, budgetLineID int64) error {
+	if bl := d.budgetLine(budgetLineID); bl != nil {
+		return d.checkMonthEditable(ctx, int64(bl.MonthID))
+	}
+	return nil
+}
+
+func (d *memoryData) category(id int64) *Category {
+	return findRow(d.categories, func(c *Category) bool { return c.ID == id })
+}
+
+func (d *memoryData) budgetLine(id int64) *BudgetLine {
+	return findRow(d.budgetLines, func(b *BudgetLine) bool { return int64(b.ID) == id })
+}
+
+// liveCategory returns a category unless it is in the trash.
+func (d *memoryData) liveCategory(id int64) *Category {
+	return findRow(d.categories, func(c *Category) bool { return c.ID == id && c.DeletedAt == nil })
+}
+
+// liveBudgetLine returns a budget line unless it is in the trash.
+func (d *memoryData) liveBudgetLine(id int64) *BudgetLine {
+	return findRow(d.budgetLines, func(b *BudgetLine) bool { return int64(b.ID) == id && b.DeletedAt == nil })
+}
+
+func (d *memoryData) transaction(id int64) *Transaction {
+	return findRow(d.transactions, func(t *Transaction) bool { return t.ID == id })
+}
+
+// liveTransaction returns a transaction unless its budget line is in the trash.
+func (d *memoryData) liveTransaction(id int64) *Transaction {
+	t := d.transaction(id)
+	if t == nil || d.liveBudgetLine(t.BudgetLineID) == nil {
+		return nil
+	}
+	return t
+}
+
+func (d *memoryData) bankTransaction(id int64) *BankTransaction {
+	return findRow(d.bankTransactions, func(t *BankTransaction) bool { return t.ID == id })
+}
+
+// deleteBudgetLines removes the matching lines with their actuals and
+// transactions, and unlinks the bank transactions that pointed at them.
+func (d *memoryData) deleteBudgetLines(match func(*BudgetLine) bool) int {
+	deleted := make(map[int64]bool)
+	for i := range d.budgetLines {
+		if match(&d.budgetLines[i]) {
+			deleted[int64(d.budgetLines[i].ID)] = true
+		}
+	}
+	if len(deleted) == 0 {
+		return 0
+	}
+	deleteRows(&d.actualLines, func(a *ActualLine) bool { return deleted[a.BudgetLineID] })
+	deleteRows(&d.transactions, func(t *Transaction) bool { return deleted[t.BudgetLineID] })
+	for i := range d.bankTransactions {
+		if t := &d.bankTransactions[i]; t.BudgetLineID != nil && deleted[*t.BudgetLineID] {
+			t.BudgetLineID = nil
+		}
+	}
+	return deleteRows(&d.budgetLines, match)
+}
+
+// deleteMonths removes the matching months with their budget lines, and unlinks
+// the bank transactions that pointed at them. Like the foreign key, it refuses
+// to delete a month that has a snapshot.
+func (d *memoryData) deleteMonths(match func(*Month) bool) (int, error) {
+	deleted := make(map[int64]bool)
+	for i := range d.months {
+		if match(&d.months[i]) {
+			deleted[d.months[i].ID] = true
+		}
+	}
+	if findRow(d.annualSnaps, func(a *AnnualSnap) bool { return deleted[a.MonthID] }) != nil {
+		return 0, errForeignKey
+	}
+	d.deleteBudgetLines(func(b *BudgetLine) bool { return deleted[int64(b.MonthID)] })
+	for i := range d.bankTransactions {
+		if t := &d.bankTransactions[i]; t.MonthID != nil && deleted[*t.MonthID] {
+			t.MonthID = nil
+		}
+	}
+	return deleteRows(&d.months, match), nil
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// dateOnly drops the time of day, as storing a value in a DATE column does.
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (m *memoryStore) GetAllCategories(ctx context.Context) ([]Category, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories := []Category{}
+	for _, c := range d.categories {
+		if c.DeletedAt == nil {
+			categories = append(categories, c)
+		}
+	}
+	sort.SliceStable(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	return categories, nil
+}
+
+func (m *memoryStore) CreateCategory(ctx context.Context, category *Category) error {
+	if category.Name == "" {
+		return fmt.Errorf("category name cannot be empty")
+	}
+	if category.Color == "" {
+		return fmt.Errorf("category color cannot be empty")
+	}
+	return m.update(ctx, func(d *memoryData) error {
+		if existing := findRow(d.categories, func(c *Category) bool { return c.Name == category.Name }); existing != nil {
+			if existing.DeletedAt != nil {
+				return fmt.Errorf("category %q: %w", category.Name, ErrCategoryNameInTrash)
+			}
+			return fmt.Errorf("failed to insert category: %w", errUnique("categories.name"))
+		}
+		category.ID = nextID(d.categories, categoryRowID)
+		d.categories = append(d.categories, *category)
+		return d.recordAudit(AuditEntityCategory, category.ID, AuditActionCreate, nil, nil, *category)
+	})
+}
+
+func (m *memoryStore) GetCategoryByID(ctx context.Context, id int64) (*Category, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := d.liveCategory(id)
+	if c == nil {
+		return nil, nil
+	}
+	category := *c
+	return &category, nil
+}
+
+func (m *memoryStore) UpdateCategory(ctx context.Context, category *Category) error {
+	if category.ID == 0 {
+		return fmt.Errorf("category ID cannot be zero for update")
+	}
+	if category.Name == "" {
+		return fmt.Errorf("category name cannot be empty for update")
+	}
+	if category.Color == "" {
+		return fmt.Errorf("category color cannot be empty for update")
+	}
+	return m.update(ctx, func(d *memoryData) error {
+		c := d.liveCategory(category.ID)
+		if c == nil {
+			return sql.ErrNoRows
+		}
+		if other := findRow(d.categories, func(o *Category) bool { return o.Name == category.Name && o.ID != category.ID }); other != nil {
+			if other.DeletedAt != nil {
+				return fmt.Errorf("category %q: %w", category.Name, ErrCategoryNameInTrash)
+			}
+			return fmt.Errorf("failed to update category: %w", errUnique("categories.name"))
+		}
+		before := *c
+		*c = *category
+		return d.recordAudit(AuditEntityCategory, category.ID, AuditActionUpdate, nil, before, *category)
+	})
+}
+
+func (m *memoryStore) DeleteCategory(ctx context.Context, id int64) error {
+	if id == 0 {
+		return fmt.Errorf("category ID cannot be zero for delete")
+	}
+	return m.update(ctx, func(d *memoryData) error {
+		c := d.liveCategory(id)
+		if c == nil {
+			return sql.ErrNoRows
+		}
+		before := *c
+		if findRow(d.budgetLines, func(b *BudgetLine) bool { return int64(b.CategoryID) == id && b.DeletedAt == nil }) != nil {
+			return ErrCategoryInUse
+		}
+		c.DeletedAt = trashTime()
+		return d.recordAudit(AuditEntityCategory, id, AuditActionDelete, nil, before, nil)
+	})
+}
+
+func (m *memoryStore) CreateBudgetLine(ctx context.Context, b *BudgetLine) (int64, error) {
+	if b.Currency == "" {
+		b.Currency = BudgetCurrency()
+	}
+	var id int64
+	err := m.update(ctx, func(d *memoryData) error {
+		if c := d.category(int64(b.CategoryID)); c != nil && c.DeletedAt != nil {
+			return fmt.Errorf("category %d: %w", b.CategoryID, ErrCategoryDeleted)
+		}
+		if err := d.checkMonthEditable(ctx, int64(b.MonthID)); err != nil {
+			return err
+		}
+		if d.month(int64(b.MonthID)) == nil || d.category(int64(b.CategoryID)) == nil {
+			return fmt.Errorf("failed to execute budget_lines insert statement: %w", errForeignKey)
+		}
+		id = nextID(d.budgetLines, budgetLineRowID)
+		created := BudgetLine{ID: int(id), MonthID: b.MonthID, CategoryID: b.CategoryID, Label: b.Label, Expected: b.Expected, Currency: b.Currency}
+		d.budgetLines = append(d.budgetLines, created)
+		d.actualLines = append(d.actualLines, ActualLine{
+			ID: nextID(d.actualLines, actualLineRowID), BudgetLineID: id, Currency: b.Currency,
+		})
+		monthID := int64(b.MonthID)
+		return d.recordAudit(AuditEntityBudgetLine, id, AuditActionCreate, &monthID, nil, created)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (m *memoryStore) GetBudgetLinesByMonthID(ctx context.Context, monthID int) ([]BudgetLine, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	budgetLines := []BudgetLine{}
+	for _, bl := range d.budgetLines {
+		if bl.MonthID != monthID || bl.DeletedAt != nil {
+			continue
+		}
+		joined := false
+		for _, al := range d.actualLines {
+			if al.BudgetLineID != int64(bl.ID) {
+				continue
+			}
+			row := bl
+			row.ActualID, row.ActualAmount, row.ActualCurrency = clonePtr(&al.ID), clonePtr(&al.Actual), clonePtr(&al.Currency)
+			budgetLines = append(budgetLines, row)
+			joined = true
+		}
+		if !joined {
+			budgetLines = append(budgetLines, bl)
+		}
+	}
+	return budgetLines, nil
+}
+
+func (m *memoryStore) UpdateBudgetLine(ctx context.Context, b *BudgetLine) error {
+	if b.Currency == "" {
+		b.Currency = BudgetCurrency()
+	}
+	return m.update(ctx, func(d *memoryData) error {
+		bl := d.liveBudgetLine(int64(b.ID))
+		if bl == nil {
+			return nil
+		}
+		if err := d.checkMonthEditable(ctx, int64(bl.MonthID)); err != nil {
+			return err
+		}
+		before := *bl
+		bl.Label, bl.Expected, bl.Currency = b.Label, b.Expected, b.Currency
+		monthID := int64(bl.MonthID)
+		return d.recordAudit(AuditEntityBudgetLine, int64(b.ID), AuditActionUpdate, &monthID, before, *bl)
+	})
+}
+
+func (m *memoryStore) UpdateActualLine(ctx context.Context, a *ActualLine) error {
+	if a.Actual < 0 {
+		return fmt.Errorf("actual amount must be non-negative, got %s", a.Actual.StringIn(a.Currency))
+	}
+	if a.Currency == "" {
+		a.Currency = BudgetCurrency()
+	}
+	var lineID int64
+	err := m.update(ctx, func(d *memoryData) error {
+		al := findRow(d.actualLines, func(al *ActualLine) bool { return al.ID == a.ID })
+		if al == nil || d.liveBudgetLine(al.BudgetLineID) == nil {
+			return fmt.Errorf("failed to get actual line with ID %d: %w", a.ID, sql.ErrNoRows)
+		}
+		lineID = al.BudgetLineID
+		if err := d.checkLineEditable(ctx, lineID); err != nil {
+			return err
+		}
+		if a.Currency != al.Currency && findRow(d.transactions, func(t *Transaction) bool { return t.BudgetLineID == lineID }) != nil {
+			return fmt.Errorf("actual line %d: %w", a.ID, ErrActualCurrencyInUse)
+		}
+		before := *al
+		before.Actual = d.transactionTotal(lineID)
+		if diff := a.Actual - before.Actual; diff != 0 {
+			date, err := d.adjustmentDate(lineID)
+			if err != nil {
+				return err
+			}
+			d.insertTransaction(Transaction{BudgetLineID: lineID, Date: date, Amount: diff, Memo: "Adjustment to the actual total"})
+		}
+		al.Currency = a.Currency
+		if err := d.syncActual(lineID); err != nil {
+			return err
+		}
+		monthID := int64(d.budgetLine(lineID).MonthID)
+		after := ActualLine{ID: a.ID, BudgetLineID: lineID, Actual: a.Actual, Currency: a.Currency}
+		return d.recordAudit(AuditEntityActualLine, a.ID, AuditActionUpdate, &monthID, before, after)
+	})
+	if err != nil {
+		return err
+	}
+	a.BudgetLineID = lineID
+	return nil
+}
+
+func (m *memoryStore) GetActualLineByID(ctx context.Context, id int64) (*ActualLine, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	al := findRow(d.actualLines, func(al *ActualLine) bool { return al.ID == id })
+	if al == nil || d.liveBudgetLine(al.BudgetLineID) == nil {
+		return nil, fmt.Errorf("failed to get actual line with ID %d: %w", id, sql.ErrNoRows)
+	}
+	actualLine := *al
+	return &actualLine, nil
+}
+
+func (m *memoryStore) GetBudgetLineByID(ctx context.Context, id int64) (*BudgetLine, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bl := d.liveBudgetLine(id)
+	if bl == nil {
+		return nil, fmt.Errorf("failed to get budget line with ID %d: %w", id, sql.ErrNoRows)
+	}
+	budgetLine := *bl
+	if al := findRow(d.actualLines, func(a *ActualLine) bool { return a.BudgetLineID == id }); al != nil {
+		budgetLine.ActualID, budgetLine.ActualAmount, budgetLine.ActualCurrency = clonePtr(&al.ID), clonePtr(&al.Actual), clonePtr(&al.Currency)
+	}
+	return &budgetLine, nil
+}
+
+func (m *memoryStore) DeleteBudgetLine(ctx context.Context, id int64) error {
+	return m.update(ctx, func(d *memoryData) error {
+		bl := d.liveBudgetLine(id)
+		if bl == nil {
+			return fmt.Errorf("no budget line found with ID %d to delete", id)
+		}
+		if err := d.checkMonthEditable(ctx, int64(bl.MonthID)); err != nil {
+			return err
+		}
+		before := *bl
+		bl.DeletedAt = trashTime()
+		monthID := int64(before.MonthID)
+		return d.recordAudit(AuditEntityBudgetLine, id, AuditActionDelete, &monthID, before, nil)
+	})
+}
+
+func (m *memoryStore) GetBoardData(ctx context.Context, monthID int) (*BoardDataPayload, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return d.boardData(monthID)
+}
+
+func (d *memoryData) boardData(monthID int) (*BoardDataPayload, error) {
+	month := d.month(int64(monthID))
+	if month == nil {
+		return nil, sql.ErrNoRows
+	}
+	monthName := "Unknown"
+	if month.Month >= 1 && month.Month <= 12 {
+		monthName = time.Month(month.Month).String()
+	}
+
+	var budgetLinesWithActuals []BudgetLineWithActual
+	for _, bl := range d.budgetLines {
+		if bl.MonthID != monthID || bl.DeletedAt != nil {
+			continue
+		}
+		c := d.category(int64(bl.CategoryID))
+		if c == nil {
+			continue
+		}
+		row := BudgetLineWithActual{
+			ID: int64(bl.ID), MonthID: int64(bl.MonthID), CategoryID: int64(bl.CategoryID),
+			CategoryName: c.Name, CategoryColor: c.Color, Label: bl.Label,
+			ExpectedAmount: bl.Expected, Currency: bl.Currency, ActualCurrency: bl.Currency,
+		}
+		joined := false
+		for _, al := range d.actualLines {
+			if al.BudgetLineID == int64(bl.ID) {
+				row.ActualAmount, row.ActualCurrency = al.Actual, al.Currency
+				budgetLinesWithActuals = append(budgetLinesWithActuals, row)
+				joined = true
+			}
+		}
+		if !joined {
+			budgetLinesWithActuals = append(budgetLinesWithActuals, row)
+		}
+	}
+	sort.SliceStable(budgetLinesWithActuals, func(i, j int) bool {
+		a, b := budgetLinesWithActuals[i], budgetLinesWithActuals[j]
+		if a.CategoryName != b.CategoryName {
+			return a.CategoryName < b.CategoryName
+		}
+		return a.Label < b.Label
+	})
+
+	return &BoardDataPayload{
+		MonthID:     int64(monthID),
+		Year:        month.Year,
+		MonthName:   monthName,
+		BudgetLines: budgetLinesWithActuals,
+		IsFinalized: month.Finalized,
+	}, nil
+}
+
+func (m *memoryStore) GetMonthsByYear(ctx context.Context, year int) ([]Month, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	months := []Month{}
+	for _, month := range d.months {
+		if month.Year == year {
+			months = append(months, month)
+		}
+	}
+	sort.SliceStable(months, func(i, j int) bool { return months[i].Month < months[j].Month })
+	return months, nil
+}
+
+func (m *memoryStore) GetMonths(ctx context.Context, filter MonthFilter) ([]Month, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	months := []Month{}
+	for _, month := range d.months {
+		if (filter.Year == nil || month.Year == *filter.Year) && (filter.Finalized == nil || month.Finalized == *filter.Finalized) {
+			months = append(months, month)
+		}
+	}
+	sort.SliceStable(months, func(i, j int) bool {
+		if months[i].Year != months[j].Year {
+			return months[i].Year < months[j].Year
+		}
+		return months[i].Month < months[j].Month
+	})
+	return months, nil
+}
+
+func (m *memoryStore) GetMonthByID(ctx context.Context, id int64) (*Month, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	month := d.month(id)
+	if month == nil {
+		return nil, fmt.Errorf("failed to get month with ID %d: %w", id, sql.ErrNoRows)
+	}
+	result := *month
+	return &result, nil
+}
+
+func (m *memoryStore) GetMonthByPeriod(ctx context.Context, year, month int) (*Month, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	found := d.monthOf(year, month)
+	if found == nil {
+		return nil, fmt.Errorf("failed to get month %d-%02d: %w", year, month, sql.ErrNoRows)
+	}
+	result := *found
+	return &result, nil
+}
+
+func (m *memoryStore) CreateMonth(ctx context.Context, month *Month) error {
+	if month.Month < 1 || month.Month > 12 {
+		return fmt.Errorf("invalid month number %d", month.Month)
+	}
+	return m.update(ctx, func(d *memoryData) error {
+		if d.monthOf(month.Year, month.Month) != nil {
+			return fmt.Errorf("%d-%02d: %w", month.Year, month.Month, ErrMonthExists)
+		}
+		month.ID = nextID(d.months, monthRowID)
+		month.Finalized = false
+		d.months = append(d.months, *month)
+		return d.recordAudit(AuditEntityMonth, month.ID, AuditActionCreate, &month.ID, nil, *month)
+	})
+}
+
+func (m *memoryStore) ReopenMonth(ctx context.Context, monthID int) error {
+	return m.update(ctx, func(d *memoryData) error {
+		month := d.month(int64(monthID))
+		if month == nil {
+			return fmt.Errorf("failed to get month with ID %d: %w", monthID, sql.ErrNoRows)
+		}
+		if !month.Finalized {
+			return fmt.Errorf("month %d: %w", monthID, ErrMonthNotFinalized)
+		}
+		before := *month
+		month.Finalized = false
+		return d.recordAudit(AuditEntityMonth, month.ID, AuditActionReopen, &month.ID, before, *month)
+	})
+}
+
+func (m *memoryStore) GetMonthSnapshots(ctx context.Context, monthID int64) ([]AnnualSnapMeta, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metas := []AnnualSnapMeta{}
+	for _, snap := range d.annualSnaps {
+		if snap.MonthID != monthID {
+			continue
+		}
+		meta, err := snapMeta(snap, d.month(monthID))
+		if err != nil {
+			return nil, fmt.Errorf("error fetching snapshots of month %d: %w", monthID, err)
+		}
+		metas = append(metas, meta)
+	}
+	sort.SliceStable(metas, func(i, j int) bool { return metas[i].Version > metas[j].Version })
+	return metas, nil
+}
+
+func (m *memoryStore) CanFinalizeMonth(ctx context.Context, monthID int) (bool, string, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return false, "", err
+	}
+	count := 0
+	for _, bl := range d.budgetLines {
+		if bl.MonthID != monthID || bl.DeletedAt != nil {
+			continue
+		}
+		for _, al := range d.actualLines {
+			if al.BudgetLineID == int64(bl.ID) && al.Actual == 0 {
+				count++
+			}
+		}
+	}
+	if count > 0 {
+		return false, fmt.Sprintf("%d budget lines still have zero actuals.", count), nil
+	}
+	return true, "", nil
+}
+
+func (m *memoryStore) FinalizeMonth(ctx context.Context, monthID int, snapJSON string) (int64, error) {
+	var newMonthID int64
+	err := m.update(ctx, func(d *memoryData) error {
+		if req, err := d.priorFinalizeResult(ctx, int64(monthID)); err != nil || req != nil {
+			if req != nil {
+				newMonthID = req.NextMonthID
+			}
+			return err
+		}
+		current := d.month(int64(monthID))
+		if current == nil {
+			return fmt.Errorf("failed to get current month details for month %d: %w", monthID, sql.ErrNoRows)
+		}
+		if current.Finalized {
+			return fmt.Errorf("month %d: %w", monthID, ErrMonthFinalized)
+		}
+		before := *current
+		current.Finalized = true
+		if err := d.recordAudit(AuditEntityMonth, current.ID, AuditActionUpdate, &current.ID, before, *current); err != nil {
+			return err
+		}
+		version, err := d.insertAnnualSnap(int64(monthID), snapJSON)
+		if err != nil {
+			return fmt.Errorf("failed to create annual snap for month %d: %w", monthID, err)
+		}
+		rolled, err := d.rollOverBudget(*current, version)
+		if err != nil {
+			return err
+		}
+		newMonthID = rolled.next.ID
+		if key := IdempotencyKey(ctx); key != "" {
+			d.finalizeRequests = append(d.finalizeRequests, FinalizeRequest{
+				Key: key, MonthID: current.ID, NextMonthID: newMonthID, CreatedAt: time.Now().UTC().Truncate(time.Second),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return newMonthID, nil
+}
+
+// priorFinalizeResult mirrors the SQL store: it returns the earlier finalize of
+// monthID made with the key on ctx, or nil.
+func (d *memoryData) priorFinalizeResult(ctx context.Context, monthID int64) (*FinalizeRequest, error) {
+	key := IdempotencyKey(ctx)
+	if key == "" {
+		return nil, nil
+	}
+	req := findRow(d.finalizeRequests, func(r *FinalizeRequest) bool { return r.Key == key })
+	if req == nil {
+		return nil, nil
+	}
+	if req.MonthID != monthID {
+		return nil, fmt.Errorf("key %q finalized month %d: %w", key, req.MonthID, ErrIdempotencyKeyReused)
+	}
+	return req, nil
+}
+
+// rollOverBudget mirrors the SQL store's: it clones current's lines into the
+// next month, creating it or adding only the lines it lacks.
+func (d *memoryData) rollOverBudget(current Month, version int) (*rollOver, error) {
+	nextYear, nextMonthVal := current.Year, current.Month+1
+	if nextMonthVal > 12 {
+		nextMonthVal = 1
+		nextYear++
+	}
+	rolled := &rollOver{}
+	if next := d.monthOf(nextYear, nextMonthVal); next != nil {
+		rolled.next = *next
+		if next.Finalized || version > 1 {
+			return rolled, nil
+		}
+	} else {
+		rolled.next = Month{ID: nextID(d.months, monthRowID), Year: nextYear, Month: nextMonthVal}
+		rolled.created = true
+		d.months = append(d.months, rolled.next)
+		if err := d.recordAudit(AuditEntityMonth, rolled.next.ID, AuditActionCreate, &rolled.next.ID, nil, rolled.next); err != nil {
+			return nil, err
+		}
+	}
+	nextMonthID := rolled.next.ID
+
+	present := map[lineLabel]bool{}
+	for _, bl := range d.budgetLines {
+		if int64(bl.MonthID) == nextMonthID && bl.DeletedAt == nil {
+			present[lineLabel{bl.CategoryID, bl.Label}] = true
+		}
+	}
+	for _, bl := range append([]BudgetLine(nil), d.budgetLines...) {
+		if int64(bl.MonthID) != current.ID || bl.DeletedAt != nil || present[lineLabel{bl.CategoryID, bl.Label}] {
+			continue
+		}
+		newBudgetLineID := nextID(d.budgetLines, budgetLineRowID)
+		cloned := BudgetLine{
+			ID: int(newBudgetLineID), MonthID: int(nextMonthID), CategoryID: bl.CategoryID, Label: bl.Label, Expected: bl.Expected, Currency: bl.Currency,
+		}
+		d.budgetLines = append(d.budgetLines, cloned)
+		d.actualLines = append(d.actualLines, ActualLine{ID: nextID(d.actualLines, actualLineRowID), BudgetLineID: newBudgetLineID, Currency: bl.Currency})
+		if err := d.recordAudit(AuditEntityBudgetLine, newBudgetLineID, AuditActionCreate, &nextMonthID, nil, cloned); err != nil {
+			return nil, err
+		}
+		rolled.cloned = append(rolled.cloned, cloned)
+	}
+	return rolled, nil
+}
+
+func (m *memoryStore) PlanFinalizeMonth(ctx context.Context, monthID int) (*FinalizePlan, error) {
+	var plan *FinalizePlan
+	err := m.update(ctx, func(d *memoryData) error {
+		current := d.month(int64(monthID))
+		if current == nil {
+			return fmt.Errorf("failed to get current month details for month %d: %w", monthID, sql.ErrNoRows)
+		}
+		if current.Finalized {
+			return fmt.Errorf("month %d: %w", monthID, ErrMonthFinalized)
+		}
+		version := d.nextSnapVersion(current.ID)
+		rolled, err := d.rollOverBudget(*current, version)
+		if err != nil {
+			return err
+		}
+		plan = rolled.plan(*current, version)
+		return errRollback
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (m *memoryStore) GetFinalizeRequest(ctx context.Context, key string) (*FinalizeRequest, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req := findRow(d.finalizeRequests, func(r *FinalizeRequest) bool { return r.Key == key })
+	if req == nil {
+		return nil, sql.ErrNoRows
+	}
+	found := *req
+	return &found, nil
+}
+
+// nextSnapVersion returns the version the month's next snapshot gets.
+func (d *memoryData) nextSnapVersion(monthID int64) int {
+	version := 1
+	for _, a := range d.annualSnaps {
+		if a.MonthID == monthID && a.Version >= version {
+			version = a.Version + 1
+		}
+	}
+	return version
+}
+
+// insertAnnualSnap records the next snapshot version of a month and returns it.
+func (d *memoryData) insertAnnualSnap(monthID int64, snapJSON string) (int, error) {
+	if d.month(monthID) == nil {
+		return 0, errForeignKey
+	}
+	version := d.nextSnapVersion(monthID)
+	d.annualSnaps = append(d.annualSnaps, AnnualSnap{
+		ID: nextID(d.annualSnaps, annualSnapRowID), MonthID: monthID, Version: version, SnapJSON: snapJSON,
+		CreatedAt: time.Now().Format("2006-01-02 15:04:05"),
+	})
+	return version, nil
+}
+
+// latestSnapshots maps each month to the ID of its current snapshot.
+func (d *memoryData) latestSnapshots() map[int64]AnnualSnap {
+	latest := make(map[int64]AnnualSnap)
+	for _, a := range d.annualSnaps {
+		if current, ok := latest[a.MonthID]; !ok || a.Version > current.Version {
+			latest[a.MonthID] = a
+		}
+	}
+	return latest
+}
+
+// snapMeta describes a snapshot of month.
+func snapMeta(snap AnnualSnap, month *Month) (AnnualSnapMeta, error) {
+	createdAt, err := parseSnapCreatedAt(snap.CreatedAt)
+	if err != nil {
+		return AnnualSnapMeta{}, err
+	}
+	return AnnualSnapMeta{
+		ID:            snap.ID,
+		MonthID:       snap.MonthID,
+		Version:       snap.Version,
+		Year:          month.Year,
+		Month:         time.Month(month.Month).String(),
+		SnapCreatedAt: createdAt,
+	}, nil
+}
+
+func (m *memoryStore) GetAnnualSnapshotsMetadataByYear(ctx context.Context, year int) ([]AnnualSnapMeta, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metas := []AnnualSnapMeta{}
+	monthNumbers := make(map[int64]int)
+	latest := d.latestSnapshots()
+	for _, snap := range d.annualSnaps {
+		month := d.month(snap.MonthID)
+		if month == nil || month.Year != year || latest[snap.MonthID].ID != snap.ID {
+			continue
+		}
+		meta, err := snapMeta(snap, month)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching annual snapshots for year %d: %w", year, err)
+		}
+		metas = append(metas, meta)
+		monthNumbers[snap.ID] = month.Month
+	}
+	sort.SliceStable(metas, func(i, j int) bool { return monthNumbers[metas[i].ID] < monthNumbers[metas[j].ID] })
+	return metas, nil
+}
+
+// parseSnapCreatedAt reads a snapshot timestamp the way the SQLite driver reads a
+// DATETIME column: in UTC, in the format FinalizeMonth writes or RFC 3339.
+func parseSnapCreatedAt(value string) (time.Time, error) {
+	createdAt, err := time.Parse("2006-01-02 15:04:05", value)
+	if err != nil {
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return createdAt, nil
+}
+
+func (m *memoryStore) GetAnnualSnapshotJSONByID(ctx context.Context, snapID int64) (string, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return "", err
+	}
+	snap := findRow(d.annualSnaps, func(a *AnnualSnap) bool { return a.ID == snapID })
+	if snap == nil {
+		return "", sql.ErrNoRows
+	}
+	return snap.SnapJSON, nil
+}
+
+func (m *memoryStore) RecordBackupRun(ctx context.Context, run *BackupRun) error {
+	if run.CreatedAt.IsZero() {
+		run.CreatedAt = time.Now()
+	}
+	return m.update(ctx, func(d *memoryData) error {
+		run.ID = nextID(d.backupRuns, backupRunRowID)
+		stored := *run
+		stored.CreatedAt = run.CreatedAt.UTC().Truncate(time.Second)
+		d.backupRuns = append(d.backupRuns, stored)
+		return nil
+	})
+}
+
+func (m *memoryStore) GetLastBackupRun(ctx context.Context) (*BackupRun, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var last *BackupRun
+	for i := range d.backupRuns {
+		run := &d.backupRuns[i]
+		if last == nil || run.CreatedAt.After(last.CreatedAt) || (run.CreatedAt.Equal(last.CreatedAt) && run.ID > last.ID) {
+			last = run
+		}
+	}
+	return clonePtr(last), nil
+}
+
+func (m *memoryStore) GetExchangeRates(ctx context.Context) ([]ExchangeRate, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rates := append([]ExchangeRate{}, d.exchangeRates...)
+	sort.SliceStable(rates, func(i, j int) bool {
+		a, b := rates[i], rates[j]
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.Year*12+a.Month < b.Year*12+b.Month
+	})
+	return rates, nil
+}
+
+func (m *memoryStore) SetExchangeRates(ctx context.Context, rates []ExchangeRate) error {
+	return m.update(ctx, func(d *memoryData) error {
+		for _, r := range rates {
+			if err := r.Validate(); err != nil {
+				return err
+			}
+			existing := findRow(d.exchangeRates, func(e *ExchangeRate) bool {
+				return e.Currency == r.Currency && e.Year == r.Year && e.Month == r.Month
+			})
+			if existing != nil {
+				existing.Rate = r.Rate
+				continue
+			}
+			d.exchangeRates = append(d.exchangeRates, ExchangeRate{
+				ID: nextID(d.exchangeRates, exchangeRateRowID), Currency: r.Currency, Year: r.Year, Month: r.Month, Rate: r.Rate,
+			})
+		}
+		return nil
+	})
+}
+
+func (m *memoryStore) DeleteExchangeRate(ctx context.Context, id int64) error {
+	return m.update(ctx, func(d *memoryData) error {
+		if deleteRows(&d.exchangeRates, func(r *ExchangeRate) bool { return r.ID == id }) == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+func (m *memoryStore) GetExchangeRatesForMonth(ctx context.Context, monthID int) (map[Currency]float64, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[Currency]float64)
+	month := d.month(int64(monthID))
+	if month == nil {
+		return rates, nil
+	}
+	latest := make(map[Currency]int)
+	for _, r := range d.exchangeRates {
+		period := r.Year*12 + r.Month
+		if period > month.Year*12+month.Month {
+			continue
+		}
+		if seen, ok := latest[r.Currency]; !ok || period > seen {
+			latest[r.Currency] = period
+			rates[r.Currency] = r.Rate
+		}
+	}
+	return rates, nil
+}
+
+func (m *memoryStore) GetTransactionsByBudgetLineID(ctx context.Context, budgetLineID int64) ([]Transaction, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if d.liveBudgetLine(budgetLineID) == nil {
+		return nil, sql.ErrNoRows
+	}
+	transactions := []Transaction{}
+	for _, t := range d.transactions {
+		if t.BudgetLineID == budgetLineID {
+			transactions = append(transactions, d.withCurrency(t))
+		}
+	}
+	sort.SliceStable(transactions, func(i, j int) bool { return transactions[i].Date.Before(transactions[j].Date) })
+	return transactions, nil
+}
+
+func (m *memoryStore) GetTransactionByID(ctx context.Context, id int64) (*Transaction, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t := d.liveTransaction(id)
+	if t == nil {
+		return nil, sql.ErrNoRows
+	}
+	transaction := d.withCurrency(*t)
+	return &transaction, nil
+}
+
+func (m *memoryStore) CreateTransaction(ctx context.Context, t *Transaction) (int64, error) {
+	var id int64
+	var currency Currency
+	err := m.update(ctx, func(d *memoryData) error {
+		if d.liveBudgetLine(t.BudgetLineID) == nil {
+			return sql.ErrNoRows
+		}
+		if err := d.checkLineEditable(ctx, t.BudgetLineID); err != nil {
+			return err
+		}
+		id = d.insertTransaction(*t)
+		if err := d.refreshActual(t.BudgetLineID); err != nil {
+			return err
+		}
+		currency = d.withCurrency(*t).Currency
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	t.ID, t.Currency = id, currency
+	return id, nil
+}
+
+func (m *memoryStore) UpdateTransaction(ctx context.Context, t *Transaction) error {
+	var lineID int64
+	var currency Currency
+	err := m.update(ctx, func(d *memoryData) error {
+		stored := d.liveTransaction(t.ID)
+		if stored == nil {
+			return sql.ErrNoRows
+		}
+		lineID = stored.BudgetLineID
+		if err := d.checkLineEditable(ctx, lineID); err != nil {
+			return err
+		}
+		stored.Date, stored.Amount, stored.Payee, stored.Memo = dateOnly(t.Date), t.Amount, t.Payee, t.Memo
+		currency = d.withCurrency(*stored).Currency
+		return d.refreshActual(lineID)
+	})
+	if err != nil {
+		return err
+	}
+	t.BudgetLineID, t.Currency = lineID, currency
+	return nil
+}
+
+func (m *memoryStore) DeleteTransaction(ctx context.Context, id int64) error {
+	return m.update(ctx, func(d *memoryData) error {
+		t := d.liveTransaction(id)
+		if t == nil {
+			return sql.ErrNoRows
+		}
+		lineID := t.BudgetLineID
+		if err := d.checkLineEditable(ctx, lineID); err != nil {
+			return err
+		}
+		deleteRows(&d.transactions, func(t *Transaction) bool { return t.ID == id })
+		return d.refreshActual(lineID)
+	})
+}
+
+// withCurrency fills in the currency of a transaction, which like the SQL store
+// is read from the line's actual rather than kept with the transaction.
+func (d *memoryData) withCurrency(t Transaction) Transaction {
+	if al := findRow(d.actualLines, func(a *ActualLine) bool { return a.BudgetLineID == t.BudgetLineID }); al != nil {
+		t.Currency = al.Currency
+	} else if bl := d.budgetLine(t.BudgetLineID); bl != nil {
+		t.Currency = bl.Currency
+	}
+	return t
+}
+
+func (d *memoryData) insertTransaction(t Transaction) int64 {
+	t.ID = nextID(d.transactions, transactionRowID)
+	t.Date = dateOnly(t.Date)
+	t.Currency = ""
+	d.transactions = append(d.transactions, t)
+	return t.ID
+}
+
+func (d *memoryData) transactionTotal(budgetLineID int64) Money {
+	var total Money
+	for _, t := range d.transactions {
+		if t.BudgetLineID == budgetLineID {
+			total += t.Amount
+		}
+	}
+	return total
+}
+
+// refreshActual works like its SQL counterpart in transactions.go.
+func (d *memoryData) refreshActual(budgetLineID int64) error {
+	before := clonePtr(findRow(d.actualLines, func(a *ActualLine) bool { return a.BudgetLineID == budgetLineID }))
+	if err := d.syncActual(budgetLineID); err != nil {
+		return err
+	}
+	after := findRow(d.actualLines, func(a *ActualLine) bool { return a.BudgetLineID == budgetLineID })
+	if after == nil {
+		return nil
+	}
+	monthID := int64(d.budgetLine(budgetLineID).MonthID)
+	switch {
+	case before == nil:
+		return d.recordAudit(AuditEntityActualLine, after.ID, AuditActionCreate, &monthID, nil, *after)
+	case before.Actual != after.Actual:
+		return d.recordAudit(AuditEntityActualLine, after.ID, AuditActionUpdate, &monthID, *before, *after)
+	}
+	return nil
+}
+
+// syncActual works like its SQL counterpart in transactions.go.
+func (d *memoryData) syncActual(budgetLineID int64) error {
+	total := d.transactionTotal(budgetLineID)
+	if total < 0 {
+		return fmt.Errorf("budget line %d would total %s: %w", budgetLineID, total, ErrNegativeActual)
+	}
+	updated := false
+	for i := range d.actualLines {
+		if d.actualLines[i].BudgetLineID == budgetLineID {
+			d.actualLines[i].Actual = total
+			updated = true
+		}
+	}
+	if bl := d.budgetLine(budgetLineID); !updated && bl != nil {
+		d.actualLines = append(d.actualLines, ActualLine{
+			ID: nextID(d.actualLines, actualLineRowID), BudgetLineID: budgetLineID, Actual: total, Currency: bl.Currency,
+		})
+	}
+	return nil
+}
+
+func (d *memoryData) adjustmentDate(budgetLineID int64) (time.Time, error) {
+	var month *Month
+	if bl := d.budgetLine(budgetLineID); bl != nil {
+		month = d.month(int64(bl.MonthID))
+	}
+	if month == nil {
+		return time.Time{}, fmt.Errorf("failed to get month of budget line %d: %w", budgetLineID, sql.ErrNoRows)
+	}
+	return adjustmentDay(month.Year, month.Month), nil
+}
+
+func (m *memoryStore) GetAuditEntries(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	from, to := filter.From.UTC().Truncate(time.Second), filter.To.UTC().Truncate(time.Second)
+	entries := []AuditEntry{}
+	for i := len(d.auditLog) - 1; i >= 0; i-- {
+		e := d.auditLog[i]
+		switch {
+		case filter.Entity != "" && e.Entity != filter.Entity,
+			filter.MonthID != nil && (e.MonthID == nil || *e.MonthID != *filter.MonthID),
+			!filter.From.IsZero() && e.CreatedAt.Before(from),
+			!filter.To.IsZero() && !e.CreatedAt.Before(to):
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.After(entries[j].CreatedAt) })
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// recordAudit works like its SQL counterpart in audit.go.
+func (d *memoryData) recordAudit(entity string, entityID int64, action string, monthID *int64, before, after interface{}) error {
+	beforeJSON, err := auditJSON(before)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s %d for the audit log: %w", entity, entityID, err)
+	}
+	afterJSON, err := auditJSON(after)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s %d for the audit log: %w", entity, entityID, err)
+	}
+	entry := AuditEntry{
+		ID: nextID(d.auditLog, auditEntryRowID), Entity: entity, EntityID: entityID, Action: action, MonthID: clonePtr(monthID),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if beforeJSON != nil {
+		entry.Before = json.RawMessage(*beforeJSON)
+	}
+	if afterJSON != nil {
+		entry.After = json.RawMessage(*afterJSON)
+	}
+	d.auditLog = append(d.auditLog, entry)
+	return nil
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+func (m *memoryStore) AddBankTransactions(ctx context.Context, txns []BankTransaction) (int, error) {
+	added := 0
+	err := m.update(ctx, func(d *memoryData) error {
+		now := time.Now().UTC().Truncate(time.Second)
+		for i := range txns {
+			t := &txns[i]
+			if t.Status == "" {
+				t.Status = BankTransactionPending
+			}
+			if findRow(d.bankTransactions, func(o *BankTransaction) bool { return o.ExternalID == t.ExternalID }) != nil {
+				continue
+			}
+			if (t.MonthID != nil && d.month(*t.MonthID) == nil) || (t.BudgetLineID != nil && d.budgetLine(*t.BudgetLineID) == nil) {
+				return fmt.Errorf("failed to insert bank transaction %s: %w", t.ExternalID, errForeignKey)
+			}
+			t.ID = nextID(d.bankTransactions, bankTransactionRowID)
+			stored := *t
+			stored.PostedOn = dateOnly(t.PostedOn)
+			stored.MonthID, stored.BudgetLineID = clonePtr(t.MonthID), clonePtr(t.BudgetLineID)
+			stored.ImportedAt = now
+			d.bankTransactions = append(d.bankTransactions, stored)
+			added++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+func (m *memoryStore) GetBankTransactions(ctx context.Context, status string) ([]BankTransaction, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txns := []BankTransaction{}
+	for _, t := range d.bankTransactions {
+		if status == "" || t.Status == status {
+			t.MonthID, t.BudgetLineID = clonePtr(t.MonthID), clonePtr(t.BudgetLineID)
+			txns = append(txns, t)
+		}
+	}
+	sort.SliceStable(txns, func(i, j int) bool { return txns[i].PostedOn.Before(txns[j].PostedOn) })
+	return txns, nil
+}
+
+func (m *memoryStore) ConfirmBankTransactions(ctx context.Context, confirmations []BankConfirmation) error {
+	return m.update(ctx, func(d *memoryData) error {
+		for _, c := range confirmations {
+			txn := d.bankTransaction(c.TransactionID)
+			if txn == nil {
+				return fmt.Errorf("failed to load bank transaction %d: %w", c.TransactionID, sql.ErrNoRows)
+			}
+			if txn.Status != BankTransactionPending {
+				return fmt.Errorf("bank transaction %d: %w", c.TransactionID, ErrTransactionNotPending)
+			}
+
+			var month *Month
+			line := d.liveBudgetLine(c.BudgetLineID)
+			if line != nil {
+				month = d.month(int64(line.MonthID))
+			}
+			if month == nil {
+				return fmt.Errorf("failed to load budget line %d: %w", c.BudgetLineID, sql.ErrNoRows)
+			}
+			if err := d.checkLineEditable(ctx, c.BudgetLineID); err != nil {
+				return fmt.Errorf("budget line %d: %w", c.BudgetLineID, err)
+			}
+
+			currency := line.Currency
+			if actual := findRow(d.actualLines, func(a *ActualLine) bool { return a.BudgetLineID == c.BudgetLineID }); actual != nil {
+				currency = actual.Currency
+			}
+			if base := BudgetCurrency(); currency != "" && currency != base {
+				return fmt.Errorf("budget line %d records actuals in %s, not %s: %w", c.BudgetLineID, currency, base, ErrCurrencyMismatch)
+			}
+			d.insertTransaction(Transaction{BudgetLineID: c.BudgetLineID, Date: txn.PostedOn, Amount: txn.Amount, Payee: txn.Description})
+			if err := d.refreshActual(c.BudgetLineID); err != nil {
+				return err
+			}
+
+			budgetLineID, monthID := c.BudgetLineID, month.ID
+			txn.Status, txn.BudgetLineID, txn.MonthID = BankTransactionApplied, &budgetLineID, &monthID
+		}
+		return nil
+	})
+}
+
+func (m *memoryStore) IgnoreBankTransaction(ctx context.Context, id int64) error {
+	return m.update(ctx, func(d *memoryData) error {
+		txn := d.bankTransaction(id)
+		if txn == nil {
+			return fmt.Errorf("failed to load bank transaction %d: %w", id, sql.ErrNoRows)
+		}
+		if txn.Status != BankTransactionPending {
+			return fmt.Errorf("bank transaction %d: %w", id, ErrTransactionNotPending)
+		}
+		txn.Status = BankTransactionIgnored
+		return nil
+	})
+}
+
+func (m *memoryStore) GetMatchRules(ctx context.Context) ([]MatchRule, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := []MatchRule{}
+	for _, r := range d.matchRules {
+		r.CategoryID, r.Amount = clonePtr(r.CategoryID), clonePtr(r.Amount)
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func (m *memoryStore) CreateMatchRule(ctx context.Context, rule *MatchRule) error {
+	var id int64
+	err := m.update(ctx, func(d *memoryData) error {
+		if rule.CategoryID != nil && d.category(*rule.CategoryID) == nil {
+			return fmt.Errorf("failed to create match rule: %w", errForeignKey)
+		}
+		stored := *rule
+		stored.ID = nextID(d.matchRules, matchRuleRowID)
+		stored.CategoryID, stored.Amount = clonePtr(rule.CategoryID), clonePtr(rule.Amount)
+		d.matchRules = append(d.matchRules, stored)
+		id = stored.ID
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	rule.ID = id
+	return nil
+}
+
+func (m *memoryStore) DeleteMatchRule(ctx context.Context, id int64) error {
+	return m.update(ctx, func(d *memoryData) error {
+		if deleteRows(&d.matchRules, func(r *MatchRule) bool { return r.ID == id }) == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
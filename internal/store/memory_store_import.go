@@ -0,0 +1,437 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+func (m *memoryStore) ExportAll(ctx context.Context, sink ExportSink) error {
+	d, err := m.read(ctx)
+	if err != nil {
+		return err
+	}
+	// The SQL store reads created_at back as a time, which turns into RFC 3339.
+	transactions := make([]Transaction, len(d.transactions))
+	for i, t := range d.transactions {
+		transactions[i] = d.withCurrency(t)
+	}
+	snaps := append([]AnnualSnap(nil), d.annualSnaps...)
+	for i := range snaps {
+		if createdAt, err := parseSnapCreatedAt(snaps[i].CreatedAt); err == nil {
+			snaps[i].CreatedAt = createdAt.Format(time.RFC3339Nano)
+		}
+	}
+	tables := []struct {
+		name string
+		rows []interface{}
+	}{
+		{"months", exportRows(d.months)},
+		{"categories", exportRows(d.categories)},
+		{"budget_lines", exportRows(d.budgetLines)},
+		{"actual_lines", exportRows(d.actualLines)},
+		{"transactions", exportRows(transactions)},
+		{"annual_snaps", exportRows(snaps)},
+		{"exchange_rates", exportRows(d.exchangeRates)},
+		{"match_rules", exportRows(d.matchRules)},
+		{"bank_transactions", exportRows(d.bankTransactions)},
+	}
+
+	for _, table := range tables {
+		if err := sink.BeginTable(table.name); err != nil {
+			return fmt.Errorf("failed to begin export of table %s: %w", table.name, err)
+		}
+		for _, row := range table.rows {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := sink.WriteRow(row); err != nil {
+				return fmt.Errorf("failed to write row of table %s: %w", table.name, err)
+			}
+		}
+		if err := sink.EndTable(); err != nil {
+			return fmt.Errorf("failed to end export of table %s: %w", table.name, err)
+		}
+	}
+	return nil
+}
+
+// exportRows hands out pointers to copies of the rows, as scanning a query would.
+func exportRows[T any](rows []T) []interface{} {
+	out := make([]interface{}, len(rows))
+	for i := range rows {
+		row := rows[i]
+		out[i] = &row
+	}
+	return out
+}
+
+// ImportAll works like its SQL counterpart in import.go.
+func (m *memoryStore) ImportAll(ctx context.Context, dump *DatabaseDump, mode ImportMode, dryRun bool) (*ImportReport, error) {
+	if mode != ImportModeReplace && mode != ImportModeMerge {
+		return nil, fmt.Errorf("unknown import mode %q", mode)
+	}
+
+	report := &ImportReport{Mode: mode, DryRun: dryRun}
+	err := m.update(ctx, func(d *memoryData) error {
+		existing := ImportTableCounts{
+			Months:           len(d.months),
+			Categories:       len(d.categories),
+			BudgetLines:      len(d.budgetLines),
+			ActualLines:      len(d.actualLines),
+			Transactions:     len(d.transactions),
+			AnnualSnaps:      len(d.annualSnaps),
+			ExchangeRates:    len(d.exchangeRates),
+			MatchRules:       len(d.matchRules),
+			BankTransactions: len(d.bankTransactions),
+		}
+		if mode == ImportModeMerge {
+			if existing.Categories+existing.BudgetLines+existing.ActualLines+existing.Transactions+existing.AnnualSnaps+existing.ExchangeRates+
+				existing.MatchRules+existing.BankTransactions > 0 {
+				return ErrDatabaseNotEmpty
+			}
+			for _, dm := range dump.Months {
+				deleted, err := d.deleteMonths(func(m *Month) bool { return m.ID == dm.ID || (m.Year == dm.Year && m.Month == dm.Month) })
+				if err != nil {
+					return fmt.Errorf("failed to clear empty month clashing with %d-%02d: %w", dm.Year, dm.Month, err)
+				}
+				report.Deleted.Months += deleted
+			}
+		} else {
+			d.bankTransactions, d.matchRules, d.exchangeRates, d.annualSnaps, d.finalizeRequests, d.auditLog = nil, nil, nil, nil, nil, nil
+			d.deleteBudgetLines(func(*BudgetLine) bool { return true })
+			d.categories = nil
+			if _, err := d.deleteMonths(func(*Month) bool { return true }); err != nil {
+				return fmt.Errorf("failed to clear table months: %w", err)
+			}
+			report.Deleted = existing
+		}
+
+		for _, mo := range dump.Months {
+			if d.month(mo.ID) != nil {
+				return fmt.Errorf("failed to import month %d: %w", mo.ID, errUnique("months.id"))
+			}
+			if d.monthOf(mo.Year, mo.Month) != nil {
+				return fmt.Errorf("failed to import month %d: %w", mo.ID, errUnique("months.year, months.month"))
+			}
+			d.months = append(d.months, mo)
+		}
+		for _, c := range dump.Categories {
+			if d.category(c.ID) != nil {
+				return fmt.Errorf("failed to import category %d (%s): %w", c.ID, c.Name, errUnique("categories.id"))
+			}
+			if findRow(d.categories, func(o *Category) bool { return o.Name == c.Name }) != nil {
+				return fmt.Errorf("failed to import category %d (%s): %w", c.ID, c.Name, errUnique("categories.name"))
+			}
+			c.DeletedAt = trashTimeOf(c.DeletedAt)
+			d.categories = append(d.categories, c)
+		}
+		for _, b := range dump.BudgetLines {
+			if b.Currency == "" {
+				b.Currency = BudgetCurrency()
+			}
+			if d.budgetLine(int64(b.ID)) != nil {
+				return fmt.Errorf("failed to import budget line %d (%s): %w", b.ID, b.Label, errUnique("budget_lines.id"))
+			}
+			if d.month(int64(b.MonthID)) == nil || d.category(int64(b.CategoryID)) == nil {
+				return fmt.Errorf("failed to import budget line %d (%s): %w", b.ID, b.Label, errForeignKey)
+			}
+			d.budgetLines = append(d.budgetLines, BudgetLine{
+				ID: b.ID, MonthID: b.MonthID, CategoryID: b.CategoryID, Label: b.Label, Expected: b.Expected, Currency: b.Currency,
+				DeletedAt: trashTimeOf(b.DeletedAt),
+			})
+		}
+		for _, a := range dump.ActualLines {
+			if a.Currency == "" {
+				a.Currency = BudgetCurrency()
+			}
+			if findRow(d.actualLines, func(o *ActualLine) bool { return o.ID == a.ID }) != nil {
+				return fmt.Errorf("failed to import actual line %d: %w", a.ID, errUnique("actual_lines.id"))
+			}
+			if d.budgetLine(a.BudgetLineID) == nil {
+				return fmt.Errorf("failed to import actual line %d: %w", a.ID, errForeignKey)
+			}
+			d.actualLines = append(d.actualLines, a)
+		}
+		for _, t := range dump.Transactions {
+			if d.transaction(t.ID) != nil {
+				return fmt.Errorf("failed to import transaction %d: %w", t.ID, errUnique("transactions.id"))
+			}
+			if d.budgetLine(t.BudgetLineID) == nil {
+				return fmt.Errorf("failed to import transaction %d: %w", t.ID, errForeignKey)
+			}
+			t.Date, t.Currency = dateOnly(t.Date), ""
+			d.transactions = append(d.transactions, t)
+		}
+		d.sortByID()
+		opened := d.recordOpeningTransactions()
+		for _, snap := range dump.AnnualSnaps {
+			if findRow(d.annualSnaps, func(o *AnnualSnap) bool { return o.ID == snap.ID }) != nil {
+				return fmt.Errorf("failed to import annual snap %d: %w", snap.ID, errUnique("annual_snaps.id"))
+			}
+			if d.month(snap.MonthID) == nil {
+				return fmt.Errorf("failed to import annual snap %d: %w", snap.ID, errForeignKey)
+			}
+			if snap.Version == 0 {
+				snap.Version = 1
+			}
+			if findRow(d.annualSnaps, func(o *AnnualSnap) bool { return o.MonthID == snap.MonthID && o.Version == snap.Version }) != nil {
+				return fmt.Errorf("failed to import annual snap %d: %w", snap.ID, errUnique("annual_snaps.month_id, annual_snaps.version"))
+			}
+			d.annualSnaps = append(d.annualSnaps, snap)
+		}
+		for _, r := range dump.ExchangeRates {
+			if findRow(d.exchangeRates, func(o *ExchangeRate) bool { return o.ID == r.ID }) != nil {
+				return fmt.Errorf("failed to import exchange rate %d (%s): %w", r.ID, r.Currency, errUnique("exchange_rates.id"))
+			}
+			if findRow(d.exchangeRates, func(o *ExchangeRate) bool { return o.Currency == r.Currency && o.Year == r.Year && o.Month == r.Month }) != nil {
+				return fmt.Errorf("failed to import exchange rate %d (%s): %w", r.ID, r.Currency,
+					errUnique("exchange_rates.currency, exchange_rates.year, exchange_rates.month"))
+			}
+			d.exchangeRates = append(d.exchangeRates, r)
+		}
+		for _, r := range dump.MatchRules {
+			if findRow(d.matchRules, func(o *MatchRule) bool { return o.ID == r.ID }) != nil {
+				return fmt.Errorf("failed to import match rule %d (%s): %w", r.ID, r.Pattern, errUnique("match_rules.id"))
+			}
+			if r.CategoryID != nil && d.category(*r.CategoryID) == nil {
+				return fmt.Errorf("failed to import match rule %d (%s): %w", r.ID, r.Pattern, errForeignKey)
+			}
+			r.CategoryID, r.Amount = clonePtr(r.CategoryID), clonePtr(r.Amount)
+			d.matchRules = append(d.matchRules, r)
+		}
+		for _, t := range dump.BankTransactions {
+			if d.bankTransaction(t.ID) != nil {
+				return fmt.Errorf("failed to import bank transaction %d (%s): %w", t.ID, t.ExternalID, errUnique("bank_transactions.id"))
+			}
+			if findRow(d.bankTransactions, func(o *BankTransaction) bool { return o.ExternalID == t.ExternalID }) != nil {
+				return fmt.Errorf("failed to import bank transaction %d (%s): %w", t.ID, t.ExternalID, errUnique("bank_transactions.external_id"))
+			}
+			if (t.MonthID != nil && d.month(*t.MonthID) == nil) || (t.BudgetLineID != nil && d.budgetLine(*t.BudgetLineID) == nil) {
+				return fmt.Errorf("failed to import bank transaction %d (%s): %w", t.ID, t.ExternalID, errForeignKey)
+			}
+			t.PostedOn, t.ImportedAt = dateOnly(t.PostedOn), t.ImportedAt.UTC().Truncate(time.Second)
+			t.MonthID, t.BudgetLineID = clonePtr(t.MonthID), clonePtr(t.BudgetLineID)
+			d.bankTransactions = append(d.bankTransactions, t)
+		}
+		d.sortByID()
+
+		report.Inserted = ImportTableCounts{
+			Months:           len(dump.Months),
+			Categories:       len(dump.Categories),
+			BudgetLines:      len(dump.BudgetLines),
+			ActualLines:      len(dump.ActualLines),
+			Transactions:     len(dump.Transactions) + opened,
+			AnnualSnaps:      len(dump.AnnualSnaps),
+			ExchangeRates:    len(dump.ExchangeRates),
+			MatchRules:       len(dump.MatchRules),
+			BankTransactions: len(dump.BankTransactions),
+		}
+		if err := d.recordAudit(AuditEntityDatabase, 0, AuditActionImport, nil, report.Deleted, report.Inserted); err != nil {
+			return err
+		}
+		if dryRun {
+			return errRollback
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// sortByID restores ID order after rows were added with IDs of their own.
+func (d *memoryData) sortByID() {
+	sortByID(d.months, monthRowID)
+	sortByID(d.categories, categoryRowID)
+	sortByID(d.budgetLines, budgetLineRowID)
+	sortByID(d.actualLines, actualLineRowID)
+	sortByID(d.transactions, transactionRowID)
+	sortByID(d.annualSnaps, annualSnapRowID)
+	sortByID(d.exchangeRates, exchangeRateRowID)
+	sortByID(d.matchRules, matchRuleRowID)
+	sortByID(d.bankTransactions, bankTransactionRowID)
+}
+
+// recordOpeningTransactions works like its SQL counterpart in transactions.go.
+func (d *memoryData) recordOpeningTransactions() int {
+	var opening []Transaction
+	for _, al := range d.actualLines {
+		var month *Month
+		if bl := d.budgetLine(al.BudgetLineID); bl != nil {
+			month = d.month(int64(bl.MonthID))
+		}
+		if month == nil {
+			continue
+		}
+		if diff := al.Actual - d.transactionTotal(al.BudgetLineID); diff != 0 {
+			opening = append(opening, Transaction{
+				BudgetLineID: al.BudgetLineID,
+				Date:         time.Date(month.Year, time.Month(month.Month), 1, 0, 0, 0, 0, time.UTC),
+				Amount:       diff,
+				Memo:         "Actual recorded before transactions",
+			})
+		}
+	}
+	for _, t := range opening {
+		d.insertTransaction(t)
+	}
+	return len(opening)
+}
+
+// ImportLegacyRows works like its SQL counterpart in legacy_import.go.
+func (m *memoryStore) ImportLegacyRows(ctx context.Context, rows []LegacyImportRow, opts LegacyImportOptions) (*LegacyImportReport, error) {
+	report := &LegacyImportReport{
+		Preview:           opts.Preview,
+		Imported:          []LegacyImportRow{},
+		Rejected:          []LegacyImportRejection{},
+		CreatedCategories: []string{},
+		CreatedMonths:     []string{},
+		FinalizedMonths:   []string{},
+	}
+
+	err := m.update(ctx, func(d *memoryData) error {
+		categoryIDs := make(map[string]int64)
+		for _, c := range d.categories {
+			categoryIDs[strings.ToLower(c.Name)] = c.ID
+		}
+		restoredCategories := make(map[int64]bool)
+
+		type monthState struct {
+			id        int64
+			finalized bool
+			created   bool
+		}
+		months := make(map[[2]int]*monthState)
+		var monthOrder [][2]int
+		seenLines := make(map[string]bool)
+
+		for _, row := range rows {
+			period := [2]int{row.Year, row.Month}
+			month, ok := months[period]
+			if !ok {
+				month = &monthState{}
+				if existing := d.monthOf(row.Year, row.Month); existing != nil {
+					month.id, month.finalized = existing.ID, existing.Finalized
+				} else {
+					month.id = nextID(d.months, monthRowID)
+					created := Month{ID: month.id, Year: row.Year, Month: row.Month}
+					d.months = append(d.months, created)
+					month.created = true
+					if err := d.recordAudit(AuditEntityMonth, month.id, AuditActionCreate, &month.id, nil, created); err != nil {
+						return err
+					}
+					report.CreatedMonths = append(report.CreatedMonths, fmt.Sprintf("%d-%02d", row.Year, row.Month))
+				}
+				months[period] = month
+				monthOrder = append(monthOrder, period)
+			}
+			if month.finalized && !month.created {
+				report.Rejected = append(report.Rejected, LegacyImportRejection{
+					Line: row.Line, Reason: fmt.Sprintf("month %d-%02d is already finalized", row.Year, row.Month),
+				})
+				continue
+			}
+
+			categoryID, ok := categoryIDs[strings.ToLower(row.Category)]
+			if !ok {
+				if findRow(d.categories, func(c *Category) bool { return c.Name == row.Category }) != nil {
+					return fmt.Errorf("failed to create category %s: %w", row.Category, errUnique("categories.name"))
+				}
+				color := legacyCategoryColors[len(report.CreatedCategories)%len(legacyCategoryColors)]
+				categoryID = nextID(d.categories, categoryRowID)
+				created := Category{ID: categoryID, Name: row.Category, Color: color}
+				d.categories = append(d.categories, created)
+				if err := d.recordAudit(AuditEntityCategory, categoryID, AuditActionCreate, nil, nil, created); err != nil {
+					return err
+				}
+				categoryIDs[strings.ToLower(row.Category)] = categoryID
+				report.CreatedCategories = append(report.CreatedCategories, row.Category)
+			}
+			if c := d.category(categoryID); c.DeletedAt != nil && !restoredCategories[categoryID] {
+				before := *c
+				c.DeletedAt = nil
+				restoredCategories[categoryID] = true
+				if err := d.recordAudit(AuditEntityCategory, categoryID, AuditActionRestore, nil, before, *c); err != nil {
+					return err
+				}
+			}
+
+			lineKey := fmt.Sprintf("%d/%d/%s", month.id, categoryID, strings.ToLower(row.Label))
+			if seenLines[lineKey] {
+				report.Rejected = append(report.Rejected, LegacyImportRejection{
+					Line: row.Line, Reason: fmt.Sprintf("duplicate line %q for %s in %d-%02d", row.Label, row.Category, row.Year, row.Month),
+				})
+				continue
+			}
+			seenLines[lineKey] = true
+			existing := findRow(d.budgetLines, func(b *BudgetLine) bool {
+				return int64(b.MonthID) == month.id && int64(b.CategoryID) == categoryID && strings.EqualFold(b.Label, row.Label) && b.DeletedAt == nil
+			})
+			if existing != nil {
+				report.Rejected = append(report.Rejected, LegacyImportRejection{
+					Line: row.Line, Reason: fmt.Sprintf("%d-%02d already has a %q line for %s", row.Year, row.Month, row.Label, row.Category),
+				})
+				continue
+			}
+
+			budgetLineID := nextID(d.budgetLines, budgetLineRowID)
+			created := BudgetLine{
+				ID: int(budgetLineID), MonthID: int(month.id), CategoryID: int(categoryID), Label: row.Label, Expected: row.Expected, Currency: BudgetCurrency(),
+			}
+			d.budgetLines = append(d.budgetLines, created)
+			if err := d.recordAudit(AuditEntityBudgetLine, budgetLineID, AuditActionCreate, &month.id, nil, created); err != nil {
+				return err
+			}
+			if row.Actual != 0 {
+				d.insertTransaction(Transaction{BudgetLineID: budgetLineID, Date: time.Date(row.Year, time.Month(row.Month), 1, 0, 0, 0, 0, time.UTC), Amount: row.Actual, Memo: "Imported actual"})
+			}
+			if err := d.refreshActual(budgetLineID); err != nil {
+				return fmt.Errorf("failed to insert actual for %s (line %d): %w", row.Label, row.Line, err)
+			}
+			report.Imported = append(report.Imported, row)
+		}
+
+		for _, period := range monthOrder {
+			month := months[period]
+			if month.finalized || !periodBefore(period, opts.FinalizeBeforeYear, opts.FinalizeBeforeMonth) {
+				continue
+			}
+			if err := d.finalizeImportedMonth(month.id); err != nil {
+				return err
+			}
+			report.FinalizedMonths = append(report.FinalizedMonths, fmt.Sprintf("%d-%02d", period[0], period[1]))
+		}
+
+		if opts.Preview {
+			return errRollback
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (d *memoryData) finalizeImportedMonth(monthID int64) error {
+	boardData, err := d.boardData(int(monthID))
+	if err != nil {
+		return err
+	}
+	boardData.IsFinalized = true
+	snapJSON, err := json.Marshal(boardData)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot for month %d: %w", monthID, err)
+	}
+	if _, err := d.insertAnnualSnap(monthID, string(snapJSON)); err != nil {
+		return fmt.Errorf("failed to create snapshot for month %d: %w", monthID, err)
+	}
+	month := d.month(monthID)
+	before := *month
+	month.Finalized = true
+	return d.recordAudit(AuditEntityMonth, monthID, AuditActionUpdate, &monthID, before, *month)
+}
@@ -0,0 +1,264 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storeBackends returns a fresh, empty instance of every Store implementation;
+// the tests below run the same scenario against each to keep them alike. The
+// SQL store runs on PostgreSQL too when a test server is configured.
+func storeBackends(t *testing.T) map[string]Store {
+	t.Helper()
+	backends := map[string]Store{
+		"sql":    NewSQLStore(newTestDB(t)),
+		"memory": NewMemoryStore(),
+	}
+	if os.Getenv(postgresTestDSNEnv) != "" {
+		backends["postgres"] = NewSQLStore(newPostgresTestDB(t))
+	}
+	return backends
+}
+
+// sqlTestDBs returns a fresh, migrated database for each SQL dialect, for the
+// code that works on the database directly rather than through a Store.
+func sqlTestDBs(t *testing.T) map[string]*sqlx.DB {
+	t.Helper()
+	dbs := map[string]*sqlx.DB{"sqlite": newTestDB(t)}
+	if os.Getenv(postgresTestDSNEnv) != "" {
+		dbs["postgres"] = newPostgresTestDB(t)
+	}
+	return dbs
+}
+
+// seedBackend loads March 2024 with two categories into an empty store.
+func seedBackend(t *testing.T, s Store) {
+	t.Helper()
+	_, err := s.ImportAll(context.Background(), &DatabaseDump{
+		Months:     []Month{{ID: 1, Year: 2024, Month: 3}},
+		Categories: []Category{{ID: 1, Name: "Food", Color: "#0f0"}, {ID: 2, Name: "Bills", Color: "#f00"}},
+	}, ImportModeReplace, false)
+	require.NoError(t, err)
+}
+
+// collectSink keeps exported rows as JSON, per table.
+type collectSink struct {
+	tables map[string][]string
+	table  string
+}
+
+func (c *collectSink) BeginTable(name string) error {
+	c.table = name
+	c.tables[name] = []string{}
+	return nil
+}
+
+func (c *collectSink) WriteRow(row interface{}) error {
+	rowJSON, err := json.Marshal(row)
+	c.tables[c.table] = append(c.tables[c.table], string(rowJSON))
+	return err
+}
+
+func (c *collectSink) EndTable() error { return nil }
+
+func exportTablesOf(t *testing.T, s Store) map[string][]string {
+	t.Helper()
+	sink := &collectSink{tables: map[string][]string{}}
+	require.NoError(t, s.ExportAll(context.Background(), sink))
+	return sink.tables
+}
+
+func TestStoreBackends_BudgetCycle(t *testing.T) {
+	exports := map[string]map[string][]string{}
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+
+			assert.Error(t, s.CreateCategory(ctx, &Category{Name: "Food", Color: "#000"}), "category names are unique")
+			_, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 9, CategoryID: 1, Label: "Nowhere"})
+			assert.Error(t, err, "the month must exist")
+
+			groceriesID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00})
+			require.NoError(t, err)
+			powerID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 2, Label: "Power", Expected: 80_00})
+			require.NoError(t, err)
+
+			day := func(d int) time.Time { return time.Date(2024, time.March, d, 9, 30, 0, 0, time.UTC) }
+			_, err = s.CreateTransaction(ctx, &Transaction{BudgetLineID: groceriesID, Date: day(12), Amount: 45_25, Payee: "Market"})
+			require.NoError(t, err)
+			_, err = s.CreateTransaction(ctx, &Transaction{BudgetLineID: groceriesID, Date: day(3), Amount: 20_00, Payee: "Bakery"})
+			require.NoError(t, err)
+			_, err = s.CreateTransaction(ctx, &Transaction{BudgetLineID: groceriesID, Date: day(4), Amount: -70_00})
+			assert.ErrorIs(t, err, ErrNegativeActual)
+
+			transactions, err := s.GetTransactionsByBudgetLineID(ctx, groceriesID)
+			require.NoError(t, err)
+			require.Len(t, transactions, 2)
+			assert.Equal(t, "Bakery", transactions[0].Payee)
+			assert.Equal(t, time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC), transactions[0].Date, "dates drop the time of day")
+
+			ok, reason, err := s.CanFinalizeMonth(ctx, 1)
+			require.NoError(t, err)
+			assert.False(t, ok)
+			assert.Equal(t, "1 budget lines still have zero actuals.", reason)
+
+			lines, err := s.GetBudgetLinesByMonthID(ctx, 1)
+			require.NoError(t, err)
+			require.Len(t, lines, 2)
+			require.NoError(t, s.UpdateActualLine(ctx, &ActualLine{ID: *lines[1].ActualID, Actual: 75_50}))
+
+			board, err := s.GetBoardData(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, "March", board.MonthName)
+			require.Len(t, board.BudgetLines, 2)
+			assert.Equal(t, "Power", board.BudgetLines[0].Label, "lines are ordered by category name")
+			assert.Equal(t, Money(75_50), board.BudgetLines[0].ActualAmount)
+			assert.Equal(t, Money(65_25), board.BudgetLines[1].ActualAmount)
+			_, err = s.GetBoardData(ctx, 9)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+
+			assert.ErrorIs(t, s.DeleteCategory(ctx, 2), ErrCategoryInUse)
+
+			newMonthID, err := s.FinalizeMonth(ctx, 1, `{"month_id":1}`)
+			require.NoError(t, err)
+			_, err = s.FinalizeMonth(ctx, 1, `{"month_id":1}`)
+			assert.Error(t, err, "a month has one snapshot")
+			months, err := s.GetMonthsByYear(ctx, 2024)
+			require.NoError(t, err)
+			assert.Equal(t, []Month{{ID: 1, Year: 2024, Month: 3, Finalized: true}, {ID: newMonthID, Year: 2024, Month: 4}}, months)
+			cloned, err := s.GetBudgetLinesByMonthID(ctx, int(newMonthID))
+			require.NoError(t, err)
+			require.Len(t, cloned, 2)
+			assert.Equal(t, "Groceries", cloned[0].Label)
+			assert.Equal(t, Money(0), *cloned[0].ActualAmount)
+
+			snaps, err := s.GetAnnualSnapshotsMetadataByYear(ctx, 2024)
+			require.NoError(t, err)
+			require.Len(t, snaps, 1)
+			assert.Equal(t, "March", snaps[0].Month)
+			snapJSON, err := s.GetAnnualSnapshotJSONByID(ctx, snaps[0].ID)
+			require.NoError(t, err)
+			assert.Equal(t, `{"month_id":1}`, snapJSON)
+
+			assert.ErrorIs(t, s.DeleteBudgetLine(ctx, powerID), ErrMonthFinalized)
+			require.NoError(t, s.DeleteBudgetLine(WithFinalizedOverride(ctx), powerID))
+			assert.Error(t, s.DeleteBudgetLine(ctx, powerID))
+			_, err = s.GetBudgetLineByID(ctx, powerID)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+			purged, err := s.PurgeTrash(ctx, time.Now().Add(time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, &TrashCounts{BudgetLines: 1}, purged)
+
+			exports[name] = exportTablesOf(t, s)
+			// Snapshots are stamped with the clock, which may tick between runs.
+			assert.Len(t, exports[name]["annual_snaps"], 1)
+			delete(exports[name], "annual_snaps")
+		})
+	}
+	assert.Equal(t, exports["sql"], exports["memory"], "both stores end up with the same rows")
+	if pg, ok := exports["postgres"]; ok {
+		// PostgreSQL sequences skip the IDs of failed inserts, so only counts compare.
+		for table, rows := range exports["sql"] {
+			assert.Len(t, pg[table], len(rows), "postgres rows in %s", table)
+		}
+	}
+}
+
+func TestStoreBackends_FailedWritesChangeNothing(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			lineID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00})
+			require.NoError(t, err)
+			_, err = s.AddBankTransactions(ctx, []BankTransaction{
+				{ExternalID: "a", PostedOn: time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC), Amount: 12_00, Description: "MARKET"},
+			})
+			require.NoError(t, err)
+			before := exportTablesOf(t, s)
+
+			pending, err := s.GetBankTransactions(ctx, BankTransactionPending)
+			require.NoError(t, err)
+			require.Len(t, pending, 1)
+			err = s.ConfirmBankTransactions(ctx, []BankConfirmation{
+				{TransactionID: pending[0].ID, BudgetLineID: lineID},
+				{TransactionID: pending[0].ID + 1, BudgetLineID: lineID},
+			})
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+
+			report, err := s.ImportLegacyRows(ctx, []LegacyImportRow{{Line: 2, Year: 2023, Month: 12, Category: "Gifts", Label: "Toys", Expected: 50_00, Actual: 45_00}},
+				LegacyImportOptions{FinalizeBeforeYear: 2024, FinalizeBeforeMonth: 1, Preview: true})
+			require.NoError(t, err)
+			assert.Equal(t, []string{"2023-12"}, report.FinalizedMonths)
+
+			_, err = s.ImportAll(ctx, &DatabaseDump{Months: []Month{{ID: 5, Year: 2020, Month: 1}}}, ImportModeReplace, true)
+			require.NoError(t, err)
+			assert.Error(t, s.SetExchangeRates(ctx, []ExchangeRate{{Currency: "EUR", Year: 2024, Month: 1, Rate: 1.1}, {Currency: "EUR", Year: 2024, Month: 2}}),
+				"the second rate has no value")
+
+			assert.Equal(t, before, exportTablesOf(t, s))
+			pending, err = s.GetBankTransactions(ctx, BankTransactionPending)
+			require.NoError(t, err)
+			assert.Len(t, pending, 1, "the first confirmation was rolled back with the second")
+		})
+	}
+}
+
+func TestMemoryStore_ConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	seedBackend(t, s)
+	lineID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Groceries"})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.CreateTransaction(ctx, &Transaction{BudgetLineID: lineID, Date: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), Amount: 1_00})
+			assert.NoError(t, err)
+			_, err = s.GetBoardData(ctx, 1)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	transactions, err := s.GetTransactionsByBudgetLineID(ctx, lineID)
+	require.NoError(t, err)
+	assert.Len(t, transactions, 20)
+	line, err := s.GetBudgetLinesByMonthID(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, Money(20_00), *line[0].ActualAmount)
+}
+
+func TestMemoryStore_HonoursContext(t *testing.T) {
+	s := NewMemoryStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, s.CreateCategory(ctx, &Category{Name: "Food", Color: "#0f0"}), context.Canceled)
+	categories, err := s.GetAllCategories(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, categories)
+}
+
+func TestMemoryStore_ExportsSnapshotTimesLikeSQL(t *testing.T) {
+	s := NewMemoryStore()
+	_, err := s.ImportAll(context.Background(), &DatabaseDump{
+		Months:      []Month{{ID: 1, Year: 2024, Month: 3, Finalized: true}},
+		AnnualSnaps: []AnnualSnap{{ID: 1, MonthID: 1, SnapJSON: `{}`, CreatedAt: "2024-04-01 10:00:00"}},
+	}, ImportModeReplace, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"id":1,"month_id":1,"version":1,"snap_json":"{}","created_at":"2024-04-01T10:00:00Z"}`}, exportTablesOf(t, s)["annual_snaps"])
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// trashTime is the deletion time of a row moved to the trash now, at the
+// precision the database keeps.
+func trashTime() *time.Time {
+	t := time.Now().UTC().Truncate(time.Second)
+	return &t
+}
+
+// trashTimeOf normalizes an imported deletion time like the database would.
+func trashTimeOf(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	normalized := t.UTC().Truncate(time.Second)
+	return &normalized
+}
+
+func (m *memoryStore) GetTrash(ctx context.Context) (*Trash, error) {
+	d, err := m.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	trash := &Trash{Categories: []Category{}, BudgetLines: []BudgetLine{}}
+	for _, c := range d.categories {
+		if c.DeletedAt != nil {
+			trash.Categories = append(trash.Categories, c)
+		}
+	}
+	for _, bl := range d.budgetLines {
+		if bl.DeletedAt == nil {
+			continue
+		}
+		if al := findRow(d.actualLines, func(al *ActualLine) bool { return al.BudgetLineID == int64(bl.ID) }); al != nil {
+			bl.ActualID, bl.ActualAmount, bl.ActualCurrency = clonePtr(&al.ID), clonePtr(&al.Actual), clonePtr(&al.Currency)
+		}
+		trash.BudgetLines = append(trash.BudgetLines, bl)
+	}
+	sort.SliceStable(trash.Categories, func(i, j int) bool {
+		a, b := trash.Categories[i], trash.Categories[j]
+		if !a.DeletedAt.Equal(*b.DeletedAt) {
+			return a.DeletedAt.After(*b.DeletedAt)
+		}
+		return a.ID > b.ID
+	})
+	sort.SliceStable(trash.BudgetLines, func(i, j int) bool {
+		a, b := trash.BudgetLines[i], trash.BudgetLines[j]
+		if !a.DeletedAt.Equal(*b.DeletedAt) {
+			return a.DeletedAt.After(*b.DeletedAt)
+		}
+		return a.ID > b.ID
+	})
+	return trash, nil
+}
+
+func (m *memoryStore) RestoreCategory(ctx context.Context, id int64) error {
+	return m.update(ctx, func(d *memoryData) error {
+		c := d.category(id)
+		if c == nil || c.DeletedAt == nil {
+			return sql.ErrNoRows
+		}
+		before := *c
+		c.DeletedAt = nil
+		return d.recordAudit(AuditEntityCategory, id, AuditActionRestore, nil, before, *c)
+	})
+}
+
+func (m *memoryStore) RestoreBudgetLine(ctx context.Context, id int64) error {
+	return m.update(ctx, func(d *memoryData) error {
+		bl := d.budgetLine(id)
+		if bl == nil || bl.DeletedAt == nil {
+			return sql.ErrNoRows
+		}
+		if d.liveCategory(int64(bl.CategoryID)) == nil {
+			return fmt.Errorf("budget line %d: %w", id, ErrCategoryDeleted)
+		}
+		if err := d.checkMonthEditable(ctx, int64(bl.MonthID)); err != nil {
+			return err
+		}
+		before := *bl
+		bl.DeletedAt = nil
+		monthID := int64(bl.MonthID)
+		return d.recordAudit(AuditEntityBudgetLine, id, AuditActionRestore, &monthID, before, *bl)
+	})
+}
+
+func (m *memoryStore) PurgeTrash(ctx context.Context, deletedBefore time.Time) (*TrashCounts, error) {
+	cutoff := deletedBefore.UTC().Truncate(time.Second)
+	counts := &TrashCounts{}
+	err := m.update(ctx, func(d *memoryData) error {
+		*counts = TrashCounts{}
+		var lines []BudgetLine
+		for _, bl := range d.budgetLines {
+			if bl.DeletedAt != nil && bl.DeletedAt.Before(cutoff) {
+				lines = append(lines, bl)
+			}
+		}
+		for _, line := range lines {
+			d.deleteBudgetLines(func(b *BudgetLine) bool { return b.ID == line.ID })
+			monthID := int64(line.MonthID)
+			if err := d.recordAudit(AuditEntityBudgetLine, int64(line.ID), AuditActionPurge, &monthID, line, nil); err != nil {
+				return err
+			}
+		}
+
+		var categories []Category
+		for _, c := range d.categories {
+			if c.DeletedAt != nil && c.DeletedAt.Before(cutoff) &&
+				findRow(d.budgetLines, func(b *BudgetLine) bool { return int64(b.CategoryID) == c.ID }) == nil {
+				categories = append(categories, c)
+			}
+		}
+		for _, category := range categories {
+			deleteRows(&d.matchRules, func(r *MatchRule) bool { return r.CategoryID != nil && *r.CategoryID == category.ID })
+			deleteRows(&d.categories, func(c *Category) bool { return c.ID == category.ID })
+			if err := d.recordAudit(AuditEntityCategory, category.ID, AuditActionPurge, nil, category, nil); err != nil {
+				return err
+			}
+		}
+		*counts = TrashCounts{Categories: len(categories), BudgetLines: len(lines)}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
@@ -0,0 +1,279 @@
+package store
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+//go:embed migrations/*.sql
+var embeddedMigrations embed.FS
+
+// migration is one numbered schema change, e.g. migrations/002_backup_runs.sql.
+type migration struct {
+	Version  int
+	Name     string
+	SQL      string
+	Checksum string
+}
+
+// moneyMigrationVersion is the SQLite migration that turns REAL amounts into
+// minor units of a currency the operator names; see MigrationOptions.
+const moneyMigrationVersion = 4
+
+// ErrMigrationCurrencyRequired is returned when a database holding REAL amounts
+// is upgraded without naming the currency they are in.
+var ErrMigrationCurrencyRequired = errors.New("the database holds amounts from before the budget recorded its currency; name the currency they are in to upgrade it")
+
+// MigrationOptions are the choices some migrations need from the operator.
+type MigrationOptions struct {
+	// Currency is what the amounts of a database from before they were kept in
+	// minor units are in. It is required to upgrade such a database when it
+	// holds any amount; otherwise the budget starts in it, or in USD if unset.
+	Currency Currency
+}
+
+// legacyBaselineVersion is the schema a database created before migrations were
+// tracked is known to have. Later files were written to be re-runnable because the
+// old runner executed every file on every boot, so they are simply applied.
+const legacyBaselineVersion = 1
+
+// RunMigrations brings the database up to date with the migrations built into the
+// binary. Each pending migration runs once, in version order, in its own
+// transaction; applied ones are recorded in schema_migrations with a checksum so
+// that a migration edited after release is reported instead of silently skipped.
+func RunMigrations(db *sqlx.DB) error {
+	return RunMigrationsWith(db, MigrationOptions{})
+}
+
+// RunMigrationsWith is RunMigrations with the operator's choices for the
+// migrations that need them.
+func RunMigrationsWith(db *sqlx.DB, opts MigrationOptions) error {
+	var fsys fs.FS = embeddedMigrations
+	if db.DriverName() == postgresDriver {
+		var err error
+		if fsys, err = fs.Sub(embeddedPostgresMigrations, "postgres"); err != nil {
+			return fmt.Errorf("failed to open PostgreSQL migrations: %w", err)
+		}
+	}
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return err
+	}
+	return applyMigrations(db, migrations, opts)
+}
+
+// loadMigrations reads the NNN_name.sql files under migrations/ in fsys.
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migration files: %w", err)
+	}
+
+	migrations := make([]migration, 0, len(files))
+	seen := make(map[int]string)
+	for _, file := range files {
+		name := path.Base(file)
+		prefix, _, ok := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration file %s must be named NNN_description.sql", name)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, name, version)
+		}
+		seen[version] = name
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file %s: %w", name, err)
+		}
+		sum := sha256.Sum256(data)
+		migrations = append(migrations, migration{
+			Version:  version,
+			Name:     name,
+			SQL:      string(data),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// applyMigrations runs the migrations that schema_migrations does not list yet.
+func applyMigrations(db *sqlx.DB, migrations []migration, opts MigrationOptions) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	if err := baselineLegacyDatabase(db, migrations); err != nil {
+		return err
+	}
+
+	var applied []struct {
+		Version  int    `db:"version"`
+		Name     string `db:"name"`
+		Checksum string `db:"checksum"`
+	}
+	if err := db.Select(&applied, `SELECT version, name, checksum FROM schema_migrations ORDER BY version`); err != nil {
+		return fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	known := make(map[int]migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+	done := make(map[int]bool, len(applied))
+	for _, a := range applied {
+		m, ok := known[a.Version]
+		if !ok {
+			return fmt.Errorf("database has migration %d (%s) applied, which this binary does not know: it was written by a newer version", a.Version, a.Name)
+		}
+		if m.Checksum != a.Checksum {
+			return fmt.Errorf("migration %s was modified after it was applied (checksum %s, recorded %s)", m.Name, m.Checksum, a.Checksum)
+		}
+		done[a.Version] = true
+	}
+
+	pending := 0
+	for _, m := range migrations {
+		if done[m.Version] {
+			continue
+		}
+		log.Printf("Applying migration: %s", m.Name)
+		if err := applyMigration(db, m, opts); err != nil {
+			return err
+		}
+		pending++
+	}
+	if pending == 0 {
+		log.Printf("Database schema is up to date (%d migrations).", len(migrations))
+	} else {
+		log.Printf("Applied %d migration(s).", pending)
+	}
+	return nil
+}
+
+func applyMigration(db *sqlx.DB, m migration, opts MigrationOptions) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for migration %s: %w", m.Name, err)
+	}
+	defer tx.Rollback()
+
+	money := m.Version == moneyMigrationVersion && db.DriverName() != postgresDriver
+	if money {
+		if err := prepareMoneyMigration(tx, opts.Currency); err != nil {
+			return fmt.Errorf("failed to prepare migration %s: %w", m.Name, err)
+		}
+	}
+	if _, err := tx.Exec(m.SQL); err != nil {
+		return fmt.Errorf("failed to execute migration %s: %w", m.Name, err)
+	}
+	if money {
+		if _, err := tx.Exec(`DROP TABLE temp.migration_currency`); err != nil {
+			return fmt.Errorf("failed to clean up after migration %s: %w", m.Name, err)
+		}
+	}
+	if err := recordMigration(tx, m); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", m.Name, err)
+	}
+	return nil
+}
+
+// baselineLegacyDatabase marks the initial schema as applied on databases that
+// were created before schema_migrations existed, so 001_init.sql is not re-run
+// against tables that are already there.
+func baselineLegacyDatabase(db *sqlx.DB, migrations []migration) error {
+	if db.DriverName() == postgresDriver {
+		// PostgreSQL support came with migration tracking.
+		return nil
+	}
+	var recorded int
+	if err := db.Get(&recorded, `SELECT COUNT(*) FROM schema_migrations`); err != nil {
+		return fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	var legacyTables int
+	if err := db.Get(&legacyTables, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'months'`); err != nil {
+		return fmt.Errorf("failed to inspect existing schema: %w", err)
+	}
+	if recorded > 0 || legacyTables == 0 {
+		return nil
+	}
+
+	log.Printf("Existing database without migration history: recording migrations up to %d as applied.", legacyBaselineVersion)
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin baseline transaction: %w", err)
+	}
+	defer tx.Rollback()
+	for _, m := range migrations {
+		if m.Version > legacyBaselineVersion {
+			break
+		}
+		if err := recordMigration(tx, m); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit baseline: %w", err)
+	}
+	return nil
+}
+
+// prepareMoneyMigration tells the money migration which currency the existing
+// REAL amounts are in, refusing to guess when there are any.
+func prepareMoneyMigration(tx *sqlx.Tx, currency Currency) error {
+	if currency == "" {
+		var amounts int
+		err := tx.Get(&amounts, `
+			SELECT (SELECT COUNT(*) FROM budget_lines) + (SELECT COUNT(*) FROM actual_lines)
+				+ (SELECT COUNT(*) FROM match_rules WHERE amount IS NOT NULL) + (SELECT COUNT(*) FROM bank_transactions)`)
+		if err != nil {
+			return fmt.Errorf("failed to count existing amounts: %w", err)
+		}
+		if amounts > 0 {
+			return ErrMigrationCurrencyRequired
+		}
+		currency = DefaultCurrency
+	}
+	currency, err := ParseCurrency(string(currency))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`CREATE TEMP TABLE migration_currency (code TEXT NOT NULL, scale INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO temp.migration_currency (code, scale) VALUES (?, ?)`, string(currency), int64(math.Pow10(currency.Decimals())))
+	return err
+}
+
+func recordMigration(tx *sqlx.Tx, m migration) error {
+	_, err := tx.Exec(tx.Rebind(`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`),
+		m.Version, m.Name, m.Checksum, time.Now().UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", m.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEmptyTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	db, err := sqlx.Connect("sqlite3", ":memory:?_foreign_keys=on")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func appliedVersions(t *testing.T, db *sqlx.DB) []int {
+	t.Helper()
+	var versions []int
+	require.NoError(t, db.Select(&versions, `SELECT version FROM schema_migrations ORDER BY version`))
+	return versions
+}
+
+func TestRunMigrations(t *testing.T) {
+	embedded, err := loadMigrations(embeddedMigrations)
+	require.NoError(t, err)
+	require.NotEmpty(t, embedded)
+	for i, m := range embedded {
+		assert.Equal(t, i+1, m.Version, "embedded migrations are numbered without gaps")
+	}
+
+	t.Run("fresh database applies every migration once", func(t *testing.T) {
+		db := newEmptyTestDB(t)
+		require.NoError(t, RunMigrations(db))
+		require.Len(t, appliedVersions(t, db), len(embedded))
+
+		require.NoError(t, RunMigrations(db))
+		assert.Len(t, appliedVersions(t, db), len(embedded))
+	})
+
+	t.Run("legacy database keeps its data", func(t *testing.T) {
+		db := newEmptyTestDB(t)
+		_, err := db.Exec(embedded[0].SQL)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO months (year, month, finalized) VALUES (2024, 5, 0)`)
+		require.NoError(t, err)
+
+		require.NoError(t, RunMigrations(db))
+		assert.Len(t, appliedVersions(t, db), len(embedded))
+		var months int
+		require.NoError(t, db.Get(&months, `SELECT COUNT(*) FROM months`))
+		assert.Equal(t, 1, months)
+	})
+}
+
+func TestApplyMigrations(t *testing.T) {
+	load := func(t *testing.T, files map[string]string) []migration {
+		t.Helper()
+		fsys := fstest.MapFS{}
+		for name, sql := range files {
+			fsys["migrations/"+name] = &fstest.MapFile{Data: []byte(sql)}
+		}
+		migrations, err := loadMigrations(fsys)
+		require.NoError(t, err)
+		return migrations
+	}
+
+	t.Run("runs pending migrations in version order", func(t *testing.T) {
+		db := newEmptyTestDB(t)
+		require.NoError(t, applyMigrations(db, load(t, map[string]string{
+			"001_init.sql": `CREATE TABLE a (id INTEGER PRIMARY KEY);`,
+		}), MigrationOptions{}))
+		require.NoError(t, applyMigrations(db, load(t, map[string]string{
+			"001_init.sql":   `CREATE TABLE a (id INTEGER PRIMARY KEY);`,
+			"010_later.sql":  `ALTER TABLE a ADD COLUMN c TEXT;`,
+			"002_second.sql": `ALTER TABLE a ADD COLUMN b TEXT;`,
+		}), MigrationOptions{}))
+		assert.Equal(t, []int{1, 2, 10}, appliedVersions(t, db))
+		_, err := db.Exec(`INSERT INTO a (b, c) VALUES ('x', 'y')`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("failed migration is rolled back and not recorded", func(t *testing.T) {
+		db := newEmptyTestDB(t)
+		err := applyMigrations(db, load(t, map[string]string{
+			"001_init.sql":   `CREATE TABLE a (id INTEGER PRIMARY KEY);`,
+			"002_broken.sql": `CREATE TABLE b (id INTEGER PRIMARY KEY); INSERT INTO missing VALUES (1);`,
+		}), MigrationOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "002_broken.sql")
+		assert.Equal(t, []int{1}, appliedVersions(t, db))
+		var tables int
+		require.NoError(t, db.Get(&tables, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'b'`))
+		assert.Equal(t, 0, tables)
+	})
+
+	t.Run("edited migration fails the checksum", func(t *testing.T) {
+		db := newEmptyTestDB(t)
+		require.NoError(t, applyMigrations(db, load(t, map[string]string{
+			"001_init.sql": `CREATE TABLE a (id INTEGER PRIMARY KEY);`,
+		}), MigrationOptions{}))
+		err := applyMigrations(db, load(t, map[string]string{
+			"001_init.sql": `CREATE TABLE a (id INTEGER PRIMARY KEY, name TEXT);`,
+		}), MigrationOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "modified after it was applied")
+	})
+
+	t.Run("unknown applied migration is rejected", func(t *testing.T) {
+		db := newEmptyTestDB(t)
+		require.NoError(t, applyMigrations(db, load(t, map[string]string{
+			"001_init.sql":  `CREATE TABLE a (id INTEGER PRIMARY KEY);`,
+			"002_newer.sql": `CREATE TABLE b (id INTEGER PRIMARY KEY);`,
+		}), MigrationOptions{}))
+		err := applyMigrations(db, load(t, map[string]string{
+			"001_init.sql": `CREATE TABLE a (id INTEGER PRIMARY KEY);`,
+		}), MigrationOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "newer version")
+	})
+}
+
+func TestLoadMigrationsRejectsBadNames(t *testing.T) {
+	_, err := loadMigrations(fstest.MapFS{"migrations/init.sql": &fstest.MapFile{Data: []byte("")}})
+	assert.Error(t, err)
+
+	_, err = loadMigrations(fstest.MapFS{
+		"migrations/002_a.sql":  &fstest.MapFile{Data: []byte("")},
+		"migrations/0002_b.sql": &fstest.MapFile{Data: []byte("")},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS backup_runs (
+  id INTEGER PRIMARY KEY,
+  kind TEXT NOT NULL,          -- 'snapshot' (on-disk copy) or 'json_export'
+  location TEXT NOT NULL,
+  size_bytes INTEGER NOT NULL,
+  created_at DATETIME NOT NULL
+);
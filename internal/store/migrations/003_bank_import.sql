@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS match_rules (
+  id INTEGER PRIMARY KEY,
+  pattern TEXT NOT NULL,       -- case-insensitive substring of the transaction description
+  category_id INT REFERENCES categories(id) ON DELETE CASCADE,
+  label TEXT NOT NULL,         -- budget line label to propose in the transaction's month
+  amount REAL                  -- only match transactions of exactly this amount when set
+);
+CREATE TABLE IF NOT EXISTS bank_transactions (
+  id INTEGER PRIMARY KEY,
+  external_id TEXT NOT NULL UNIQUE, -- OFX FITID, or a hash of the CSV row
+  posted_on DATE NOT NULL,
+  amount REAL NOT NULL,             -- money spent, always positive
+  description TEXT NOT NULL,
+  month_id INT REFERENCES months(id) ON DELETE SET NULL,
+  budget_line_id INT REFERENCES budget_lines(id) ON DELETE SET NULL,
+  match_reason TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'pending', -- 'pending' (review queue), 'applied' or 'ignored'
+  imported_at DATETIME NOT NULL
+);
@@ -0,0 +1,29 @@
+-- Amounts become INTEGER minor units of the budget currency. Nothing recorded
+-- which currency the REAL amounts so far were in, so the operator names it when
+-- upgrading a database that holds any (-currency). RunMigrations puts it and the
+-- number of minor units per major unit in temp.migration_currency beforehand.
+CREATE TABLE settings (
+  key TEXT PRIMARY KEY,
+  value TEXT NOT NULL
+);
+INSERT INTO settings (key, value) SELECT 'currency', code FROM temp.migration_currency;
+
+ALTER TABLE budget_lines ADD COLUMN expected_minor INTEGER NOT NULL DEFAULT 0;
+UPDATE budget_lines SET expected_minor = CAST(ROUND(expected * (SELECT scale FROM temp.migration_currency)) AS INTEGER);
+ALTER TABLE budget_lines DROP COLUMN expected;
+ALTER TABLE budget_lines RENAME COLUMN expected_minor TO expected;
+
+ALTER TABLE actual_lines ADD COLUMN actual_minor INTEGER NOT NULL DEFAULT 0;
+UPDATE actual_lines SET actual_minor = CAST(ROUND(actual * (SELECT scale FROM temp.migration_currency)) AS INTEGER);
+ALTER TABLE actual_lines DROP COLUMN actual;
+ALTER TABLE actual_lines RENAME COLUMN actual_minor TO actual;
+
+ALTER TABLE match_rules ADD COLUMN amount_minor INTEGER;
+UPDATE match_rules SET amount_minor = CAST(ROUND(amount * (SELECT scale FROM temp.migration_currency)) AS INTEGER) WHERE amount IS NOT NULL;
+ALTER TABLE match_rules DROP COLUMN amount;
+ALTER TABLE match_rules RENAME COLUMN amount_minor TO amount;
+
+ALTER TABLE bank_transactions ADD COLUMN amount_minor INTEGER NOT NULL DEFAULT 0;
+UPDATE bank_transactions SET amount_minor = CAST(ROUND(amount * (SELECT scale FROM temp.migration_currency)) AS INTEGER);
+ALTER TABLE bank_transactions DROP COLUMN amount;
+ALTER TABLE bank_transactions RENAME COLUMN amount_minor TO amount;
@@ -0,0 +1,16 @@
+-- Budget lines and actuals carry the ISO 4217 code of the currency they are kept
+-- in; everything so far was in the budget currency.
+ALTER TABLE budget_lines ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+UPDATE budget_lines SET currency = (SELECT value FROM settings WHERE key = 'currency');
+ALTER TABLE actual_lines ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+UPDATE actual_lines SET currency = (SELECT value FROM settings WHERE key = 'currency');
+
+-- A rate applies from its month until the next rate of the same currency.
+CREATE TABLE exchange_rates (
+  id INTEGER PRIMARY KEY,
+  currency TEXT NOT NULL,      -- ISO 4217 code of the foreign currency
+  year INT NOT NULL,
+  month INT NOT NULL,
+  rate REAL NOT NULL,          -- units of the budget currency per unit of currency
+  UNIQUE (currency, year, month)
+);
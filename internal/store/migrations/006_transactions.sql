@@ -0,0 +1,19 @@
+-- Dated payments against a budget line. The line's actual_lines.actual becomes
+-- the sum of its transactions, kept in step by the store.
+CREATE TABLE transactions (
+  id INTEGER PRIMARY KEY,
+  budget_line_id INT NOT NULL REFERENCES budget_lines(id) ON DELETE CASCADE,
+  date DATE NOT NULL,
+  amount INTEGER NOT NULL,     -- minor units, in the currency of the line's actuals
+  payee TEXT NOT NULL DEFAULT '',
+  memo TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX transactions_budget_line_id ON transactions (budget_line_id);
+
+-- Totals recorded so far become one transaction on the first day of their month.
+INSERT INTO transactions (budget_line_id, date, amount, memo)
+SELECT al.budget_line_id, printf('%04d-%02d-01', m.year, m.month), al.actual, 'Actual recorded before transactions'
+FROM actual_lines al
+JOIN budget_lines bl ON bl.id = al.budget_line_id
+JOIN months m ON m.id = bl.month_id
+WHERE al.actual != 0;
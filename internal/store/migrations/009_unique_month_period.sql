@@ -0,0 +1,25 @@
+-- A year and month may only have one row. Duplicates left by earlier versions
+-- are merged into the oldest row of their period: lines, bank transactions and
+-- the snapshot move over, and the period stays finalized if any copy was.
+UPDATE months SET finalized = TRUE
+WHERE finalized = FALSE AND EXISTS (
+  SELECT 1 FROM months d WHERE d.year = months.year AND d.month = months.month AND d.id > months.id AND d.finalized = TRUE);
+
+UPDATE budget_lines SET month_id = (
+  SELECT MIN(k.id) FROM months k JOIN months m ON k.year = m.year AND k.month = m.month WHERE m.id = budget_lines.month_id);
+UPDATE bank_transactions SET month_id = (
+  SELECT MIN(k.id) FROM months k JOIN months m ON k.year = m.year AND k.month = m.month WHERE m.id = bank_transactions.month_id)
+WHERE month_id IS NOT NULL;
+UPDATE annual_snaps SET month_id = (
+  SELECT MIN(k.id) FROM months k JOIN months m ON k.year = m.year AND k.month = m.month WHERE m.id = annual_snaps.month_id)
+WHERE NOT EXISTS (
+  SELECT 1 FROM annual_snaps s JOIN months k ON k.id = s.month_id JOIN months m ON k.year = m.year AND k.month = m.month
+  WHERE m.id = annual_snaps.month_id AND k.id < m.id);
+
+-- A period whose copies each have a snapshot cannot be merged; the index below
+-- then fails and the duplicate must be resolved by hand.
+DELETE FROM months
+WHERE EXISTS (SELECT 1 FROM months k WHERE k.year = months.year AND k.month = months.month AND k.id < months.id)
+  AND NOT EXISTS (SELECT 1 FROM annual_snaps s WHERE s.month_id = months.id);
+
+CREATE UNIQUE INDEX months_year_month ON months (year, month);
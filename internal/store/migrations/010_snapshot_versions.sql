@@ -0,0 +1,15 @@
+-- A month keeps every snapshot it was finalized with: reopening and finalizing
+-- it again adds the next version, and the highest version is the current one.
+-- SQLite cannot drop the old UNIQUE (month_id), so the table is rebuilt.
+CREATE TABLE annual_snaps_versioned (
+  id INTEGER PRIMARY KEY,
+  month_id INT NOT NULL REFERENCES months(id),
+  version INT NOT NULL DEFAULT 1,
+  snap_json TEXT NOT NULL,
+  created_at DATETIME NOT NULL,
+  UNIQUE (month_id, version)
+);
+INSERT INTO annual_snaps_versioned (id, month_id, version, snap_json, created_at)
+  SELECT id, month_id, 1, snap_json, created_at FROM annual_snaps;
+DROP TABLE annual_snaps;
+ALTER TABLE annual_snaps_versioned RENAME TO annual_snaps;
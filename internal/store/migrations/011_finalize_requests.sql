@@ -0,0 +1,9 @@
+-- The outcome of each finalize request sent with an idempotency key, so that a
+-- repeated request gets the original answer. Like the audit log it has no
+-- foreign keys.
+CREATE TABLE finalize_requests (
+  idempotency_key TEXT PRIMARY KEY,
+  month_id INTEGER NOT NULL,       -- the month that was finalized
+  next_month_id INTEGER NOT NULL,  -- the month its budget rolled over into
+  created_at DATETIME NOT NULL
+);
@@ -1,148 +1,437 @@
 package store
 
 import (
+	"context"
 	"errors"
+	"time"
 )
 
 type ReusableMockStore struct {
-	MockGetAllCategories func() ([]Category, error)
-	MockCreateCategory   func(category *Category) error
-	MockGetCategoryByID  func(id int64) (*Category, error)
-	MockUpdateCategory   func(category *Category) error
-	MockDeleteCategory   func(id int64) error
+	MockGetAllCategories func(ctx context.Context) ([]Category, error)
+	MockCreateCategory   func(ctx context.Context, category *Category) error
+	MockGetCategoryByID  func(ctx context.Context, id int64) (*Category, error)
+	MockUpdateCategory   func(ctx context.Context, category *Category) error
+	MockDeleteCategory   func(ctx context.Context, id int64) error
 
-	MockCreateBudgetLine        func(b *BudgetLine) (int64, error)
-	MockGetBudgetLinesByMonthID func(monthID int) ([]BudgetLine, error)
-	MockUpdateBudgetLine        func(b *BudgetLine) error
-	MockDeleteBudgetLine        func(id int64) error
-	MockUpdateActualLine        func(a *ActualLine) error
-	MockGetActualLineByID       func(id int64) (*ActualLine, error)
-	MockGetBudgetLineByID       func(id int64) (*BudgetLine, error)
+	MockCreateBudgetLine        func(ctx context.Context, b *BudgetLine) (int64, error)
+	MockGetBudgetLinesByMonthID func(ctx context.Context, monthID int) ([]BudgetLine, error)
+	MockUpdateBudgetLine        func(ctx context.Context, b *BudgetLine) error
+	MockDeleteBudgetLine        func(ctx context.Context, id int64) error
+	MockUpdateActualLine        func(ctx context.Context, a *ActualLine) error
+	MockGetActualLineByID       func(ctx context.Context, id int64) (*ActualLine, error)
+	MockGetBudgetLineByID       func(ctx context.Context, id int64) (*BudgetLine, error)
 
-	MockGetBoardData func(monthID int) (*BoardDataPayload, error)
+	MockGetBoardData func(ctx context.Context, monthID int) (*BoardDataPayload, error)
 
-	MockCanFinalizeMonth func(monthID int) (bool, string, error)
-	MockFinalizeMonth    func(monthID int, snapJSON string) (int64, error)
+	MockGetMonthsByYear    func(ctx context.Context, year int) ([]Month, error)
+	MockGetMonths          func(ctx context.Context, filter MonthFilter) ([]Month, error)
+	MockGetMonthByID       func(ctx context.Context, id int64) (*Month, error)
+	MockGetMonthByPeriod   func(ctx context.Context, year, month int) (*Month, error)
+	MockCreateMonth        func(ctx context.Context, month *Month) error
+	MockReopenMonth        func(ctx context.Context, monthID int) error
+	MockGetMonthSnapshots  func(ctx context.Context, monthID int64) ([]AnnualSnapMeta, error)
+	MockCanFinalizeMonth   func(ctx context.Context, monthID int) (bool, string, error)
+	MockFinalizeMonth      func(ctx context.Context, monthID int, snapJSON string) (int64, error)
+	MockGetFinalizeRequest func(ctx context.Context, key string) (*FinalizeRequest, error)
+	MockPlanFinalizeMonth  func(ctx context.Context, monthID int) (*FinalizePlan, error)
 
-	MockGetAnnualSnapshotsMetadataByYear func(year int) ([]AnnualSnapMeta, error)
-	MockGetAnnualSnapshotJSONByID        func(snapID int64) (string, error)
+	MockGetAnnualSnapshotsMetadataByYear func(ctx context.Context, year int) ([]AnnualSnapMeta, error)
+	MockGetAnnualSnapshotJSONByID        func(ctx context.Context, snapID int64) (string, error)
+
+	MockExportAll        func(ctx context.Context, sink ExportSink) error
+	MockImportAll        func(ctx context.Context, dump *DatabaseDump, mode ImportMode, dryRun bool) (*ImportReport, error)
+	MockImportLegacyRows func(ctx context.Context, rows []LegacyImportRow, opts LegacyImportOptions) (*LegacyImportReport, error)
+
+	MockAddBankTransactions     func(ctx context.Context, txns []BankTransaction) (int, error)
+	MockGetBankTransactions     func(ctx context.Context, status string) ([]BankTransaction, error)
+	MockConfirmBankTransactions func(ctx context.Context, confirmations []BankConfirmation) error
+	MockIgnoreBankTransaction   func(ctx context.Context, id int64) error
+	MockGetMatchRules           func(ctx context.Context) ([]MatchRule, error)
+	MockCreateMatchRule         func(ctx context.Context, rule *MatchRule) error
+	MockDeleteMatchRule         func(ctx context.Context, id int64) error
+
+	MockRecordBackupRun  func(ctx context.Context, run *BackupRun) error
+	MockGetLastBackupRun func(ctx context.Context) (*BackupRun, error)
+
+	MockGetExchangeRates         func(ctx context.Context) ([]ExchangeRate, error)
+	MockSetExchangeRates         func(ctx context.Context, rates []ExchangeRate) error
+	MockDeleteExchangeRate       func(ctx context.Context, id int64) error
+	MockGetExchangeRatesForMonth func(ctx context.Context, monthID int) (map[Currency]float64, error)
+
+	MockGetTransactionsByBudgetLineID func(ctx context.Context, budgetLineID int64) ([]Transaction, error)
+	MockGetTransactionByID            func(ctx context.Context, id int64) (*Transaction, error)
+	MockCreateTransaction             func(ctx context.Context, t *Transaction) (int64, error)
+	MockUpdateTransaction             func(ctx context.Context, t *Transaction) error
+	MockDeleteTransaction             func(ctx context.Context, id int64) error
+
+	MockGetAuditEntries func(ctx context.Context, filter AuditFilter) ([]AuditEntry, error)
+
+	MockGetTrash          func(ctx context.Context) (*Trash, error)
+	MockRestoreCategory   func(ctx context.Context, id int64) error
+	MockRestoreBudgetLine func(ctx context.Context, id int64) error
+	MockPurgeTrash        func(ctx context.Context, deletedBefore time.Time) (*TrashCounts, error)
 }
 
-func (m *ReusableMockStore) GetAllCategories() ([]Category, error) {
+func (m *ReusableMockStore) GetAllCategories(ctx context.Context) ([]Category, error) {
 	if m.MockGetAllCategories != nil {
-		return m.MockGetAllCategories()
+		return m.MockGetAllCategories(ctx)
 	}
 	return nil, errors.New("ReusableMockStore: MockGetAllCategories not implemented")
 }
 
-func (m *ReusableMockStore) CreateCategory(category *Category) error {
+func (m *ReusableMockStore) CreateCategory(ctx context.Context, category *Category) error {
 	if m.MockCreateCategory != nil {
-		return m.MockCreateCategory(category)
+		return m.MockCreateCategory(ctx, category)
 	}
 	return errors.New("ReusableMockStore: MockCreateCategory not implemented")
 }
 
-func (m *ReusableMockStore) GetCategoryByID(id int64) (*Category, error) {
+func (m *ReusableMockStore) GetCategoryByID(ctx context.Context, id int64) (*Category, error) {
 	if m.MockGetCategoryByID != nil {
-		return m.MockGetCategoryByID(id)
+		return m.MockGetCategoryByID(ctx, id)
 	}
 	return nil, errors.New("ReusableMockStore: MockGetCategoryByID not implemented")
 }
 
-func (m *ReusableMockStore) UpdateCategory(category *Category) error {
+func (m *ReusableMockStore) UpdateCategory(ctx context.Context, category *Category) error {
 	if m.MockUpdateCategory != nil {
-		return m.MockUpdateCategory(category)
+		return m.MockUpdateCategory(ctx, category)
 	}
 	return errors.New("ReusableMockStore: MockUpdateCategory not implemented")
 }
 
-func (m *ReusableMockStore) DeleteCategory(id int64) error {
+func (m *ReusableMockStore) DeleteCategory(ctx context.Context, id int64) error {
 	if m.MockDeleteCategory != nil {
-		return m.MockDeleteCategory(id)
+		return m.MockDeleteCategory(ctx, id)
 	}
 	return errors.New("ReusableMockStore: MockDeleteCategory not implemented")
 }
 
-func (m *ReusableMockStore) CreateBudgetLine(b *BudgetLine) (int64, error) {
+func (m *ReusableMockStore) CreateBudgetLine(ctx context.Context, b *BudgetLine) (int64, error) {
 	if m.MockCreateBudgetLine != nil {
-		return m.MockCreateBudgetLine(b)
+		return m.MockCreateBudgetLine(ctx, b)
 	}
 	return 0, errors.New("ReusableMockStore: MockCreateBudgetLine not implemented")
 }
 
-func (m *ReusableMockStore) GetBudgetLinesByMonthID(monthID int) ([]BudgetLine, error) {
+func (m *ReusableMockStore) GetBudgetLinesByMonthID(ctx context.Context, monthID int) ([]BudgetLine, error) {
 	if m.MockGetBudgetLinesByMonthID != nil {
-		return m.MockGetBudgetLinesByMonthID(monthID)
+		return m.MockGetBudgetLinesByMonthID(ctx, monthID)
 	}
 	return nil, errors.New("ReusableMockStore: MockGetBudgetLinesByMonthID not implemented")
 }
 
-func (m *ReusableMockStore) UpdateBudgetLine(b *BudgetLine) error {
+func (m *ReusableMockStore) UpdateBudgetLine(ctx context.Context, b *BudgetLine) error {
 	if m.MockUpdateBudgetLine != nil {
-		return m.MockUpdateBudgetLine(b)
+		return m.MockUpdateBudgetLine(ctx, b)
 	}
 	return errors.New("ReusableMockStore: MockUpdateBudgetLine not implemented")
 }
 
-func (m *ReusableMockStore) DeleteBudgetLine(id int64) error {
+func (m *ReusableMockStore) DeleteBudgetLine(ctx context.Context, id int64) error {
 	if m.MockDeleteBudgetLine != nil {
-		return m.MockDeleteBudgetLine(id)
+		return m.MockDeleteBudgetLine(ctx, id)
 	}
 	return errors.New("ReusableMockStore: MockDeleteBudgetLine not implemented")
 }
 
-func (m *ReusableMockStore) UpdateActualLine(a *ActualLine) error {
+func (m *ReusableMockStore) UpdateActualLine(ctx context.Context, a *ActualLine) error {
 	if m.MockUpdateActualLine != nil {
-		return m.MockUpdateActualLine(a)
+		return m.MockUpdateActualLine(ctx, a)
 	}
 	return errors.New("ReusableMockStore: MockUpdateActualLine not implemented")
 }
 
-func (m *ReusableMockStore) GetActualLineByID(id int64) (*ActualLine, error) {
+func (m *ReusableMockStore) GetActualLineByID(ctx context.Context, id int64) (*ActualLine, error) {
 	if m.MockGetActualLineByID != nil {
-		return m.MockGetActualLineByID(id)
+		return m.MockGetActualLineByID(ctx, id)
 	}
 	return nil, errors.New("ReusableMockStore: MockGetActualLineByID not implemented")
 }
 
-func (m *ReusableMockStore) GetBudgetLineByID(id int64) (*BudgetLine, error) {
+func (m *ReusableMockStore) GetBudgetLineByID(ctx context.Context, id int64) (*BudgetLine, error) {
 	if m.MockGetBudgetLineByID != nil {
-		return m.MockGetBudgetLineByID(id)
+		return m.MockGetBudgetLineByID(ctx, id)
 	}
 	return nil, errors.New("ReusableMockStore: MockGetBudgetLineByID not implemented")
 }
 
-func (m *ReusableMockStore) GetBoardData(monthID int) (*BoardDataPayload, error) {
+func (m *ReusableMockStore) GetBoardData(ctx context.Context, monthID int) (*BoardDataPayload, error) {
 	if m.MockGetBoardData != nil {
-		return m.MockGetBoardData(monthID)
+		return m.MockGetBoardData(ctx, monthID)
 	}
 	return nil, errors.New("ReusableMockStore: MockGetBoardData not implemented")
 }
 
-func (m *ReusableMockStore) CanFinalizeMonth(monthID int) (bool, string, error) {
+func (m *ReusableMockStore) GetMonthsByYear(ctx context.Context, year int) ([]Month, error) {
+	if m.MockGetMonthsByYear != nil {
+		return m.MockGetMonthsByYear(ctx, year)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetMonthsByYear not implemented")
+}
+
+func (m *ReusableMockStore) GetMonths(ctx context.Context, filter MonthFilter) ([]Month, error) {
+	if m.MockGetMonths != nil {
+		return m.MockGetMonths(ctx, filter)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetMonths not implemented")
+}
+
+func (m *ReusableMockStore) GetMonthByID(ctx context.Context, id int64) (*Month, error) {
+	if m.MockGetMonthByID != nil {
+		return m.MockGetMonthByID(ctx, id)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetMonthByID not implemented")
+}
+
+func (m *ReusableMockStore) GetMonthByPeriod(ctx context.Context, year, month int) (*Month, error) {
+	if m.MockGetMonthByPeriod != nil {
+		return m.MockGetMonthByPeriod(ctx, year, month)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetMonthByPeriod not implemented")
+}
+
+func (m *ReusableMockStore) CreateMonth(ctx context.Context, month *Month) error {
+	if m.MockCreateMonth != nil {
+		return m.MockCreateMonth(ctx, month)
+	}
+	return errors.New("ReusableMockStore: MockCreateMonth not implemented")
+}
+
+func (m *ReusableMockStore) ReopenMonth(ctx context.Context, monthID int) error {
+	if m.MockReopenMonth != nil {
+		return m.MockReopenMonth(ctx, monthID)
+	}
+	return errors.New("ReusableMockStore: MockReopenMonth not implemented")
+}
+
+func (m *ReusableMockStore) GetMonthSnapshots(ctx context.Context, monthID int64) ([]AnnualSnapMeta, error) {
+	if m.MockGetMonthSnapshots != nil {
+		return m.MockGetMonthSnapshots(ctx, monthID)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetMonthSnapshots not implemented")
+}
+
+func (m *ReusableMockStore) CanFinalizeMonth(ctx context.Context, monthID int) (bool, string, error) {
 	if m.MockCanFinalizeMonth != nil {
-		return m.MockCanFinalizeMonth(monthID)
+		return m.MockCanFinalizeMonth(ctx, monthID)
 	}
 	return false, "", errors.New("ReusableMockStore: MockCanFinalizeMonth not implemented")
 }
 
-func (m *ReusableMockStore) FinalizeMonth(monthID int, snapJSON string) (int64, error) {
+func (m *ReusableMockStore) FinalizeMonth(ctx context.Context, monthID int, snapJSON string) (int64, error) {
 	if m.MockFinalizeMonth != nil {
-		return m.MockFinalizeMonth(monthID, snapJSON)
+		return m.MockFinalizeMonth(ctx, monthID, snapJSON)
 	}
 	return 0, errors.New("ReusableMockStore: MockFinalizeMonth not implemented")
 }
 
-func (m *ReusableMockStore) GetAnnualSnapshotsMetadataByYear(year int) ([]AnnualSnapMeta, error) {
+func (m *ReusableMockStore) GetFinalizeRequest(ctx context.Context, key string) (*FinalizeRequest, error) {
+	if m.MockGetFinalizeRequest != nil {
+		return m.MockGetFinalizeRequest(ctx, key)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetFinalizeRequest not implemented")
+}
+
+func (m *ReusableMockStore) PlanFinalizeMonth(ctx context.Context, monthID int) (*FinalizePlan, error) {
+	if m.MockPlanFinalizeMonth != nil {
+		return m.MockPlanFinalizeMonth(ctx, monthID)
+	}
+	return nil, errors.New("ReusableMockStore: MockPlanFinalizeMonth not implemented")
+}
+
+func (m *ReusableMockStore) GetAnnualSnapshotsMetadataByYear(ctx context.Context, year int) ([]AnnualSnapMeta, error) {
 	if m.MockGetAnnualSnapshotsMetadataByYear != nil {
-		return m.MockGetAnnualSnapshotsMetadataByYear(year)
+		return m.MockGetAnnualSnapshotsMetadataByYear(ctx, year)
 	}
 	return nil, errors.New("ReusableMockStore: MockGetAnnualSnapshotsMetadataByYear not implemented")
 }
 
-func (m *ReusableMockStore) GetAnnualSnapshotJSONByID(snapID int64) (string, error) {
+func (m *ReusableMockStore) GetAnnualSnapshotJSONByID(ctx context.Context, snapID int64) (string, error) {
 	if m.MockGetAnnualSnapshotJSONByID != nil {
-		return m.MockGetAnnualSnapshotJSONByID(snapID)
+		return m.MockGetAnnualSnapshotJSONByID(ctx, snapID)
 	}
 	return "", errors.New("ReusableMockStore: MockGetAnnualSnapshotJSONByID not implemented")
 }
+
+func (m *ReusableMockStore) ExportAll(ctx context.Context, sink ExportSink) error {
+	if m.MockExportAll != nil {
+		return m.MockExportAll(ctx, sink)
+	}
+	return errors.New("ReusableMockStore: MockExportAll not implemented")
+}
+
+func (m *ReusableMockStore) ImportAll(ctx context.Context, dump *DatabaseDump, mode ImportMode, dryRun bool) (*ImportReport, error) {
+	if m.MockImportAll != nil {
+		return m.MockImportAll(ctx, dump, mode, dryRun)
+	}
+	return nil, errors.New("ReusableMockStore: MockImportAll not implemented")
+}
+
+func (m *ReusableMockStore) ImportLegacyRows(ctx context.Context, rows []LegacyImportRow, opts LegacyImportOptions) (*LegacyImportReport, error) {
+	if m.MockImportLegacyRows != nil {
+		return m.MockImportLegacyRows(ctx, rows, opts)
+	}
+	return nil, errors.New("ReusableMockStore: MockImportLegacyRows not implemented")
+}
+
+func (m *ReusableMockStore) AddBankTransactions(ctx context.Context, txns []BankTransaction) (int, error) {
+	if m.MockAddBankTransactions != nil {
+		return m.MockAddBankTransactions(ctx, txns)
+	}
+	return 0, errors.New("ReusableMockStore: MockAddBankTransactions not implemented")
+}
+
+func (m *ReusableMockStore) GetBankTransactions(ctx context.Context, status string) ([]BankTransaction, error) {
+	if m.MockGetBankTransactions != nil {
+		return m.MockGetBankTransactions(ctx, status)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetBankTransactions not implemented")
+}
+
+func (m *ReusableMockStore) ConfirmBankTransactions(ctx context.Context, confirmations []BankConfirmation) error {
+	if m.MockConfirmBankTransactions != nil {
+		return m.MockConfirmBankTransactions(ctx, confirmations)
+	}
+	return errors.New("ReusableMockStore: MockConfirmBankTransactions not implemented")
+}
+
+func (m *ReusableMockStore) IgnoreBankTransaction(ctx context.Context, id int64) error {
+	if m.MockIgnoreBankTransaction != nil {
+		return m.MockIgnoreBankTransaction(ctx, id)
+	}
+	return errors.New("ReusableMockStore: MockIgnoreBankTransaction not implemented")
+}
+
+func (m *ReusableMockStore) GetMatchRules(ctx context.Context) ([]MatchRule, error) {
+	if m.MockGetMatchRules != nil {
+		return m.MockGetMatchRules(ctx)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetMatchRules not implemented")
+}
+
+func (m *ReusableMockStore) CreateMatchRule(ctx context.Context, rule *MatchRule) error {
+	if m.MockCreateMatchRule != nil {
+		return m.MockCreateMatchRule(ctx, rule)
+	}
+	return errors.New("ReusableMockStore: MockCreateMatchRule not implemented")
+}
+
+func (m *ReusableMockStore) DeleteMatchRule(ctx context.Context, id int64) error {
+	if m.MockDeleteMatchRule != nil {
+		return m.MockDeleteMatchRule(ctx, id)
+	}
+	return errors.New("ReusableMockStore: MockDeleteMatchRule not implemented")
+}
+
+func (m *ReusableMockStore) RecordBackupRun(ctx context.Context, run *BackupRun) error {
+	if m.MockRecordBackupRun != nil {
+		return m.MockRecordBackupRun(ctx, run)
+	}
+	return errors.New("ReusableMockStore: MockRecordBackupRun not implemented")
+}
+
+func (m *ReusableMockStore) GetLastBackupRun(ctx context.Context) (*BackupRun, error) {
+	if m.MockGetLastBackupRun != nil {
+		return m.MockGetLastBackupRun(ctx)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetLastBackupRun not implemented")
+}
+
+func (m *ReusableMockStore) GetExchangeRates(ctx context.Context) ([]ExchangeRate, error) {
+	if m.MockGetExchangeRates != nil {
+		return m.MockGetExchangeRates(ctx)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetExchangeRates not implemented")
+}
+
+func (m *ReusableMockStore) SetExchangeRates(ctx context.Context, rates []ExchangeRate) error {
+	if m.MockSetExchangeRates != nil {
+		return m.MockSetExchangeRates(ctx, rates)
+	}
+	return errors.New("ReusableMockStore: MockSetExchangeRates not implemented")
+}
+
+func (m *ReusableMockStore) DeleteExchangeRate(ctx context.Context, id int64) error {
+	if m.MockDeleteExchangeRate != nil {
+		return m.MockDeleteExchangeRate(ctx, id)
+	}
+	return errors.New("ReusableMockStore: MockDeleteExchangeRate not implemented")
+}
+
+func (m *ReusableMockStore) GetExchangeRatesForMonth(ctx context.Context, monthID int) (map[Currency]float64, error) {
+	if m.MockGetExchangeRatesForMonth != nil {
+		return m.MockGetExchangeRatesForMonth(ctx, monthID)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetExchangeRatesForMonth not implemented")
+}
+
+func (m *ReusableMockStore) GetTransactionsByBudgetLineID(ctx context.Context, budgetLineID int64) ([]Transaction, error) {
+	if m.MockGetTransactionsByBudgetLineID != nil {
+		return m.MockGetTransactionsByBudgetLineID(ctx, budgetLineID)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetTransactionsByBudgetLineID not implemented")
+}
+
+func (m *ReusableMockStore) GetTransactionByID(ctx context.Context, id int64) (*Transaction, error) {
+	if m.MockGetTransactionByID != nil {
+		return m.MockGetTransactionByID(ctx, id)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetTransactionByID not implemented")
+}
+
+func (m *ReusableMockStore) CreateTransaction(ctx context.Context, t *Transaction) (int64, error) {
+	if m.MockCreateTransaction != nil {
+		return m.MockCreateTransaction(ctx, t)
+	}
+	return 0, errors.New("ReusableMockStore: MockCreateTransaction not implemented")
+}
+
+func (m *ReusableMockStore) UpdateTransaction(ctx context.Context, t *Transaction) error {
+	if m.MockUpdateTransaction != nil {
+		return m.MockUpdateTransaction(ctx, t)
+	}
+	return errors.New("ReusableMockStore: MockUpdateTransaction not implemented")
+}
+
+func (m *ReusableMockStore) DeleteTransaction(ctx context.Context, id int64) error {
+	if m.MockDeleteTransaction != nil {
+		return m.MockDeleteTransaction(ctx, id)
+	}
+	return errors.New("ReusableMockStore: MockDeleteTransaction not implemented")
+}
+
+func (m *ReusableMockStore) GetAuditEntries(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	if m.MockGetAuditEntries != nil {
+		return m.MockGetAuditEntries(ctx, filter)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetAuditEntries not implemented")
+}
+
+func (m *ReusableMockStore) GetTrash(ctx context.Context) (*Trash, error) {
+	if m.MockGetTrash != nil {
+		return m.MockGetTrash(ctx)
+	}
+	return nil, errors.New("ReusableMockStore: MockGetTrash not implemented")
+}
+
+func (m *ReusableMockStore) RestoreCategory(ctx context.Context, id int64) error {
+	if m.MockRestoreCategory != nil {
+		return m.MockRestoreCategory(ctx, id)
+	}
+	return errors.New("ReusableMockStore: MockRestoreCategory not implemented")
+}
+
+func (m *ReusableMockStore) RestoreBudgetLine(ctx context.Context, id int64) error {
+	if m.MockRestoreBudgetLine != nil {
+		return m.MockRestoreBudgetLine(ctx, id)
+	}
+	return errors.New("ReusableMockStore: MockRestoreBudgetLine not implemented")
+}
+
+func (m *ReusableMockStore) PurgeTrash(ctx context.Context, deletedBefore time.Time) (*TrashCounts, error) {
+	if m.MockPurgeTrash != nil {
+		return m.MockPurgeTrash(ctx, deletedBefore)
+	}
+	return nil, errors.New("ReusableMockStore: MockPurgeTrash not implemented")
+}
@@ -1,11 +1,15 @@
 package store
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Category struct {
-	ID    int64  `json:"id" db:"id"`
-	Name  string `json:"name" db:"name"`
-	Color string `json:"color" db:"color"`
+	ID        int64      `json:"id" db:"id"`
+	Name      string     `json:"name" db:"name"`
+	Color     string     `json:"color" db:"color"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"` // set while in the trash
 }
 
 type Month struct {
@@ -15,25 +19,52 @@ type Month struct {
 	Finalized bool  `json:"finalized" db:"finalized"`
 }
 
+// MonthFilter narrows GetMonths. Nil fields match every month.
+type MonthFilter struct {
+	Year      *int
+	Finalized *bool
+}
+
 type BudgetLine struct {
-	ID           int     `json:"id" db:"id"`
-	MonthID      int     `json:"month_id" db:"month_id"`
-	CategoryID   int     `json:"category_id" db:"category_id"`
-	Label        string  `json:"label" db:"label"`
-	Expected     float64 `json:"expected" db:"expected"`
-	ActualID     *int64  `json:"actual_id,omitempty" db:"actual_id"`
-	ActualAmount *float64 `json:"actual_amount,omitempty" db:"actual_amount"`
+	ID             int        `json:"id" db:"id"`
+	MonthID        int        `json:"month_id" db:"month_id"`
+	CategoryID     int        `json:"category_id" db:"category_id"`
+	Label          string     `json:"label" db:"label"`
+	Expected       Money      `json:"expected" db:"expected"`
+	Currency       Currency   `json:"currency" db:"currency"`
+	ActualID       *int64     `json:"actual_id,omitempty" db:"actual_id"`
+	ActualAmount   *Money     `json:"actual_amount,omitempty" db:"actual_amount"`
+	ActualCurrency *Currency  `json:"actual_currency,omitempty" db:"actual_currency"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty" db:"deleted_at"` // set while in the trash
 }
 
 type ActualLine struct {
-	ID           int64   `json:"id" db:"id"`
-	BudgetLineID int64   `json:"budget_line_id" db:"budget_line_id"`
-	Actual       float64 `json:"actual" db:"actual"`
+	ID           int64    `json:"id" db:"id"`
+	BudgetLineID int64    `json:"budget_line_id" db:"budget_line_id"`
+	Actual       Money    `json:"actual" db:"actual"`
+	Currency     Currency `json:"currency" db:"currency"`
+}
+
+// Transaction is a single dated payment booked against a budget line. A line's
+// actual is the sum of its transactions, in the actual's currency, which is
+// Currency; it is read from the actual and not stored with the transaction.
+type Transaction struct {
+	ID           int64     `json:"id" db:"id"`
+	BudgetLineID int64     `json:"budget_line_id" db:"budget_line_id"`
+	Date         time.Time `json:"date" db:"date"`
+	Amount       Money     `json:"amount" db:"amount"`
+	Currency     Currency  `json:"currency" db:"currency"`
+	Payee        string    `json:"payee" db:"payee"`
+	Memo         string    `json:"memo" db:"memo"`
 }
 
+// AnnualSnap is a month's board as it was finalized. A month finalized again
+// after being reopened gets the next Version; backups from before versions
+// leave it 0, which means 1.
 type AnnualSnap struct {
 	ID        int64  `json:"id" db:"id"`
 	MonthID   int64  `json:"month_id" db:"month_id"`
+	Version   int    `json:"version" db:"version"`
 	SnapJSON  string `json:"snap_json" db:"snap_json"`
 	CreatedAt string `json:"created_at" db:"created_at"`
 }
@@ -41,20 +72,42 @@ type AnnualSnap struct {
 type AnnualSnapMeta struct {
 	ID            int64     `json:"id" db:"id"`
 	MonthID       int64     `json:"month_id" db:"month_id"`
+	Version       int       `json:"version" db:"version"`
 	Year          int       `json:"year" db:"year"`
 	Month         string    `json:"month" db:"month_name"`
 	SnapCreatedAt time.Time `json:"snap_created_at" db:"created_at"`
 }
 
+// FinalizeRequest records what finalizing a month returned for a request sent
+// with an idempotency key.
+type FinalizeRequest struct {
+	Key         string    `json:"key" db:"idempotency_key"`
+	MonthID     int64     `json:"month_id" db:"month_id"`
+	NextMonthID int64     `json:"next_month_id" db:"next_month_id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// FinalizePlan is what finalizing a month would do with its budget. NextMonth
+// and the cloned lines have no IDs yet when the next month would be created.
+type FinalizePlan struct {
+	MonthID          int64        `json:"month_id"`
+	SnapshotVersion  int          `json:"snapshot_version"`
+	NextMonth        Month        `json:"next_month"`
+	CreatesNextMonth bool         `json:"creates_next_month"`
+	ClonedLines      []BudgetLine `json:"cloned_lines"`
+}
+
 type BudgetLineWithActual struct {
-	ID             int64   `json:"id" db:"id"`
-	MonthID        int64   `json:"month_id" db:"month_id"`
-	CategoryID     int64   `json:"category_id" db:"category_id"`
-	CategoryName   string  `json:"category_name" db:"category_name"`
-	CategoryColor  string  `json:"category_color" db:"category_color"`
-	Label          string  `json:"label" db:"label"`
-	ExpectedAmount float64 `json:"expected_amount" db:"expected_amount"`
-	ActualAmount   float64 `json:"actual_amount" db:"actual_amount"`
+	ID             int64    `json:"id" db:"id"`
+	MonthID        int64    `json:"month_id" db:"month_id"`
+	CategoryID     int64    `json:"category_id" db:"category_id"`
+	CategoryName   string   `json:"category_name" db:"category_name"`
+	CategoryColor  string   `json:"category_color" db:"category_color"`
+	Label          string   `json:"label" db:"label"`
+	ExpectedAmount Money    `json:"expected_amount" db:"expected_amount"`
+	ActualAmount   Money    `json:"actual_amount" db:"actual_amount"`
+	Currency       Currency `json:"currency" db:"currency"`
+	ActualCurrency Currency `json:"actual_currency" db:"actual_currency"`
 }
 
 type BoardDataPayload struct {
@@ -64,3 +117,194 @@ type BoardDataPayload struct {
 	BudgetLines []BudgetLineWithActual `json:"budget_lines"`
 	IsFinalized bool                   `json:"is_finalized"` // New field
 }
+
+// DatabaseDump holds every row of the database, as written to and read from JSON backups.
+type DatabaseDump struct {
+	Months           []Month           `json:"months"`
+	Categories       []Category        `json:"categories"`
+	BudgetLines      []BudgetLine      `json:"budget_lines"`
+	ActualLines      []ActualLine      `json:"actual_lines"`
+	Transactions     []Transaction     `json:"transactions"`
+	AnnualSnaps      []AnnualSnap      `json:"annual_snaps"`
+	ExchangeRates    []ExchangeRate    `json:"exchange_rates"`
+	MatchRules       []MatchRule       `json:"match_rules"`
+	BankTransactions []BankTransaction `json:"bank_transactions"`
+}
+
+type ImportMode string
+
+const (
+	ImportModeReplace ImportMode = "replace"
+	ImportModeMerge   ImportMode = "merge"
+)
+
+type ImportTableCounts struct {
+	Months           int `json:"months"`
+	Categories       int `json:"categories"`
+	BudgetLines      int `json:"budget_lines"`
+	ActualLines      int `json:"actual_lines"`
+	Transactions     int `json:"transactions"`
+	AnnualSnaps      int `json:"annual_snaps"`
+	ExchangeRates    int `json:"exchange_rates"`
+	MatchRules       int `json:"match_rules"`
+	BankTransactions int `json:"bank_transactions"`
+}
+
+type ImportReport struct {
+	Mode     ImportMode        `json:"mode"`
+	DryRun   bool              `json:"dry_run"`
+	Deleted  ImportTableCounts `json:"deleted"`
+	Inserted ImportTableCounts `json:"inserted"`
+}
+
+const (
+	BackupKindSnapshot   = "snapshot"
+	BackupKindJSONExport = "json_export"
+)
+
+type BackupRun struct {
+	ID        int64     `json:"id" db:"id"`
+	Kind      string    `json:"kind" db:"kind"`
+	Location  string    `json:"location" db:"location"`
+	SizeBytes int64     `json:"size_bytes" db:"size_bytes"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// LegacyImportRow is one budget line read from an old spreadsheet. Line is the
+// row number in the source file, used to report problems back to the user.
+type LegacyImportRow struct {
+	Line     int    `json:"line"`
+	Year     int    `json:"year"`
+	Month    int    `json:"month"`
+	Category string `json:"category"`
+	Label    string `json:"label"`
+	Expected Money  `json:"expected"`
+	Actual   Money  `json:"actual"`
+}
+
+type LegacyImportRejection struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+type LegacyImportOptions struct {
+	// FinalizeBefore marks every imported month before this year/month as finalized
+	// with a snapshot. Zero values leave all months open.
+	FinalizeBeforeYear  int
+	FinalizeBeforeMonth int
+	Preview             bool
+}
+
+type LegacyImportReport struct {
+	Preview           bool                    `json:"preview"`
+	Imported          []LegacyImportRow       `json:"imported"`
+	Rejected          []LegacyImportRejection `json:"rejected"`
+	CreatedCategories []string                `json:"created_categories"`
+	CreatedMonths     []string                `json:"created_months"`
+	FinalizedMonths   []string                `json:"finalized_months"`
+}
+
+const (
+	BankTransactionPending = "pending"
+	BankTransactionApplied = "applied"
+	BankTransactionIgnored = "ignored"
+)
+
+// BankTransaction is one spending line of an imported bank statement. While it is
+// pending it sits in the review queue; BudgetLineID is then the proposed line,
+// and once applied it is the line whose actual the amount was added to.
+type BankTransaction struct {
+	ID           int64     `json:"id" db:"id"`
+	ExternalID   string    `json:"external_id" db:"external_id"`
+	PostedOn     time.Time `json:"posted_on" db:"posted_on"`
+	Amount       Money     `json:"amount" db:"amount"`
+	Description  string    `json:"description" db:"description"`
+	MonthID      *int64    `json:"month_id" db:"month_id"`
+	BudgetLineID *int64    `json:"budget_line_id" db:"budget_line_id"`
+	MatchReason  string    `json:"match_reason" db:"match_reason"`
+	Status       string    `json:"status" db:"status"`
+	ImportedAt   time.Time `json:"imported_at" db:"imported_at"`
+}
+
+// MatchRule proposes the budget line labelled Label (in CategoryID, when set) for
+// transactions whose description contains Pattern, optionally only for one Amount.
+type MatchRule struct {
+	ID         int64  `json:"id" db:"id"`
+	Pattern    string `json:"pattern" db:"pattern"`
+	CategoryID *int64 `json:"category_id" db:"category_id"`
+	Label      string `json:"label" db:"label"`
+	Amount     *Money `json:"amount" db:"amount"`
+}
+
+// ExchangeRate converts Currency to the budget currency from Year/Month until the
+// next rate of the same currency.
+type ExchangeRate struct {
+	ID       int64    `json:"id" db:"id"`
+	Currency Currency `json:"currency" db:"currency"`
+	Year     int      `json:"year" db:"year"`
+	Month    int      `json:"month" db:"month"`
+	Rate     float64  `json:"rate" db:"rate"`
+}
+
+// BankConfirmation applies a pending transaction to a budget line, which may
+// differ from the proposed one.
+type BankConfirmation struct {
+	TransactionID int64 `json:"transaction_id"`
+	BudgetLineID  int64 `json:"budget_line_id"`
+}
+
+// Trash holds the deleted categories and budget lines that can still be
+// restored, most recently deleted first.
+type Trash struct {
+	Categories  []Category   `json:"categories"`
+	BudgetLines []BudgetLine `json:"budget_lines"`
+}
+
+// TrashCounts is how many rows of each kind a purge deleted for good.
+type TrashCounts struct {
+	Categories  int `json:"categories"`
+	BudgetLines int `json:"budget_lines"`
+}
+
+// Entities and actions recorded in the audit log.
+const (
+	AuditEntityCategory   = "category"
+	AuditEntityBudgetLine = "budget_line"
+	AuditEntityActualLine = "actual_line"
+	AuditEntityMonth      = "month"
+	AuditEntityDatabase   = "database" // the whole budget; its entity ID is 0
+
+	AuditActionCreate   = "create"
+	AuditActionUpdate   = "update"
+	AuditActionDelete   = "delete"   // moved to the trash
+	AuditActionRestore  = "restore"  // taken out of the trash
+	AuditActionPurge    = "purge"    // deleted from the trash for good
+	AuditActionReopen   = "reopen"   // a finalized month made editable again
+	AuditActionOverride = "override" // a finalized month changed without reopening it
+	AuditActionImport   = "import"   // a backup loaded; Before and After count the rows replaced and loaded
+)
+
+// AuditEntry records one change to a row. Before is null for a created row and
+// After for a deleted one; MonthID is the month the row belongs to, and nil for
+// categories and imports. An override entry carries neither and sits next to
+// the entry of the change it let through.
+type AuditEntry struct {
+	ID        int64           `json:"id" db:"id"`
+	Entity    string          `json:"entity" db:"entity"`
+	EntityID  int64           `json:"entity_id" db:"entity_id"`
+	Action    string          `json:"action" db:"action"`
+	MonthID   *int64          `json:"month_id" db:"month_id"`
+	Before    json.RawMessage `json:"before" db:"before_json"`
+	After     json.RawMessage `json:"after" db:"after_json"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AuditFilter narrows GetAuditEntries. Zero fields match every entry; From is
+// inclusive and To exclusive, and a Limit of 0 returns all matches.
+type AuditFilter struct {
+	Entity  string
+	MonthID *int64
+	From    time.Time
+	To      time.Time
+	Limit   int
+}
@@ -0,0 +1,331 @@
+package store
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Currency is an ISO 4217 currency code such as USD, EUR or CLP.
+type Currency string
+
+const DefaultCurrency Currency = "USD"
+
+// currencyDecimals lists the currencies whose minor unit is not a hundredth of
+// the major unit.
+var currencyDecimals = map[Currency]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// Decimals is the number of decimal places of the currency's minor unit. An
+// empty code means the budget currency.
+func (c Currency) Decimals() int {
+	if c == "" {
+		c = BudgetCurrency()
+	}
+	if d, ok := currencyDecimals[Currency(strings.ToUpper(string(c)))]; ok {
+		return d
+	}
+	return 2
+}
+
+// ParseCurrency normalises a three-letter currency code.
+func ParseCurrency(code string) (Currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", fmt.Errorf("invalid currency code %q: use a three-letter ISO 4217 code such as USD", code)
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("invalid currency code %q: use a three-letter ISO 4217 code such as USD", code)
+		}
+	}
+	return Currency(code), nil
+}
+
+var budgetCurrency atomic.Value
+
+// BudgetCurrency is the currency amounts are kept in; it decides how many
+// decimals a Money value has.
+func BudgetCurrency() Currency {
+	if c, ok := budgetCurrency.Load().(Currency); ok {
+		return c
+	}
+	return DefaultCurrency
+}
+
+// SetBudgetCurrency sets the process-wide budget currency. LoadBudgetCurrency
+// does this from the database at startup.
+func SetBudgetCurrency(c Currency) {
+	budgetCurrency.Store(c)
+}
+
+// Money is an amount in integer minor units (cents for USD, pesos for CLP) of the
+// currency it is kept in, so sums are exact. It is stored as an INTEGER column
+// and written to JSON as a decimal number, e.g. 12.34. A bare Money is in the
+// budget currency; the rows that carry a currency, such as budget lines and
+// actuals, read and write their amounts with that currency's decimals.
+type Money int64
+
+// MoneyFromFloat rounds a decimal amount to the nearest minor unit of the budget
+// currency.
+func MoneyFromFloat(v float64) Money {
+	return MoneyFromFloatIn(v, BudgetCurrency())
+}
+
+// MoneyFromFloatIn rounds a decimal amount to the nearest minor unit of c.
+func MoneyFromFloatIn(v float64, c Currency) Money {
+	return Money(math.Round(v * math.Pow10(c.Decimals())))
+}
+
+// ParseMoney reads a decimal amount in the budget currency; see ParseMoneyIn.
+func ParseMoney(s string) (Money, error) {
+	return ParseMoneyIn(s, BudgetCurrency())
+}
+
+// ParseMoneyIn reads a decimal amount such as "-1234.5" in minor units of c
+// exactly, rounding half away from zero when it has more decimals than c.
+func ParseMoneyIn(s string, c Currency) (Money, error) {
+	decimals := c.Decimals()
+	text := strings.TrimSpace(s)
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "-"), "+")
+	whole, frac, _ := strings.Cut(text, ".")
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil && !math.IsInf(v, 0) && !math.IsNaN(v) {
+			// Exponent notation, e.g. 1e3.
+			return MoneyFromFloatIn(v, c), nil
+		}
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	roundUp := len(frac) > decimals && frac[decimals] >= '5'
+	if len(frac) > decimals {
+		frac = frac[:decimals]
+	}
+	digits := strings.TrimLeft(whole+frac+strings.Repeat("0", decimals-len(frac)), "0")
+	var minor int64
+	if digits != "" {
+		var err error
+		if minor, err = strconv.ParseInt(digits, 10, 64); err != nil {
+			return 0, fmt.Errorf("amount %q is out of range", s)
+		}
+	}
+	if roundUp {
+		minor++
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money(minor), nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// Float64 is the amount in major units of the budget currency, for charts and
+// spreadsheets.
+func (m Money) Float64() float64 {
+	return m.Float64In(BudgetCurrency())
+}
+
+// Float64In is the amount in major units of c.
+func (m Money) Float64In(c Currency) float64 {
+	return float64(m) / math.Pow10(c.Decimals())
+}
+
+// Convert turns an amount in from into to at rate units of to per unit of from,
+// rounding to the nearest minor unit of to.
+func (m Money) Convert(from, to Currency, rate float64) Money {
+	return Money(math.Round(float64(m) * rate * math.Pow10(to.Decimals()-from.Decimals())))
+}
+
+// String formats the amount with the budget currency's decimals; see StringIn.
+func (m Money) String() string {
+	return m.StringIn(BudgetCurrency())
+}
+
+// StringIn formats the amount with the decimals of c and no symbol, e.g. "-12.30".
+func (m Money) StringIn(c Currency) string {
+	decimals := c.Decimals()
+	sign := ""
+	minor := int64(m)
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	if decimals == 0 {
+		return sign + strconv.FormatInt(minor, 10)
+	}
+	scale := int64(math.Pow10(decimals))
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/scale, decimals, minor%scale)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a number or a numeric string.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	return m.unmarshalJSONIn(data, BudgetCurrency())
+}
+
+func (m *Money) unmarshalJSONIn(data []byte, c Currency) error {
+	text := string(data)
+	if text == "" || text == "null" {
+		return nil
+	}
+	parsed, err := ParseMoneyIn(strings.Trim(text, `"`), c)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// jsonIn writes the amount with the decimals of c.
+func (m Money) jsonIn(c Currency) json.RawMessage {
+	return json.RawMessage(m.StringIn(c))
+}
+
+// Decimal is an amount sent in a request before the currency it is in is
+// known, such as the new actual of a line. It accepts what Money does.
+type Decimal string
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if _, err := ParseMoneyIn(text, DefaultCurrency); err != nil {
+		return err
+	}
+	*d = Decimal(text)
+	return nil
+}
+
+// In reads the amount in minor units of c.
+func (d Decimal) In(c Currency) (Money, error) {
+	return ParseMoneyIn(string(d), c)
+}
+
+// Scan reads an INTEGER amount; REAL values, left by older writers, are rounded.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v)
+	case float64:
+		*m = Money(math.Round(v))
+	case []byte:
+		return m.Scan(string(v))
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid stored amount %q: %w", v, err)
+		}
+		*m = Money(n)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+// moneyColumns are every column holding Money. Those kept in the budget currency
+// are rescaled when it changes to one with a different number of decimals; the
+// condition, when set, picks them out by the currency passed as its parameter.
+var moneyColumns = []struct{ table, column, inCurrency string }{
+	{"budget_lines", "expected", "currency = ?"},
+	{"actual_lines", "actual", "currency = ?"},
+	{"transactions", "amount", "budget_line_id IN (SELECT budget_line_id FROM actual_lines WHERE currency = ?)"},
+	{"match_rules", "amount", ""},
+	{"bank_transactions", "amount", ""},
+}
+
+// LoadBudgetCurrency sets the budget currency from the settings table.
+func LoadBudgetCurrency(db *sqlx.DB) error {
+	var code string
+	if err := db.Get(&code, `SELECT value FROM settings WHERE key = 'currency'`); err != nil {
+		return fmt.Errorf("failed to read budget currency: %w", err)
+	}
+	c, err := ParseCurrency(code)
+	if err != nil {
+		return err
+	}
+	SetBudgetCurrency(c)
+	return nil
+}
+
+// ChangeBudgetCurrency switches the budget to another currency. Amounts in the
+// old budget currency keep their value and are re-rounded to the new currency's
+// decimals, so 12.34 stays 12.34 in EUR and becomes 12 in CLP; no exchange rate
+// is applied. Lines kept in the old budget currency are relabelled to the new
+// one, lines kept in others are left as they are, and exchange rates are left
+// for the user to review.
+func ChangeBudgetCurrency(db *sqlx.DB, c Currency) error {
+	if err := LoadBudgetCurrency(db); err != nil {
+		return err
+	}
+	from := BudgetCurrency()
+	if from == c {
+		return nil
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if shift := c.Decimals() - from.Decimals(); shift != 0 {
+		factor := math.Pow10(shift)
+		for _, mc := range moneyColumns {
+			query := fmt.Sprintf(`UPDATE %s SET %s = CAST(ROUND(%s * CAST(? AS DOUBLE PRECISION)) AS BIGINT) WHERE %s IS NOT NULL`,
+				mc.table, mc.column, mc.column, mc.column)
+			args := []interface{}{factor}
+			if mc.inCurrency != "" {
+				query += " AND " + mc.inCurrency
+				args = append(args, string(from))
+			}
+			if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+				return fmt.Errorf("failed to rescale %s.%s: %w", mc.table, mc.column, err)
+			}
+		}
+	}
+	for _, table := range []string{"budget_lines", "actual_lines"} {
+		if _, err := tx.Exec(tx.Rebind(`UPDATE `+table+` SET currency = ? WHERE currency = ?`), string(c), string(from)); err != nil {
+			return fmt.Errorf("failed to relabel %s currency: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec(tx.Rebind(`UPDATE settings SET value = ? WHERE key = 'currency'`), string(c)); err != nil {
+		return fmt.Errorf("failed to save budget currency: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit currency change: %w", err)
+	}
+	SetBudgetCurrency(c)
+	return nil
+}
@@ -0,0 +1,119 @@
+package store
+
+import "encoding/json"
+
+// The rows below carry the currency their amounts are kept in, so they write
+// and read those amounts with its decimals rather than the budget currency's.
+// The currency comes before the amounts in a row, but a client may send them in
+// any order, so the amounts are parsed once the whole row is decoded.
+
+func (b BudgetLine) MarshalJSON() ([]byte, error) {
+	type plain BudgetLine
+	out := struct {
+		plain
+		Expected     json.RawMessage `json:"expected"`
+		ActualAmount json.RawMessage `json:"actual_amount,omitempty"`
+	}{plain: plain(b), Expected: b.Expected.jsonIn(b.Currency)}
+	if b.ActualAmount != nil {
+		out.ActualAmount = b.ActualAmount.jsonIn(b.TransactionCurrency())
+	}
+	return json.Marshal(out)
+}
+
+func (b *BudgetLine) UnmarshalJSON(data []byte) error {
+	type plain BudgetLine
+	in := struct {
+		*plain
+		Expected     json.RawMessage `json:"expected"`
+		ActualAmount json.RawMessage `json:"actual_amount"`
+	}{plain: (*plain)(b)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if err := b.Expected.unmarshalJSONIn(in.Expected, b.Currency); err != nil {
+		return err
+	}
+	b.ActualAmount = nil
+	if in.ActualAmount != nil && string(in.ActualAmount) != "null" {
+		var actual Money
+		if err := actual.unmarshalJSONIn(in.ActualAmount, b.TransactionCurrency()); err != nil {
+			return err
+		}
+		b.ActualAmount = &actual
+	}
+	return nil
+}
+
+// TransactionCurrency is the currency the line's actual and transactions are
+// kept in: the line's own unless its actual says otherwise.
+func (b *BudgetLine) TransactionCurrency() Currency {
+	if b.ActualCurrency != nil {
+		return *b.ActualCurrency
+	}
+	return b.Currency
+}
+
+func (a ActualLine) MarshalJSON() ([]byte, error) {
+	type plain ActualLine
+	return json.Marshal(struct {
+		plain
+		Actual json.RawMessage `json:"actual"`
+	}{plain: plain(a), Actual: a.Actual.jsonIn(a.Currency)})
+}
+
+func (a *ActualLine) UnmarshalJSON(data []byte) error {
+	type plain ActualLine
+	in := struct {
+		*plain
+		Actual json.RawMessage `json:"actual"`
+	}{plain: (*plain)(a)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	return a.Actual.unmarshalJSONIn(in.Actual, a.Currency)
+}
+
+func (t Transaction) MarshalJSON() ([]byte, error) {
+	type plain Transaction
+	return json.Marshal(struct {
+		plain
+		Amount json.RawMessage `json:"amount"`
+	}{plain: plain(t), Amount: t.Amount.jsonIn(t.Currency)})
+}
+
+func (t *Transaction) UnmarshalJSON(data []byte) error {
+	type plain Transaction
+	in := struct {
+		*plain
+		Amount json.RawMessage `json:"amount"`
+	}{plain: (*plain)(t)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	return t.Amount.unmarshalJSONIn(in.Amount, t.Currency)
+}
+
+func (l BudgetLineWithActual) MarshalJSON() ([]byte, error) {
+	type plain BudgetLineWithActual
+	return json.Marshal(struct {
+		plain
+		ExpectedAmount json.RawMessage `json:"expected_amount"`
+		ActualAmount   json.RawMessage `json:"actual_amount"`
+	}{plain: plain(l), ExpectedAmount: l.ExpectedAmount.jsonIn(l.Currency), ActualAmount: l.ActualAmount.jsonIn(l.ActualCurrency)})
+}
+
+func (l *BudgetLineWithActual) UnmarshalJSON(data []byte) error {
+	type plain BudgetLineWithActual
+	in := struct {
+		*plain
+		ExpectedAmount json.RawMessage `json:"expected_amount"`
+		ActualAmount   json.RawMessage `json:"actual_amount"`
+	}{plain: (*plain)(l)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if err := l.ExpectedAmount.unmarshalJSONIn(in.ExpectedAmount, l.Currency); err != nil {
+		return err
+	}
+	return l.ActualAmount.unmarshalJSONIn(in.ActualAmount, l.ActualCurrency)
+}
@@ -0,0 +1,268 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func useBudgetCurrency(t *testing.T, c Currency) {
+	t.Helper()
+	previous := BudgetCurrency()
+	SetBudgetCurrency(c)
+	t.Cleanup(func() { SetBudgetCurrency(previous) })
+}
+
+func TestParseMoney(t *testing.T) {
+	for input, want := range map[string]Money{
+		"0":         0,
+		"12":        12_00,
+		"12.3":      12_30,
+		"-12.34":    -12_34,
+		"0.005":     1,
+		"-0.005":    -1,
+		"1234.5649": 1234_56,
+		".5":        50,
+		"1e2":       100_00,
+	} {
+		got, err := ParseMoney(input)
+		if assert.NoError(t, err, input) {
+			assert.Equal(t, want, got, input)
+		}
+	}
+	for _, input := range []string{"", "abc", "1.2.3", "--1"} {
+		_, err := ParseMoney(input)
+		assert.Error(t, err, input)
+	}
+
+	useBudgetCurrency(t, "CLP")
+	got, err := ParseMoney("1500.5")
+	require.NoError(t, err)
+	assert.Equal(t, Money(1501), got)
+}
+
+func TestMoney_StringAndJSON(t *testing.T) {
+	assert.Equal(t, "12.30", Money(12_30).String())
+	assert.Equal(t, "-0.05", Money(-5).String())
+
+	data, err := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{Amount: 1234_50})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount": 1234.50}`, string(data))
+
+	var decoded struct {
+		A Money  `json:"a"`
+		B Money  `json:"b"`
+		C *Money `json:"c"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"a": 0.1, "b": "19.99", "c": null}`), &decoded))
+	assert.Equal(t, Money(10), decoded.A)
+	assert.Equal(t, Money(19_99), decoded.B)
+	assert.Nil(t, decoded.C)
+	assert.Error(t, json.Unmarshal([]byte(`{"a": true}`), &decoded))
+
+	useBudgetCurrency(t, "CLP")
+	assert.Equal(t, "1500", Money(1500).String())
+	data, err = json.Marshal(Money(1500))
+	require.NoError(t, err)
+	assert.Equal(t, "1500", string(data))
+}
+
+func TestMoney_InItsOwnCurrency(t *testing.T) {
+	yen, err := ParseMoneyIn("1500.4", "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, Money(1500), yen)
+	dollars, err := ParseMoneyIn("12.345", "usd")
+	require.NoError(t, err)
+	assert.Equal(t, Money(12_35), dollars)
+	assert.Equal(t, "1500", yen.StringIn("JPY"))
+	assert.Equal(t, "12.35", dollars.StringIn("USD"))
+	assert.Equal(t, 1500.0, yen.Float64In("JPY"))
+	assert.Equal(t, Money(100_50), Money(15_000).Convert("JPY", "USD", 0.0067))
+	assert.Equal(t, Money(1_500), Money(10_00).Convert("USD", "JPY", 150))
+
+	usd := Currency("USD")
+	for _, base := range []Currency{"USD", "CLP"} {
+		useBudgetCurrency(t, base)
+		line := BudgetLine{ID: 1, Label: "Tokyo", Expected: 1500, Currency: "JPY", ActualAmount: &dollars, ActualCurrency: &usd}
+		data, err := json.Marshal(line)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"expected":1500,`, base)
+		assert.Contains(t, string(data), `"actual_amount":12.35`, base)
+		var decoded BudgetLine
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, line, decoded, base)
+	}
+}
+
+func TestStoreBackends_AmountsKeepTheirCurrencysDecimals(t *testing.T) {
+	useBudgetCurrency(t, "CLP")
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			tripID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Miami", Expected: 120_50, Currency: "USD"})
+			require.NoError(t, err)
+			rentID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 2, Label: "Rent", Expected: 450_000})
+			require.NoError(t, err)
+			taxi := &Transaction{BudgetLineID: tripID, Date: time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC), Amount: 35_75}
+			_, err = s.CreateTransaction(ctx, taxi)
+			require.NoError(t, err)
+			assert.Equal(t, Currency("USD"), taxi.Currency)
+
+			tables := exportTablesOf(t, s)
+			assert.Contains(t, tables["budget_lines"][0], `"expected":120.50`)
+			assert.Contains(t, tables["budget_lines"][1], `"expected":450000}`)
+			assert.Contains(t, tables["transactions"][0], `"amount":35.75`)
+
+			dump := dumpOf(t, s)
+			require.Len(t, dump.Transactions, 1)
+			assert.Equal(t, Money(35_75), dump.Transactions[0].Amount, "the cents survive a CLP budget")
+			trip, err := s.GetBudgetLineByID(ctx, tripID)
+			require.NoError(t, err)
+			assert.Equal(t, Money(35_75), *trip.ActualAmount)
+			rent, err := s.GetBudgetLineByID(ctx, rentID)
+			require.NoError(t, err)
+			assert.Equal(t, Money(450_000), rent.Expected)
+		})
+	}
+}
+
+func TestMoney_Scan(t *testing.T) {
+	var m Money
+	require.NoError(t, m.Scan(int64(12_34)))
+	assert.Equal(t, Money(12_34), m)
+	require.NoError(t, m.Scan(float64(99.6)))
+	assert.Equal(t, Money(100), m)
+	require.NoError(t, m.Scan([]byte("250")))
+	assert.Equal(t, Money(250), m)
+	require.NoError(t, m.Scan(nil))
+	assert.Equal(t, Money(0), m)
+	assert.Error(t, m.Scan(true))
+}
+
+func TestMigration004_ConvertsAmountsToCents(t *testing.T) {
+	embedded, err := loadMigrations(embeddedMigrations)
+	require.NoError(t, err)
+	db := newEmptyTestDB(t)
+	require.NoError(t, applyMigrations(db, embedded[:3], MigrationOptions{}))
+
+	_, err = db.Exec(`INSERT INTO months (id, year, month, finalized) VALUES (1, 2024, 1, 0)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO categories (id, name, color) VALUES (1, 'Food', '#fff')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO budget_lines (id, month_id, category_id, label, expected) VALUES (1, 1, 1, 'Groceries', 0.1 + 0.2)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO actual_lines (budget_line_id, actual) VALUES (1, 19.99)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO match_rules (pattern, label) VALUES ('market', 'Groceries')`)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, RunMigrations(db), ErrMigrationCurrencyRequired, "existing amounts need their currency named")
+	require.NoError(t, RunMigrationsWith(db, MigrationOptions{Currency: "USD"}))
+
+	var expected, actual Money
+	require.NoError(t, db.Get(&expected, `SELECT expected FROM budget_lines WHERE id = 1`))
+	require.NoError(t, db.Get(&actual, `SELECT actual FROM actual_lines WHERE budget_line_id = 1`))
+	assert.Equal(t, Money(30), expected)
+	assert.Equal(t, Money(19_99), actual)
+
+	var ruleAmount *Money
+	require.NoError(t, db.Get(&ruleAmount, `SELECT amount FROM match_rules`))
+	assert.Nil(t, ruleAmount, "a rule without an amount stays NULL")
+
+	var columnType string
+	require.NoError(t, db.Get(&columnType, `SELECT type FROM pragma_table_info('budget_lines') WHERE name = 'expected'`))
+	assert.Equal(t, "INTEGER", columnType)
+}
+
+func TestMigration004_UsesTheDecimalsOfTheNamedCurrency(t *testing.T) {
+	embedded, err := loadMigrations(embeddedMigrations)
+	require.NoError(t, err)
+	db := newEmptyTestDB(t)
+	require.NoError(t, applyMigrations(db, embedded[:3], MigrationOptions{}))
+
+	_, err = db.Exec(`INSERT INTO months (id, year, month, finalized) VALUES (1, 2024, 1, 0)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO categories (id, name, color) VALUES (1, 'Food', '#fff')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO budget_lines (id, month_id, category_id, label, expected) VALUES (1, 1, 1, 'Groceries', 1500)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO actual_lines (budget_line_id, actual) VALUES (1, 1499.6)`)
+	require.NoError(t, err)
+
+	require.NoError(t, RunMigrationsWith(db, MigrationOptions{Currency: "clp"}))
+
+	var expected, actual Money
+	require.NoError(t, db.Get(&expected, `SELECT expected FROM budget_lines WHERE id = 1`))
+	require.NoError(t, db.Get(&actual, `SELECT actual FROM actual_lines WHERE budget_line_id = 1`))
+	assert.Equal(t, Money(1500), expected, "pesos have no minor unit")
+	assert.Equal(t, Money(1500), actual)
+
+	var currency string
+	require.NoError(t, db.Get(&currency, `SELECT value FROM settings WHERE key = 'currency'`))
+	assert.Equal(t, "CLP", currency)
+}
+
+func TestMigration004_DefaultsAnEmptyDatabaseToUSD(t *testing.T) {
+	db := newEmptyTestDB(t)
+	require.NoError(t, RunMigrations(db))
+
+	var currency string
+	require.NoError(t, db.Get(&currency, `SELECT value FROM settings WHERE key = 'currency'`))
+	assert.Equal(t, "USD", currency)
+}
+
+func TestSQLBackends_ChangeBudgetCurrency(t *testing.T) {
+	for name, db := range sqlTestDBs(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			t.Cleanup(func() { SetBudgetCurrency(DefaultCurrency) })
+			require.NoError(t, LoadBudgetCurrency(db))
+			assert.Equal(t, DefaultCurrency, BudgetCurrency())
+
+			s := NewSQLStore(db)
+			_, err := s.ImportAll(ctx, &DatabaseDump{
+				Months:     []Month{{ID: 1, Year: 2024, Month: 1}},
+				Categories: []Category{{ID: 1, Name: "Food", Color: "#fff"}},
+				BudgetLines: []BudgetLine{
+					{ID: 1, MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 12_34, Currency: "USD"},
+					{ID: 2, MonthID: 1, CategoryID: 1, Label: "London", Expected: 50_00, Currency: "GBP"},
+				},
+			}, ImportModeReplace, false)
+			require.NoError(t, err)
+			expectedOf := func() Money {
+				t.Helper()
+				line, err := s.GetBudgetLineByID(ctx, 1)
+				require.NoError(t, err)
+				return line.Expected
+			}
+
+			require.NoError(t, ChangeBudgetCurrency(db, "EUR"))
+			assert.Equal(t, Money(12_34), expectedOf(), "same decimals keep the stored amount")
+			lines, err := s.GetBudgetLinesByMonthID(ctx, 1)
+			require.NoError(t, err)
+			require.Len(t, lines, 2)
+			assert.Equal(t, []Currency{"EUR", "GBP"}, []Currency{lines[0].Currency, lines[1].Currency}, "lines in the old budget currency are relabelled")
+
+			require.NoError(t, ChangeBudgetCurrency(db, "CLP"))
+			assert.Equal(t, Money(12), expectedOf())
+			assert.Equal(t, Currency("CLP"), BudgetCurrency())
+
+			require.NoError(t, ChangeBudgetCurrency(db, "KWD"))
+			assert.Equal(t, Money(12_000), expectedOf())
+			london, err := s.GetBudgetLineByID(ctx, 2)
+			require.NoError(t, err)
+			assert.Equal(t, Money(50_00), london.Expected, "amounts in other currencies keep their own decimals")
+
+			SetBudgetCurrency(DefaultCurrency)
+			require.NoError(t, LoadBudgetCurrency(db))
+			assert.Equal(t, Currency("KWD"), BudgetCurrency(), "the currency is persisted")
+		})
+	}
+}
@@ -1,21 +1,117 @@
 package store
 
 import (
+	"context"
 	"database/sql" // Used by sqlx, good to have explicitly if direct use is ever needed.
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
-func (s *sqlStore) CanFinalizeMonth(monthID int) (bool, string, error) {
+// ErrMonthExists is returned when creating a month whose year and month are
+// already taken.
+var ErrMonthExists = errors.New("month already exists")
+
+// ErrMonthNotFinalized is returned when reopening a month that is still open.
+var ErrMonthNotFinalized = errors.New("month is not finalized")
+
+// GetMonths returns the months matching filter in calendar order.
+func (s *sqlStore) GetMonths(ctx context.Context, filter MonthFilter) ([]Month, error) {
+	query := `SELECT id, year, month, finalized FROM months WHERE 1 = 1`
+	args := []interface{}{}
+	if filter.Year != nil {
+		query += ` AND year = ?`
+		args = append(args, *filter.Year)
+	}
+	if filter.Finalized != nil {
+		query += ` AND finalized = ?`
+		args = append(args, *filter.Finalized)
+	}
+	query += ` ORDER BY year, month`
+
+	months := []Month{}
+	if err := s.DB.SelectContext(ctx, &months, s.DB.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("error fetching months: %w", err)
+	}
+	return months, nil
+}
+
+// GetMonthByID returns a month, or an error wrapping sql.ErrNoRows.
+func (s *sqlStore) GetMonthByID(ctx context.Context, id int64) (*Month, error) {
+	var month Month
+	err := s.DB.GetContext(ctx, &month, s.DB.Rebind(`SELECT id, year, month, finalized FROM months WHERE id = ?`), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get month with ID %d: %w", id, err)
+	}
+	return &month, nil
+}
+
+// GetMonthByPeriod returns the month of a year, or an error wrapping sql.ErrNoRows.
+func (s *sqlStore) GetMonthByPeriod(ctx context.Context, year, month int) (*Month, error) {
+	var m Month
+	err := s.DB.GetContext(ctx, &m, s.DB.Rebind(`SELECT id, year, month, finalized FROM months WHERE year = ? AND month = ?`), year, month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get month %d-%02d: %w", year, month, err)
+	}
+	return &m, nil
+}
+
+// CreateMonth adds an open month without budget lines and sets its ID. It
+// returns ErrMonthExists when the period is taken.
+func (s *sqlStore) CreateMonth(ctx context.Context, month *Month) error {
+	if month.Month < 1 || month.Month > 12 {
+		return fmt.Errorf("invalid month number %d", month.Month)
+	}
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.GetContext(ctx, &exists, tx.Rebind(`SELECT EXISTS (SELECT 1 FROM months WHERE year = ? AND month = ?)`), month.Year, month.Month)
+	if err != nil {
+		return fmt.Errorf("failed to check for month %d-%02d: %w", month.Year, month.Month, err)
+	}
+	if exists {
+		return fmt.Errorf("%d-%02d: %w", month.Year, month.Month, ErrMonthExists)
+	}
+	month.Finalized = false
+	err = tx.GetContext(ctx, &month.ID, tx.Rebind(`INSERT INTO months (year, month, finalized) VALUES (?, ?, FALSE) RETURNING id`), month.Year, month.Month)
+	if err != nil {
+		return fmt.Errorf("failed to create month %d-%02d: %w", month.Year, month.Month, err)
+	}
+	if err := recordAudit(ctx, tx, AuditEntityMonth, month.ID, AuditActionCreate, &month.ID, nil, *month); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit month %d-%02d: %w", month.Year, month.Month, err)
+	}
+	return nil
+}
+
+// GetMonthsByYear returns the months of a year in calendar order.
+func (s *sqlStore) GetMonthsByYear(ctx context.Context, year int) ([]Month, error) {
+	months := []Month{}
+	err := s.DB.SelectContext(ctx, &months, s.DB.Rebind(`SELECT id, year, month, finalized FROM months WHERE year = ? ORDER BY month, id;`), year)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching months for year %d: %w", year, err)
+	}
+	return months, nil
+}
+
+func (s *sqlStore) CanFinalizeMonth(ctx context.Context, monthID int) (bool, string, error) {
 	var count int
 	query := `
 	SELECT COUNT(bl.id)
 	FROM budget_lines bl
 	INNER JOIN actual_lines al ON bl.id = al.budget_line_id 
-	WHERE bl.month_id = ? AND al.actual = 0;
+	WHERE bl.month_id = ? AND bl.deleted_at IS NULL AND al.actual = 0;
 	`
 
-	err := s.DB.Get(&count, query, monthID)
+	err := s.DB.GetContext(ctx, &count, s.DB.Rebind(query), monthID)
 	if err != nil {
 		return false, "", fmt.Errorf("error checking finalization status for month %d: %w", monthID, err)
 	}
@@ -26,88 +122,294 @@ func (s *sqlStore) CanFinalizeMonth(monthID int) (bool, string, error) {
 	return true, "", nil
 }
 
-func (s *sqlStore) FinalizeMonth(monthID int, snapJSON string) (int64, error) {
-	tx, err := s.DB.Beginx()
+// FinalizeMonth stores snapJSON as the month's next snapshot version, marks the
+// month finalized and rolls its budget over into the next month. It returns the
+// next month's ID, or ErrMonthFinalized when the month is already finalized.
+// Under WithIdempotencyKey a repeated call returns the first call's result.
+func (s *sqlStore) FinalizeMonth(ctx context.Context, monthID int, snapJSON string) (int64, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	var committed bool = false
-	defer func() {
-		if !committed {
-			tx.Rollback()
+	defer tx.Rollback()
+
+	// Lock the month before reading anything, so that of two calls racing to
+	// finalize it the second waits for the first to commit and then finds the
+	// month finalized. SQLite cannot make a transaction that has already read
+	// wait for the write lock: it fails with SQLITE_BUSY instead.
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE months SET finalized = finalized WHERE id = ?`), monthID); err != nil {
+		return 0, fmt.Errorf("failed to lock month %d: %w", monthID, err)
+	}
+	if nextMonthID, found, err := priorFinalizeResult(ctx, tx, int64(monthID)); err != nil || found {
+		return nextMonthID, err
+	}
+
+	var currentMonth Month
+	err = tx.GetContext(ctx, &currentMonth, tx.Rebind(`SELECT id, year, month, finalized FROM months WHERE id = ?;`), monthID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get current month details for month %d: %w", monthID, err)
+	}
+	res, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE months SET finalized = TRUE WHERE id = ? AND finalized = FALSE;`), monthID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark month %d as finalized: %w", monthID, err)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected finalizing month %d: %w", monthID, err)
+	}
+	if updated == 0 {
+		// The call that won may have used the same key.
+		if nextMonthID, found, err := priorFinalizeResult(ctx, tx, int64(monthID)); err != nil || found {
+			return nextMonthID, err
 		}
-	}()
+		return 0, fmt.Errorf("month %d: %w", monthID, ErrMonthFinalized)
+	}
+	finalizedMonth := currentMonth
+	finalizedMonth.Finalized = true
+	if err := recordAudit(ctx, tx, AuditEntityMonth, currentMonth.ID, AuditActionUpdate, &currentMonth.ID, currentMonth, finalizedMonth); err != nil {
+		return 0, err
+	}
 
-	createdAt := time.Now().Format("2006-01-02 15:04:05")
-	_, err = tx.Exec(`
-		INSERT INTO annual_snaps (month_id, snap_json, created_at)
-		VALUES (?, ?, ?);`, monthID, snapJSON, createdAt)
+	version, err := insertSnapshot(ctx, tx, currentMonth.ID, snapJSON)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create annual snap for month %d: %w", monthID, err)
+		return 0, err
+	}
+	rolled, err := rollOverBudget(ctx, tx, currentMonth, version, false)
+	if err != nil {
+		return 0, err
+	}
+	if err := recordFinalizeRequest(ctx, tx, currentMonth.ID, rolled.next.ID); err != nil {
+		return 0, err
 	}
 
-	_, err = tx.Exec(`UPDATE months SET finalized = 1 WHERE id = ?;`, monthID)
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction for finalizing month %d: %w", monthID, err)
+	}
+	return rolled.next.ID, nil
+}
+
+// PlanFinalizeMonth works out what FinalizeMonth would do with the month's
+// budget. It only reads, so a preview takes no write lock and uses up no
+// PostgreSQL sequence values. It returns ErrMonthFinalized when the month is
+// already finalized.
+func (s *sqlStore) PlanFinalizeMonth(ctx context.Context, monthID int) (*FinalizePlan, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
 	if err != nil {
-		return 0, fmt.Errorf("failed to mark month %d as finalized: %w", monthID, err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
 	var currentMonth Month
-	err = tx.Get(&currentMonth, `SELECT id, year, month, finalized FROM months WHERE id = ?;`, monthID)
+	err = tx.GetContext(ctx, &currentMonth, tx.Rebind(`SELECT id, year, month, finalized FROM months WHERE id = ?`), monthID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get current month details for month %d: %w", monthID, err)
+		return nil, fmt.Errorf("failed to get current month details for month %d: %w", monthID, err)
+	}
+	if currentMonth.Finalized {
+		return nil, fmt.Errorf("month %d: %w", monthID, ErrMonthFinalized)
+	}
+	version, err := nextSnapshotVersion(ctx, tx, currentMonth.ID)
+	if err != nil {
+		return nil, err
+	}
+	rolled, err := rollOverBudget(ctx, tx, currentMonth, version, true)
+	if err != nil {
+		return nil, err
+	}
+	return rolled.plan(currentMonth, version), nil
+}
+
+// lineLabel identifies a budget line within a month for rolling over: a line
+// the next month already has under the same category and label is not cloned.
+type lineLabel struct {
+	categoryID int
+	label      string
+}
+
+// rollOver is what rolling a month's budget over did: the next month, whether
+// it was created, and the lines cloned into it.
+type rollOver struct {
+	next    Month
+	created bool
+	cloned  []BudgetLine
+}
+
+// plan describes the roll-over for a preview. IDs are left out, since
+// finalizing for real may get others.
+func (r *rollOver) plan(current Month, version int) *FinalizePlan {
+	plan := &FinalizePlan{
+		MonthID: current.ID, SnapshotVersion: version, NextMonth: r.next, CreatesNextMonth: r.created,
+		ClonedLines: []BudgetLine{},
+	}
+	if r.created {
+		plan.NextMonth.ID = 0
+	}
+	for _, bl := range r.cloned {
+		bl.ID = 0
+		if r.created {
+			bl.MonthID = 0
+		}
+		plan.ClonedLines = append(plan.ClonedLines, bl)
 	}
+	return plan
+}
 
-	nextYear, nextMonthVal := currentMonth.Year, currentMonth.Month+1
+// rollOverBudget clones the budget lines of a month being finalized into the
+// following month. The next month is created if needed; if it exists, it only
+// gets the lines it lacks. A finalized next month is left alone, and so is any
+// next month when current is being finalized again after a reopen: it rolled
+// over the first time and may have been edited since. A dry run only works out
+// the roll-over and writes nothing.
+func rollOverBudget(ctx context.Context, tx *sqlx.Tx, current Month, version int, dryRun bool) (*rollOver, error) {
+	nextYear, nextMonthVal := current.Year, current.Month+1
 	if nextMonthVal > 12 {
 		nextMonthVal = 1
 		nextYear++
 	}
 
-	res, err := tx.Exec(`
-		INSERT INTO months (year, month, finalized)
-		VALUES (?, ?, 0);`, nextYear, nextMonthVal)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create next month record for %d-%d: %w", nextYear, nextMonthVal, err)
+	rolled := &rollOver{}
+	next := &rolled.next
+	err := tx.GetContext(ctx, next, tx.Rebind(`SELECT id, year, month, finalized FROM months WHERE year = ? AND month = ?`), nextYear, nextMonthVal)
+	switch {
+	case err == sql.ErrNoRows:
+		next.Year, next.Month, rolled.created = nextYear, nextMonthVal, true
+		if dryRun {
+			break
+		}
+		err = tx.GetContext(ctx, &next.ID, tx.Rebind(`
+			INSERT INTO months (year, month, finalized)
+			VALUES (?, ?, FALSE)
+			RETURNING id;`), nextYear, nextMonthVal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create next month record for %d-%d: %w", nextYear, nextMonthVal, err)
+		}
+		if err := recordAudit(ctx, tx, AuditEntityMonth, next.ID, AuditActionCreate, &next.ID, nil, *next); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to look up next month %d-%d: %w", nextYear, nextMonthVal, err)
+	case next.Finalized || version > 1:
+		return rolled, nil
 	}
-	newMonthID, err := res.LastInsertId()
+
+	var existing []BudgetLine
+	err = tx.SelectContext(ctx, &existing, tx.Rebind(`
+		SELECT category_id, label FROM budget_lines WHERE month_id = ? AND deleted_at IS NULL`), next.ID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get ID of new month (%d-%d): %w", nextYear, nextMonthVal, err)
+		return nil, fmt.Errorf("failed to fetch budget lines for month %d: %w", next.ID, err)
+	}
+	present := map[lineLabel]bool{}
+	for _, bl := range existing {
+		present[lineLabel{bl.CategoryID, bl.Label}] = true
 	}
 
 	var budgetLines []BudgetLine
-	err = tx.Select(&budgetLines, `
-		SELECT category_id, label, expected 
-		FROM budget_lines WHERE month_id = ?;`, monthID)
+	err = tx.SelectContext(ctx, &budgetLines, tx.Rebind(`
+		SELECT category_id, label, expected, currency
+		FROM budget_lines WHERE month_id = ? AND deleted_at IS NULL ORDER BY id;`), current.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-		} else {
-			return 0, fmt.Errorf("failed to fetch budget lines for month %d: %w", monthID, err)
-		}
+		return nil, fmt.Errorf("failed to fetch budget lines for month %d: %w", current.ID, err)
 	}
-	
 	for _, bl := range budgetLines {
-		clonedLineRes, err := tx.Exec(`
-			INSERT INTO budget_lines (month_id, category_id, label, expected)
-			VALUES (?, ?, ?, ?);`, newMonthID, bl.CategoryID, bl.Label, bl.Expected)
-		if err != nil {
-			return 0, fmt.Errorf("failed to clone budget line (label: %s) for new month %d: %w", bl.Label, newMonthID, err)
+		if present[lineLabel{bl.CategoryID, bl.Label}] {
+			continue
 		}
-		newBudgetLineID, err := clonedLineRes.LastInsertId()
+		cloned := BudgetLine{MonthID: int(next.ID), CategoryID: bl.CategoryID, Label: bl.Label, Expected: bl.Expected, Currency: bl.Currency}
+		if dryRun {
+			rolled.cloned = append(rolled.cloned, cloned)
+			continue
+		}
+		var newBudgetLineID int64
+		err := tx.GetContext(ctx, &newBudgetLineID, tx.Rebind(`
+			INSERT INTO budget_lines (month_id, category_id, label, expected, currency)
+			VALUES (?, ?, ?, ?, ?)
+			RETURNING id;`), next.ID, bl.CategoryID, bl.Label, bl.Expected, bl.Currency)
 		if err != nil {
-			return 0, fmt.Errorf("failed to get ID of cloned budget line (label: %s) for new month %d: %w", bl.Label, newMonthID, err)
+			return nil, fmt.Errorf("failed to clone budget line (label: %s) for month %d: %w", bl.Label, next.ID, err)
 		}
 
-		_, err = tx.Exec(`
-			INSERT INTO actual_lines (budget_line_id, actual)
-			VALUES (?, 0);`, newBudgetLineID)
+		_, err = tx.ExecContext(ctx, tx.Rebind(`
+			INSERT INTO actual_lines (budget_line_id, actual, currency)
+			VALUES (?, 0, ?);`), newBudgetLineID, bl.Currency)
 		if err != nil {
-			return 0, fmt.Errorf("failed to create actual line for cloned budget line ID %d (label: %s): %w", newBudgetLineID, bl.Label, err)
+			return nil, fmt.Errorf("failed to create actual line for cloned budget line ID %d (label: %s): %w", newBudgetLineID, bl.Label, err)
+		}
+		cloned.ID = int(newBudgetLineID)
+		if err := recordAudit(ctx, tx, AuditEntityBudgetLine, newBudgetLineID, AuditActionCreate, &next.ID, nil, cloned); err != nil {
+			return nil, err
 		}
+		rolled.cloned = append(rolled.cloned, cloned)
 	}
+	return rolled, nil
+}
 
-	if err = tx.Commit(); err != nil {
-		return 0, fmt.Errorf("failed to commit transaction for finalizing month %d and creating month %d: %w", monthID, newMonthID, err)
+// ReopenMonth makes a finalized month editable again. Its snapshots are kept;
+// finalizing it again stores the next version.
+func (s *sqlStore) ReopenMonth(ctx context.Context, monthID int) error {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before Month
+	err = tx.GetContext(ctx, &before, tx.Rebind(`SELECT id, year, month, finalized FROM months WHERE id = ?`), monthID)
+	if err != nil {
+		return fmt.Errorf("failed to get month with ID %d: %w", monthID, err)
+	}
+	if !before.Finalized {
+		return fmt.Errorf("month %d: %w", monthID, ErrMonthNotFinalized)
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE months SET finalized = FALSE WHERE id = ?`), monthID); err != nil {
+		return fmt.Errorf("failed to reopen month %d: %w", monthID, err)
+	}
+	after := before
+	after.Finalized = false
+	if err := recordAudit(ctx, tx, AuditEntityMonth, before.ID, AuditActionReopen, &before.ID, before, after); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reopening of month %d: %w", monthID, err)
+	}
+	return nil
+}
+
+// GetMonthSnapshots returns every snapshot version of a month, newest first.
+func (s *sqlStore) GetMonthSnapshots(ctx context.Context, monthID int64) ([]AnnualSnapMeta, error) {
+	var rows []annualSnapMetaRow
+	err := s.DB.SelectContext(ctx, &rows, s.DB.Rebind(`
+		SELECT a.id, a.month_id, a.version, m.year, m.month, a.created_at
+		FROM annual_snaps a
+		JOIN months m ON a.month_id = m.id
+		WHERE a.month_id = ?
+		ORDER BY a.version DESC`), monthID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching snapshots of month %d: %w", monthID, err)
+	}
+	return annualSnapMetas(rows), nil
+}
+
+// nextSnapshotVersion returns the version the month's next snapshot gets.
+func nextSnapshotVersion(ctx context.Context, tx *sqlx.Tx, monthID int64) (int, error) {
+	var version int
+	err := tx.GetContext(ctx, &version, tx.Rebind(`SELECT COALESCE(MAX(version), 0) + 1 FROM annual_snaps WHERE month_id = ?`), monthID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get snapshot version for month %d: %w", monthID, err)
+	}
+	return version, nil
+}
+
+// insertSnapshot stores the next snapshot version of a month and returns it.
+func insertSnapshot(ctx context.Context, tx *sqlx.Tx, monthID int64, snapJSON string) (int, error) {
+	version, err := nextSnapshotVersion(ctx, tx, monthID)
+	if err != nil {
+		return 0, err
+	}
+	createdAt := time.Now().Format("2006-01-02 15:04:05")
+	_, err = tx.ExecContext(ctx, tx.Rebind(`
+		INSERT INTO annual_snaps (month_id, version, snap_json, created_at)
+		VALUES (?, ?, ?, ?);`), monthID, version, snapJSON, createdAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create annual snap for month %d: %w", monthID, err)
 	}
-	committed = true
-	return newMonthID, nil
+	return version, nil
 }
@@ -1,9 +1,16 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	// "github.com/jmoiron/sqlx" // Implicitly used
 )
 
@@ -92,7 +99,7 @@ func TestCanFinalizeMonth(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotCan, gotReason, err := s.CanFinalizeMonth(tt.monthID)
+			gotCan, gotReason, err := s.CanFinalizeMonth(context.Background(), tt.monthID)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CanFinalizeMonth() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -111,6 +118,7 @@ func TestCanFinalizeMonth(t *testing.T) {
 }
 
 func TestFinalizeMonth(t *testing.T) {
+	ctx := context.Background()
 	db := newTestDB(t)
 	s := NewSQLStore(db).(*sqlStore)
 
@@ -133,7 +141,7 @@ func TestFinalizeMonth(t *testing.T) {
 	snapJSON := string(snapJSONBytes)
 
 	// Execute FinalizeMonth
-	newMonthID, err := s.FinalizeMonth(int(originalMonthID), snapJSON)
+	newMonthID, err := s.FinalizeMonth(ctx, int(originalMonthID), snapJSON)
 	if err != nil {
 		t.Fatalf("FinalizeMonth() failed: %v", err)
 	}
@@ -193,11 +201,11 @@ func TestFinalizeMonth(t *testing.T) {
 	expectedClonedLines := []struct {
 		CategoryID int64
 		Label      string
-		Expected   float64
+		Expected   Money
 	}{
-		{catRentID, "Apartment Rent", 1200.00},
-		{catFoodID, "Groceries", 300.50},
-		{catFoodID, "Restaurants", 150.00}, // This line had no actual, should still be cloned
+		{catRentID, "Apartment Rent", 1200_00},
+		{catFoodID, "Groceries", 300_50},
+		{catFoodID, "Restaurants", 150_00}, // This line had no actual, should still be cloned
 	}
 	if len(clonedLines) != len(expectedClonedLines) {
 		t.Fatalf("Number of cloned lines mismatch: got %d, want %d. Got: %+v", len(clonedLines), len(expectedClonedLines), clonedLines)
@@ -206,7 +214,7 @@ func TestFinalizeMonth(t *testing.T) {
 	for i, el := range expectedClonedLines {
 		cl := clonedLines[i]
 		if cl.CategoryID != int(el.CategoryID) || cl.Label != el.Label || cl.Expected != el.Expected {
-			t.Errorf("Cloned line mismatch at index %d: got CatID %d, Label %s, Exp %.2f; want CatID %d, Label %s, Exp %.2f",
+			t.Errorf("Cloned line mismatch at index %d: got CatID %d, Label %s, Exp %s; want CatID %d, Label %s, Exp %s",
 				i, cl.CategoryID, cl.Label, cl.Expected, el.CategoryID, el.Label, el.Expected)
 		}
 	}
@@ -228,7 +236,7 @@ func TestFinalizeMonth(t *testing.T) {
 	}
 	for i, al := range actualsForNewMonth {
 		if al.Actual != 0 {
-			t.Errorf("Actual line for cloned budget line at index %d has non-zero actual: got %.2f, want 0.00", i, al.Actual)
+			t.Errorf("Actual line for cloned budget line at index %d has non-zero actual: got %s, want 0.00", i, al.Actual)
 		}
 	}
 
@@ -242,7 +250,7 @@ func TestFinalizeMonth(t *testing.T) {
 		blDec := createTestBudgetLine(t, yearWrapDB, decMonthID, catID, "Year End", 100.0)
 		createTestActualLine(t, yearWrapDB, blDec, 100.0)
 
-		nextNewMonthID, err := yearWrapStore.FinalizeMonth(int(decMonthID), "{}")
+		nextNewMonthID, err := yearWrapStore.FinalizeMonth(ctx, int(decMonthID), "{}")
 		if err != nil {
 			t.Fatalf("FinalizeMonth for year wrap failed: %v", err)
 		}
@@ -281,7 +289,7 @@ func TestFinalizeMonth(t *testing.T) {
 		// which will cause the `tx.Get(&currentMonth, ...)` to fail.
 		// The goal is to ensure no partial data is written.
 		invalidMonthID := 9999
-		_, err := errorStore.FinalizeMonth(invalidMonthID, "{}")
+		_, err := errorStore.FinalizeMonth(ctx, invalidMonthID, "{}")
 		if err == nil {
 			t.Errorf("Expected FinalizeMonth to fail for invalid month ID, but it didn't")
 		}
@@ -318,7 +326,7 @@ func TestFinalizeMonth_NoBudgetLines(t *testing.T) {
 
 	snapJSON := `{"message": "No budget lines to snapshot"}`
 
-	newMonthID, err := s.FinalizeMonth(int(originalMonthID), snapJSON)
+	newMonthID, err := s.FinalizeMonth(context.Background(), int(originalMonthID), snapJSON)
 	if err != nil {
 		t.Fatalf("FinalizeMonth() for month with no budget lines failed: %v", err)
 	}
@@ -369,3 +377,399 @@ func TestFinalizeMonth_NoBudgetLines(t *testing.T) {
 		t.Errorf("Expected 0 actual lines for new month, got %d", actualsCount)
 	}
 }
+
+func TestGetMonthsByYear(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	s := NewSQLStore(db).(*sqlStore)
+
+	createTestMonth(t, db, 2024, 3, false)
+	createTestMonth(t, db, 2023, 12, true)
+	createTestMonth(t, db, 2024, 1, true)
+
+	months, err := s.GetMonthsByYear(ctx, 2024)
+	if err != nil {
+		t.Fatalf("GetMonthsByYear failed: %v", err)
+	}
+	if len(months) != 2 || months[0].Month != 1 || months[1].Month != 3 {
+		t.Fatalf("Expected January and March 2024 in order, got %+v", months)
+	}
+	if !months[0].Finalized {
+		t.Errorf("Expected January to be finalized")
+	}
+
+	months, err = s.GetMonthsByYear(ctx, 2030)
+	if err != nil || len(months) != 0 {
+		t.Errorf("Expected no months for 2030, got %+v (err %v)", months, err)
+	}
+}
+
+func TestGetAnnualSnapshotsMetadataByYear(t *testing.T) {
+	db := newTestDB(t)
+	s := NewSQLStore(db).(*sqlStore)
+
+	march := createTestMonth(t, db, 2024, 3, true)
+	january := createTestMonth(t, db, 2024, 1, true)
+	createTestMonth(t, db, 2024, 4, false)
+	lastYear := createTestMonth(t, db, 2023, 12, true)
+	for _, monthID := range []int64{march, january, lastYear} {
+		if _, err := db.Exec(`INSERT INTO annual_snaps (month_id, snap_json, created_at) VALUES (?, '{}', '2024-04-01 10:00:00')`, monthID); err != nil {
+			t.Fatalf("Failed to insert snapshot: %v", err)
+		}
+	}
+
+	metas, err := s.GetAnnualSnapshotsMetadataByYear(context.Background(), 2024)
+	if err != nil {
+		t.Fatalf("GetAnnualSnapshotsMetadataByYear failed: %v", err)
+	}
+	if len(metas) != 2 {
+		t.Fatalf("Expected 2 snapshots for 2024, got %d", len(metas))
+	}
+	if metas[0].Month != "January" || metas[0].MonthID != january || metas[1].Month != "March" {
+		t.Errorf("Expected January then March, got %+v", metas)
+	}
+	if metas[0].SnapCreatedAt.IsZero() {
+		t.Errorf("Expected snapshot creation time to be set")
+	}
+}
+
+func TestStoreBackends_Months(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+
+			december := &Month{Year: 2023, Month: 12, Finalized: true}
+			require.NoError(t, s.CreateMonth(ctx, december))
+			assert.False(t, december.Finalized, "new months are open")
+			require.NoError(t, s.CreateMonth(ctx, &Month{Year: 2024, Month: 4}))
+			assert.ErrorIs(t, s.CreateMonth(ctx, &Month{Year: 2024, Month: 3}), ErrMonthExists)
+			assert.Error(t, s.CreateMonth(ctx, &Month{Year: 2024, Month: 13}))
+
+			all, err := s.GetMonths(ctx, MonthFilter{})
+			require.NoError(t, err)
+			require.Len(t, all, 3)
+			assert.Equal(t, []int{12, 3, 4}, []int{all[0].Month, all[1].Month, all[2].Month}, "calendar order")
+			year := 2024
+			open := false
+			filtered, err := s.GetMonths(ctx, MonthFilter{Year: &year, Finalized: &open})
+			require.NoError(t, err)
+			assert.Len(t, filtered, 2)
+			finalized := true
+			filtered, err = s.GetMonths(ctx, MonthFilter{Finalized: &finalized})
+			require.NoError(t, err)
+			assert.Empty(t, filtered)
+
+			month, err := s.GetMonthByID(ctx, december.ID)
+			require.NoError(t, err)
+			assert.Equal(t, *december, *month)
+			_, err = s.GetMonthByID(ctx, 99)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+			month, err = s.GetMonthByPeriod(ctx, 2024, 3)
+			require.NoError(t, err)
+			assert.Equal(t, Month{ID: 1, Year: 2024, Month: 3}, *month)
+			_, err = s.GetMonthByPeriod(ctx, 2024, 5)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+
+			created, err := s.GetAuditEntries(ctx, AuditFilter{Entity: AuditEntityMonth})
+			require.NoError(t, err)
+			assert.Len(t, created, 2)
+		})
+	}
+}
+
+func TestMigration009_MergesDuplicateMonths(t *testing.T) {
+	embedded, err := loadMigrations(embeddedMigrations)
+	require.NoError(t, err)
+	db := newEmptyTestDB(t)
+	require.NoError(t, applyMigrations(db, embedded[:8], MigrationOptions{}))
+
+	_, err = db.Exec(`INSERT INTO months (id, year, month, finalized) VALUES (1, 2024, 3, 0), (2, 2024, 4, 0), (3, 2024, 3, 1), (4, 2024, 4, 0)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO categories (id, name, color) VALUES (1, 'Food', '#fff')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO budget_lines (id, month_id, category_id, label, expected) VALUES (1, 1, 1, 'Groceries', 100), (2, 3, 1, 'Snacks', 200), (3, 4, 1, 'Groceries', 100)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO annual_snaps (month_id, snap_json, created_at) VALUES (3, '{}', '2024-04-01 10:00:00')`)
+	require.NoError(t, err)
+
+	require.NoError(t, RunMigrations(db))
+
+	s := &sqlStore{DB: db}
+	months, err := s.GetMonths(context.Background(), MonthFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []Month{{ID: 1, Year: 2024, Month: 3, Finalized: true}, {ID: 2, Year: 2024, Month: 4}}, months)
+	var lineMonths []int
+	require.NoError(t, db.Select(&lineMonths, `SELECT month_id FROM budget_lines ORDER BY id`))
+	assert.Equal(t, []int{1, 1, 2}, lineMonths)
+	var snapMonth int
+	require.NoError(t, db.Get(&snapMonth, `SELECT month_id FROM annual_snaps`))
+	assert.Equal(t, 1, snapMonth)
+
+	_, err = db.Exec(`INSERT INTO months (year, month, finalized) VALUES (2024, 3, 0)`)
+	assert.Error(t, err, "year and month are unique")
+}
+
+func TestStoreBackends_ReopenMonth(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			_, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00})
+			require.NoError(t, err)
+			lines, err := s.GetBudgetLinesByMonthID(ctx, 1)
+			require.NoError(t, err)
+			require.NoError(t, s.UpdateActualLine(ctx, &ActualLine{ID: *lines[0].ActualID, Actual: 380_00}))
+			aprilID, err := s.FinalizeMonth(ctx, 1, `{"version":1}`)
+			require.NoError(t, err)
+			_, err = s.CreateBudgetLine(ctx, &BudgetLine{MonthID: int(aprilID), CategoryID: 2, Label: "Power", Expected: 80_00})
+			require.NoError(t, err)
+
+			assert.ErrorIs(t, s.ReopenMonth(ctx, int(aprilID)), ErrMonthNotFinalized)
+			assert.ErrorIs(t, s.ReopenMonth(ctx, 99), sql.ErrNoRows)
+			require.NoError(t, s.ReopenMonth(ctx, 1))
+			march, err := s.GetMonthByID(ctx, 1)
+			require.NoError(t, err)
+			assert.False(t, march.Finalized)
+			reopened, err := s.GetAuditEntries(ctx, AuditFilter{Limit: 1})
+			require.NoError(t, err)
+			assert.Equal(t, AuditActionReopen, reopened[0].Action)
+			assert.JSONEq(t, `{"id":1,"year":2024,"month":3,"finalized":false}`, string(reopened[0].After))
+
+			require.NoError(t, s.UpdateActualLine(ctx, &ActualLine{ID: *lines[0].ActualID, Actual: 412_00}), "a late bill")
+			nextID, err := s.FinalizeMonth(ctx, 1, `{"version":2}`)
+			require.NoError(t, err)
+			assert.Equal(t, aprilID, nextID, "the next month is not created again")
+			_, err = s.FinalizeMonth(ctx, 1, `{"version":3}`)
+			assert.ErrorIs(t, err, ErrMonthFinalized)
+
+			april, err := s.GetBudgetLinesByMonthID(ctx, int(aprilID))
+			require.NoError(t, err)
+			assert.Len(t, april, 2, "the next month keeps its lines and gets no new clones")
+			months, err := s.GetMonths(ctx, MonthFilter{})
+			require.NoError(t, err)
+			assert.Len(t, months, 2)
+
+			versions, err := s.GetMonthSnapshots(ctx, 1)
+			require.NoError(t, err)
+			require.Len(t, versions, 2)
+			assert.Equal(t, []int{2, 1}, []int{versions[0].Version, versions[1].Version})
+			previous, err := s.GetAnnualSnapshotJSONByID(ctx, versions[1].ID)
+			require.NoError(t, err)
+			assert.Equal(t, `{"version":1}`, previous, "the prior version is kept")
+			current, err := s.GetAnnualSnapshotsMetadataByYear(ctx, 2024)
+			require.NoError(t, err)
+			require.Len(t, current, 1, "reports use the current version")
+			assert.Equal(t, versions[0].ID, current[0].ID)
+		})
+	}
+}
+
+func TestStoreBackends_FinalizeMonthMergesIntoNextMonth(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			for _, bl := range []BudgetLine{
+				{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00},
+				{MonthID: 1, CategoryID: 2, Label: "Power", Expected: 80_00},
+			} {
+				_, err := s.CreateBudgetLine(ctx, &bl)
+				require.NoError(t, err)
+			}
+			april := &Month{Year: 2024, Month: 4}
+			require.NoError(t, s.CreateMonth(ctx, april))
+			for _, bl := range []BudgetLine{
+				{MonthID: int(april.ID), CategoryID: 1, Label: "Groceries", Expected: 450_00},
+				{MonthID: int(april.ID), CategoryID: 1, Label: "Power", Expected: 10_00},
+			} {
+				_, err := s.CreateBudgetLine(ctx, &bl)
+				require.NoError(t, err)
+			}
+
+			nextMonthID, err := s.FinalizeMonth(ctx, 1, `{}`)
+			require.NoError(t, err)
+			assert.Equal(t, april.ID, nextMonthID, "the existing April is used")
+			months, err := s.GetMonths(ctx, MonthFilter{})
+			require.NoError(t, err)
+			assert.Len(t, months, 2)
+
+			lines, err := s.GetBudgetLinesByMonthID(ctx, int(april.ID))
+			require.NoError(t, err)
+			require.Len(t, lines, 3)
+			assert.Equal(t, "Groceries", lines[0].Label)
+			assert.Equal(t, Money(450_00), lines[0].Expected, "a line April already has is kept as it is")
+			assert.Equal(t, BudgetLine{MonthID: int(april.ID), CategoryID: 2, Label: "Power", Expected: 80_00, Currency: "USD"},
+				BudgetLine{MonthID: lines[2].MonthID, CategoryID: lines[2].CategoryID, Label: lines[2].Label, Expected: lines[2].Expected, Currency: lines[2].Currency},
+				"the same label under another category is a different line")
+			assert.Equal(t, Money(0), *lines[2].ActualAmount)
+
+			_, err = s.FinalizeMonth(ctx, 1, `{}`)
+			assert.ErrorIs(t, err, ErrMonthFinalized)
+			_, err = s.FinalizeMonth(ctx, 99, `{}`)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+		})
+	}
+}
+
+func TestStoreBackends_FinalizeMonthIdempotencyKey(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			_, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00})
+			require.NoError(t, err)
+			_, err = s.GetFinalizeRequest(ctx, "tab-1")
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+
+			keyed := WithIdempotencyKey(ctx, "tab-1")
+			nextMonthID, err := s.FinalizeMonth(keyed, 1, `{"try":1}`)
+			require.NoError(t, err)
+			again, err := s.FinalizeMonth(keyed, 1, `{"try":2}`)
+			require.NoError(t, err, "a repeat with the same key is answered, not rejected")
+			assert.Equal(t, nextMonthID, again)
+
+			snaps, err := s.GetMonthSnapshots(ctx, 1)
+			require.NoError(t, err)
+			assert.Len(t, snaps, 1, "the repeat stores no snapshot")
+			lines, err := s.GetBudgetLinesByMonthID(ctx, int(nextMonthID))
+			require.NoError(t, err)
+			assert.Len(t, lines, 1, "and clones nothing")
+			req, err := s.GetFinalizeRequest(ctx, "tab-1")
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), req.MonthID)
+			assert.Equal(t, nextMonthID, req.NextMonthID)
+			assert.Equal(t, time.UTC, req.CreatedAt.Location())
+
+			_, err = s.FinalizeMonth(ctx, 1, `{}`)
+			assert.ErrorIs(t, err, ErrMonthFinalized, "without the key the month is simply finalized")
+			_, err = s.FinalizeMonth(WithIdempotencyKey(ctx, "tab-2"), 1, `{}`)
+			assert.ErrorIs(t, err, ErrMonthFinalized)
+			_, err = s.FinalizeMonth(keyed, int(nextMonthID), `{}`)
+			assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+		})
+	}
+}
+
+func TestSQLiteStore_ConcurrentFinalizeMonth(t *testing.T) {
+	const calls = 4
+	opts := DefaultSQLiteOptions
+	opts.MaxOpenConns = calls + 1
+	db, err := NewStore(filepath.Join(t.TempDir(), "budget.db"), opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, RunMigrations(db))
+	s := NewSQLStore(db)
+	seedBackend(t, s)
+	ctx := context.Background()
+	_, err = s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00})
+	require.NoError(t, err)
+
+	// Another writer holds the database while the calls start, so they all
+	// overlap it and each other.
+	blocker, err := db.Beginx()
+	require.NoError(t, err)
+	_, err = blocker.Exec(`UPDATE months SET finalized = finalized WHERE id = 1`)
+	require.NoError(t, err)
+	errs := make(chan error, calls)
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.FinalizeMonth(ctx, 1, `{}`)
+			errs <- err
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, blocker.Commit())
+	wg.Wait()
+	close(errs)
+
+	won := 0
+	for err := range errs {
+		if err == nil {
+			won++
+			continue
+		}
+		assert.ErrorIs(t, err, ErrMonthFinalized, "the calls that lose the race find the month finalized")
+	}
+	assert.Equal(t, 1, won)
+	snaps, err := s.GetMonthSnapshots(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, snaps, 1)
+}
+
+func TestStoreBackends_PlanFinalizeMonth(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			for _, bl := range []BudgetLine{
+				{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00},
+				{MonthID: 1, CategoryID: 2, Label: "Power", Expected: 80_00},
+			} {
+				_, err := s.CreateBudgetLine(ctx, &bl)
+				require.NoError(t, err)
+			}
+			before, err := s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+
+			plan, err := s.PlanFinalizeMonth(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, &FinalizePlan{
+				MonthID: 1, SnapshotVersion: 1, NextMonth: Month{Year: 2024, Month: 4}, CreatesNextMonth: true,
+				ClonedLines: []BudgetLine{
+					{CategoryID: 1, Label: "Groceries", Expected: 400_00, Currency: "USD"},
+					{CategoryID: 2, Label: "Power", Expected: 80_00, Currency: "USD"},
+				},
+			}, plan)
+			months, err := s.GetMonths(ctx, MonthFilter{})
+			require.NoError(t, err)
+			assert.Len(t, months, 1, "planning changes nothing")
+			after, err := s.GetAuditEntries(ctx, AuditFilter{})
+			require.NoError(t, err)
+			assert.Len(t, after, len(before))
+
+			april := &Month{Year: 2024, Month: 4}
+			require.NoError(t, s.CreateMonth(ctx, april))
+			assert.EqualValues(t, 2, april.ID, "planning uses up no IDs")
+			_, err = s.CreateBudgetLine(ctx, &BudgetLine{MonthID: int(april.ID), CategoryID: 1, Label: "Groceries", Expected: 450_00})
+			require.NoError(t, err)
+			plan, err = s.PlanFinalizeMonth(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, *april, plan.NextMonth)
+			assert.False(t, plan.CreatesNextMonth)
+			assert.Equal(t, []BudgetLine{{MonthID: int(april.ID), CategoryID: 2, Label: "Power", Expected: 80_00, Currency: "USD"}}, plan.ClonedLines)
+
+			_, err = s.FinalizeMonth(ctx, 1, `{}`)
+			require.NoError(t, err)
+			_, err = s.PlanFinalizeMonth(ctx, 1)
+			assert.ErrorIs(t, err, ErrMonthFinalized)
+			_, err = s.PlanFinalizeMonth(ctx, 99)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+		})
+	}
+}
+
+func TestMigration010_NumbersExistingSnapshots(t *testing.T) {
+	embedded, err := loadMigrations(embeddedMigrations)
+	require.NoError(t, err)
+	db := newEmptyTestDB(t)
+	require.NoError(t, applyMigrations(db, embedded[:9], MigrationOptions{}))
+
+	_, err = db.Exec(`INSERT INTO months (id, year, month, finalized) VALUES (1, 2024, 3, 1)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO annual_snaps (id, month_id, snap_json, created_at) VALUES (7, 1, '{}', '2024-04-01 10:00:00')`)
+	require.NoError(t, err)
+
+	require.NoError(t, RunMigrations(db))
+
+	snaps, err := (&sqlStore{DB: db}).GetMonthSnapshots(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, snaps, 1)
+	assert.Equal(t, int64(7), snaps[0].ID)
+	assert.Equal(t, 1, snaps[0].Version)
+	_, err = db.Exec(`INSERT INTO annual_snaps (month_id, version, snap_json, created_at) VALUES (1, 1, '{}', '2024-04-02 10:00:00')`)
+	assert.Error(t, err, "one snapshot per version")
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// postgresDriver is the database/sql driver name of lib/pq. Store code writes
+// queries with ? placeholders and rebinds them for the driver in use.
+const postgresDriver = "postgres"
+
+// PostgreSQL has its own migration set: the SQLite files rebuild tables and use
+// SQLite functions, so they cannot run there unchanged.
+//
+//go:embed postgres/migrations/*.sql
+var embeddedPostgresMigrations embed.FS
+
+// NewPostgresStore connects to a PostgreSQL database, given as a lib/pq
+// connection string such as "postgres://budget@localhost/budget?sslmode=disable".
+// The returned database works with RunMigrations and NewSQLStore like a SQLite one.
+func NewPostgresStore(dataSourceName string) (*sqlx.DB, error) {
+	db, err := sqlx.Connect(postgresDriver, dataSourceName)
+	if err != nil {
+		// The connection string may hold a password, so it is not logged.
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	var database, version string
+	if err := db.QueryRow(`SELECT current_database(), current_setting('server_version')`).Scan(&database, &version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to query PostgreSQL server: %w", err)
+	}
+	log.Printf("Successfully connected to PostgreSQL %s, database %s", version, database)
+	return db, nil
+}
+
+// idSequenceTables are the tables whose id sequence resetIDSequences moves past
+// the IDs of imported rows.
+var idSequenceTables = []string{"months", "categories", "budget_lines", "actual_lines", "transactions", "annual_snaps", "exchange_rates", "match_rules", "bank_transactions"}
+
+// resetIDSequences makes PostgreSQL hand out IDs after those of rows inserted
+// with explicit IDs, as SQLite does on its own. Sequences are not transactional,
+// so this must not run in a transaction that is rolled back afterwards: the real
+// rows could then be handed out again.
+func resetIDSequences(ctx context.Context, tx *sqlx.Tx) error {
+	if tx.DriverName() != postgresDriver {
+		return nil
+	}
+	for _, table := range idSequenceTables {
+		query := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %s`, table, table)
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to reset the id sequence of %s: %w", table, err)
+		}
+	}
+	return nil
+}
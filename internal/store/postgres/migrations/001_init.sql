@@ -0,0 +1,83 @@
+-- The schema of the SQLite migrations 001 to 006 for PostgreSQL. A later schema
+-- change adds a migration here as well as under migrations/.
+CREATE TABLE months (
+  id BIGSERIAL PRIMARY KEY,
+  year INT NOT NULL,
+  month INT NOT NULL,
+  finalized BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE TABLE categories (
+  id BIGSERIAL PRIMARY KEY,
+  name TEXT UNIQUE NOT NULL,
+  color TEXT NOT NULL          -- Tailwind colour class
+);
+CREATE TABLE budget_lines (
+  id BIGSERIAL PRIMARY KEY,
+  month_id BIGINT NOT NULL REFERENCES months(id) ON DELETE CASCADE,
+  category_id BIGINT NOT NULL REFERENCES categories(id),
+  label TEXT NOT NULL,
+  expected BIGINT NOT NULL DEFAULT 0, -- minor units of currency
+  currency TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE actual_lines (
+  id BIGSERIAL PRIMARY KEY,
+  budget_line_id BIGINT NOT NULL REFERENCES budget_lines(id) ON DELETE CASCADE,
+  actual BIGINT NOT NULL DEFAULT 0,   -- sum of the line's transactions
+  currency TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE annual_snaps (
+  id BIGSERIAL PRIMARY KEY,
+  month_id BIGINT NOT NULL UNIQUE REFERENCES months(id),
+  snap_json TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+CREATE TABLE backup_runs (
+  id BIGSERIAL PRIMARY KEY,
+  kind TEXT NOT NULL,          -- 'snapshot' (on-disk copy) or 'json_export'
+  location TEXT NOT NULL,
+  size_bytes BIGINT NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+CREATE TABLE match_rules (
+  id BIGSERIAL PRIMARY KEY,
+  pattern TEXT NOT NULL,       -- case-insensitive substring of the transaction description
+  category_id BIGINT REFERENCES categories(id) ON DELETE CASCADE,
+  label TEXT NOT NULL,         -- budget line label to propose in the transaction's month
+  amount BIGINT                -- only match transactions of exactly this amount when set
+);
+CREATE TABLE bank_transactions (
+  id BIGSERIAL PRIMARY KEY,
+  external_id TEXT NOT NULL UNIQUE, -- OFX FITID, or a hash of the CSV row
+  posted_on DATE NOT NULL,
+  amount BIGINT NOT NULL,           -- money spent, always positive
+  description TEXT NOT NULL,
+  month_id BIGINT REFERENCES months(id) ON DELETE SET NULL,
+  budget_line_id BIGINT REFERENCES budget_lines(id) ON DELETE SET NULL,
+  match_reason TEXT NOT NULL DEFAULT '',
+  status TEXT NOT NULL DEFAULT 'pending', -- 'pending' (review queue), 'applied' or 'ignored'
+  imported_at TIMESTAMP NOT NULL
+);
+CREATE TABLE settings (
+  key TEXT PRIMARY KEY,
+  value TEXT NOT NULL
+);
+INSERT INTO settings (key, value) VALUES ('currency', 'USD');
+
+-- A rate applies from its month until the next rate of the same currency.
+CREATE TABLE exchange_rates (
+  id BIGSERIAL PRIMARY KEY,
+  currency TEXT NOT NULL,      -- ISO 4217 code of the foreign currency
+  year INT NOT NULL,
+  month INT NOT NULL,
+  rate DOUBLE PRECISION NOT NULL, -- units of the budget currency per unit of currency
+  UNIQUE (currency, year, month)
+);
+CREATE TABLE transactions (
+  id BIGSERIAL PRIMARY KEY,
+  budget_line_id BIGINT NOT NULL REFERENCES budget_lines(id) ON DELETE CASCADE,
+  date DATE NOT NULL,
+  amount BIGINT NOT NULL,      -- minor units, in the currency of the line's actuals
+  payee TEXT NOT NULL DEFAULT '',
+  memo TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX transactions_budget_line_id ON transactions (budget_line_id);
@@ -0,0 +1,16 @@
+-- One row per change to a category, budget line, actual or month, or per import
+-- of the whole database, written in the same transaction as the change. There
+-- are no foreign keys, so the history of deleted rows stays. The entity and
+-- action values are the AuditEntity* and AuditAction* constants in models.go.
+CREATE TABLE audit_log (
+  id BIGSERIAL PRIMARY KEY,
+  entity TEXT NOT NULL,        -- 'category', 'budget_line', 'actual_line', 'month' or 'database'
+  entity_id BIGINT NOT NULL,
+  action TEXT NOT NULL,        -- 'create', 'update', 'delete', 'restore', 'purge', 'reopen', 'override' or 'import'
+  month_id BIGINT,             -- the month the row belongs to; NULL for categories and imports
+  before_json TEXT,            -- the row before the change; NULL on create and override
+  after_json TEXT,             -- the row after the change; NULL on delete, purge and override
+  created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX audit_log_created_at ON audit_log (created_at);
+CREATE INDEX audit_log_month_id ON audit_log (month_id);
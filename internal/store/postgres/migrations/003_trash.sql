@@ -0,0 +1,6 @@
+-- Deleted categories and budget lines stay in the trash, with their actuals and
+-- transactions, until they are restored or purged.
+ALTER TABLE categories ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE budget_lines ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX categories_deleted_at ON categories (deleted_at);
+CREATE INDEX budget_lines_deleted_at ON budget_lines (deleted_at);
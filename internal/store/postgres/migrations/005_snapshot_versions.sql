@@ -0,0 +1,5 @@
+-- A month keeps every snapshot it was finalized with: reopening and finalizing
+-- it again adds the next version, and the highest version is the current one.
+ALTER TABLE annual_snaps DROP CONSTRAINT annual_snaps_month_id_key;
+ALTER TABLE annual_snaps ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE annual_snaps ADD CONSTRAINT annual_snaps_month_id_version_key UNIQUE (month_id, version);
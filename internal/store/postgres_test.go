@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postgresTestDSNEnv names the PostgreSQL server the store tests also run
+// against, e.g. postgres://postgres@localhost:55432/postgres?sslmode=disable
+// (see make test_postgres). Without it only SQLite and the memory store are tested.
+const postgresTestDSNEnv = "GANDALF_TEST_POSTGRES_DSN"
+
+// newPostgresTestDB returns a migrated database in a schema of its own, dropped
+// when the test ends. It skips the test when no server is configured.
+func newPostgresTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	dsn := os.Getenv(postgresTestDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresTestDSNEnv)
+	}
+
+	admin, err := sqlx.Connect(postgresDriver, dsn)
+	require.NoError(t, err)
+	schema := fmt.Sprintf("gandalf_test_%d", time.Now().UnixNano())
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("Failed to drop test schema %s: %v", schema, err)
+		}
+		admin.Close()
+	})
+
+	db, err := NewPostgresStore(withSearchPath(dsn, schema))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, RunMigrations(db))
+	return db
+}
+
+// withSearchPath adds a search_path setting to a URL or key=value connection string.
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		return dsn + sep + "search_path=" + schema
+	}
+	return dsn + " search_path=" + schema
+}
+
+func TestPostgresMigrations_CreateEveryTable(t *testing.T) {
+	fsys, err := fs.Sub(embeddedPostgresMigrations, "postgres")
+	require.NoError(t, err)
+	migrations, err := loadMigrations(fsys)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	var schema strings.Builder
+	for _, m := range migrations {
+		schema.WriteString(m.SQL)
+	}
+	tables := []string{"settings", "backup_runs", "match_rules", "bank_transactions"}
+	for _, table := range exportTables {
+		tables = append(tables, table.name)
+	}
+	for _, table := range tables {
+		assert.Contains(t, schema.String(), "CREATE TABLE "+table+" (", "the PostgreSQL schema keeps up with the SQLite one")
+	}
+}
+
+func TestWithSearchPath(t *testing.T) {
+	assert.Equal(t, "postgres://u@h/db?search_path=s", withSearchPath("postgres://u@h/db", "s"))
+	assert.Equal(t, "postgres://u@h/db?sslmode=disable&search_path=s", withSearchPath("postgres://u@h/db?sslmode=disable", "s"))
+	assert.Equal(t, "host=h dbname=db search_path=s", withSearchPath("host=h dbname=db", "s"))
+}
+
+func TestPostgresStore_ImportContinuesIDs(t *testing.T) {
+	ctx := context.Background()
+	db := newPostgresTestDB(t)
+	s := NewSQLStore(db)
+
+	dump := &DatabaseDump{
+		Months:     []Month{{ID: 7, Year: 2024, Month: 3}},
+		Categories: []Category{{ID: 4, Name: "Food", Color: "#0f0"}},
+	}
+	_, err := s.ImportAll(ctx, dump, ImportModeReplace, false)
+	require.NoError(t, err)
+	_, err = s.ImportAll(ctx, &DatabaseDump{Categories: []Category{{ID: 1, Name: "Other", Color: "#000"}}}, ImportModeReplace, true)
+	require.NoError(t, err, "a dry run leaves the sequences alone")
+
+	category := &Category{Name: "Bills", Color: "#f00"}
+	require.NoError(t, s.CreateCategory(ctx, category))
+	assert.Equal(t, int64(5), category.ID, "new rows get IDs after the imported ones")
+	lineID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 7, CategoryID: 4, Label: "Groceries"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), lineID)
+	assert.ErrorIs(t, s.DeleteCategory(ctx, 4), ErrCategoryInUse)
+}
@@ -1,99 +1,273 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func NewStore(dataSourceName string) (*sqlx.DB, error) {
-	db, err := sqlx.Connect("sqlite3", dataSourceName)
+// SQLiteOptions are the connection settings NewStore applies to every pooled
+// connection. An empty JournalMode or Synchronous leaves SQLite's default.
+type SQLiteOptions struct {
+	ForeignKeys  bool
+	JournalMode  string // e.g. WAL, DELETE
+	BusyTimeout  time.Duration
+	Synchronous  string // OFF, NORMAL, FULL or EXTRA
+	MaxOpenConns int
+}
+
+// DefaultSQLiteOptions suit the single-file production database: WAL lets the
+// backup job and readers run alongside writes, and NORMAL sync is durable in WAL
+// mode except across power loss.
+var DefaultSQLiteOptions = SQLiteOptions{
+	ForeignKeys:  true,
+	JournalMode:  "WAL",
+	BusyTimeout:  5 * time.Second,
+	Synchronous:  "NORMAL",
+	MaxOpenConns: 4,
+}
+
+var synchronousLevels = map[string]int{"OFF": 0, "NORMAL": 1, "FULL": 2, "EXTRA": 3}
+
+// NewStore opens the SQLite database with the given options and checks that the
+// pragmas took effect, since the driver ignores settings it cannot apply.
+func NewStore(dataSourceName string, opts SQLiteOptions) (*sqlx.DB, error) {
+	opts.JournalMode = strings.ToUpper(opts.JournalMode)
+	opts.Synchronous = strings.ToUpper(opts.Synchronous)
+	if _, ok := synchronousLevels[opts.Synchronous]; opts.Synchronous != "" && !ok {
+		return nil, fmt.Errorf("invalid synchronous level %q: use OFF, NORMAL, FULL or EXTRA", opts.Synchronous)
+	}
+	if opts.BusyTimeout < 0 || opts.MaxOpenConns < 0 {
+		return nil, fmt.Errorf("busy timeout and connection pool size must not be negative")
+	}
+
+	db, err := sqlx.Connect("sqlite3", sqliteDSN(dataSourceName, opts))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database %s: %w", dataSourceName, err)
 	}
-	log.Printf("Successfully connected to database: %s", dataSourceName)
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+		db.SetMaxIdleConns(opts.MaxOpenConns)
+	}
+	if err := verifySQLiteOptions(db, opts); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("database %s: %w", dataSourceName, err)
+	}
+	if opts.ForeignKeys {
+		logForeignKeyViolations(db)
+	}
+	log.Printf("Successfully connected to database: %s (foreign_keys=%t, journal_mode=%s, synchronous=%s, busy_timeout=%s)",
+		dataSourceName, opts.ForeignKeys, opts.JournalMode, opts.Synchronous, opts.BusyTimeout)
 	return db, nil
 }
 
+// sqliteDSN passes the options as go-sqlite3 connection parameters, which the
+// driver runs on each new connection rather than only the first.
+func sqliteDSN(dataSourceName string, opts SQLiteOptions) string {
+	params := url.Values{}
+	if opts.ForeignKeys {
+		params.Set("_foreign_keys", "on")
+	} else {
+		params.Set("_foreign_keys", "off")
+	}
+	if opts.JournalMode != "" {
+		params.Set("_journal_mode", opts.JournalMode)
+	}
+	params.Set("_busy_timeout", strconv.FormatInt(opts.BusyTimeout.Milliseconds(), 10))
+	if opts.Synchronous != "" {
+		params.Set("_synchronous", opts.Synchronous)
+	}
+	sep := "?"
+	if strings.Contains(dataSourceName, "?") {
+		sep = "&"
+	}
+	return dataSourceName + sep + params.Encode()
+}
+
+func verifySQLiteOptions(db *sqlx.DB, opts SQLiteOptions) error {
+	var foreignKeys bool
+	if err := db.Get(&foreignKeys, "PRAGMA foreign_keys"); err != nil {
+		return fmt.Errorf("failed to read foreign_keys pragma: %w", err)
+	}
+	if foreignKeys != opts.ForeignKeys {
+		return fmt.Errorf("foreign_keys is %t, want %t", foreignKeys, opts.ForeignKeys)
+	}
+
+	if opts.JournalMode != "" {
+		var journalMode string
+		if err := db.Get(&journalMode, "PRAGMA journal_mode"); err != nil {
+			return fmt.Errorf("failed to read journal_mode pragma: %w", err)
+		}
+		if !strings.EqualFold(journalMode, opts.JournalMode) {
+			return fmt.Errorf("journal_mode is %s, want %s", journalMode, opts.JournalMode)
+		}
+	}
+
+	var busyTimeout int64
+	if err := db.Get(&busyTimeout, "PRAGMA busy_timeout"); err != nil {
+		return fmt.Errorf("failed to read busy_timeout pragma: %w", err)
+	}
+	if busyTimeout != opts.BusyTimeout.Milliseconds() {
+		return fmt.Errorf("busy_timeout is %dms, want %dms", busyTimeout, opts.BusyTimeout.Milliseconds())
+	}
+
+	if opts.Synchronous != "" {
+		var synchronous int
+		if err := db.Get(&synchronous, "PRAGMA synchronous"); err != nil {
+			return fmt.Errorf("failed to read synchronous pragma: %w", err)
+		}
+		if synchronous != synchronousLevels[opts.Synchronous] {
+			return fmt.Errorf("synchronous is %d, want %s", synchronous, opts.Synchronous)
+		}
+	}
+	return nil
+}
+
+// logForeignKeyViolations reports rows orphaned while enforcement was off; they
+// stay readable, but SQLite will reject updates that keep the broken reference.
+func logForeignKeyViolations(db *sqlx.DB) {
+	var violations []struct {
+		Table  string        `db:"table"`
+		RowID  sql.NullInt64 `db:"rowid"`
+		Parent string        `db:"parent"`
+		FKID   int           `db:"fkid"`
+	}
+	if err := db.Select(&violations, "PRAGMA foreign_key_check"); err != nil {
+		log.Printf("Could not check existing rows for foreign key violations: %v", err)
+		return
+	}
+	for _, v := range violations {
+		log.Printf("Warning: %s row %d references a missing %s row", v.Table, v.RowID.Int64, v.Parent)
+	}
+}
+
 type Store interface {
-	GetAllCategories() ([]Category, error)
-	CreateCategory(category *Category) error
-	GetCategoryByID(id int64) (*Category, error)
-	UpdateCategory(category *Category) error
-	DeleteCategory(id int64) error
-
-	CreateBudgetLine(b *BudgetLine) (int64, error)
-	GetBudgetLinesByMonthID(monthID int) ([]BudgetLine, error)
-	UpdateBudgetLine(b *BudgetLine) error
-	DeleteBudgetLine(id int64) error
-	UpdateActualLine(a *ActualLine) error
-	GetActualLineByID(id int64) (*ActualLine, error)
-	GetBudgetLineByID(id int64) (*BudgetLine, error)
-
-	GetBoardData(monthID int) (*BoardDataPayload, error)
-
-	CanFinalizeMonth(monthID int) (bool, string, error)
-	FinalizeMonth(monthID int, snapJSON string) (int64, error)
-
-	GetAnnualSnapshotsMetadataByYear(year int) ([]AnnualSnapMeta, error)
-	GetAnnualSnapshotJSONByID(snapID int64) (string, error)
+	GetAllCategories(ctx context.Context) ([]Category, error)
+	CreateCategory(ctx context.Context, category *Category) error
+	GetCategoryByID(ctx context.Context, id int64) (*Category, error)
+	UpdateCategory(ctx context.Context, category *Category) error
+	DeleteCategory(ctx context.Context, id int64) error
+
+	CreateBudgetLine(ctx context.Context, b *BudgetLine) (int64, error)
+	GetBudgetLinesByMonthID(ctx context.Context, monthID int) ([]BudgetLine, error)
+	UpdateBudgetLine(ctx context.Context, b *BudgetLine) error
+	DeleteBudgetLine(ctx context.Context, id int64) error
+	UpdateActualLine(ctx context.Context, a *ActualLine) error
+	GetActualLineByID(ctx context.Context, id int64) (*ActualLine, error)
+	GetBudgetLineByID(ctx context.Context, id int64) (*BudgetLine, error)
+
+	GetBoardData(ctx context.Context, monthID int) (*BoardDataPayload, error)
+
+	GetMonthsByYear(ctx context.Context, year int) ([]Month, error)
+	GetMonths(ctx context.Context, filter MonthFilter) ([]Month, error)
+	GetMonthByID(ctx context.Context, id int64) (*Month, error)
+	GetMonthByPeriod(ctx context.Context, year, month int) (*Month, error)
+	CreateMonth(ctx context.Context, month *Month) error
+	ReopenMonth(ctx context.Context, monthID int) error
+	GetMonthSnapshots(ctx context.Context, monthID int64) ([]AnnualSnapMeta, error)
+	CanFinalizeMonth(ctx context.Context, monthID int) (bool, string, error)
+	FinalizeMonth(ctx context.Context, monthID int, snapJSON string) (int64, error)
+	GetFinalizeRequest(ctx context.Context, key string) (*FinalizeRequest, error)
+	PlanFinalizeMonth(ctx context.Context, monthID int) (*FinalizePlan, error)
+
+	GetAnnualSnapshotsMetadataByYear(ctx context.Context, year int) ([]AnnualSnapMeta, error)
+	GetAnnualSnapshotJSONByID(ctx context.Context, snapID int64) (string, error)
+
+	ExportAll(ctx context.Context, sink ExportSink) error
+	ImportAll(ctx context.Context, dump *DatabaseDump, mode ImportMode, dryRun bool) (*ImportReport, error)
+	ImportLegacyRows(ctx context.Context, rows []LegacyImportRow, opts LegacyImportOptions) (*LegacyImportReport, error)
+
+	AddBankTransactions(ctx context.Context, txns []BankTransaction) (int, error)
+	GetBankTransactions(ctx context.Context, status string) ([]BankTransaction, error)
+	ConfirmBankTransactions(ctx context.Context, confirmations []BankConfirmation) error
+	IgnoreBankTransaction(ctx context.Context, id int64) error
+	GetMatchRules(ctx context.Context) ([]MatchRule, error)
+	CreateMatchRule(ctx context.Context, rule *MatchRule) error
+	DeleteMatchRule(ctx context.Context, id int64) error
+
+	RecordBackupRun(ctx context.Context, run *BackupRun) error
+	GetLastBackupRun(ctx context.Context) (*BackupRun, error)
+
+	GetExchangeRates(ctx context.Context) ([]ExchangeRate, error)
+	SetExchangeRates(ctx context.Context, rates []ExchangeRate) error
+	DeleteExchangeRate(ctx context.Context, id int64) error
+	GetExchangeRatesForMonth(ctx context.Context, monthID int) (map[Currency]float64, error)
+
+	GetTransactionsByBudgetLineID(ctx context.Context, budgetLineID int64) ([]Transaction, error)
+	GetTransactionByID(ctx context.Context, id int64) (*Transaction, error)
+	CreateTransaction(ctx context.Context, t *Transaction) (int64, error)
+	UpdateTransaction(ctx context.Context, t *Transaction) error
+	DeleteTransaction(ctx context.Context, id int64) error
+
+	GetAuditEntries(ctx context.Context, filter AuditFilter) ([]AuditEntry, error)
+
+	GetTrash(ctx context.Context) (*Trash, error)
+	RestoreCategory(ctx context.Context, id int64) error
+	RestoreBudgetLine(ctx context.Context, id int64) error
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (*TrashCounts, error)
 }
 
 type sqlStore struct {
 	DB *sqlx.DB
 }
 
-func (s *sqlStore) GetAnnualSnapshotsMetadataByYear(year int) ([]AnnualSnapMeta, error) {
-	panic("implement me")
+type annualSnapMetaRow struct {
+	ID        int64     `db:"id"`
+	MonthID   int64     `db:"month_id"`
+	Version   int       `db:"version"`
+	Year      int       `db:"year"`
+	Month     int       `db:"month"`
+	CreatedAt time.Time `db:"created_at"`
 }
 
-func NewSQLStore(db *sqlx.DB) Store {
-	return &sqlStore{DB: db}
+func annualSnapMetas(rows []annualSnapMetaRow) []AnnualSnapMeta {
+	metas := make([]AnnualSnapMeta, 0, len(rows))
+	for _, row := range rows {
+		metas = append(metas, AnnualSnapMeta{
+			ID:            row.ID,
+			MonthID:       row.MonthID,
+			Version:       row.Version,
+			Year:          row.Year,
+			Month:         time.Month(row.Month).String(),
+			SnapCreatedAt: row.CreatedAt,
+		})
+	}
+	return metas
 }
 
-func RunMigrations(db *sqlx.DB, migrationsDir string) error {
-	log.Printf("Looking for migrations in: %s", migrationsDir)
-	files, err := filepath.Glob(filepath.Join(migrationsDir, "*.sql"))
-	if err != nil {
-		return fmt.Errorf("failed to glob migration files: %w", err)
-	}
-	if len(files) == 0 {
-		log.Println("No migration files found.")
-		return nil
+// GetAnnualSnapshotsMetadataByYear returns the current (latest) snapshot of
+// each month of a year.
+func (s *sqlStore) GetAnnualSnapshotsMetadataByYear(ctx context.Context, year int) ([]AnnualSnapMeta, error) {
+	var rows []annualSnapMetaRow
+	query := `
+	SELECT a.id, a.month_id, a.version, m.year, m.month, a.created_at
+	FROM annual_snaps a
+	JOIN months m ON a.month_id = m.id
+	WHERE m.year = ? AND a.version = (SELECT MAX(v.version) FROM annual_snaps v WHERE v.month_id = a.month_id)
+	ORDER BY m.month;
+	`
+	if err := s.DB.SelectContext(ctx, &rows, s.DB.Rebind(query), year); err != nil {
+		return nil, fmt.Errorf("error fetching annual snapshots for year %d: %w", year, err)
 	}
+	return annualSnapMetas(rows), nil
+}
 
-	log.Printf("Found %d migration files. Applying...", len(files))
-	for _, file := range files {
-		log.Printf("Applying migration: %s", file)
-		queryBytes, err := os.ReadFile(file)
-		if err != nil {
-			return fmt.Errorf("failed to read migration file %s: %w", file, err)
-		}
-		query := string(queryBytes)
-		_, err = db.Exec(query)
-		if err != nil {
-			if strings.Contains(err.Error(), "already exists") {
-				log.Printf("Table in migration %s likely already exists, skipping: %v", file, err)
-				continue
-			}
-			return fmt.Errorf("failed to execute migration file %s: %w", file, err)
-		}
-		log.Printf("Successfully applied migration: %s", file)
-	}
-	log.Println("All migrations applied successfully.")
-	return nil
+func NewSQLStore(db *sqlx.DB) Store {
+	return &sqlStore{DB: db}
 }
 
-func (s *sqlStore) GetAnnualSnapshotJSONByID(snapID int64) (string, error) {
+func (s *sqlStore) GetAnnualSnapshotJSONByID(ctx context.Context, snapID int64) (string, error) {
 	var snapJSON string
 	query := `SELECT snap_json FROM annual_snaps WHERE id = ?;`
-	err := s.DB.Get(&snapJSON, query, snapID)
+	err := s.DB.GetContext(ctx, &snapJSON, s.DB.Rebind(query), snapID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", sql.ErrNoRows
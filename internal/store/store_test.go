@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStore(t *testing.T) {
+	t.Run("applies and verifies the default options", func(t *testing.T) {
+		db, err := NewStore(filepath.Join(t.TempDir(), "budget.db"), DefaultSQLiteOptions)
+		require.NoError(t, err)
+		defer db.Close()
+		assert.Equal(t, DefaultSQLiteOptions.MaxOpenConns, db.Stats().MaxOpenConnections)
+
+		// Every pooled connection gets the pragmas, not just the first one.
+		ctx := context.Background()
+		conns := make([]*sqlx.Conn, 0, DefaultSQLiteOptions.MaxOpenConns)
+		for i := 0; i < DefaultSQLiteOptions.MaxOpenConns; i++ {
+			conn, err := db.Connx(ctx)
+			require.NoError(t, err)
+			conns = append(conns, conn)
+
+			var foreignKeys bool
+			var journalMode string
+			var busyTimeout, synchronous int
+			require.NoError(t, conn.GetContext(ctx, &foreignKeys, "PRAGMA foreign_keys"))
+			require.NoError(t, conn.GetContext(ctx, &journalMode, "PRAGMA journal_mode"))
+			require.NoError(t, conn.GetContext(ctx, &busyTimeout, "PRAGMA busy_timeout"))
+			require.NoError(t, conn.GetContext(ctx, &synchronous, "PRAGMA synchronous"))
+			assert.True(t, foreignKeys)
+			assert.Equal(t, "wal", journalMode)
+			assert.Equal(t, 5000, busyTimeout)
+			assert.Equal(t, 1, synchronous)
+		}
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+
+	t.Run("enforces foreign keys", func(t *testing.T) {
+		db, err := NewStore(filepath.Join(t.TempDir(), "budget.db"), DefaultSQLiteOptions)
+		require.NoError(t, err)
+		defer db.Close()
+		require.NoError(t, RunMigrations(db))
+
+		_, err = db.Exec(`INSERT INTO budget_lines (month_id, category_id, label, expected) VALUES (99, 99, 'Orphan', 1)`)
+		assert.Error(t, err)
+	})
+
+	t.Run("fails when a pragma does not take effect", func(t *testing.T) {
+		// An in-memory database cannot use WAL; SQLite silently keeps "memory".
+		_, err := NewStore(":memory:", DefaultSQLiteOptions)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "journal_mode")
+	})
+
+	t.Run("rejects invalid options", func(t *testing.T) {
+		opts := DefaultSQLiteOptions
+		opts.Synchronous = "sometimes"
+		_, err := NewStore(filepath.Join(t.TempDir(), "budget.db"), opts)
+		assert.Error(t, err)
+
+		opts = DefaultSQLiteOptions
+		opts.BusyTimeout = -time.Second
+		_, err = NewStore(filepath.Join(t.TempDir(), "budget.db"), opts)
+		assert.Error(t, err)
+	})
+}
+
+func TestDeleteCategory_InUse(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	s := NewSQLStore(db)
+	catID := createTestCategory(t, db, "Groceries", "bg-green-500")
+	monthID := createTestMonth(t, db, 2024, 5, false)
+	createTestBudgetLine(t, db, monthID, catID, "Supermarket", 300)
+
+	assert.ErrorIs(t, s.DeleteCategory(ctx, catID), ErrCategoryInUse)
+
+	unusedID := createTestCategory(t, db, "Travel", "bg-blue-500")
+	assert.NoError(t, s.DeleteCategory(ctx, unusedID))
+}
+
+func TestStore_HonoursContext(t *testing.T) {
+	db := newTestDB(t)
+	s := NewSQLStore(db)
+	catID := createTestCategory(t, db, "Food", "#0f0")
+	monthID := createTestMonth(t, db, 2024, 3, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.GetAllCategories(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = s.CreateBudgetLine(ctx, &BudgetLine{MonthID: int(monthID), CategoryID: int(catID), Label: "Groceries", Expected: 10_00})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, countRows(t, s.(*sqlStore), "budget_lines"), "a cancelled write changes nothing")
+}
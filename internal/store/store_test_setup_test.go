@@ -1,8 +1,6 @@
 package store
 
 import (
-	"os"
-	"path/filepath"
 	"testing"
 
 	"github.com/jmoiron/sqlx"
@@ -16,22 +14,11 @@ func newTestDB(t *testing.T) *sqlx.DB {
 	if err != nil {
 		t.Fatalf("Failed to connect to in-memory sqlite3: %v", err)
 	}
+	// Every new connection to :memory: opens a fresh, empty database.
+	db.SetMaxOpenConns(1)
 
-	migrationPath := filepath.Join("..", "..", "internal", "store", "migrations", "001_init.sql")
-
-	queryBytes, err := os.ReadFile(migrationPath)
-	if err != nil {
-		altMigrationPath := filepath.Join("migrations", "001_init.sql")
-		queryBytes, err = os.ReadFile(altMigrationPath)
-		if err != nil {
-			t.Fatalf("Failed to read migration file from %s or %s: %v", migrationPath, altMigrationPath, err)
-		}
-	}
-
-	query := string(queryBytes)
-	_, err = db.Exec(query)
-	if err != nil {
-		t.Fatalf("Failed to execute migration: %v", err)
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
 	}
 
 	t.Cleanup(func() {
@@ -77,7 +64,7 @@ func createTestMonth(t *testing.T, db *sqlx.DB, year int, month int, finalized b
 func createTestBudgetLine(t *testing.T, db *sqlx.DB, monthID int64, categoryID int64, label string, expected float64) int64 {
 	t.Helper()
 	res, err := db.Exec("INSERT INTO budget_lines (month_id, category_id, label, expected) VALUES (?, ?, ?, ?)",
-		monthID, categoryID, label, expected)
+		monthID, categoryID, label, MoneyFromFloat(expected))
 	if err != nil {
 		t.Fatalf("Failed to create test budget line '%s': %v", label, err)
 	}
@@ -90,7 +77,7 @@ func createTestBudgetLine(t *testing.T, db *sqlx.DB, monthID int64, categoryID i
 
 func createTestActualLine(t *testing.T, db *sqlx.DB, budgetLineID int64, actual float64) int64 {
 	t.Helper()
-	res, err := db.Exec("INSERT INTO actual_lines (budget_line_id, actual) VALUES (?, ?)", budgetLineID, actual)
+	res, err := db.Exec("INSERT INTO actual_lines (budget_line_id, actual) VALUES (?, ?)", budgetLineID, MoneyFromFloat(actual))
 	if err != nil {
 		t.Fatalf("Failed to create test actual line for budget_line_id %d: %v", budgetLineID, err)
 	}
@@ -98,5 +85,14 @@ func createTestActualLine(t *testing.T, db *sqlx.DB, budgetLineID int64, actual
 	if err != nil {
 		t.Fatalf("Failed to get last insert ID for actual line (budget_line_id %d): %v", budgetLineID, err)
 	}
+	// The actual is the sum of the line's transactions, so record it as one.
+	if actual != 0 {
+		_, err = db.Exec(`INSERT INTO transactions (budget_line_id, date, amount)
+			SELECT bl.id, printf('%04d-%02d-01', m.year, m.month), ? FROM budget_lines bl JOIN months m ON m.id = bl.month_id WHERE bl.id = ?`,
+			MoneyFromFloat(actual), budgetLineID)
+		if err != nil {
+			t.Fatalf("Failed to create test transaction for budget_line_id %d: %v", budgetLineID, err)
+		}
+	}
 	return id
 }
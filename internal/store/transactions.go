@@ -0,0 +1,338 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const transactionDateLayout = "2006-01-02"
+
+// ErrNegativeActual is returned when a change to a budget line's transactions
+// would leave the line with a negative actual. Refunds may be booked as
+// negative transactions, but only against what was spent.
+var ErrNegativeActual = errors.New("actual total cannot be negative")
+
+// recordOpeningTransactions records, for every actual line whose total differs
+// from the sum of its transactions, the difference as a transaction on the first
+// day of the line's month, and returns how many it recorded. It brings totals
+// from before transactions existed, such as those of old backups, in line with
+// their transactions.
+func recordOpeningTransactions(ctx context.Context, tx *sqlx.Tx) (int, error) {
+	var opening []struct {
+		BudgetLineID int64 `db:"budget_line_id"`
+		Year         int   `db:"year"`
+		Month        int   `db:"month"`
+		Diff         Money `db:"diff"`
+	}
+	err := tx.SelectContext(ctx, &opening, `
+		SELECT al.budget_line_id, m.year, m.month,
+			al.actual - COALESCE((SELECT SUM(t.amount) FROM transactions t WHERE t.budget_line_id = al.budget_line_id), 0) AS diff
+		FROM actual_lines al
+		JOIN budget_lines bl ON bl.id = al.budget_line_id
+		JOIN months m ON m.id = bl.month_id
+		WHERE al.actual != COALESCE((SELECT SUM(t.amount) FROM transactions t WHERE t.budget_line_id = al.budget_line_id), 0)
+		ORDER BY al.id`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find actuals without transactions: %w", err)
+	}
+	for _, o := range opening {
+		t := &Transaction{
+			BudgetLineID: o.BudgetLineID,
+			Date:         time.Date(o.Year, time.Month(o.Month), 1, 0, 0, 0, 0, time.UTC),
+			Amount:       o.Diff,
+			Memo:         "Actual recorded before transactions",
+		}
+		if _, err := insertTransaction(ctx, tx, t); err != nil {
+			return 0, err
+		}
+	}
+	return len(opening), nil
+}
+
+func (s *sqlStore) GetTransactionsByBudgetLineID(ctx context.Context, budgetLineID int64) ([]Transaction, error) {
+	var exists bool
+	if err := s.DB.GetContext(ctx, &exists, s.DB.Rebind(`SELECT EXISTS (SELECT 1 FROM budget_lines WHERE id = ? AND deleted_at IS NULL)`), budgetLineID); err != nil {
+		return nil, fmt.Errorf("failed to check budget line %d: %w", budgetLineID, err)
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	transactions := []Transaction{}
+	err := s.DB.SelectContext(ctx, &transactions, s.DB.Rebind(`
+		SELECT t.id, t.budget_line_id, t.date, t.amount, `+transactionCurrencySQL+` AS currency, t.payee, t.memo
+		FROM transactions t JOIN budget_lines bl ON bl.id = t.budget_line_id
+		WHERE t.budget_line_id = ? ORDER BY t.date, t.id`), budgetLineID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions of budget line %d: %w", budgetLineID, err)
+	}
+	// lib/pq gives dates a zone of its own; they are UTC midnights throughout.
+	for i := range transactions {
+		transactions[i].Date = transactions[i].Date.UTC()
+	}
+	return transactions, nil
+}
+
+func (s *sqlStore) GetTransactionByID(ctx context.Context, id int64) (*Transaction, error) {
+	var t Transaction
+	err := s.DB.GetContext(ctx, &t, s.DB.Rebind(`
+		SELECT t.id, t.budget_line_id, t.date, t.amount, `+transactionCurrencySQL+` AS currency, t.payee, t.memo
+		FROM transactions t JOIN budget_lines bl ON bl.id = t.budget_line_id
+		WHERE t.id = ? AND bl.deleted_at IS NULL`), id)
+	if err == sql.ErrNoRows {
+		return nil, sql.ErrNoRows
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction %d: %w", id, err)
+	}
+	t.Date = t.Date.UTC()
+	return &t, nil
+}
+
+// transactionCurrencySQL is the currency of the transaction t on the budget
+// line bl: that of the line's actual, or of the line before it has one.
+const transactionCurrencySQL = `COALESCE((SELECT al.currency FROM actual_lines al WHERE al.budget_line_id = t.budget_line_id ORDER BY al.id LIMIT 1), bl.currency)`
+
+// liveTransactionLineSQL finds the budget line of a transaction, unless the
+// line is in the trash.
+const liveTransactionLineSQL = `
+	SELECT t.budget_line_id FROM transactions t JOIN budget_lines bl ON bl.id = t.budget_line_id
+	WHERE t.id = ? AND bl.deleted_at IS NULL`
+
+// CreateTransaction adds a transaction to its budget line and updates the
+// line's actual. It returns sql.ErrNoRows when the budget line does not exist
+// and ErrMonthFinalized when its month is finalized.
+func (s *sqlStore) CreateTransaction(ctx context.Context, t *Transaction) (int64, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, tx.Rebind(`SELECT EXISTS (SELECT 1 FROM budget_lines WHERE id = ? AND deleted_at IS NULL)`), t.BudgetLineID); err != nil {
+		return 0, fmt.Errorf("failed to check budget line %d: %w", t.BudgetLineID, err)
+	}
+	if !exists {
+		return 0, sql.ErrNoRows
+	}
+	if err := checkLineEditable(ctx, tx, t.BudgetLineID); err != nil {
+		return 0, err
+	}
+
+	id, err := insertTransaction(ctx, tx, t)
+	if err != nil {
+		return 0, err
+	}
+	if err := refreshActual(ctx, tx, t.BudgetLineID); err != nil {
+		return 0, err
+	}
+	currency, err := transactionCurrency(ctx, tx, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction %d: %w", id, err)
+	}
+	t.ID, t.Currency = id, currency
+	return id, nil
+}
+
+// UpdateTransaction changes a transaction's date, amount, payee and memo; it
+// stays on its budget line.
+func (s *sqlStore) UpdateTransaction(ctx context.Context, t *Transaction) error {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var budgetLineID int64
+	if err := tx.GetContext(ctx, &budgetLineID, tx.Rebind(liveTransactionLineSQL), t.ID); err != nil {
+		if err == sql.ErrNoRows {
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to load transaction %d: %w", t.ID, err)
+	}
+	if err := checkLineEditable(ctx, tx, budgetLineID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, tx.Rebind(`UPDATE transactions SET date = ?, amount = ?, payee = ?, memo = ? WHERE id = ?`),
+		t.Date.Format(transactionDateLayout), t.Amount, t.Payee, t.Memo, t.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update transaction %d: %w", t.ID, err)
+	}
+	if err := refreshActual(ctx, tx, budgetLineID); err != nil {
+		return err
+	}
+	currency, err := transactionCurrency(ctx, tx, t.ID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction %d: %w", t.ID, err)
+	}
+	t.BudgetLineID, t.Currency = budgetLineID, currency
+	return nil
+}
+
+func (s *sqlStore) DeleteTransaction(ctx context.Context, id int64) error {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var budgetLineID int64
+	if err := tx.GetContext(ctx, &budgetLineID, tx.Rebind(liveTransactionLineSQL), id); err != nil {
+		if err == sql.ErrNoRows {
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to load transaction %d: %w", id, err)
+	}
+	if err := checkLineEditable(ctx, tx, budgetLineID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM transactions WHERE id = ?`), id); err != nil {
+		return fmt.Errorf("failed to delete transaction %d: %w", id, err)
+	}
+	if err := refreshActual(ctx, tx, budgetLineID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit deletion of transaction %d: %w", id, err)
+	}
+	return nil
+}
+
+// transactionCurrency returns the currency a transaction's amount is in.
+func transactionCurrency(ctx context.Context, tx *sqlx.Tx, id int64) (Currency, error) {
+	var currency Currency
+	err := tx.GetContext(ctx, &currency, tx.Rebind(`
+		SELECT `+transactionCurrencySQL+` FROM transactions t JOIN budget_lines bl ON bl.id = t.budget_line_id WHERE t.id = ?`), id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get currency of transaction %d: %w", id, err)
+	}
+	return currency, nil
+}
+
+func insertTransaction(ctx context.Context, tx *sqlx.Tx, t *Transaction) (int64, error) {
+	var id int64
+	err := tx.GetContext(ctx, &id, tx.Rebind(`INSERT INTO transactions (budget_line_id, date, amount, payee, memo) VALUES (?, ?, ?, ?, ?) RETURNING id`),
+		t.BudgetLineID, t.Date.Format(transactionDateLayout), t.Amount, t.Payee, t.Memo)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert transaction for budget line %d: %w", t.BudgetLineID, err)
+	}
+	return id, nil
+}
+
+// refreshActual sets a budget line's actual to the sum of its transactions,
+// creating the actual line in the budget line's currency if it has none, and
+// logs the change to the actual in the audit log.
+func refreshActual(ctx context.Context, tx *sqlx.Tx, budgetLineID int64) error {
+	before, monthID, err := actualOfLine(ctx, tx, budgetLineID)
+	if err != nil {
+		return err
+	}
+	if err := syncActual(ctx, tx, budgetLineID); err != nil {
+		return err
+	}
+	after, _, err := actualOfLine(ctx, tx, budgetLineID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case after == nil:
+		return nil
+	case before == nil:
+		return recordAudit(ctx, tx, AuditEntityActualLine, after.ID, AuditActionCreate, &monthID, nil, *after)
+	case before.Actual != after.Actual:
+		return recordAudit(ctx, tx, AuditEntityActualLine, after.ID, AuditActionUpdate, &monthID, *before, *after)
+	}
+	return nil
+}
+
+// actualOfLine returns a budget line's actual line, or nil when it has none,
+// with the line's month.
+func actualOfLine(ctx context.Context, tx *sqlx.Tx, budgetLineID int64) (*ActualLine, int64, error) {
+	var row struct {
+		ActualLine
+		MonthID int64 `db:"month_id"`
+	}
+	err := tx.GetContext(ctx, &row, tx.Rebind(`
+		SELECT al.id, al.budget_line_id, al.actual, al.currency, bl.month_id
+		FROM actual_lines al JOIN budget_lines bl ON bl.id = al.budget_line_id
+		WHERE al.budget_line_id = ? ORDER BY al.id LIMIT 1`), budgetLineID)
+	if err == sql.ErrNoRows {
+		var monthID int64
+		if err := tx.GetContext(ctx, &monthID, tx.Rebind(`SELECT month_id FROM budget_lines WHERE id = ?`), budgetLineID); err != nil && err != sql.ErrNoRows {
+			return nil, 0, fmt.Errorf("failed to get month of budget line %d: %w", budgetLineID, err)
+		}
+		return nil, monthID, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get actual of budget line %d: %w", budgetLineID, err)
+	}
+	return &row.ActualLine, row.MonthID, nil
+}
+
+// syncActual is refreshActual without the audit entry, for callers that log
+// the change themselves.
+func syncActual(ctx context.Context, tx *sqlx.Tx, budgetLineID int64) error {
+	var total Money
+	if err := tx.GetContext(ctx, &total, tx.Rebind(`SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE budget_line_id = ?`), budgetLineID); err != nil {
+		return fmt.Errorf("failed to sum transactions of budget line %d: %w", budgetLineID, err)
+	}
+	if total < 0 {
+		return fmt.Errorf("budget line %d would total %s: %w", budgetLineID, total, ErrNegativeActual)
+	}
+
+	res, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE actual_lines SET actual = ? WHERE budget_line_id = ?`), total, budgetLineID)
+	if err != nil {
+		return fmt.Errorf("failed to update actual of budget line %d: %w", budgetLineID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected updating actual of budget line %d: %w", budgetLineID, err)
+	} else if n > 0 {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx, tx.Rebind(`INSERT INTO actual_lines (budget_line_id, actual, currency) SELECT id, CAST(? AS BIGINT), currency FROM budget_lines WHERE id = ?`),
+		total, budgetLineID)
+	if err != nil {
+		return fmt.Errorf("failed to create actual of budget line %d: %w", budgetLineID, err)
+	}
+	return nil
+}
+
+// adjustmentDate dates the transaction that brings a line's actual to a total
+// set directly: today, or the last day of the line's month once it has passed.
+func adjustmentDate(ctx context.Context, tx *sqlx.Tx, budgetLineID int64) (time.Time, error) {
+	var period struct {
+		Year  int `db:"year"`
+		Month int `db:"month"`
+	}
+	err := tx.GetContext(ctx, &period, tx.Rebind(`SELECT m.year, m.month FROM budget_lines bl JOIN months m ON m.id = bl.month_id WHERE bl.id = ?`), budgetLineID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get month of budget line %d: %w", budgetLineID, err)
+	}
+	return adjustmentDay(period.Year, period.Month), nil
+}
+
+func adjustmentDay(year, month int) time.Time {
+	first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch {
+	case today.Before(first):
+		return first
+	case today.After(last):
+		return last
+	}
+	return today
+}
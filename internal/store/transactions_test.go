@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// actualOf reads a line's actual from the store's export, which also covers
+// lines in the trash.
+func actualOf(t *testing.T, s Store, budgetLineID int64) Money {
+	t.Helper()
+	for _, actual := range dumpOf(t, s).ActualLines {
+		if actual.BudgetLineID == budgetLineID {
+			return actual.Actual
+		}
+	}
+	t.Fatalf("Budget line %d has no actual line", budgetLineID)
+	return 0
+}
+
+func TestStoreBackends_Transactions(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			blID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00})
+			require.NoError(t, err)
+
+			day := func(d int) time.Time { return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC) }
+			marketID, err := s.CreateTransaction(ctx, &Transaction{BudgetLineID: blID, Date: day(12), Amount: 45_25, Payee: "Market"})
+			require.NoError(t, err)
+			_, err = s.CreateTransaction(ctx, &Transaction{BudgetLineID: blID, Date: day(3), Amount: 20_00, Payee: "Bakery", Memo: "Cake"})
+			require.NoError(t, err)
+			assert.Equal(t, Money(65_25), actualOf(t, s, blID), "the actual is the sum of the transactions")
+
+			refund := &Transaction{BudgetLineID: blID, Date: day(14), Amount: -5_25, Payee: "Market", Memo: "Refund"}
+			_, err = s.CreateTransaction(ctx, refund)
+			require.NoError(t, err)
+			assert.Equal(t, Money(60_00), actualOf(t, s, blID))
+
+			transactions, err := s.GetTransactionsByBudgetLineID(ctx, blID)
+			require.NoError(t, err)
+			require.Len(t, transactions, 3)
+			assert.Equal(t, "Bakery", transactions[0].Payee, "transactions are listed by date")
+			assert.Equal(t, day(3), transactions[0].Date)
+
+			market, err := s.GetTransactionByID(ctx, marketID)
+			require.NoError(t, err)
+			market.Amount, market.Date = 50_00, day(13)
+			require.NoError(t, s.UpdateTransaction(ctx, market))
+			assert.Equal(t, Money(64_75), actualOf(t, s, blID))
+
+			refund.Amount = -100_00
+			assert.ErrorIs(t, s.UpdateTransaction(ctx, refund), ErrNegativeActual)
+			assert.Equal(t, Money(64_75), actualOf(t, s, blID), "a rejected change leaves the actual alone")
+
+			require.NoError(t, s.DeleteTransaction(ctx, marketID))
+			assert.Equal(t, Money(14_75), actualOf(t, s, blID))
+			assert.ErrorIs(t, s.DeleteTransaction(ctx, marketID), sql.ErrNoRows)
+			_, err = s.GetTransactionByID(ctx, marketID)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+
+			_, err = s.CreateTransaction(ctx, &Transaction{BudgetLineID: blID + 100, Date: day(1), Amount: 1_00})
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+			_, err = s.GetTransactionsByBudgetLineID(ctx, blID+100)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+
+			require.NoError(t, s.DeleteBudgetLine(ctx, blID))
+			_, err = s.GetTransactionsByBudgetLineID(ctx, blID)
+			assert.ErrorIs(t, err, sql.ErrNoRows, "a line in the trash hides its transactions")
+			assert.Equal(t, 2, countRows(t, s, "transactions"), "and keeps them until it is purged")
+			_, err = s.PurgeTrash(ctx, time.Now().Add(time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, 0, countRows(t, s, "transactions"))
+		})
+	}
+}
+
+func TestStoreBackends_UpdateActualLineBooksAdjustment(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, err := s.ImportAll(ctx, &DatabaseDump{
+				Months:       []Month{{ID: 1, Year: 2024, Month: 1}},
+				Categories:   []Category{{ID: 1, Name: "Bills", Color: "#f00"}},
+				BudgetLines:  []BudgetLine{{ID: 1, MonthID: 1, CategoryID: 1, Label: "Power", Expected: 80_00}},
+				ActualLines:  []ActualLine{{ID: 1, BudgetLineID: 1, Actual: 30_00}},
+				Transactions: []Transaction{{ID: 1, BudgetLineID: 1, Date: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), Amount: 30_00}},
+			}, ImportModeReplace, false)
+			require.NoError(t, err)
+
+			require.NoError(t, s.UpdateActualLine(ctx, &ActualLine{ID: 1, Actual: 75_50}))
+			transactions, err := s.GetTransactionsByBudgetLineID(ctx, 1)
+			require.NoError(t, err)
+			require.Len(t, transactions, 2)
+			adjustment := transactions[1]
+			assert.Equal(t, Money(45_50), adjustment.Amount)
+			assert.Equal(t, time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC), adjustment.Date, "a past month's adjustment falls on its last day")
+			assert.Equal(t, Money(75_50), actualOf(t, s, 1))
+
+			require.NoError(t, s.UpdateActualLine(ctx, &ActualLine{ID: 1, Actual: 75_50}))
+			assert.Equal(t, 2, countRows(t, s, "transactions"), "an unchanged total books nothing")
+
+			require.NoError(t, s.UpdateActualLine(ctx, &ActualLine{ID: 1, Actual: 0}))
+			assert.Equal(t, Money(0), actualOf(t, s, 1))
+		})
+	}
+}
+
+func TestStoreBackends_UpdateActualLineCurrency(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, err := s.ImportAll(ctx, &DatabaseDump{
+				Months:       []Month{{ID: 1, Year: 2024, Month: 1}},
+				Categories:   []Category{{ID: 1, Name: "Bills", Color: "#f00"}},
+				BudgetLines:  []BudgetLine{{ID: 1, MonthID: 1, CategoryID: 1, Label: "Power", Expected: 80_00}, {ID: 2, MonthID: 1, CategoryID: 1, Label: "Hotel", Expected: 200_00}},
+				ActualLines:  []ActualLine{{ID: 1, BudgetLineID: 1, Actual: 30_00}, {ID: 2, BudgetLineID: 2}},
+				Transactions: []Transaction{{ID: 1, BudgetLineID: 1, Date: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), Amount: 30_00}},
+			}, ImportModeReplace, false)
+			require.NoError(t, err)
+
+			err = s.UpdateActualLine(ctx, &ActualLine{ID: 1, Actual: 30_00, Currency: "EUR"})
+			assert.ErrorIs(t, err, ErrActualCurrencyInUse, "the transactions would change currency unconverted")
+			actual, err := s.GetActualLineByID(ctx, 1)
+			require.NoError(t, err)
+			assert.Equal(t, Currency("USD"), actual.Currency)
+			assert.Equal(t, 1, countRows(t, s, "transactions"))
+
+			require.NoError(t, s.UpdateActualLine(ctx, &ActualLine{ID: 2, Actual: 150_00, Currency: "EUR"}), "a line without transactions can switch")
+			actual, err = s.GetActualLineByID(ctx, 2)
+			require.NoError(t, err)
+			assert.Equal(t, Currency("EUR"), actual.Currency)
+			transactions, err := s.GetTransactionsByBudgetLineID(ctx, 2)
+			require.NoError(t, err)
+			require.Len(t, transactions, 1)
+			assert.Equal(t, Money(150_00), transactions[0].Amount)
+			assert.Equal(t, Currency("EUR"), transactions[0].Currency, "the adjustment is booked in the new currency")
+		})
+	}
+}
+
+func TestMigration006_RecordsOpeningTransactions(t *testing.T) {
+	embedded, err := loadMigrations(embeddedMigrations)
+	require.NoError(t, err)
+	db := newEmptyTestDB(t)
+	require.NoError(t, applyMigrations(db, embedded[:5], MigrationOptions{}))
+
+	_, err = db.Exec(`INSERT INTO months (id, year, month, finalized) VALUES (1, 2024, 2, 0)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO categories (id, name, color) VALUES (1, 'Food', '#fff')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO budget_lines (id, month_id, category_id, label, expected) VALUES (1, 1, 1, 'Groceries', 300_00), (2, 1, 1, 'Snacks', 20_00)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO actual_lines (budget_line_id, actual) VALUES (1, 12_34), (2, 0)`)
+	require.NoError(t, err)
+
+	require.NoError(t, RunMigrations(db))
+
+	s := &sqlStore{DB: db}
+	transactions, err := s.GetTransactionsByBudgetLineID(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, Money(12_34), transactions[0].Amount)
+	assert.Equal(t, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), transactions[0].Date)
+	assert.Equal(t, 1, countRows(t, s, "transactions"), "zero actuals need no transaction")
+}
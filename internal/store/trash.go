@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// trashTimestamp is the deleted_at value of a row moved to the trash now.
+func trashTimestamp() string {
+	return time.Now().UTC().Format(timestampLayout)
+}
+
+// nullableTimestamp formats an optional time for a DATETIME column.
+func nullableTimestamp(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.UTC().Format(timestampLayout)
+	return &s
+}
+
+// GetTrash returns the deleted categories and budget lines, with each line's
+// actual, most recently deleted first.
+func (s *sqlStore) GetTrash(ctx context.Context) (*Trash, error) {
+	trash := &Trash{Categories: []Category{}, BudgetLines: []BudgetLine{}}
+	err := s.DB.SelectContext(ctx, &trash.Categories, `
+		SELECT id, name, color, deleted_at FROM categories
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted categories: %w", err)
+	}
+	err = s.DB.SelectContext(ctx, &trash.BudgetLines, `
+		SELECT
+			bl.id, bl.month_id, bl.category_id, bl.label, bl.expected, bl.currency, bl.deleted_at,
+			al.id AS actual_id, al.actual AS actual_amount, al.currency AS actual_currency
+		FROM budget_lines bl
+		LEFT JOIN actual_lines al ON bl.id = al.budget_line_id
+		WHERE bl.deleted_at IS NOT NULL
+		ORDER BY bl.deleted_at DESC, bl.id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted budget lines: %w", err)
+	}
+	for i := range trash.Categories {
+		*trash.Categories[i].DeletedAt = trash.Categories[i].DeletedAt.UTC()
+	}
+	for i := range trash.BudgetLines {
+		*trash.BudgetLines[i].DeletedAt = trash.BudgetLines[i].DeletedAt.UTC()
+	}
+	return trash, nil
+}
+
+// RestoreCategory takes a category out of the trash. It returns sql.ErrNoRows
+// when the category is not in the trash.
+func (s *sqlStore) RestoreCategory(ctx context.Context, id int64) error {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before Category
+	err = tx.GetContext(ctx, &before, tx.Rebind(`SELECT id, name, color, deleted_at FROM categories WHERE id = ? AND deleted_at IS NOT NULL`), id)
+	if err == sql.ErrNoRows {
+		return sql.ErrNoRows
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get deleted category %d: %w", id, err)
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE categories SET deleted_at = NULL WHERE id = ?`), id); err != nil {
+		return fmt.Errorf("failed to restore category %d: %w", id, err)
+	}
+	*before.DeletedAt = before.DeletedAt.UTC()
+	after := before
+	after.DeletedAt = nil
+	if err := recordAudit(ctx, tx, AuditEntityCategory, id, AuditActionRestore, nil, before, after); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit restore of category %d: %w", id, err)
+	}
+	return nil
+}
+
+// RestoreBudgetLine takes a budget line out of the trash with its actual and
+// transactions. It returns sql.ErrNoRows when the line is not in the trash and
+// ErrCategoryDeleted while the line's category is. A line of a finalized month
+// stays in the trash unless ctx carries WithFinalizedOverride.
+func (s *sqlStore) RestoreBudgetLine(ctx context.Context, id int64) error {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before BudgetLine
+	err = tx.GetContext(ctx, &before, tx.Rebind(`
+		SELECT id, month_id, category_id, label, expected, currency, deleted_at
+		FROM budget_lines WHERE id = ? AND deleted_at IS NOT NULL`), id)
+	if err == sql.ErrNoRows {
+		return sql.ErrNoRows
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get deleted budget line %d: %w", id, err)
+	}
+	var categoryDeleted bool
+	err = tx.GetContext(ctx, &categoryDeleted, tx.Rebind(`SELECT EXISTS (SELECT 1 FROM categories WHERE id = ? AND deleted_at IS NOT NULL)`), before.CategoryID)
+	if err != nil {
+		return fmt.Errorf("failed to check category of budget line %d: %w", id, err)
+	}
+	if categoryDeleted {
+		return fmt.Errorf("budget line %d: %w", id, ErrCategoryDeleted)
+	}
+	if err := checkMonthEditable(ctx, tx, int64(before.MonthID)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE budget_lines SET deleted_at = NULL WHERE id = ?`), id); err != nil {
+		return fmt.Errorf("failed to restore budget line %d: %w", id, err)
+	}
+	*before.DeletedAt = before.DeletedAt.UTC()
+	after := before
+	after.DeletedAt = nil
+	monthID := int64(before.MonthID)
+	if err := recordAudit(ctx, tx, AuditEntityBudgetLine, id, AuditActionRestore, &monthID, before, after); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit restore of budget line %d: %w", id, err)
+	}
+	return nil
+}
+
+// PurgeTrash deletes for good the budget lines, with their actuals and
+// transactions, and the categories that went to the trash before deletedBefore.
+// A category stays while a budget line in the trash still uses it.
+func (s *sqlStore) PurgeTrash(ctx context.Context, deletedBefore time.Time) (*TrashCounts, error) {
+	tx, err := s.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	cutoff := deletedBefore.UTC().Format(timestampLayout)
+	var lines []BudgetLine
+	err = tx.SelectContext(ctx, &lines, tx.Rebind(`
+		SELECT id, month_id, category_id, label, expected, currency, deleted_at
+		FROM budget_lines WHERE deleted_at < ? ORDER BY id`), cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find budget lines to purge: %w", err)
+	}
+	for _, line := range lines {
+		if err := deleteBudgetLineRows(ctx, tx, int64(line.ID)); err != nil {
+			return nil, err
+		}
+		*line.DeletedAt = line.DeletedAt.UTC()
+		monthID := int64(line.MonthID)
+		if err := recordAudit(ctx, tx, AuditEntityBudgetLine, int64(line.ID), AuditActionPurge, &monthID, line, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var categories []Category
+	err = tx.SelectContext(ctx, &categories, tx.Rebind(`
+		SELECT id, name, color, deleted_at FROM categories c
+		WHERE deleted_at < ? AND NOT EXISTS (SELECT 1 FROM budget_lines bl WHERE bl.category_id = c.id)
+		ORDER BY id`), cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find categories to purge: %w", err)
+	}
+	for _, category := range categories {
+		if _, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM categories WHERE id = ?`), category.ID); err != nil {
+			return nil, fmt.Errorf("failed to purge category %d: %w", category.ID, err)
+		}
+		*category.DeletedAt = category.DeletedAt.UTC()
+		if err := recordAudit(ctx, tx, AuditEntityCategory, category.ID, AuditActionPurge, nil, category, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit trash purge: %w", err)
+	}
+	return &TrashCounts{Categories: len(categories), BudgetLines: len(lines)}, nil
+}
+
+// deleteBudgetLineRows deletes a budget line with its actual and transactions.
+func deleteBudgetLineRows(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM transactions WHERE budget_line_id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete transactions for budget line ID %d: %w", id, err)
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM actual_lines WHERE budget_line_id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete actual line for budget line ID %d: %w", id, err)
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM budget_lines WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete budget line with ID %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreBackends_Trash(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			start := time.Now().UTC().Add(-time.Second)
+
+			groceriesID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 1, Label: "Groceries", Expected: 400_00})
+			require.NoError(t, err)
+			powerID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 2, Label: "Power", Expected: 80_00})
+			require.NoError(t, err)
+			_, err = s.CreateTransaction(ctx, &Transaction{BudgetLineID: powerID, Date: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC), Amount: 75_50})
+			require.NoError(t, err)
+
+			require.NoError(t, s.DeleteBudgetLine(ctx, powerID))
+			lines, err := s.GetBudgetLinesByMonthID(ctx, 1)
+			require.NoError(t, err)
+			require.Len(t, lines, 1)
+			assert.Equal(t, groceriesID, int64(lines[0].ID))
+			board, err := s.GetBoardData(ctx, 1)
+			require.NoError(t, err)
+			assert.Len(t, board.BudgetLines, 1)
+			_, err = s.GetBudgetLineByID(ctx, powerID)
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+
+			require.NoError(t, s.DeleteCategory(ctx, 2), "a category whose lines are all in the trash can go too")
+			categories, err := s.GetAllCategories(ctx)
+			require.NoError(t, err)
+			require.Len(t, categories, 1)
+			assert.Equal(t, "Food", categories[0].Name)
+			_, err = s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 2, Label: "Water"})
+			assert.ErrorIs(t, err, ErrCategoryDeleted)
+			assert.ErrorIs(t, s.CreateCategory(ctx, &Category{Name: "Bills", Color: "#00f"}), ErrCategoryNameInTrash, "the name is held by the trashed category")
+			assert.ErrorIs(t, s.UpdateCategory(ctx, &Category{ID: 1, Name: "Bills", Color: "#0f0"}), ErrCategoryNameInTrash, "so is a rename to it")
+			require.NoError(t, s.UpdateCategory(ctx, &Category{ID: 1, Name: "Food", Color: "#0f1"}), "keeping the name is fine")
+
+			trash, err := s.GetTrash(ctx)
+			require.NoError(t, err)
+			require.Len(t, trash.Categories, 1)
+			assert.Equal(t, "Bills", trash.Categories[0].Name)
+			require.NotNil(t, trash.Categories[0].DeletedAt)
+			assert.False(t, trash.Categories[0].DeletedAt.Before(start.Truncate(time.Second)))
+			assert.Equal(t, time.UTC, trash.Categories[0].DeletedAt.Location())
+			require.Len(t, trash.BudgetLines, 1)
+			assert.Equal(t, "Power", trash.BudgetLines[0].Label)
+			assert.Equal(t, Money(75_50), *trash.BudgetLines[0].ActualAmount, "the actual stays with the line")
+
+			assert.ErrorIs(t, s.RestoreBudgetLine(ctx, powerID), ErrCategoryDeleted, "restore the category first")
+			assert.ErrorIs(t, s.RestoreBudgetLine(ctx, groceriesID), sql.ErrNoRows, "not in the trash")
+			assert.ErrorIs(t, s.RestoreCategory(ctx, 1), sql.ErrNoRows, "not in the trash")
+			require.NoError(t, s.RestoreCategory(ctx, 2))
+			require.NoError(t, s.RestoreBudgetLine(ctx, powerID))
+			lines, err = s.GetBudgetLinesByMonthID(ctx, 1)
+			require.NoError(t, err)
+			require.Len(t, lines, 2)
+			assert.Equal(t, Money(75_50), *lines[1].ActualAmount)
+			transactions, err := s.GetTransactionsByBudgetLineID(ctx, powerID)
+			require.NoError(t, err)
+			assert.Len(t, transactions, 1)
+
+			restores, err := s.GetAuditEntries(ctx, AuditFilter{Limit: 2})
+			require.NoError(t, err)
+			require.Len(t, restores, 2)
+			assert.Equal(t, AuditActionRestore, restores[0].Action)
+			assert.Equal(t, AuditEntityBudgetLine, restores[0].Entity)
+			assert.JSONEq(t, `{"id":2,"month_id":1,"category_id":2,"label":"Power","expected":80,"currency":"USD"}`, string(restores[0].After))
+
+			trash, err = s.GetTrash(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, trash.Categories)
+			assert.Empty(t, trash.BudgetLines)
+		})
+	}
+}
+
+func TestStoreBackends_PurgeTrash(t *testing.T) {
+	for name, s := range storeBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			seedBackend(t, s)
+			powerID, err := s.CreateBudgetLine(ctx, &BudgetLine{MonthID: 1, CategoryID: 2, Label: "Power", Expected: 80_00})
+			require.NoError(t, err)
+			require.NoError(t, s.DeleteBudgetLine(ctx, powerID))
+			require.NoError(t, s.DeleteCategory(ctx, 2))
+
+			purged, err := s.PurgeTrash(ctx, time.Now().Add(-time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, &TrashCounts{}, purged, "nothing is old enough")
+
+			purged, err = s.PurgeTrash(ctx, time.Now().Add(time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, &TrashCounts{Categories: 1, BudgetLines: 1}, purged)
+			trash, err := s.GetTrash(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, trash.Categories)
+			assert.Empty(t, trash.BudgetLines)
+			assert.ErrorIs(t, s.RestoreCategory(ctx, 2), sql.ErrNoRows)
+			assert.NoError(t, s.CreateCategory(ctx, &Category{Name: "Bills", Color: "#111"}), "a purged name is free again")
+
+			purges, err := s.GetAuditEntries(ctx, AuditFilter{Limit: 3})
+			require.NoError(t, err)
+			require.Len(t, purges, 3)
+			assert.Equal(t, []string{AuditActionPurge, AuditActionPurge},
+				[]string{purges[1].Action, purges[2].Action})
+			assert.Nil(t, purges[1].After)
+		})
+	}
+}
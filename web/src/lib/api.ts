@@ -213,3 +213,33 @@ export async function getAnnualSnapshots(year: number): Promise<AnnualSnapMeta[]
 export async function getSnapshotDetail(snapId: number): Promise<DashboardPayload> {
   return get<DashboardPayload>(`/reports/snapshots/${snapId}`);
 }
+
+export interface BackupRun {
+  id: number;
+  kind: 'snapshot' | 'json_export';
+  location: string;
+  size_bytes: number;
+  created_at: string;
+  encrypted: boolean;
+}
+
+export interface BackupFile {
+  name: string;
+  size_bytes: number;
+  created_at: string;
+}
+
+export interface BackupListResponse {
+  directory: string;
+  interval_seconds: number;
+  last_backup: BackupRun | null;
+  backups: BackupFile[];
+}
+
+export async function getBackups(): Promise<BackupListResponse> {
+  return get<BackupListResponse>('/backups');
+}
+
+export async function triggerBackup(): Promise<BackupFile> {
+  return post<BackupFile, null>('/backups', null);
+}
@@ -1,29 +1,43 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useCallback } from 'react';
 import Button from '../components/ui/Button';
 import Card from '../components/ui/Card';
 import { textMutedClasses } from '../styles/commonClasses';
-
-const LAST_BACKUP_TIMESTAMP_KEY = 'lastBackupTimestamp';
+import { getBackups, triggerBackup, BackupListResponse } from '../lib/api';
 
 export default function BackupPage() {
-  const [lastBackupDateISO, setLastBackupDateISO] = useState<string | null>(null);
+  const [backupInfo, setBackupInfo] = useState<BackupListResponse | null>(null);
+  const [error, setError] = useState<string | null>(null);
+  const [backingUp, setBackingUp] = useState<boolean>(false);
 
-  useEffect(() => {
-    const timestamp = localStorage.getItem(LAST_BACKUP_TIMESTAMP_KEY);
-    if (timestamp) {
-      const date = new Date(parseInt(timestamp, 10));
-      setLastBackupDateISO(date.toISOString());
+  const loadBackups = useCallback(async () => {
+    try {
+      setBackupInfo(await getBackups());
+      setError(null);
+    } catch (e) {
+      setError(e instanceof Error ? e.message : 'Failed to load backups');
     }
   }, []);
 
+  useEffect(() => {
+    loadBackups();
+  }, [loadBackups]);
+
   const handleExportJson = () => {
-    // Trigger the download
+    // Trigger the download; the server records it as the last backup.
     window.location.href = '/api/v1/export/json';
+    setTimeout(loadBackups, 2000);
+  };
 
-    // Update localStorage
-    const now = new Date();
-    localStorage.setItem(LAST_BACKUP_TIMESTAMP_KEY, now.getTime().toString());
-    setLastBackupDateISO(now.toISOString()); // Update UI immediately with ISO string
+  const handleBackupNow = async () => {
+    setBackingUp(true);
+    try {
+      await triggerBackup();
+      await loadBackups();
+    } catch (e) {
+      setError(e instanceof Error ? e.message : 'Failed to create backup');
+    } finally {
+      setBackingUp(false);
+    }
   };
 
   const calculateDaysAgo = (isoDateString: string | null): string => {
@@ -46,11 +60,13 @@ export default function BackupPage() {
   };
 
   const displayLastBackupInfo = () => {
-    if (!lastBackupDateISO) {
+    const lastBackup = backupInfo?.last_backup;
+    if (!lastBackup) {
       return 'No backup performed yet.';
     }
-    const dateObj = new Date(lastBackupDateISO);
-    return `Last backup: ${dateObj.toLocaleDateString()} (${calculateDaysAgo(lastBackupDateISO)})`;
+    const dateObj = new Date(lastBackup.created_at);
+    const kind = lastBackup.kind === 'snapshot' ? 'database copy' : 'JSON export';
+    return `Last backup: ${dateObj.toLocaleDateString()} (${calculateDaysAgo(lastBackup.created_at)}, ${kind})`;
   };
 
   return (
@@ -71,6 +87,29 @@ export default function BackupPage() {
           <p className={`mt-4 text-sm ${textMutedClasses}`}>
             {displayLastBackupInfo()}
           </p>
+          {error && <p className="mt-2 text-sm text-red-400">{error}</p>}
+        </div>
+      </Card>
+      <Card className="max-w-md mx-auto mt-6">
+        <div className="p-6">
+          <h2 className="text-xl font-semibold mb-4 text-gray-200">Database Backups</h2>
+          <p className={`mb-4 ${textMutedClasses}`}>
+            Copies of the database are kept in <code>{backupInfo?.directory ?? '…'}</code>.
+          </p>
+          <Button
+            onClick={handleBackupNow}
+            disabled={backingUp}
+            className="w-full bg-blue-600 hover:bg-blue-700"
+          >
+            {backingUp ? 'Backing up…' : 'Back up now'}
+          </Button>
+          <ul className={`mt-4 text-sm ${textMutedClasses}`}>
+            {backupInfo?.backups.map((file) => (
+              <li key={file.name}>
+                {new Date(file.created_at).toLocaleString()} ({Math.ceil(file.size_bytes / 1024)} KB{file.encrypted ? ', encrypted' : ''})
+              </li>
+            ))}
+          </ul>
         </div>
       </Card>
     </div>
@@ -114,7 +114,15 @@ export default function BoardPage() {
             min="1"
           />
         </div>
-        <div>
+        <div className="flex items-center space-x-2">
+          <Button
+            variant="secondary"
+            onClick={() => { window.location.href = `/api/v1/reports/pdf?month_id=${currentMonthId}`; }}
+            disabled={isLoading || !boardData}
+            className="text-sm"
+          >
+            Print Summary (.pdf)
+          </Button>
           <Button
             onClick={handleFinalizeMonth}
             disabled={isFinalizing || isLoading || !boardData?.budget_lines || boardData.budget_lines.length === 0 || boardData?.is_finalized}
@@ -106,6 +106,20 @@ export default function ReportPage() {
               {loadingSnapshots && <Loader2 className="mr-2 h-4 w-4 animate-spin" />}
               Load Snapshots
             </Button>
+            <Button
+              variant="secondary"
+              onClick={() => { window.location.href = `/api/v1/export/xlsx?year=${yearInput}`; }}
+              disabled={yearInput.length !== 4}
+            >
+              Download Workbook (.xlsx)
+            </Button>
+            <Button
+              variant="secondary"
+              onClick={() => { window.location.href = `/api/v1/reports/pdf?year=${yearInput}`; }}
+              disabled={yearInput.length !== 4}
+            >
+              Annual Summary (.pdf)
+            </Button>
           </div>
           {errorSnapshots && !loadingSnapshots && (
              <Alert variant={snapshots.length > 0 ? "destructive" : "info"}>